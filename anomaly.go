@@ -0,0 +1,82 @@
+package main
+
+import (
+	"math"
+	"sort"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultAnomalyFilterSamples = 10
+
+	// minAnomalyFilterSamples is how many recent request rates are needed before any request rate is considered an anomaly
+	minAnomalyFilterSamples = 5
+
+	// minAnomalyFilterRelativeDeviation is the smallest standard deviation the filter assumes, as fraction of the median, so a spike after a flat history is still an anomaly
+	minAnomalyFilterRelativeDeviation = 0.1
+)
+
+// NumberOfAnomalyFilterSamples returns how many recent request rates the anomaly filter compares with; it defaults to 10
+func (c *MIGConfiguration) NumberOfAnomalyFilterSamples() int {
+	if c.AnomalyFilterSamples <= 0 {
+		return defaultAnomalyFilterSamples
+	}
+	return c.AnomalyFilterSamples
+}
+
+// filterAnomaly returns the median of the recent request rates instead of the request rate if it's more than anomalyFilterDeviations standard deviations away from it, so scraping glitches or load test bursts don't drive scaling; the request rate is remembered either way, so a lasting change becomes the new normal after a few iterations. The standard deviation is at least a tenth of the median, so a flat history doesn't let every spike through
+func (s *MIGScaler) filterAnomaly(configItem MIGConfiguration, requestRate float64) float64 {
+
+	if configItem.AnomalyFilterDeviations <= 0 {
+		return requestRate
+	}
+
+	var recent []float64
+	s.withState(configItem, func(state *migState) {
+		recent = append([]float64{}, state.recentRequestRates...)
+		state.recentRequestRates = append(state.recentRequestRates, requestRate)
+		if excess := len(state.recentRequestRates) - configItem.NumberOfAnomalyFilterSamples(); excess > 0 {
+			state.recentRequestRates = state.recentRequestRates[excess:]
+		}
+	})
+
+	if len(recent) < minAnomalyFilterSamples {
+		return requestRate
+	}
+
+	median, standardDeviation := medianAndStandardDeviation(recent)
+	standardDeviation = math.Max(standardDeviation, minAnomalyFilterRelativeDeviation*math.Abs(median))
+	if standardDeviation == 0 || math.Abs(requestRate-median) <= configItem.AnomalyFilterDeviations*standardDeviation {
+		return requestRate
+	}
+
+	log.Warn().Msgf("Request rate %v for mig %v is more than %v standard deviations from the recent median %v, using the median instead", requestRate, configItem.InstanceGroupName, configItem.AnomalyFilterDeviations, median)
+	anomaliesFilteredVector.WithLabelValues(configItem.InstanceGroupName).Inc()
+
+	return median
+}
+
+func medianAndStandardDeviation(values []float64) (median, standardDeviation float64) {
+
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	median = sorted[len(sorted)/2]
+	if len(sorted)%2 == 0 {
+		median = (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+	}
+
+	mean := 0.0
+	for _, value := range values {
+		mean += value
+	}
+	mean /= float64(len(values))
+
+	variance := 0.0
+	for _, value := range values {
+		variance += (value - mean) * (value - mean)
+	}
+	standardDeviation = math.Sqrt(variance / float64(len(values)))
+
+	return
+}
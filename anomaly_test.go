@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterAnomaly(t *testing.T) {
+
+	configItem := MIGConfiguration{InstanceGroupName: "anomalous", AnomalyFilterDeviations: 3, AnomalyFilterSamples: 6}
+
+	t.Run("ReplacesSpikeWithRecentMedian", func(t *testing.T) {
+
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+		for _, requestRate := range []float64{100, 104, 98, 101, 97, 102} {
+			scaler.filterAnomaly(configItem, requestRate)
+		}
+		filtered := testutil.ToFloat64(anomaliesFilteredVector.WithLabelValues("anomalous"))
+
+		// act
+		requestRate := scaler.filterAnomaly(configItem, 2500)
+
+		assert.Equal(t, 100.5, requestRate)
+		assert.Equal(t, filtered+1, testutil.ToFloat64(anomaliesFilteredVector.WithLabelValues("anomalous")))
+	})
+
+	t.Run("AcceptsLastingChangeAfterAFewIterations", func(t *testing.T) {
+
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+		for _, requestRate := range []float64{100, 104, 98, 101, 97, 102} {
+			scaler.filterAnomaly(configItem, requestRate)
+		}
+
+		// act
+		requestRates := []float64{}
+		for i := 0; i < 4; i++ {
+			requestRates = append(requestRates, scaler.filterAnomaly(configItem, 400))
+		}
+
+		assert.Equal(t, []float64{100.5, 400, 400, 400}, requestRates)
+	})
+
+	t.Run("KeepsRequestRateWithoutEnoughRecentRequestRates", func(t *testing.T) {
+
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+		scaler.filterAnomaly(configItem, 100)
+
+		// act
+		requestRate := scaler.filterAnomaly(configItem, 2500)
+
+		assert.Equal(t, float64(2500), requestRate)
+	})
+
+	t.Run("ReplacesSpikeAfterFlatHistory", func(t *testing.T) {
+
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+		for i := 0; i < 6; i++ {
+			scaler.filterAnomaly(configItem, 100)
+		}
+
+		// act
+		requestRates := []float64{scaler.filterAnomaly(configItem, 125), scaler.filterAnomaly(configItem, 2500)}
+
+		assert.Equal(t, []float64{125, 100}, requestRates)
+	})
+}
+
+func TestGetRequestRateWithAnomalyFilter(t *testing.T) {
+
+	t.Run("KeepsPredictedRampUpOfTrendQuery", func(t *testing.T) {
+
+		metricSources := map[string]MetricSource{
+			"prometheus": &fakeMetricSource{requestRates: map[string]float64{
+				"sum(rate(nginx_http_requests_total[10m]))":                             100,
+				"predict_linear(sum(rate(nginx_http_requests_total[5m]))[15m:1m], 300)": 2500,
+			}},
+		}
+		configItem := MIGConfiguration{
+			InstanceGroupName:       "anomalous-trend",
+			RequestRateQuery:        "sum(rate(nginx_http_requests_total[10m]))",
+			TrendQuery:              "predict_linear(sum(rate(nginx_http_requests_total[5m]))[15m:1m], 300)",
+			AnomalyFilterDeviations: 3,
+			AnomalyFilterSamples:    6,
+		}
+		scaler := NewMIGScaler(nil, metricSources, MIGScalerOptions{})
+		for i := 0; i < 6; i++ {
+			scaler.filterAnomaly(configItem, 100)
+		}
+
+		// act
+		requestRate, err := scaler.getRequestRate(context.Background(), configItem)
+
+		assert.Nil(t, err)
+		assert.Equal(t, float64(2500), requestRate)
+	})
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	compute "google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+)
+
+// maxAutoscalerPatchAttempts is how often patching an autoscaler is attempted when it's modified concurrently, rereading it in between
+const maxAutoscalerPatchAttempts = 3
+
+// AutoscalerPatch returns an autoscaler with only the name and the changed fields of its autoscaling policy set, so patching it leaves all other fields, including ones changed by others in the meantime, as they are
+func AutoscalerPatch(name string, updateMin bool, minNumReplicas int64, updateMax bool, maxNumReplicas int64) *compute.Autoscaler {
+
+	patch := &compute.Autoscaler{
+		Name:              name,
+		AutoscalingPolicy: &compute.AutoscalingPolicy{},
+	}
+	if updateMin {
+		patch.AutoscalingPolicy.MinNumReplicas = minNumReplicas
+
+		// a minimum of 0 for scaling to zero would be left out of the request as empty value otherwise
+		patch.AutoscalingPolicy.ForceSendFields = append(patch.AutoscalingPolicy.ForceSendFields, "MinNumReplicas")
+	}
+	if updateMax {
+		patch.AutoscalingPolicy.MaxNumReplicas = maxNumReplicas
+	}
+
+	return patch
+}
+
+// IsConcurrentAutoscalerModification returns whether patching an autoscaler failed because it was modified concurrently; autoscalers have no fingerprint, so the compute api reports this as a conflict, failed precondition or a resource that isn't ready while another operation on it runs
+func IsConcurrentAutoscalerModification(err error) bool {
+
+	apiErr, ok := err.(*googleapi.Error)
+	if !ok {
+		return false
+	}
+	if apiErr.Code == http.StatusConflict || apiErr.Code == http.StatusPreconditionFailed {
+		return true
+	}
+	for _, item := range apiErr.Errors {
+		if item.Reason == "resourceNotReady" || item.Reason == "conditionNotMet" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getAutoscaler rereads the autoscaler by name, to base a retried patch on its current state
+func (s *MIGScaler) getAutoscaler(ctx context.Context, configItem MIGConfiguration, name string) (*Autoscaler, error) {
+	computeClient, err := s.computeClientFor(ctx, configItem)
+	if err != nil {
+		return nil, err
+	}
+	return computeClient.GetAutoscaler(ctx, configItem, name)
+}
+
+// patchAutoscaler patches the autoscaler with the changed fields only
+func (s *MIGScaler) patchAutoscaler(ctx context.Context, configItem MIGConfiguration, patch *compute.Autoscaler) (*compute.Operation, error) {
+	computeClient, err := s.computeClientFor(ctx, configItem)
+	if err != nil {
+		return nil, err
+	}
+	return computeClient.PatchAutoscaler(ctx, configItem, patch)
+}
+
+// findAutoscaler retrieves the autoscaler of the managed instance group by autoscalerName if set, or otherwise by searching for the autoscaler targeting it
+func (s *MIGScaler) findAutoscaler(ctx context.Context, configItem MIGConfiguration, instanceGroupManager *compute.InstanceGroupManager) (*Autoscaler, error) {
+
+	// the autoscaler is retrieved by its cached name, so its policy, mode and status are current
+	if name, ok := s.cache.autoscalerName(configItem, instanceGroupManager.SelfLink, time.Now()); ok {
+		autoscaler, err := s.getAutoscaler(ctx, configItem, name)
+		if err == nil && IsSameResource(autoscaler.Target, instanceGroupManager.SelfLink) {
+			return autoscaler, nil
+		}
+		if err != nil && !isNotFound(err) {
+			return nil, err
+		}
+		// it was deleted or targets another mig by now, so search for it again
+		s.cache.invalidate(autoscalerCacheKey(configItem))
+	}
+
+	autoscaler, err := s.searchAutoscaler(ctx, configItem, instanceGroupManager)
+	if err != nil {
+		s.cache.invalidateOnNotFound(configItem, err)
+		return nil, err
+	}
+
+	s.cache.setAutoscaler(configItem, autoscaler, time.Now())
+
+	return autoscaler, nil
+}
+
+func (s *MIGScaler) searchAutoscaler(ctx context.Context, configItem MIGConfiguration, instanceGroupManager *compute.InstanceGroupManager) (*Autoscaler, error) {
+
+	if configItem.AutoscalerName != "" {
+		autoscaler, err := s.getAutoscaler(ctx, configItem, configItem.AutoscalerName)
+		if err != nil {
+			return nil, err
+		}
+		if !IsSameResource(autoscaler.Target, instanceGroupManager.SelfLink) {
+			return nil, fmt.Errorf("Autoscaler %v targets %v instead of mig %v", configItem.AutoscalerName, autoscaler.Target, configItem.InstanceGroupName)
+		}
+		return autoscaler, nil
+	}
+
+	computeClient, err := s.computeClientFor(ctx, configItem)
+	if err != nil {
+		return nil, err
+	}
+
+	autoscalers, err := computeClient.ListAutoscalers(ctx, configItem, fmt.Sprintf("target eq %v", instanceGroupManager.SelfLink))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(autoscalers) != 1 {
+		return nil, fmt.Errorf("An incorrect number of %v autoscalers for mig %v were retrieved", len(autoscalers), configItem.InstanceGroupName)
+	}
+
+	return autoscalers[0], nil
+}
+
+// IsSameResource returns whether two urls of compute resources refer to the same resource, regardless of the api version in them
+func IsSameResource(a, b string) bool {
+	relative := func(u string) string {
+		if i := strings.Index(u, "/projects/"); i >= 0 {
+			return u[i:]
+		}
+		return u
+	}
+	return relative(a) == relative(b)
+}
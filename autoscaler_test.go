@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	compute "google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+)
+
+func TestAutoscalerPatch(t *testing.T) {
+
+	t.Run("ReturnsPatchWithOnlyMinNumReplicasIncludingZero", func(t *testing.T) {
+
+		// act
+		patch := AutoscalerPatch("web-autoscaler", true, 0, false, 50)
+
+		data, err := json.Marshal(patch)
+		assert.Nil(t, err)
+		assert.JSONEq(t, `{"name":"web-autoscaler","autoscalingPolicy":{"minNumReplicas":0}}`, string(data))
+	})
+
+	t.Run("ReturnsPatchWithMinAndMaxNumReplicas", func(t *testing.T) {
+
+		// act
+		patch := AutoscalerPatch("web-autoscaler", true, 12, true, 50)
+
+		data, err := json.Marshal(patch)
+		assert.Nil(t, err)
+		assert.JSONEq(t, `{"name":"web-autoscaler","autoscalingPolicy":{"minNumReplicas":12,"maxNumReplicas":50}}`, string(data))
+	})
+
+	t.Run("ReturnsPatchWithOnlyMaxNumReplicas", func(t *testing.T) {
+
+		// act
+		patch := AutoscalerPatch("web-autoscaler", false, 12, true, 50)
+
+		data, err := json.Marshal(patch)
+		assert.Nil(t, err)
+		assert.JSONEq(t, `{"name":"web-autoscaler","autoscalingPolicy":{"maxNumReplicas":50}}`, string(data))
+	})
+}
+
+func TestIsConcurrentAutoscalerModification(t *testing.T) {
+
+	t.Run("ReturnsTrueForConflict", func(t *testing.T) {
+
+		// act
+		concurrent := IsConcurrentAutoscalerModification(&googleapi.Error{Code: http.StatusConflict})
+
+		assert.True(t, concurrent)
+	})
+
+	t.Run("ReturnsTrueForResourceNotReady", func(t *testing.T) {
+
+		// act
+		concurrent := IsConcurrentAutoscalerModification(&googleapi.Error{Code: http.StatusBadRequest, Errors: []googleapi.ErrorItem{{Reason: "resourceNotReady"}}})
+
+		assert.True(t, concurrent)
+	})
+
+	t.Run("ReturnsFalseForOtherApiErrors", func(t *testing.T) {
+
+		// act
+		concurrent := IsConcurrentAutoscalerModification(&googleapi.Error{Code: http.StatusBadRequest, Errors: []googleapi.ErrorItem{{Reason: "invalid"}}})
+
+		assert.False(t, concurrent)
+	})
+
+	t.Run("ReturnsFalseForOtherErrors", func(t *testing.T) {
+
+		// act
+		concurrent := IsConcurrentAutoscalerModification(errors.New("connection reset"))
+
+		assert.False(t, concurrent)
+	})
+}
+
+func TestFindAutoscaler(t *testing.T) {
+
+	instanceGroupManager := &compute.InstanceGroupManager{SelfLink: "https://www.googleapis.com/compute/v1/projects/project-id/zones/europe-west1-b/instanceGroupManagers/web"}
+
+	t.Run("GetsAutoscalerByAutoscalerNameWithoutSearching", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/project-id/zones/europe-west1-b/autoscalers/web-autoscaler", r.URL.Path)
+			w.Write([]byte(`{"name":"web-autoscaler","target":"https://www.googleapis.com/compute/v1/projects/project-id/zones/europe-west1-b/instanceGroupManagers/web"}`))
+		}))
+		defer server.Close()
+
+		computeClient, _ := NewGAComputeClient(server.Client(), ComputeClientOptions{BasePath: server.URL + "/"})
+		scaler := NewMIGScaler(computeClient, nil, MIGScalerOptions{})
+		configItem := MIGConfiguration{GCloudProject: "project-id", GCloudZone: "europe-west1-b", InstanceGroupName: "web", AutoscalerName: "web-autoscaler"}
+
+		// act
+		autoscaler, err := scaler.findAutoscaler(context.Background(), configItem, instanceGroupManager)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "web-autoscaler", autoscaler.Name)
+	})
+
+	t.Run("ReturnsErrorIfAutoscalerByAutoscalerNameTargetsAnotherMig", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"name":"web-autoscaler","target":"https://www.googleapis.com/compute/v1/projects/project-id/zones/europe-west1-b/instanceGroupManagers/api"}`))
+		}))
+		defer server.Close()
+
+		computeClient, _ := NewGAComputeClient(server.Client(), ComputeClientOptions{BasePath: server.URL + "/"})
+		scaler := NewMIGScaler(computeClient, nil, MIGScalerOptions{})
+		configItem := MIGConfiguration{GCloudProject: "project-id", GCloudZone: "europe-west1-b", InstanceGroupName: "web", AutoscalerName: "web-autoscaler"}
+
+		// act
+		_, err := scaler.findAutoscaler(context.Background(), configItem, instanceGroupManager)
+
+		assert.EqualError(t, err, "Autoscaler web-autoscaler targets https://www.googleapis.com/compute/v1/projects/project-id/zones/europe-west1-b/instanceGroupManagers/api instead of mig web")
+	})
+
+	t.Run("SearchesAutoscalerTargetingMigIfAutoscalerNameIsNotSet", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/project-id/zones/europe-west1-b/autoscalers", r.URL.Path)
+			assert.Equal(t, "target eq "+instanceGroupManager.SelfLink, r.URL.Query().Get("filter"))
+			w.Write([]byte(`{"items":[{"name":"web-autoscaler"}]}`))
+		}))
+		defer server.Close()
+
+		computeClient, _ := NewGAComputeClient(server.Client(), ComputeClientOptions{BasePath: server.URL + "/"})
+		scaler := NewMIGScaler(computeClient, nil, MIGScalerOptions{})
+		configItem := MIGConfiguration{GCloudProject: "project-id", GCloudZone: "europe-west1-b", InstanceGroupName: "web"}
+
+		// act
+		autoscaler, err := scaler.findAutoscaler(context.Background(), configItem, instanceGroupManager)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "web-autoscaler", autoscaler.Name)
+	})
+}
+
+func TestIsSameResource(t *testing.T) {
+
+	t.Run("ReturnsTrueForUrlsOfDifferentApiVersions", func(t *testing.T) {
+
+		// act
+		same := IsSameResource("https://www.googleapis.com/compute/v1/projects/p/zones/z/instanceGroupManagers/web", "https://www.googleapis.com/compute/beta/projects/p/zones/z/instanceGroupManagers/web")
+
+		assert.True(t, same)
+	})
+
+	t.Run("ReturnsFalseForDifferentResources", func(t *testing.T) {
+
+		// act
+		same := IsSameResource("https://www.googleapis.com/compute/v1/projects/p/zones/z/instanceGroupManagers/web", "https://www.googleapis.com/compute/v1/projects/p/zones/z/instanceGroupManagers/api")
+
+		assert.False(t, same)
+	})
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	onAutoscalerMode           = "ON"
+	offAutoscalerMode          = "OFF"
+	onlyScaleOutAutoscalerMode = "ONLY_SCALE_OUT"
+
+	// onlyUpAutoscalerMode is the deprecated name of ONLY_SCALE_OUT
+	onlyUpAutoscalerMode = "ONLY_UP"
+)
+
+// autoscalerModes are the modes exported for every autoscaler, so a mode changing shows as one series going to 0 and another to 1
+var autoscalerModes = []string{onAutoscalerMode, offAutoscalerMode, onlyScaleOutAutoscalerMode, onlyUpAutoscalerMode}
+
+const (
+	// ignoreAutoscalerModePolicy only exports the mode of the autoscaler
+	ignoreAutoscalerModePolicy = "ignore"
+
+	// warnAutoscalerModePolicy warns about an autoscaler that isn't on; it's the default
+	warnAutoscalerModePolicy = "warn"
+
+	// enforceOnAutoscalerModePolicy sets the mode of the autoscaler back to ON
+	enforceOnAutoscalerModePolicy = "enforceOn"
+)
+
+// autoscalerModePolicies are the supported values for autoscalerModePolicy
+var autoscalerModePolicies = []string{ignoreAutoscalerModePolicy, warnAutoscalerModePolicy, enforceOnAutoscalerModePolicy}
+
+// EffectiveMode returns the mode of the autoscaler, which is ON for autoscalers that predate modes
+func (a *Autoscaler) EffectiveMode() string {
+	if a.Mode == "" {
+		return onAutoscalerMode
+	}
+	return a.Mode
+}
+
+// AutoscalerModeWarning returns why the min instances the scaler sets don't fully take effect in the mode, or empty if they do
+func AutoscalerModeWarning(mode string) string {
+	switch mode {
+	case onAutoscalerMode:
+		return ""
+	case offAutoscalerMode:
+		return "it's off, so its min instances have no effect"
+	case onlyScaleOutAutoscalerMode, onlyUpAutoscalerMode:
+		return "it only scales out, so lowering its min instances doesn't scale in"
+	}
+	return fmt.Sprintf("its mode %v is unknown, so its min instances may not take effect", mode)
+}
+
+// reportAutoscalerMode exports the mode of the autoscaler and, unless autoscalerModePolicy is ignore, warns about it if the min instances the scaler sets don't fully take effect in it; it runs every iteration, also while updates are skipped
+func reportAutoscalerMode(configItem MIGConfiguration, autoScaler *Autoscaler, configRevision string) {
+
+	mode := autoScaler.EffectiveMode()
+	exportAutoscalerMode(configItem.InstanceGroupName, mode)
+
+	warning := AutoscalerModeWarning(mode)
+	if warning == "" || configItem.AutoscalerModePolicy == ignoreAutoscalerModePolicy {
+		return
+	}
+
+	log.Warn().Str("configRevision", configRevision).Msgf("Autoscaler for mig %v is in mode %v: %v", configItem.InstanceGroupName, mode, warning)
+}
+
+// enforceAutoscalerMode sets the mode of the autoscaler back to ON with the enforceOn autoscalerModePolicy if the min instances the scaler sets don't fully take effect in it; it's part of updating the autoscaler, so it doesn't run while updates are skipped
+func (s *MIGScaler) enforceAutoscalerMode(ctx context.Context, configItem MIGConfiguration, autoScaler *Autoscaler, configRevision string) {
+
+	if configItem.AutoscalerModePolicy != enforceOnAutoscalerModePolicy || AutoscalerModeWarning(autoScaler.EffectiveMode()) == "" {
+		return
+	}
+
+	log.Info().Str("configRevision", configRevision).Msgf("Setting mode of autoscaler for mig %v back to %v...", configItem.InstanceGroupName, onAutoscalerMode)
+
+	computeClient, err := s.computeClientFor(ctx, configItem)
+	if err != nil {
+		log.Error().Err(err).Msgf("Creating client for mig %v failed", configItem.InstanceGroupName)
+		return
+	}
+	operation, err := computeClient.PatchAutoscalerMode(ctx, configItem, autoScaler.Name, onAutoscalerMode)
+	if err != nil {
+		log.Error().Err(err).Msgf("Updating mode of autoscaler %v failed", configItem.InstanceGroupName)
+		autoscalerUpdatesVector.WithLabelValues(configItem.InstanceGroupName, failedOperationResult).Inc()
+		s.cache.invalidateOnNotFound(configItem, err)
+		return
+	}
+	if !s.awaitAutoscalerUpdate(ctx, configItem, operation) {
+		return
+	}
+
+	// this updates the cached autoscaler as well
+	autoScaler.Mode = onAutoscalerMode
+	exportAutoscalerMode(configItem.InstanceGroupName, onAutoscalerMode)
+
+	log.Info().Str("configRevision", configRevision).Msgf("Set mode of autoscaler for mig %v back to %v", configItem.InstanceGroupName, onAutoscalerMode)
+}
+
+// exportAutoscalerMode sets the gauge of the mode of the autoscaler to 1 and those of all other modes to 0
+func exportAutoscalerMode(instanceGroupName, mode string) {
+	known := false
+	for _, m := range autoscalerModes {
+		value := 0.0
+		if m == mode {
+			value = 1
+			known = true
+		}
+		autoscalerModeVector.WithLabelValues(instanceGroupName, m).Set(value)
+	}
+	if !known {
+		autoscalerModeVector.WithLabelValues(instanceGroupName, mode).Set(1)
+	}
+}
+
+// validateAutoscalerModePolicy checks whether autoscalerModePolicy is supported
+func (c *MIGConfiguration) validateAutoscalerModePolicy(addError func(field, message string)) {
+	switch c.AutoscalerModePolicy {
+	case "", ignoreAutoscalerModePolicy, warnAutoscalerModePolicy, enforceOnAutoscalerModePolicy:
+	default:
+		addError("autoscalerModePolicy", fmt.Sprintf("should be one of %v", autoscalerModePolicies))
+	}
+}
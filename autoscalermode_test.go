@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	compute "google.golang.org/api/compute/v1"
+)
+
+func TestAutoscalerModeWarning(t *testing.T) {
+
+	t.Run("ReturnsEmptyForModeOn", func(t *testing.T) {
+
+		// act
+		warning := AutoscalerModeWarning("ON")
+
+		assert.Equal(t, "", warning)
+	})
+
+	t.Run("ReturnsWarningForModeOff", func(t *testing.T) {
+
+		// act
+		warning := AutoscalerModeWarning("OFF")
+
+		assert.Equal(t, "it's off, so its min instances have no effect", warning)
+	})
+
+	t.Run("ReturnsWarningForModeOnlyScaleOut", func(t *testing.T) {
+
+		// act
+		warning := AutoscalerModeWarning("ONLY_SCALE_OUT")
+
+		assert.Equal(t, "it only scales out, so lowering its min instances doesn't scale in", warning)
+	})
+}
+
+func TestEffectiveMode(t *testing.T) {
+
+	t.Run("ReturnsOnForAutoscalerWithoutMode", func(t *testing.T) {
+
+		autoScaler := &Autoscaler{Autoscaler: &compute.Autoscaler{}}
+
+		// act
+		mode := autoScaler.EffectiveMode()
+
+		assert.Equal(t, "ON", mode)
+	})
+}
+
+func TestReportAutoscalerMode(t *testing.T) {
+
+	t.Run("ExportsMode", func(t *testing.T) {
+
+		configItem := MIGConfiguration{GCloudProject: "project-id", GCloudZone: "europe-west1-b", InstanceGroupName: "mode-default"}
+		autoScaler := &Autoscaler{Autoscaler: &compute.Autoscaler{Name: "mode-default-autoscaler"}, Mode: "OFF"}
+
+		// act
+		reportAutoscalerMode(configItem, autoScaler, "")
+
+		assert.Equal(t, "OFF", autoScaler.Mode)
+		assert.Equal(t, float64(1), testutil.ToFloat64(autoscalerModeVector.WithLabelValues("mode-default", "OFF")))
+		assert.Equal(t, float64(0), testutil.ToFloat64(autoscalerModeVector.WithLabelValues("mode-default", "ON")))
+	})
+}
+
+func TestEnforceAutoscalerMode(t *testing.T) {
+
+	t.Run("DoesNotUpdateAutoscalerByDefault", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("Unexpected request %v %v", r.Method, r.URL.Path)
+		}))
+		defer server.Close()
+
+		computeClient, _ := NewGAComputeClient(server.Client(), ComputeClientOptions{BasePath: server.URL + "/"})
+		scaler := NewMIGScaler(computeClient, nil, MIGScalerOptions{})
+		configItem := MIGConfiguration{GCloudProject: "project-id", GCloudZone: "europe-west1-b", InstanceGroupName: "mode-default"}
+		autoScaler := &Autoscaler{Autoscaler: &compute.Autoscaler{Name: "mode-default-autoscaler"}, Mode: "OFF"}
+
+		// act
+		scaler.enforceAutoscalerMode(context.Background(), configItem, autoScaler, "")
+
+		assert.Equal(t, "OFF", autoScaler.Mode)
+	})
+
+	t.Run("SetsModeBackToOnWithEnforceOn", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPatch, r.Method)
+			assert.Equal(t, "/project-id/zones/europe-west1-b/autoscalers", r.URL.Path)
+			assert.Equal(t, "mode-enforce-autoscaler", r.URL.Query().Get("autoscaler"))
+			body, _ := ioutil.ReadAll(r.Body)
+			assert.Equal(t, `{"autoscalingPolicy":{"mode":"ON"}}`, string(body))
+			w.Write([]byte(`{"name":"operation-1","status":"DONE"}`))
+		}))
+		defer server.Close()
+
+		computeClient, _ := NewGAComputeClient(server.Client(), ComputeClientOptions{BasePath: server.URL + "/"})
+		scaler := NewMIGScaler(computeClient, nil, MIGScalerOptions{})
+		configItem := MIGConfiguration{GCloudProject: "project-id", GCloudZone: "europe-west1-b", InstanceGroupName: "mode-enforce", AutoscalerModePolicy: "enforceOn"}
+		autoScaler := &Autoscaler{Autoscaler: &compute.Autoscaler{Name: "mode-enforce-autoscaler"}, Mode: "ONLY_SCALE_OUT"}
+
+		// act
+		scaler.enforceAutoscalerMode(context.Background(), configItem, autoScaler, "")
+
+		assert.Equal(t, "ON", autoScaler.Mode)
+		assert.Equal(t, float64(1), testutil.ToFloat64(autoscalerModeVector.WithLabelValues("mode-enforce", "ON")))
+		assert.Equal(t, float64(0), testutil.ToFloat64(autoscalerModeVector.WithLabelValues("mode-enforce", "ONLY_SCALE_OUT")))
+	})
+}
+
+func TestValidateAutoscalerModePolicy(t *testing.T) {
+
+	t.Run("ReturnsErrorForUnsupportedPolicy", func(t *testing.T) {
+
+		configItem := MIGConfiguration{AutoscalerModePolicy: "flip"}
+		fields := []string{}
+
+		// act
+		configItem.validateAutoscalerModePolicy(func(field, message string) {
+			fields = append(fields, field)
+		})
+
+		assert.Equal(t, []string{"autoscalerModePolicy"}, fields)
+	})
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"reflect"
+
+	"github.com/rs/zerolog/log"
+)
+
+// errorAutoscalerStatus is the status of an autoscaler whose configuration has an error
+const errorAutoscalerStatus = "ERROR"
+
+// errorAutoscalerStatusDetailTypes are the status detail types that keep the autoscaler from scaling as it's configured to; other types, like CAPPED_AT_MAX_NUM_REPLICAS or MODE_OFF, are informational
+var errorAutoscalerStatusDetailTypes = map[string]bool{
+	"ALL_INSTANCES_UNHEALTHY":                           true,
+	"BACKEND_SERVICE_DOES_NOT_EXIST":                    true,
+	"CUSTOM_METRIC_DATA_POINTS_TOO_SPARSE":              true,
+	"CUSTOM_METRIC_INVALID":                             true,
+	"MISSING_CUSTOM_METRIC_DATA_POINTS":                 true,
+	"MISSING_LOAD_BALANCING_DATA_POINTS":                true,
+	"MORE_THAN_ONE_BACKEND_SERVICE":                     true,
+	"NOT_ENOUGH_QUOTA_AVAILABLE":                        true,
+	"REGION_RESOURCE_STOCKOUT":                          true,
+	"SCALING_TARGET_DOES_NOT_EXIST":                     true,
+	"UNSUPPORTED_MAX_RATE_LOAD_BALANCING_CONFIGURATION": true,
+	"ZONE_RESOURCE_STOCKOUT":                            true,
+}
+
+// StatusDetails returns the message of every status detail of the autoscaler by its type
+func (a *Autoscaler) StatusDetails() map[string]string {
+	details := map[string]string{}
+	for _, detail := range a.Autoscaler.StatusDetails {
+		details[detail.Type] = detail.Message
+	}
+	return details
+}
+
+// ReportsErrors returns whether the autoscaler has status ERROR or a status detail that keeps it from scaling as it's configured to
+func (a *Autoscaler) ReportsErrors() bool {
+	if a.Status == errorAutoscalerStatus {
+		return true
+	}
+	for _, detail := range a.Autoscaler.StatusDetails {
+		if errorAutoscalerStatusDetailTypes[detail.Type] {
+			return true
+		}
+	}
+	return false
+}
+
+// holdScaleDownOnAutoscalerErrors keeps the min instances of the autoscaler from being lowered while it reports errors, with holdScaleDownOnAutoscalerErrors; an autoscaler that can't tell how many instances are needed, or can't create them, shouldn't lose the floor under it
+func holdScaleDownOnAutoscalerErrors(configItem MIGConfiguration, autoScaler *Autoscaler, minimumNumberOfInstances int) int {
+
+	currentMinimum := int(autoScaler.AutoscalingPolicy.MinNumReplicas)
+	if !configItem.HoldScaleDownOnStatusErrors || minimumNumberOfInstances >= currentMinimum || !autoScaler.ReportsErrors() {
+		return minimumNumberOfInstances
+	}
+
+	log.Info().Msgf("Holding min instances of mig %v at %v instead of lowering it to %v, its autoscaler reports errors", configItem.InstanceGroupName, currentMinimum, minimumNumberOfInstances)
+	scaleDownsHeldVector.WithLabelValues(configItem.InstanceGroupName).Inc()
+
+	return currentMinimum
+}
+
+// reportAutoscalerStatus exports the recommended size and status details of the autoscaler, and logs its status details when they change
+func (s *MIGScaler) reportAutoscalerStatus(configItem MIGConfiguration, autoScaler *Autoscaler) {
+
+	autoscalerRecommendedSizeVector.WithLabelValues(configItem.InstanceGroupName).Set(float64(autoScaler.RecommendedSize))
+
+	details := autoScaler.StatusDetails()
+
+	var previous map[string]string
+	s.withState(configItem, func(state *migState) {
+		previous, state.autoscalerStatusDetails = state.autoscalerStatusDetails, details
+	})
+
+	for detailType := range previous {
+		if _, ok := details[detailType]; !ok {
+			autoscalerStatusDetailsVector.DeleteLabelValues(configItem.InstanceGroupName, detailType)
+		}
+	}
+	for detailType := range details {
+		autoscalerStatusDetailsVector.WithLabelValues(configItem.InstanceGroupName, detailType).Set(1)
+	}
+
+	if reflect.DeepEqual(previous, details) || (len(previous) == 0 && len(details) == 0) {
+		return
+	}
+	for _, detail := range autoScaler.Autoscaler.StatusDetails {
+		if errorAutoscalerStatusDetailTypes[detail.Type] {
+			log.Warn().Str("type", detail.Type).Str("status", autoScaler.Status).Msgf("Autoscaler for mig %v reports: %v", configItem.InstanceGroupName, detail.Message)
+		} else {
+			log.Info().Str("type", detail.Type).Str("status", autoScaler.Status).Msgf("Autoscaler for mig %v reports: %v", configItem.InstanceGroupName, detail.Message)
+		}
+	}
+	if len(details) == 0 {
+		log.Info().Str("status", autoScaler.Status).Msgf("Autoscaler for mig %v no longer reports any status details", configItem.InstanceGroupName)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	compute "google.golang.org/api/compute/v1"
+)
+
+func TestReportsErrors(t *testing.T) {
+
+	t.Run("ReturnsTrueForStatusError", func(t *testing.T) {
+
+		autoScaler := &Autoscaler{Autoscaler: &compute.Autoscaler{Status: "ERROR"}}
+
+		// act
+		reportsErrors := autoScaler.ReportsErrors()
+
+		assert.True(t, reportsErrors)
+	})
+
+	t.Run("ReturnsTrueForErrorStatusDetail", func(t *testing.T) {
+
+		autoScaler := &Autoscaler{Autoscaler: &compute.Autoscaler{
+			Status:        "ACTIVE",
+			StatusDetails: []*compute.AutoscalerStatusDetails{{Type: "NOT_ENOUGH_QUOTA_AVAILABLE", Message: "Quota CPUS exceeded"}},
+		}}
+
+		// act
+		reportsErrors := autoScaler.ReportsErrors()
+
+		assert.True(t, reportsErrors)
+	})
+
+	t.Run("ReturnsFalseForInformationalStatusDetail", func(t *testing.T) {
+
+		autoScaler := &Autoscaler{Autoscaler: &compute.Autoscaler{
+			Status:        "ACTIVE",
+			StatusDetails: []*compute.AutoscalerStatusDetails{{Type: "CAPPED_AT_MAX_NUM_REPLICAS", Message: "The autoscaler is capped at its max instances"}},
+		}}
+
+		// act
+		reportsErrors := autoScaler.ReportsErrors()
+
+		assert.False(t, reportsErrors)
+	})
+}
+
+func TestHoldScaleDownOnAutoscalerErrors(t *testing.T) {
+
+	erroringAutoscaler := &Autoscaler{Autoscaler: &compute.Autoscaler{
+		Status:            "ACTIVE",
+		AutoscalingPolicy: &compute.AutoscalingPolicy{MinNumReplicas: 10},
+		StatusDetails:     []*compute.AutoscalerStatusDetails{{Type: "ALL_INSTANCES_UNHEALTHY"}},
+	}}
+
+	t.Run("HoldsLowerMinimumWhileAutoscalerReportsErrors", func(t *testing.T) {
+
+		configItem := MIGConfiguration{InstanceGroupName: "held", HoldScaleDownOnStatusErrors: true}
+
+		// act
+		minimumNumberOfInstances := holdScaleDownOnAutoscalerErrors(configItem, erroringAutoscaler, 6)
+
+		assert.Equal(t, 10, minimumNumberOfInstances)
+		assert.Equal(t, float64(1), testutil.ToFloat64(scaleDownsHeldVector.WithLabelValues("held")))
+	})
+
+	t.Run("AllowsHigherMinimumWhileAutoscalerReportsErrors", func(t *testing.T) {
+
+		configItem := MIGConfiguration{InstanceGroupName: "raised", HoldScaleDownOnStatusErrors: true}
+
+		// act
+		minimumNumberOfInstances := holdScaleDownOnAutoscalerErrors(configItem, erroringAutoscaler, 12)
+
+		assert.Equal(t, 12, minimumNumberOfInstances)
+	})
+
+	t.Run("AllowsLowerMinimumWithoutHoldScaleDownOnAutoscalerErrors", func(t *testing.T) {
+
+		configItem := MIGConfiguration{InstanceGroupName: "unheld"}
+
+		// act
+		minimumNumberOfInstances := holdScaleDownOnAutoscalerErrors(configItem, erroringAutoscaler, 6)
+
+		assert.Equal(t, 6, minimumNumberOfInstances)
+	})
+}
+
+func TestReportAutoscalerStatus(t *testing.T) {
+
+	t.Run("ExportsRecommendedSizeAndCurrentStatusDetailsOnly", func(t *testing.T) {
+
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+		configItem := MIGConfiguration{InstanceGroupName: "status"}
+		scaler.reportAutoscalerStatus(configItem, &Autoscaler{
+			Autoscaler:      &compute.Autoscaler{StatusDetails: []*compute.AutoscalerStatusDetails{{Type: "ZONE_RESOURCE_STOCKOUT", Message: "Zone is out of resources"}}},
+			RecommendedSize: 7,
+		})
+
+		// act
+		scaler.reportAutoscalerStatus(configItem, &Autoscaler{
+			Autoscaler:      &compute.Autoscaler{StatusDetails: []*compute.AutoscalerStatusDetails{{Type: "CAPPED_AT_MAX_NUM_REPLICAS", Message: "Capped at max instances"}}},
+			RecommendedSize: 9,
+		})
+
+		assert.Equal(t, float64(9), testutil.ToFloat64(autoscalerRecommendedSizeVector.WithLabelValues("status")))
+		assert.Equal(t, float64(1), testutil.ToFloat64(autoscalerStatusDetailsVector.WithLabelValues("status", "CAPPED_AT_MAX_NUM_REPLICAS")))
+		assert.False(t, autoscalerStatusDetailsVector.DeleteLabelValues("status", "ZONE_RESOURCE_STOCKOUT"))
+	})
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+// defaultBigQueryRefreshSeconds is how long the result of a bigquery query is reused when bigqueryRefreshSeconds isn't set, since running it every iteration adds up in query costs
+const defaultBigQueryRefreshSeconds = 300
+
+// BigQueryMetricSource retrieves request rates by running standard sql queries in bigquery that return a single numeric value, enabling scaling on business metrics
+type BigQueryMetricSource struct {
+	service *bigquery.Service
+
+	mutex   sync.Mutex
+	results map[string]bigQueryResult
+}
+
+type bigQueryResult struct {
+	value     float64
+	queriedAt time.Time
+}
+
+// NewBigQueryMetricSource returns a bigquery metric source using the authorized google client
+func NewBigQueryMetricSource(client *http.Client) (*BigQueryMetricSource, error) {
+	service, err := bigquery.New(client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BigQueryMetricSource{
+		service: service,
+		results: map[string]bigQueryResult{},
+	}, nil
+}
+
+// GetRequestRate runs the request rate query in bigqueryProject, which defaults to the project of the managed instance group, at most once every bigqueryRefreshSeconds and returns the value of the first column of the first row
+func (s *BigQueryMetricSource) GetRequestRate(ctx context.Context, configItem MIGConfiguration) (float64, error) {
+
+	refreshSeconds := configItem.BigQueryRefreshSeconds
+	if refreshSeconds <= 0 {
+		refreshSeconds = defaultBigQueryRefreshSeconds
+	}
+
+	project := configItem.BigQueryProject
+	if project == "" {
+		project = configItem.GCloudProject
+	}
+
+	// results are shared by managed instance groups running the same query in the same project, and a changed query is run right away
+	key := project + "|" + configItem.RequestRateQuery
+
+	s.mutex.Lock()
+	result, ok := s.results[key]
+	s.mutex.Unlock()
+	if ok && time.Since(result.queriedAt) < time.Duration(refreshSeconds)*time.Second {
+		return result.value, nil
+	}
+
+	useLegacySQL := false
+	response, err := s.service.Jobs.Query(project, &bigquery.QueryRequest{
+		Query:        configItem.RequestRateQuery,
+		UseLegacySql: &useLegacySQL,
+		TimeoutMs:    30000,
+	}).Context(ctx).Do()
+	if err != nil {
+		return 0, fmt.Errorf("Executing bigquery query failed: %v", err)
+	}
+
+	value, err := GetBigQueryValue(response)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mutex.Lock()
+	s.results[key] = bigQueryResult{value: value, queriedAt: time.Now()}
+	s.mutex.Unlock()
+
+	return value, nil
+}
+
+// GetBigQueryValue returns the first column of the first row of a query response as float64; bigquery returns all values as string
+func GetBigQueryValue(response *bigquery.QueryResponse) (float64, error) {
+
+	if !response.JobComplete {
+		return 0, errors.New("Bigquery query didn't complete within 30 seconds")
+	}
+	if len(response.Rows) == 0 || len(response.Rows[0].F) == 0 {
+		return 0, errEmptyResponse
+	}
+
+	value, ok := response.Rows[0].F[0].V.(string)
+	if !ok {
+		return 0, errors.New("Bigquery query returned null")
+	}
+
+	return strconv.ParseFloat(value, 64)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+func TestGetBigQueryValue(t *testing.T) {
+
+	t.Run("ReturnsFirstColumnOfFirstRowAsFloat64", func(t *testing.T) {
+
+		response := &bigquery.QueryResponse{
+			JobComplete: true,
+			Rows: []*bigquery.TableRow{
+				&bigquery.TableRow{F: []*bigquery.TableCell{&bigquery.TableCell{V: "42.5"}}},
+			},
+		}
+
+		// act
+		value, err := GetBigQueryValue(response)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 42.5, value)
+	})
+
+	t.Run("ReturnsErrorIfJobIsNotComplete", func(t *testing.T) {
+
+		response := &bigquery.QueryResponse{JobComplete: false}
+
+		// act
+		_, err := GetBigQueryValue(response)
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestBigQueryMetricSourceGetRequestRate(t *testing.T) {
+
+	newSource := func(t *testing.T, queries map[string]int) (*BigQueryMetricSource, func()) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var request bigquery.QueryRequest
+			json.NewDecoder(r.Body).Decode(&request)
+			queries[r.URL.Path+"|"+request.Query]++
+			w.Write([]byte(`{"jobComplete":true,"rows":[{"f":[{"v":"42"}]}]}`))
+		}))
+		source, err := NewBigQueryMetricSource(server.Client())
+		assert.Nil(t, err)
+		source.service.BasePath = server.URL + "/"
+		return source, server.Close
+	}
+
+	t.Run("RunsQueryOnceWithinRefreshForMigsSharingIt", func(t *testing.T) {
+
+		queries := map[string]int{}
+		source, closeServer := newSource(t, queries)
+		defer closeServer()
+
+		// act
+		_, errA := source.GetRequestRate(context.Background(), MIGConfiguration{InstanceGroupName: "web", GCloudProject: "project-id", RequestRateQuery: "SELECT 42"})
+		_, errB := source.GetRequestRate(context.Background(), MIGConfiguration{InstanceGroupName: "api", GCloudProject: "project-id", RequestRateQuery: "SELECT 42"})
+
+		assert.Nil(t, errA)
+		assert.Nil(t, errB)
+		assert.Equal(t, map[string]int{"/projects/project-id/queries|SELECT 42": 1}, queries)
+	})
+
+	t.Run("RunsQueryOfOtherProjectOrChangedQueryRightAway", func(t *testing.T) {
+
+		queries := map[string]int{}
+		source, closeServer := newSource(t, queries)
+		defer closeServer()
+
+		// act
+		source.GetRequestRate(context.Background(), MIGConfiguration{InstanceGroupName: "web", GCloudProject: "project-id", RequestRateQuery: "SELECT 42"})
+		source.GetRequestRate(context.Background(), MIGConfiguration{InstanceGroupName: "web", GCloudProject: "other-project-id", RequestRateQuery: "SELECT 42"})
+		source.GetRequestRate(context.Background(), MIGConfiguration{InstanceGroupName: "web", GCloudProject: "project-id", RequestRateQuery: "SELECT 43"})
+
+		assert.Equal(t, map[string]int{"/projects/project-id/queries|SELECT 42": 1, "/projects/other-project-id/queries|SELECT 42": 1, "/projects/project-id/queries|SELECT 43": 1}, queries)
+	})
+}
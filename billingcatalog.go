@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// BillingCatalog holds the prices of the skus of a Cloud Billing catalog service, like 6F81-5844-456A for Compute Engine, to estimate the hourly cost of instances with
+type BillingCatalog struct {
+	APIURL    string
+	ServiceID string
+	client    *http.Client
+
+	mu     sync.RWMutex
+	prices map[string]float64
+}
+
+// NewBillingCatalog returns the billing catalog of a service, retrieved with the authenticated google client; its prices are only available after a refresh
+func NewBillingCatalog(client *http.Client, serviceID string) *BillingCatalog {
+	return &BillingCatalog{
+		APIURL:    "https://cloudbilling.googleapis.com",
+		ServiceID: serviceID,
+		client:    client,
+	}
+}
+
+type billingSkuList struct {
+	Skus []struct {
+		SkuID       string `json:"skuId"`
+		PricingInfo []struct {
+			PricingExpression struct {
+				TieredRates []struct {
+					StartUsageAmount float64 `json:"startUsageAmount"`
+					UnitPrice        struct {
+						Units string `json:"units"`
+						Nanos int64  `json:"nanos"`
+					} `json:"unitPrice"`
+				} `json:"tieredRates"`
+			} `json:"pricingExpression"`
+		} `json:"pricingInfo"`
+	} `json:"skus"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+// Refresh retrieves all skus of the service and replaces the prices with their current price in usd; the price of a sku with tiered rates is the one of the tier with the highest start usage amount, whatever the order they're listed in. On failure the previous prices are kept
+func (c *BillingCatalog) Refresh(ctx context.Context) error {
+
+	prices := map[string]float64{}
+	pageToken := ""
+
+	for {
+		skuList, err := c.getSkus(ctx, pageToken)
+		if err != nil {
+			return err
+		}
+
+		for _, sku := range skuList.Skus {
+			if len(sku.PricingInfo) == 0 {
+				continue
+			}
+			tieredRates := sku.PricingInfo[0].PricingExpression.TieredRates
+			if len(tieredRates) == 0 {
+				continue
+			}
+			highest := 0
+			for i, tieredRate := range tieredRates {
+				if tieredRate.StartUsageAmount > tieredRates[highest].StartUsageAmount {
+					highest = i
+				}
+			}
+			unitPrice := tieredRates[highest].UnitPrice
+			units, err := strconv.ParseInt(unitPrice.Units, 10, 64)
+			if err != nil && unitPrice.Units != "" {
+				return fmt.Errorf("Price of sku %v has invalid units %v", sku.SkuID, unitPrice.Units)
+			}
+			prices[sku.SkuID] = float64(units) + float64(unitPrice.Nanos)/1e9
+		}
+
+		if skuList.NextPageToken == "" {
+			break
+		}
+		pageToken = skuList.NextPageToken
+	}
+
+	c.mu.Lock()
+	c.prices = prices
+	c.mu.Unlock()
+
+	return nil
+}
+
+func (c *BillingCatalog) getSkus(ctx context.Context, pageToken string) (skuList billingSkuList, err error) {
+
+	query := url.Values{}
+	query.Set("currencyCode", "USD")
+	if pageToken != "" {
+		query.Set("pageToken", pageToken)
+	}
+
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%v/v1/services/%v/skus?%v", c.APIURL, c.ServiceID, query.Encode()), nil)
+	if err != nil {
+		return
+	}
+
+	resp, err := c.client.Do(request.WithContext(ctx))
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return skuList, fmt.Errorf("Cloud Billing catalog returned status code %v", resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return
+	}
+
+	err = json.Unmarshal(body, &skuList)
+
+	return
+}
+
+// RunBillingCatalogRefresh refreshes the billing catalog at every interval until the context is cancelled
+func RunBillingCatalogRefresh(ctx context.Context, catalog *BillingCatalog, interval time.Duration) {
+
+	for {
+		if err := catalog.Refresh(ctx); err != nil {
+			log.Error().Err(err).Msg("Refreshing billing catalog failed, keeping previously retrieved prices")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// HourlyCost returns the sum of the price of every sku times its quantity, or false if the price of any of them isn't known
+func (c *BillingCatalog) HourlyCost(skus []InstanceCostSku) (hourlyCost float64, ok bool) {
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, sku := range skus {
+		price, ok := c.prices[sku.SkuID]
+		if !ok {
+			return 0, false
+		}
+		hourlyCost += price * sku.Quantity
+	}
+
+	return hourlyCost, true
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBillingCatalogRefresh(t *testing.T) {
+
+	t.Run("RetrievesPricesOfAllPages", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/services/6F81-5844-456A/skus", r.URL.Path)
+			assert.Equal(t, "USD", r.URL.Query().Get("currencyCode"))
+			if r.URL.Query().Get("pageToken") == "" {
+				w.Write([]byte(`{"skus":[{"skuId":"CP-CORE","pricingInfo":[{"pricingExpression":{"usageUnit":"h","tieredRates":[{"startUsageAmount":0,"unitPrice":{"currencyCode":"USD","units":"0","nanos":31611000}}]}}]}],"nextPageToken":"page-2"}`))
+				return
+			}
+			w.Write([]byte(`{"skus":[{"skuId":"CP-RAM","pricingInfo":[{"pricingExpression":{"usageUnit":"GiBy.h","tieredRates":[{"startUsageAmount":0,"unitPrice":{"currencyCode":"USD","nanos":0}},{"startUsageAmount":1,"unitPrice":{"currencyCode":"USD","units":"0","nanos":4237000}}]}}]}]}`))
+		}))
+		defer server.Close()
+
+		catalog := NewBillingCatalog(server.Client(), "6F81-5844-456A")
+		catalog.APIURL = server.URL
+
+		// act
+		err := catalog.Refresh(context.Background())
+
+		assert.Nil(t, err)
+		hourlyCost, ok := catalog.HourlyCost([]InstanceCostSku{{SkuID: "CP-CORE", Quantity: 4}, {SkuID: "CP-RAM", Quantity: 16}})
+		assert.True(t, ok)
+		assert.InDelta(t, 0.194236, hourlyCost, 0.0000001)
+	})
+
+	t.Run("UsesPriceOfTierWithHighestStartUsageAmountInAnyOrder", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"skus":[{"skuId":"CP-RAM","pricingInfo":[{"pricingExpression":{"tieredRates":[{"startUsageAmount":1,"unitPrice":{"units":"0","nanos":4237000}},{"startUsageAmount":0,"unitPrice":{"nanos":0}}]}}]}]}`))
+		}))
+		defer server.Close()
+
+		catalog := NewBillingCatalog(server.Client(), "6F81-5844-456A")
+		catalog.APIURL = server.URL
+
+		// act
+		err := catalog.Refresh(context.Background())
+
+		assert.Nil(t, err)
+		hourlyCost, ok := catalog.HourlyCost([]InstanceCostSku{{SkuID: "CP-RAM", Quantity: 1}})
+		assert.True(t, ok)
+		assert.InDelta(t, 0.004237, hourlyCost, 0.0000001)
+	})
+
+	t.Run("KeepsPreviousPricesOnFailure", func(t *testing.T) {
+
+		failing := false
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if failing {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`{"skus":[{"skuId":"CP-CORE","pricingInfo":[{"pricingExpression":{"tieredRates":[{"unitPrice":{"units":"1","nanos":500000000}}]}}]}]}`))
+		}))
+		defer server.Close()
+
+		catalog := NewBillingCatalog(server.Client(), "6F81-5844-456A")
+		catalog.APIURL = server.URL
+		catalog.Refresh(context.Background())
+		failing = true
+
+		// act
+		err := catalog.Refresh(context.Background())
+
+		assert.NotNil(t, err)
+		hourlyCost, ok := catalog.HourlyCost([]InstanceCostSku{{SkuID: "CP-CORE", Quantity: 2}})
+		assert.True(t, ok)
+		assert.Equal(t, 3.0, hourlyCost)
+	})
+}
+
+func TestBillingCatalogHourlyCost(t *testing.T) {
+
+	t.Run("ReturnsFalseForUnknownSku", func(t *testing.T) {
+
+		catalog := &BillingCatalog{prices: map[string]float64{"CP-CORE": 0.03}}
+
+		// act
+		_, ok := catalog.HourlyCost([]InstanceCostSku{{SkuID: "CP-CORE", Quantity: 4}, {SkuID: "CP-RAM", Quantity: 16}})
+
+		assert.False(t, ok)
+	})
+}
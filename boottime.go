@@ -0,0 +1,22 @@
+package main
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// applyBootTimeLead inflates a rising request rate to what it will be bootTimeLeadSeconds from now if it keeps rising as fast as since the previous iteration, so new instances are healthy by the time the traffic arrives instead of after
+func (s *MIGScaler) applyBootTimeLead(configItem MIGConfiguration, requestRate float64, now time.Time) float64 {
+
+	changePerSecond, ok := s.observeRequestRate(configItem, requestRate, now)
+	if configItem.BootTimeLeadSeconds <= 0 || !ok || changePerSecond <= 0 {
+		return requestRate
+	}
+
+	leadRequestRate := requestRate + changePerSecond*float64(configItem.BootTimeLeadSeconds)
+
+	log.Info().Msgf("Request rate for mig %v is rising by %.2f per second, using %v instead of %v to have instances booted in time", configItem.InstanceGroupName, changePerSecond, leadRequestRate, requestRate)
+
+	return leadRequestRate
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyBootTimeLead(t *testing.T) {
+
+	configItem := MIGConfiguration{InstanceGroupName: "instance-group-name", BootTimeLeadSeconds: 360}
+	start := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("InflatesRisingRequestRate", func(t *testing.T) {
+
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+		scaler.applyBootTimeLead(configItem, 100, start)
+
+		// act
+		requestRate := scaler.applyBootTimeLead(configItem, 160, start.Add(time.Minute))
+
+		assert.Equal(t, float64(520), requestRate)
+	})
+
+	t.Run("KeepsFallingRequestRate", func(t *testing.T) {
+
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+		scaler.applyBootTimeLead(configItem, 160, start)
+
+		// act
+		requestRate := scaler.applyBootTimeLead(configItem, 100, start.Add(time.Minute))
+
+		assert.Equal(t, float64(100), requestRate)
+	})
+
+	t.Run("KeepsRequestRateOfFirstIteration", func(t *testing.T) {
+
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+
+		// act
+		requestRate := scaler.applyBootTimeLead(configItem, 100, start)
+
+		assert.Equal(t, float64(100), requestRate)
+	})
+}
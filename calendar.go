@@ -0,0 +1,416 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// maxCalendarFeedSize is the maximum size of the calendar feed, so a wrong url can't exhaust the memory of the scaler
+	maxCalendarFeedSize = 10 * 1024 * 1024
+
+	// calendarRecurrenceHorizon is how far ahead recurring events are expanded at every refresh
+	calendarRecurrenceHorizon = 31 * 24 * time.Hour
+)
+
+// calendarTagRegex matches the tags in the summary or description of a calendar event that raise the minimum number of instances of a managed instance group, like mig:instance-group-name=40
+var calendarTagRegex = regexp.MustCompile(`mig:([a-z0-9-]+)=(\d+)`)
+
+// CalendarEvent raises the minimum number of instances of the managed instance groups it's tagged with between start and end
+type CalendarEvent struct {
+	Start    time.Time
+	End      time.Time
+	Minimums map[string]int
+}
+
+// Calendar is an iCalendar feed, like the secret ical address of a google calendar, whose events raise the minimum number of instances of managed instance groups
+type Calendar struct {
+	URL    string
+	client *http.Client
+
+	mu     sync.RWMutex
+	events []CalendarEvent
+}
+
+// NewCalendar returns a calendar for the iCalendar feed url; its events are only available after a refresh
+func NewCalendar(url string) *Calendar {
+	return &Calendar{
+		URL:    url,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Refresh retrieves the feed and replaces the events with the ones it contains, with recurring events expanded for the next calendarRecurrenceHorizon; on failure the previous events are kept
+func (c *Calendar) Refresh(ctx context.Context) error {
+
+	request, err := http.NewRequest(http.MethodGet, c.URL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.Do(request.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Calendar feed returned status code %v", resp.StatusCode)
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxCalendarFeedSize+1))
+	if err != nil {
+		return err
+	}
+	if len(data) > maxCalendarFeedSize {
+		return fmt.Errorf("Calendar feed is larger than %v bytes", maxCalendarFeedSize)
+	}
+
+	now := time.Now()
+	events, err := ParseICalendar(data, now, now.Add(calendarRecurrenceHorizon))
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.events = events
+	c.mu.Unlock()
+
+	return nil
+}
+
+// RunCalendarRefresh refreshes the calendar every interval, until the context is cancelled
+func RunCalendarRefresh(ctx context.Context, calendar *Calendar, interval time.Duration) {
+
+	for {
+		if err := calendar.Refresh(ctx); err != nil {
+			log.Error().Err(err).Msg("Refreshing calendar failed, keeping previously retrieved events")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// MinimumNumberOfInstances returns the highest minimum number of instances of the events for the managed instance group that are going on at the time, and 0 if there are none
+func (c *Calendar) MinimumNumberOfInstances(instanceGroupName string, now time.Time) (minimumNumberOfInstances int) {
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, event := range c.events {
+		if now.Before(event.Start) || !now.Before(event.End) {
+			continue
+		}
+		if minimum := event.Minimums[instanceGroupName]; minimum > minimumNumberOfInstances {
+			minimumNumberOfInstances = minimum
+		}
+	}
+
+	return
+}
+
+// applyCalendar raises the minimum number of instances to the minimum of the calendar events going on for the managed instance group
+func (s *MIGScaler) applyCalendar(configItem MIGConfiguration, minimumNumberOfInstances int, now time.Time) int {
+
+	if s.options.Calendar == nil {
+		return minimumNumberOfInstances
+	}
+
+	scheduled := s.options.Calendar.MinimumNumberOfInstances(configItem.InstanceGroupName, now)
+	if scheduled <= minimumNumberOfInstances {
+		return minimumNumberOfInstances
+	}
+
+	log.Info().Msgf("Raising min instances for mig %v from %v to calendar event minimum %v", configItem.InstanceGroupName, minimumNumberOfInstances, scheduled)
+
+	return scheduled
+}
+
+// icalendarEvent is a VEVENT as it's parsed, before recurring events are expanded into their occurrences
+type icalendarEvent struct {
+	uid          string
+	start        time.Time
+	end          time.Time
+	duration     time.Duration
+	hasDuration  bool
+	isDate       bool
+	text         string
+	cancelled    bool
+	rule         string
+	exceptions   []time.Time
+	recurrenceID time.Time
+	err          error
+}
+
+// ParseICalendar returns the events of an iCalendar feed that are tagged with mig:instance-group-name=minimum in their summary or description; recurring events are expanded into their occurrences that end after from and start before until, leaving out the ones excluded with EXDATE or replaced with RECURRENCE-ID, and cancelled events are left out. Events that can't be parsed, like ones with an unknown TZID or an unsupported recurrence rule, are skipped with a warning
+func ParseICalendar(data []byte, from, until time.Time) (events []CalendarEvent, err error) {
+
+	lines, err := unfoldICalendarLines(data)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed := []*icalendarEvent{}
+	var event *icalendarEvent
+
+	for _, line := range lines {
+
+		name, params, value := parseICalendarProperty(line)
+
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			event = &icalendarEvent{}
+
+		case event == nil:
+			continue
+
+		case name == "END" && value == "VEVENT":
+			if event.err != nil {
+				log.Warn().Err(event.err).Msgf("Skipping calendar event %v", strings.TrimSpace(event.text))
+			} else {
+				parsed = append(parsed, event)
+			}
+			event = nil
+
+		case event.err != nil:
+			continue
+
+		case name == "UID":
+			event.uid = value
+
+		case name == "DTSTART":
+			if event.start, event.err = parseICalendarTime(params, value); event.err != nil {
+				event.err = fmt.Errorf("Parsing start %v of calendar event failed: %v", value, event.err)
+			}
+			event.isDate = params["VALUE"] == "DATE"
+
+		case name == "DTEND":
+			if event.end, event.err = parseICalendarTime(params, value); event.err != nil {
+				event.err = fmt.Errorf("Parsing end %v of calendar event failed: %v", value, event.err)
+			}
+
+		case name == "DURATION":
+			if event.duration, event.err = parseICalendarDuration(value); event.err != nil {
+				event.err = fmt.Errorf("Parsing duration %v of calendar event failed: %v", value, event.err)
+			}
+			event.hasDuration = true
+
+		case name == "RRULE":
+			event.rule = value
+
+		case name == "EXDATE":
+			for _, exception := range strings.Split(value, ",") {
+				exceptionTime, err := parseICalendarTime(params, exception)
+				if err != nil {
+					event.err = fmt.Errorf("Parsing excluded date %v of calendar event failed: %v", exception, err)
+					break
+				}
+				event.exceptions = append(event.exceptions, exceptionTime)
+			}
+
+		case name == "RECURRENCE-ID":
+			if event.recurrenceID, event.err = parseICalendarTime(params, value); event.err != nil {
+				event.err = fmt.Errorf("Parsing recurrence id %v of calendar event failed: %v", value, event.err)
+			}
+
+		case name == "SUMMARY" || name == "DESCRIPTION":
+			event.text += " " + unescapeICalendarText(value)
+
+		case name == "STATUS":
+			event.cancelled = value == "CANCELLED"
+		}
+	}
+
+	// occurrences of recurring events that were modified or cancelled are replaced by the events with their recurrence id
+	replaced := map[string][]time.Time{}
+	for _, event := range parsed {
+		if !event.recurrenceID.IsZero() {
+			replaced[event.uid] = append(replaced[event.uid], event.recurrenceID)
+		}
+	}
+
+	for _, event := range parsed {
+
+		minimums := parseCalendarTags(event.text)
+		if event.cancelled || len(minimums) == 0 {
+			continue
+		}
+
+		duration := event.end.Sub(event.start)
+		switch {
+		case event.hasDuration:
+			duration = event.duration
+		case event.end.IsZero() && event.isDate:
+			duration = 24 * time.Hour
+		}
+		if duration <= 0 {
+			continue
+		}
+
+		if event.rule == "" || !event.recurrenceID.IsZero() {
+			events = append(events, CalendarEvent{Start: event.start, End: endOfCalendarEvent(event, event.start, duration), Minimums: minimums})
+			continue
+		}
+
+		rule, err := ParseRecurrenceRule(event.rule)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Skipping recurring calendar event %v", strings.TrimSpace(event.text))
+			continue
+		}
+		excluded := append(event.exceptions, replaced[event.uid]...)
+		for _, start := range rule.Occurrences(event.start, until) {
+			end := endOfCalendarEvent(event, start, duration)
+			if !end.After(from) || containsTime(excluded, start) {
+				continue
+			}
+			events = append(events, CalendarEvent{Start: start, End: end, Minimums: minimums})
+		}
+	}
+
+	return events, nil
+}
+
+// endOfCalendarEvent returns the end of the occurrence of the event starting at start; all day events end at the same time of day as they start, also across daylight saving time transitions
+func endOfCalendarEvent(event *icalendarEvent, start time.Time, duration time.Duration) time.Time {
+	if event.isDate && duration%(24*time.Hour) == 0 {
+		return start.AddDate(0, 0, int(duration/(24*time.Hour)))
+	}
+	return start.Add(duration)
+}
+
+func containsTime(times []time.Time, t time.Time) bool {
+	for _, other := range times {
+		if other.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// icalendarDurationRegex matches the durations of iCalendar, like P1D, PT1H30M or P2W
+var icalendarDurationRegex = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseICalendarDuration parses an iCalendar duration into a duration, counting a day as 24 hours
+func parseICalendarDuration(value string) (time.Duration, error) {
+
+	match := icalendarDurationRegex.FindStringSubmatch(value)
+	if match == nil || value == "P" || strings.HasSuffix(value, "T") {
+		return 0, fmt.Errorf("Duration %v is invalid", value)
+	}
+
+	duration := time.Duration(0)
+	for i, unit := range []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second} {
+		if match[i+2] == "" {
+			continue
+		}
+		number, err := strconv.Atoi(match[i+2])
+		if err != nil {
+			return 0, err
+		}
+		duration += time.Duration(number) * unit
+	}
+	if match[1] == "-" {
+		duration = -duration
+	}
+
+	return duration, nil
+}
+
+// unfoldICalendarLines joins lines continued on the next line that starts with a space or tab
+func unfoldICalendarLines(data []byte) (lines []string, err error) {
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Reading calendar feed failed: %v", err)
+	}
+
+	return
+}
+
+// parseICalendarProperty splits a line like DTSTART;TZID=Europe/Amsterdam:20200605T180000 into its name, parameters and value
+func parseICalendarProperty(line string) (name string, params map[string]string, value string) {
+
+	params = map[string]string{}
+
+	i := strings.Index(line, ":")
+	if i < 0 {
+		return strings.ToUpper(line), params, ""
+	}
+	value = line[i+1:]
+
+	parts := strings.Split(line[:i], ";")
+	name = strings.ToUpper(parts[0])
+	for _, param := range parts[1:] {
+		if kv := strings.SplitN(param, "=", 2); len(kv) == 2 {
+			params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+		}
+	}
+
+	return
+}
+
+func parseICalendarTime(params map[string]string, value string) (time.Time, error) {
+
+	if params["VALUE"] == "DATE" {
+		return time.ParseInLocation("20060102", value, time.UTC)
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse("20060102T150405Z", value)
+	}
+
+	location := time.UTC
+	if tzid, ok := params["TZID"]; ok {
+		var err error
+		if location, err = time.LoadLocation(tzid); err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	return time.ParseInLocation("20060102T150405", value, location)
+}
+
+func unescapeICalendarText(value string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
+
+func parseCalendarTags(text string) map[string]int {
+
+	minimums := map[string]int{}
+	for _, match := range calendarTagRegex.FindAllStringSubmatch(text, -1) {
+		minimum, err := strconv.Atoi(match[2])
+		if err != nil {
+			continue
+		}
+		if minimum > minimums[match[1]] {
+			minimums[match[1]] = minimum
+		}
+	}
+
+	return minimums
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var calendarFeed = []byte("BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART:20200605T180000Z\r\n" +
+	"DTEND:20200605T210000Z\r\n" +
+	"SUMMARY:TV campaign\r\n" +
+	"DESCRIPTION:Capacity: mig:web-europe=40\\, mig:api-\r\n" +
+	" europe=20\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;TZID=Europe/Amsterdam:20200605T200000\r\n" +
+	"DTEND;TZID=Europe/Amsterdam:20200605T220000\r\n" +
+	"SUMMARY:Newsletter mig:web-europe=60\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20200606\r\n" +
+	"SUMMARY:Sale mig:web-europe=30\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART:20200605T180000Z\r\n" +
+	"DTEND:20200605T210000Z\r\n" +
+	"SUMMARY:Cancelled mig:web-europe=100\r\n" +
+	"STATUS:CANCELLED\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART:20200605T180000Z\r\n" +
+	"DTEND:20200605T210000Z\r\n" +
+	"SUMMARY:Team lunch\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n")
+
+func TestParseICalendar(t *testing.T) {
+
+	t.Run("ReturnsTaggedEvents", func(t *testing.T) {
+
+		// act
+		events, err := ParseICalendar(calendarFeed, time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC))
+
+		assert.Nil(t, err)
+		if assert.Equal(t, 3, len(events)) {
+			assert.Equal(t, time.Date(2020, 6, 5, 18, 0, 0, 0, time.UTC), events[0].Start)
+			assert.Equal(t, map[string]int{"web-europe": 40, "api-europe": 20}, events[0].Minimums)
+			assert.Equal(t, time.Date(2020, 6, 5, 18, 0, 0, 0, time.UTC), events[1].Start.UTC())
+			assert.Equal(t, time.Date(2020, 6, 7, 0, 0, 0, 0, time.UTC), events[2].End)
+		}
+	})
+
+	t.Run("ExpandsRecurringEventsLeavingOutExcludedAndReplacedOccurrences", func(t *testing.T) {
+
+		feed := []byte("BEGIN:VCALENDAR\r\n" +
+			"BEGIN:VEVENT\r\n" +
+			"UID:standup\r\n" +
+			"DTSTART;TZID=Europe/Amsterdam:20200302T090000\r\n" +
+			"DTEND;TZID=Europe/Amsterdam:20200302T100000\r\n" +
+			"RRULE:FREQ=WEEKLY;BYDAY=MO,WE\r\n" +
+			"EXDATE;TZID=Europe/Amsterdam:20200401T090000\r\n" +
+			"SUMMARY:Standup mig:web-europe=40\r\n" +
+			"END:VEVENT\r\n" +
+			"BEGIN:VEVENT\r\n" +
+			"UID:standup\r\n" +
+			"RECURRENCE-ID;TZID=Europe/Amsterdam:20200330T090000\r\n" +
+			"DTSTART;TZID=Europe/Amsterdam:20200330T110000\r\n" +
+			"DTEND;TZID=Europe/Amsterdam:20200330T120000\r\n" +
+			"SUMMARY:Standup mig:web-europe=40\r\n" +
+			"END:VEVENT\r\n" +
+			"END:VCALENDAR\r\n")
+
+		// act
+		events, err := ParseICalendar(feed, time.Date(2020, 3, 25, 0, 0, 0, 0, time.UTC), time.Date(2020, 4, 7, 0, 0, 0, 0, time.UTC))
+
+		assert.Nil(t, err)
+		starts := []time.Time{}
+		for _, event := range events {
+			starts = append(starts, event.Start.UTC())
+			assert.Equal(t, time.Hour, event.End.Sub(event.Start))
+		}
+		assert.ElementsMatch(t, []time.Time{
+			time.Date(2020, 3, 25, 8, 0, 0, 0, time.UTC),
+			time.Date(2020, 3, 30, 9, 0, 0, 0, time.UTC),
+			time.Date(2020, 4, 6, 7, 0, 0, 0, time.UTC),
+		}, starts)
+	})
+
+	t.Run("UsesDurationForEnd", func(t *testing.T) {
+
+		feed := []byte("BEGIN:VCALENDAR\r\n" +
+			"BEGIN:VEVENT\r\n" +
+			"DTSTART:20200605T180000Z\r\n" +
+			"DURATION:PT1H30M\r\n" +
+			"SUMMARY:Launch mig:web-europe=40\r\n" +
+			"END:VEVENT\r\n" +
+			"END:VCALENDAR\r\n")
+
+		// act
+		events, err := ParseICalendar(feed, time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC))
+
+		assert.Nil(t, err)
+		if assert.Equal(t, 1, len(events)) {
+			assert.Equal(t, time.Date(2020, 6, 5, 19, 30, 0, 0, time.UTC), events[0].End)
+		}
+	})
+
+	t.Run("SkipsEventsThatCannotBeParsedAndKeepsTheOthers", func(t *testing.T) {
+
+		feed := []byte("BEGIN:VCALENDAR\r\n" +
+			"BEGIN:VEVENT\r\n" +
+			"DTSTART;TZID=Mars/Olympus_Mons:20200605T180000\r\n" +
+			"DTEND;TZID=Mars/Olympus_Mons:20200605T210000\r\n" +
+			"SUMMARY:Unknown timezone mig:web-europe=40\r\n" +
+			"END:VEVENT\r\n" +
+			"BEGIN:VEVENT\r\n" +
+			"DTSTART:20200605T180000Z\r\n" +
+			"DURATION:soon\r\n" +
+			"SUMMARY:Invalid duration mig:web-europe=40\r\n" +
+			"END:VEVENT\r\n" +
+			"BEGIN:VEVENT\r\n" +
+			"DTSTART:20200605T180000Z\r\n" +
+			"DTEND:20200605T210000Z\r\n" +
+			"RRULE:FREQ=YEARLY;BYMONTH=6\r\n" +
+			"SUMMARY:Unsupported recurrence mig:web-europe=40\r\n" +
+			"END:VEVENT\r\n" +
+			"BEGIN:VEVENT\r\n" +
+			"DTSTART:20200605T180000Z\r\n" +
+			"DTEND:20200605T210000Z\r\n" +
+			"SUMMARY:TV campaign mig:web-europe=60\r\n" +
+			"END:VEVENT\r\n" +
+			"END:VCALENDAR\r\n")
+
+		// act
+		events, err := ParseICalendar(feed, time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC))
+
+		assert.Nil(t, err)
+		if assert.Equal(t, 1, len(events)) {
+			assert.Equal(t, map[string]int{"web-europe": 60}, events[0].Minimums)
+		}
+	})
+}
+
+func TestParseICalendarDuration(t *testing.T) {
+
+	t.Run("ParsesWeeksDaysAndTime", func(t *testing.T) {
+
+		// act
+		weeks, weeksErr := parseICalendarDuration("P2W")
+		dayAndTime, dayAndTimeErr := parseICalendarDuration("P1DT2H30M15S")
+		negative, negativeErr := parseICalendarDuration("-PT15M")
+
+		assert.Nil(t, weeksErr)
+		assert.Nil(t, dayAndTimeErr)
+		assert.Nil(t, negativeErr)
+		assert.Equal(t, 14*24*time.Hour, weeks)
+		assert.Equal(t, 26*time.Hour+30*time.Minute+15*time.Second, dayAndTime)
+		assert.Equal(t, -15*time.Minute, negative)
+	})
+
+	t.Run("ReturnsErrorForInvalidDuration", func(t *testing.T) {
+
+		for _, value := range []string{"", "P", "PT", "1H", "PT1.5H"} {
+
+			// act
+			_, err := parseICalendarDuration(value)
+
+			assert.NotNil(t, err, value)
+		}
+	})
+}
+
+func TestCalendar(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(calendarFeed)
+	}))
+	defer server.Close()
+
+	calendar := NewCalendar(server.URL)
+	err := calendar.Refresh(context.Background())
+	assert.Nil(t, err)
+
+	t.Run("ReturnsHighestMinimumOfOngoingEvents", func(t *testing.T) {
+
+		// act
+		minimumNumberOfInstances := calendar.MinimumNumberOfInstances("web-europe", time.Date(2020, 6, 5, 19, 30, 0, 0, time.UTC))
+
+		assert.Equal(t, 60, minimumNumberOfInstances)
+	})
+
+	t.Run("ReturnsZeroOutsideEvents", func(t *testing.T) {
+
+		// act
+		minimumNumberOfInstances := calendar.MinimumNumberOfInstances("api-europe", time.Date(2020, 6, 5, 21, 0, 0, 0, time.UTC))
+
+		assert.Equal(t, 0, minimumNumberOfInstances)
+	})
+
+	t.Run("ReturnsErrorForFeedLargerThanMaximumSizeAndKeepsEvents", func(t *testing.T) {
+
+		largeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write(make([]byte, maxCalendarFeedSize+1))
+		}))
+		defer largeServer.Close()
+		calendar.URL = largeServer.URL
+
+		// act
+		err := calendar.Refresh(context.Background())
+
+		assert.NotNil(t, err)
+		assert.Equal(t, 60, calendar.MinimumNumberOfInstances("web-europe", time.Date(2020, 6, 5, 19, 30, 0, 0, time.UTC)))
+	})
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	monitoring "google.golang.org/api/monitoring/v3"
+)
+
+const cloudMonitoringQueryURL = "https://monitoring.googleapis.com/v3/projects/%v/timeSeries:query"
+
+// CloudMonitoringMetricSource retrieves request rates from Google Cloud Monitoring, with either an MQL query or a monitoring filter
+type CloudMonitoringMetricSource struct {
+	client  *http.Client
+	service *monitoring.Service
+}
+
+// NewCloudMonitoringMetricSource returns a cloud monitoring metric source using the authorized google client
+func NewCloudMonitoringMetricSource(client *http.Client) (*CloudMonitoringMetricSource, error) {
+	service, err := monitoring.New(client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CloudMonitoringMetricSource{
+		client:  client,
+		service: service,
+	}, nil
+}
+
+// GetRequestRate executes the request rate query in the project of the managed instance group; queries starting with fetch or { are MQL, anything else is a monitoring filter whose time series are aligned as rate and summed
+func (s *CloudMonitoringMetricSource) GetRequestRate(ctx context.Context, configItem MIGConfiguration) (float64, error) {
+	if IsMQLQuery(configItem.RequestRateQuery) {
+		return s.getRequestRateWithMQL(ctx, configItem.GCloudProject, configItem.RequestRateQuery)
+	}
+	return s.getRequestRateWithFilter(ctx, configItem.GCloudProject, configItem.RequestRateQuery, "ALIGN_RATE")
+}
+
+// IsMQLQuery returns true if the query is written in the monitoring query language instead of being a monitoring filter
+func IsMQLQuery(query string) bool {
+	query = strings.TrimSpace(query)
+	return strings.HasPrefix(query, "fetch") || strings.HasPrefix(query, "{")
+}
+
+// CloudMonitoringQueryResponse is used to unmarshal the response of an MQL query
+type CloudMonitoringQueryResponse struct {
+	TimeSeriesData []struct {
+		PointData []struct {
+			Values []CloudMonitoringTypedValue `json:"values"`
+		} `json:"pointData"`
+	} `json:"timeSeriesData"`
+}
+
+// CloudMonitoringTypedValue is used to unmarshal a single value of an MQL query response; int64 values are serialized as string
+type CloudMonitoringTypedValue struct {
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	Int64Value  *string  `json:"int64Value,omitempty"`
+}
+
+// GetRequestRate returns the first value of the most recent point of the first time series
+func (r *CloudMonitoringQueryResponse) GetRequestRate() (float64, error) {
+	if len(r.TimeSeriesData) == 0 || len(r.TimeSeriesData[0].PointData) == 0 || len(r.TimeSeriesData[0].PointData[0].Values) == 0 {
+		return 0, errEmptyResponse
+	}
+
+	value := r.TimeSeriesData[0].PointData[0].Values[0]
+	switch {
+	case value.DoubleValue != nil:
+		return *value.DoubleValue, nil
+	case value.Int64Value != nil:
+		return strconv.ParseFloat(*value.Int64Value, 64)
+	}
+
+	return 0, errors.New("Value is not numeric")
+}
+
+func (s *CloudMonitoringMetricSource) getRequestRateWithMQL(ctx context.Context, project, query string) (float64, error) {
+
+	requestBody, err := json.Marshal(map[string]string{"query": query})
+	if err != nil {
+		return 0, err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf(cloudMonitoringQueryURL, project), bytes.NewReader(requestBody))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(request.WithContext(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("Executing cloud monitoring query failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("Reading cloud monitoring query response body failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("Cloud monitoring query returned status code %v: %v", resp.StatusCode, string(body))
+	}
+
+	var queryResponse CloudMonitoringQueryResponse
+	if err = json.Unmarshal(body, &queryResponse); err != nil {
+		return 0, fmt.Errorf("Unmarshalling cloud monitoring query response body failed: %v", err)
+	}
+
+	return queryResponse.GetRequestRate()
+}
+
+// getRequestRateWithFilter lists the time series matching the filter over the last 5 minutes, aligns them per minute with the aligner and returns the most recent value of their sum
+func (s *CloudMonitoringMetricSource) getRequestRateWithFilter(ctx context.Context, project, filter, aligner string) (float64, error) {
+
+	now := time.Now().UTC()
+	response, err := s.service.Projects.TimeSeries.List(fmt.Sprintf("projects/%v", project)).
+		Filter(filter).
+		IntervalStartTime(now.Add(-5 * time.Minute).Format(time.RFC3339)).
+		IntervalEndTime(now.Format(time.RFC3339)).
+		AggregationAlignmentPeriod("60s").
+		AggregationPerSeriesAligner(aligner).
+		AggregationCrossSeriesReducer("REDUCE_SUM").
+		Context(ctx).
+		Do()
+	if err != nil {
+		return 0, fmt.Errorf("Listing cloud monitoring time series failed: %v", err)
+	}
+
+	if len(response.TimeSeries) == 0 || len(response.TimeSeries[0].Points) == 0 || response.TimeSeries[0].Points[0].Value == nil {
+		return 0, errEmptyResponse
+	}
+
+	// points are returned in reverse time order, so the first one is the most recent
+	value := response.TimeSeries[0].Points[0].Value
+	switch {
+	case value.DoubleValue != nil:
+		return *value.DoubleValue, nil
+	case value.Int64Value != nil:
+		return float64(*value.Int64Value), nil
+	}
+
+	return 0, errors.New("Value is not numeric")
+}
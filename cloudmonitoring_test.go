@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsMQLQuery(t *testing.T) {
+
+	t.Run("ReturnsTrueForFetchQuery", func(t *testing.T) {
+
+		// act
+		isMQL := IsMQLQuery("fetch https_lb_rule | metric 'loadbalancing.googleapis.com/https/request_count' | align rate(1m) | every 1m | group_by [], [sum(val())]")
+
+		assert.True(t, isMQL)
+	})
+
+	t.Run("ReturnsFalseForMonitoringFilter", func(t *testing.T) {
+
+		// act
+		isMQL := IsMQLQuery("metric.type=\"loadbalancing.googleapis.com/https/request_count\" AND resource.labels.backend_target_name=\"backend\"")
+
+		assert.False(t, isMQL)
+	})
+}
+
+func TestCloudMonitoringQueryResponseGetRequestRate(t *testing.T) {
+
+	t.Run("ReturnsDoubleValueOfFirstPoint", func(t *testing.T) {
+
+		var queryResponse CloudMonitoringQueryResponse
+		json.Unmarshal([]byte(`{"timeSeriesData":[{"pointData":[{"values":[{"doubleValue":225.4}]},{"values":[{"doubleValue":180.1}]}]}]}`), &queryResponse)
+
+		// act
+		requestRate, err := queryResponse.GetRequestRate()
+
+		assert.Nil(t, err)
+		assert.Equal(t, 225.4, requestRate)
+	})
+
+	t.Run("ReturnsInt64ValueOfFirstPoint", func(t *testing.T) {
+
+		var queryResponse CloudMonitoringQueryResponse
+		json.Unmarshal([]byte(`{"timeSeriesData":[{"pointData":[{"values":[{"int64Value":"225"}]}]}]}`), &queryResponse)
+
+		// act
+		requestRate, err := queryResponse.GetRequestRate()
+
+		assert.Nil(t, err)
+		assert.Equal(t, 225.0, requestRate)
+	})
+
+	t.Run("ReturnsErrorForEmptyResponse", func(t *testing.T) {
+
+		queryResponse := CloudMonitoringQueryResponse{}
+
+		// act
+		_, err := queryResponse.GetRequestRate()
+
+		assert.NotNil(t, err)
+	})
+}
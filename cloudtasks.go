@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	cloudTasksDepthMetric        = "depth"
+	cloudTasksDispatchRateMetric = "dispatchRate"
+)
+
+// CloudTasksMetricSource uses the depth or dispatch rate of a cloud tasks queue, retrieved from cloud monitoring, as scaling signal
+type CloudTasksMetricSource struct {
+	CloudMonitoring *CloudMonitoringMetricSource
+}
+
+// GetRequestRate returns the number of tasks in the queue, or the number of dispatch attempts per second if cloudTasksMetric is dispatchRate
+func (s *CloudTasksMetricSource) GetRequestRate(ctx context.Context, configItem MIGConfiguration) (float64, error) {
+
+	project := configItem.CloudTasksProject
+	if project == "" {
+		project = configItem.GCloudProject
+	}
+
+	if configItem.CloudTasksMetric == cloudTasksDispatchRateMetric {
+		return s.CloudMonitoring.getRequestRateWithFilter(ctx, project, CloudTasksFilter("task_attempt_count", configItem.CloudTasksLocation, configItem.CloudTasksQueue), "ALIGN_RATE")
+	}
+
+	return s.CloudMonitoring.getRequestRateWithFilter(ctx, project, CloudTasksFilter("depth", configItem.CloudTasksLocation, configItem.CloudTasksQueue), "ALIGN_MEAN")
+}
+
+// CloudTasksFilter returns the cloud monitoring filter for a queue metric of a cloud tasks queue
+func CloudTasksFilter(metric, location, queue string) string {
+	return fmt.Sprintf("metric.type=\"cloudtasks.googleapis.com/queue/%v\" AND resource.type=\"cloud_tasks_queue\" AND resource.labels.location=\"%v\" AND resource.labels.queue_id=\"%v\"", metric, location, queue)
+}
+
+// validateCloudTasksConfig checks the fields required for the cloudtasks metric source, which reads a queue instead of executing requestRateQuery
+func validateCloudTasksConfig(c *MIGConfiguration, addError func(field, message string)) {
+	if c.CloudTasksLocation == "" {
+		addError("cloudTasksLocation", "is required for metricSource cloudtasks")
+	}
+	if c.CloudTasksQueue == "" {
+		addError("cloudTasksQueue", "is required for metricSource cloudtasks")
+	}
+	if c.CloudTasksMetric != "" && c.CloudTasksMetric != cloudTasksDepthMetric && c.CloudTasksMetric != cloudTasksDispatchRateMetric {
+		addError("cloudTasksMetric", fmt.Sprintf("should be either %v or %v", cloudTasksDepthMetric, cloudTasksDispatchRateMetric))
+	}
+}
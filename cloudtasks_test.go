@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloudTasksFilter(t *testing.T) {
+
+	t.Run("ReturnsFilterForQueueMetric", func(t *testing.T) {
+
+		// act
+		filter := CloudTasksFilter("depth", "europe-west1", "emails")
+
+		assert.Equal(t, "metric.type=\"cloudtasks.googleapis.com/queue/depth\" AND resource.type=\"cloud_tasks_queue\" AND resource.labels.location=\"europe-west1\" AND resource.labels.queue_id=\"emails\"", filter)
+	})
+}
+
+func TestValidateCloudTasksConfig(t *testing.T) {
+
+	t.Run("ReturnsErrorsForMissingQueueAndUnknownMetric", func(t *testing.T) {
+
+		configItem := MIGConfiguration{CloudTasksLocation: "europe-west1", CloudTasksMetric: "latency"}
+		fields := []string{}
+
+		// act
+		validateCloudTasksConfig(&configItem, func(field, message string) {
+			fields = append(fields, field)
+		})
+
+		assert.Equal(t, []string{"cloudTasksQueue", "cloudTasksMetric"}, fields)
+	})
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+)
+
+// CloudWatchMetricSource retrieves request rates by evaluating metric math expressions with the CloudWatch GetMetricData api; credentials come from the default aws credential chain
+type CloudWatchMetricSource struct {
+	newClient func(region string) (cloudwatchiface.CloudWatchAPI, error)
+
+	mutex   sync.Mutex
+	clients map[string]cloudwatchiface.CloudWatchAPI
+}
+
+// NewCloudWatchMetricSource returns a cloudwatch metric source that creates a client per aws region using the default credential chain
+func NewCloudWatchMetricSource() *CloudWatchMetricSource {
+	return &CloudWatchMetricSource{
+		newClient: func(region string) (cloudwatchiface.CloudWatchAPI, error) {
+			config := aws.NewConfig()
+			if region != "" {
+				config = config.WithRegion(region)
+			}
+			sess, err := session.NewSessionWithOptions(session.Options{
+				Config:            *config,
+				SharedConfigState: session.SharedConfigEnable,
+			})
+			if err != nil {
+				return nil, err
+			}
+			return cloudwatch.New(sess), nil
+		},
+		clients: map[string]cloudwatchiface.CloudWatchAPI{},
+	}
+}
+
+// GetRequestRate evaluates the request rate query as metric math expression over the last 10 minutes in the aws region of the managed instance group and returns the most recent value
+func (s *CloudWatchMetricSource) GetRequestRate(ctx context.Context, configItem MIGConfiguration) (float64, error) {
+
+	client, err := s.getClient(configItem.AWSRegion)
+	if err != nil {
+		return 0, fmt.Errorf("Creating cloudwatch client failed: %v", err)
+	}
+
+	now := time.Now()
+	output, err := client.GetMetricDataWithContext(ctx, &cloudwatch.GetMetricDataInput{
+		StartTime: aws.Time(now.Add(-10 * time.Minute)),
+		EndTime:   aws.Time(now),
+		ScanBy:    aws.String(cloudwatch.ScanByTimestampDescending),
+		MetricDataQueries: []*cloudwatch.MetricDataQuery{
+			{
+				Id:         aws.String("requestrate"),
+				Expression: aws.String(configItem.RequestRateQuery),
+				Period:     aws.Int64(60),
+			},
+		},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("Executing cloudwatch query failed: %v", err)
+	}
+
+	// values are sorted by descending timestamp, so the first value is the most recent
+	if len(output.MetricDataResults) == 0 || len(output.MetricDataResults[0].Values) == 0 || output.MetricDataResults[0].Values[0] == nil {
+		return 0, errEmptyResponse
+	}
+
+	return *output.MetricDataResults[0].Values[0], nil
+}
+
+func (s *CloudWatchMetricSource) getClient(region string) (cloudwatchiface.CloudWatchAPI, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if client, ok := s.clients[region]; ok {
+		return client, nil
+	}
+
+	client, err := s.newClient(region)
+	if err != nil {
+		return nil, err
+	}
+	s.clients[region] = client
+
+	return client, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeCloudWatchClient struct {
+	cloudwatchiface.CloudWatchAPI
+	input  *cloudwatch.GetMetricDataInput
+	output *cloudwatch.GetMetricDataOutput
+}
+
+func (c *fakeCloudWatchClient) GetMetricDataWithContext(ctx aws.Context, input *cloudwatch.GetMetricDataInput, opts ...request.Option) (*cloudwatch.GetMetricDataOutput, error) {
+	c.input = input
+	return c.output, nil
+}
+
+func TestCloudWatchMetricSourceGetRequestRate(t *testing.T) {
+
+	t.Run("ReturnsMostRecentValueOfExpressionInRegionOfMIG", func(t *testing.T) {
+
+		client := &fakeCloudWatchClient{
+			output: &cloudwatch.GetMetricDataOutput{
+				MetricDataResults: []*cloudwatch.MetricDataResult{
+					{Values: aws.Float64Slice([]float64{225.4, 180.1})},
+				},
+			},
+		}
+		regions := []string{}
+		source := &CloudWatchMetricSource{
+			newClient: func(region string) (cloudwatchiface.CloudWatchAPI, error) {
+				regions = append(regions, region)
+				return client, nil
+			},
+			clients: map[string]cloudwatchiface.CloudWatchAPI{},
+		}
+		configItem := MIGConfiguration{AWSRegion: "eu-west-1", RequestRateQuery: "SUM(SEARCH('{AWS/ApplicationELB,LoadBalancer} MetricName=\"RequestCount\"', 'Sum', 60))/60"}
+
+		// act
+		requestRate, err := source.GetRequestRate(context.Background(), configItem)
+		source.GetRequestRate(context.Background(), configItem)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 225.4, requestRate)
+		assert.Equal(t, configItem.RequestRateQuery, *client.input.MetricDataQueries[0].Expression)
+		assert.Equal(t, []string{"eu-west-1"}, regions)
+	})
+
+	t.Run("ReturnsErrorForEmptyResult", func(t *testing.T) {
+
+		client := &fakeCloudWatchClient{
+			output: &cloudwatch.GetMetricDataOutput{},
+		}
+		source := &CloudWatchMetricSource{
+			newClient: func(region string) (cloudwatchiface.CloudWatchAPI, error) {
+				return client, nil
+			},
+			clients: map[string]cloudwatchiface.CloudWatchAPI{},
+		}
+
+		// act
+		_, err := source.GetRequestRate(context.Background(), MIGConfiguration{RequestRateQuery: "m1"})
+
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const (
+	maxQueryAggregation = "max"
+	sumQueryAggregation = "sum"
+	avgQueryAggregation = "avg"
+
+	// weightedQueryAggregation calculates the number of instances needed per query with its own numberOfRequestsPerInstance, and sums them multiplied by their weight
+	weightedQueryAggregation = "weighted"
+)
+
+// queryAggregations are the supported values for queryAggregation
+var queryAggregations = []string{maxQueryAggregation, sumQueryAggregation, avgQueryAggregation, weightedQueryAggregation}
+
+// QueryConfigs returns the configuration for each entry in queries; every entry inherits all fields of the managed instance group and only has to set the ones that differ, like metricSource and requestRateQuery
+func (c *MIGConfiguration) QueryConfigs() (queryConfigs []MIGConfiguration, err error) {
+	for _, query := range c.Queries {
+		queryConfig, err := c.overlay(query)
+		if err != nil {
+			return queryConfigs, err
+		}
+		queryConfigs = append(queryConfigs, queryConfig)
+	}
+	return
+}
+
+// overlay returns the configuration of the managed instance group with the fields in overrides applied on top of it
+func (c *MIGConfiguration) overlay(overrides map[string]interface{}) (overlaid MIGConfiguration, err error) {
+
+	base := *c
+	base.Queries = nil
+	base.QueryAggregation = ""
+	base.Fallbacks = nil
+
+	return base.merge(overrides)
+}
+
+// merge returns the configuration with the fields in overrides applied on top of it, and the request rate query rendered again if overrides has its own template
+func (c MIGConfiguration) merge(overrides map[string]interface{}) (merged MIGConfiguration, err error) {
+
+	baseJSON, err := json.Marshal(c)
+	if err != nil {
+		return
+	}
+	var baseMap map[string]interface{}
+	if err = json.Unmarshal(baseJSON, &baseMap); err != nil {
+		return
+	}
+
+	// a query template in the overrides should be rendered instead of inheriting the already rendered query
+	if _, ok := overrides["requestRateQueryTemplate"]; ok {
+		if _, ok := overrides["requestRateQuery"]; !ok {
+			delete(baseMap, "requestRateQuery")
+		}
+	}
+
+	mergedJSON, err := json.Marshal(mergeMaps(baseMap, overrides))
+	if err != nil {
+		return
+	}
+	if err = json.Unmarshal(mergedJSON, &merged); err != nil {
+		return
+	}
+
+	err = merged.RenderRequestRateQuery()
+
+	return
+}
+
+// getCompositeRequestRate executes all queries of the managed instance group and combines their values with queryAggregation; it fails if any query fails, since leaving out a signal could underestimate the traffic
+func getCompositeRequestRate(ctx context.Context, metricSources map[string]MetricSource, configItem MIGConfiguration) (float64, error) {
+
+	queryConfigs, err := configItem.QueryConfigs()
+	if err != nil {
+		return 0, err
+	}
+
+	requestRates := []float64{}
+	weightedNumberOfInstances := 0.0
+	for i, queryConfig := range queryConfigs {
+		requestRate, err := getRequestRateWithFallbacks(ctx, metricSources, queryConfig)
+		if err != nil {
+			return 0, fmt.Errorf("Query %v failed: %w", i, err)
+		}
+		requestRates = append(requestRates, requestRate)
+		weightedNumberOfInstances += queryConfig.QueryWeight() * requestRate / queryConfig.RequestsPerInstance()
+	}
+
+	if configItem.QueryAggregation == weightedQueryAggregation {
+		// express the combined number of instances as the request rate that needs as many instances at the managed instance group's own numberOfRequestsPerInstance
+		return weightedNumberOfInstances * configItem.RequestsPerInstance(), nil
+	}
+
+	return AggregateRequestRates(requestRates, configItem.QueryAggregation)
+}
+
+// QueryWeight returns the weight of a query for the weighted query aggregation; it defaults to 1 when weight isn't set
+func (c *MIGConfiguration) QueryWeight() float64 {
+	if c.Weight == nil {
+		return 1
+	}
+	return *c.Weight
+}
+
+// AggregateRequestRates combines the values of multiple queries; aggregation defaults to max
+func AggregateRequestRates(requestRates []float64, aggregation string) (float64, error) {
+
+	if len(requestRates) == 0 {
+		return 0, errors.New("No request rates to aggregate")
+	}
+
+	switch aggregation {
+	case "", maxQueryAggregation:
+		max := requestRates[0]
+		for _, requestRate := range requestRates[1:] {
+			if requestRate > max {
+				max = requestRate
+			}
+		}
+		return max, nil
+
+	case sumQueryAggregation, avgQueryAggregation:
+		sum := 0.0
+		for _, requestRate := range requestRates {
+			sum += requestRate
+		}
+		if aggregation == avgQueryAggregation {
+			return sum / float64(len(requestRates)), nil
+		}
+		return sum, nil
+	}
+
+	return 0, fmt.Errorf("Query aggregation %v is not supported", aggregation)
+}
+
+// validateQueries checks the configuration of every entry in queries, with all fields inherited from the managed instance group
+func (c *MIGConfiguration) validateQueries(addError func(field, message string)) {
+
+	supportedAggregation := c.QueryAggregation == ""
+	for _, aggregation := range queryAggregations {
+		if c.QueryAggregation == aggregation {
+			supportedAggregation = true
+		}
+	}
+	if !supportedAggregation {
+		addError("queryAggregation", fmt.Sprintf("should be one of %v", queryAggregations))
+	}
+
+	queryConfigs, err := c.QueryConfigs()
+	if err != nil {
+		addError("queries", err.Error())
+		return
+	}
+
+	for i, queryConfig := range queryConfigs {
+		addQueryError := func(field, message string) {
+			addError(fmt.Sprintf("queries[%v].%v", i, field), message)
+		}
+		queryConfig.validateMetricSource(addQueryError)
+		queryConfig.validateFallbacks(addQueryError)
+
+		// the number of requests per instance query is executed once for the managed instance group, so a query can only inherit it
+		for _, field := range []string{"numberOfRequestsPerInstanceQuery", "minNumberOfRequestsPerInstance", "maxNumberOfRequestsPerInstance"} {
+			if _, ok := c.Queries[i][field]; ok {
+				addQueryError(field, "can only be set on the managed instance group, not per query")
+			}
+		}
+		if queryConfig.NumberOfRequestsPerInstance <= 0 && queryConfig.RequestsPerInstanceQuery == "" {
+			addQueryError("numberOfRequestsPerInstance", "should be larger than 0")
+		}
+		if queryConfig.Weight != nil && *queryConfig.Weight <= 0 {
+			addQueryError("weight", "should be larger than 0")
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeMetricSource struct {
+	requestRates map[string]float64
+}
+
+func (s *fakeMetricSource) GetRequestRate(ctx context.Context, configItem MIGConfiguration) (float64, error) {
+	return s.requestRates[configItem.RequestRateQuery], nil
+}
+
+func TestQueryConfigs(t *testing.T) {
+
+	t.Run("ReturnsQueriesWithFieldsInheritedFromMIG", func(t *testing.T) {
+
+		configs, err := UnmarshalConfig([]byte(`
+- instanceGroupName: instance-group-name
+  gcloudProject: project-id
+  prometheusUrl: http://prometheus
+  queries:
+  - requestRateQuery: sum(rate(nginx_http_requests_total[10m]))
+  - metricSource: cloudmonitoring
+    requestRateQuery: fetch https_lb_rule
+`))
+		assert.Nil(t, err)
+
+		// act
+		queryConfigs, err := configs.MIGs[0].QueryConfigs()
+
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(queryConfigs))
+		assert.Equal(t, "instance-group-name", queryConfigs[0].InstanceGroupName)
+		assert.Equal(t, "http://prometheus", queryConfigs[0].PrometheusURL)
+		assert.Equal(t, "sum(rate(nginx_http_requests_total[10m]))", queryConfigs[0].RequestRateQuery)
+		assert.Equal(t, "cloudmonitoring", queryConfigs[1].MetricSource)
+		assert.Equal(t, "project-id", queryConfigs[1].GCloudProject)
+		assert.Equal(t, 0, len(queryConfigs[1].Queries))
+	})
+}
+
+func TestAggregateRequestRates(t *testing.T) {
+
+	t.Run("ReturnsMaxByDefault", func(t *testing.T) {
+
+		// act
+		requestRate, err := AggregateRequestRates([]float64{10, 30, 20}, "")
+
+		assert.Nil(t, err)
+		assert.Equal(t, 30.0, requestRate)
+	})
+
+	t.Run("ReturnsSum", func(t *testing.T) {
+
+		// act
+		requestRate, err := AggregateRequestRates([]float64{10, 30, 20}, "sum")
+
+		assert.Nil(t, err)
+		assert.Equal(t, 60.0, requestRate)
+	})
+
+	t.Run("ReturnsAverage", func(t *testing.T) {
+
+		// act
+		requestRate, err := AggregateRequestRates([]float64{10, 30, 20}, "avg")
+
+		assert.Nil(t, err)
+		assert.Equal(t, 20.0, requestRate)
+	})
+}
+
+func TestGetCompositeRequestRate(t *testing.T) {
+
+	t.Run("ReturnsAggregatedValueOfAllQueries", func(t *testing.T) {
+
+		metricSources := map[string]MetricSource{
+			"prometheus": &fakeMetricSource{requestRates: map[string]float64{"api": 120, "websockets": 80}},
+		}
+		configItem := MIGConfiguration{
+			Queries: []map[string]interface{}{
+				map[string]interface{}{"requestRateQuery": "api"},
+				map[string]interface{}{"requestRateQuery": "websockets"},
+			},
+			QueryAggregation: "sum",
+		}
+
+		// act
+		requestRate, err := getCompositeRequestRate(context.Background(), metricSources, configItem)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 200.0, requestRate)
+	})
+
+	t.Run("ReturnsRequestRateForWeightedSumOfInstancesPerQuery", func(t *testing.T) {
+
+		metricSources := map[string]MetricSource{
+			"prometheus": &fakeMetricSource{requestRates: map[string]float64{"api": 100, "batch": 30}},
+		}
+		configItem := MIGConfiguration{
+			NumberOfRequestsPerInstance: 10,
+			Queries: []map[string]interface{}{
+				map[string]interface{}{"requestRateQuery": "api"},
+				map[string]interface{}{"requestRateQuery": "batch", "numberOfRequestsPerInstance": 5, "weight": 0.5},
+			},
+			QueryAggregation: "weighted",
+		}
+
+		// act
+		requestRate, err := getCompositeRequestRate(context.Background(), metricSources, configItem)
+
+		assert.Nil(t, err)
+		// api needs 10 instances, batch 6 instances at weight 0.5, so 13 instances at 10 requests per instance
+		assert.Equal(t, 130.0, requestRate)
+		assert.Equal(t, 13, CalculateMinimumNumberOfInstances(configItem, requestRate))
+	})
+}
+
+func TestValidateQueries(t *testing.T) {
+
+	t.Run("ReturnsErrorForQueryWithOwnNumberOfRequestsPerInstanceQuery", func(t *testing.T) {
+
+		configItem := MIGConfiguration{
+			PrometheusURL: "http://prometheus",
+			Queries: []map[string]interface{}{
+				map[string]interface{}{"requestRateQuery": "api", "numberOfRequestsPerInstance": 10},
+				map[string]interface{}{"requestRateQuery": "batch", "numberOfRequestsPerInstanceQuery": "batch_per_instance", "minNumberOfRequestsPerInstance": 1, "maxNumberOfRequestsPerInstance": 5},
+			},
+		}
+		fields := []string{}
+
+		// act
+		configItem.validateQueries(func(field, message string) {
+			fields = append(fields, field)
+		})
+
+		assert.Equal(t, []string{"queries[1].numberOfRequestsPerInstanceQuery", "queries[1].minNumberOfRequestsPerInstance", "queries[1].maxNumberOfRequestsPerInstance"}, fields)
+	})
+
+	t.Run("ReturnsErrorForQueryWithWeightOfZero", func(t *testing.T) {
+
+		configItem := MIGConfiguration{
+			PrometheusURL:               "http://prometheus",
+			NumberOfRequestsPerInstance: 10,
+			Queries: []map[string]interface{}{
+				map[string]interface{}{"requestRateQuery": "api"},
+				map[string]interface{}{"requestRateQuery": "batch", "weight": 0},
+			},
+			QueryAggregation: "weighted",
+		}
+		fields := []string{}
+
+		// act
+		configItem.validateQueries(func(field, message string) {
+			fields = append(fields, field)
+		})
+
+		assert.Equal(t, []string{"queries[1].weight"}, fields)
+	})
+}
+
+func TestQueryWeight(t *testing.T) {
+
+	t.Run("ReturnsOneIfWeightIsNotSet", func(t *testing.T) {
+
+		configItem := MIGConfiguration{}
+
+		// act
+		weight := configItem.QueryWeight()
+
+		assert.Equal(t, 1.0, weight)
+	})
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+// computeCache holds which autoscaler targets a managed instance group for a while, so steady state iterations can get it by name instead of listing all autoscalers in the location with a filter; that replaces a call rather than saving one, since only the immutable name and target are cached, as the target size of instance group managers and the policy, mode and status of autoscalers have to be current every iteration
+type computeCache struct {
+	sync.Mutex
+	ttl     time.Duration
+	entries map[string]computeCacheEntry
+}
+
+type computeCacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// newComputeCache returns a cache that keeps entries for the ttl; with a ttl of 0 nothing is cached
+func newComputeCache(ttl time.Duration) *computeCache {
+	return &computeCache{
+		ttl:     ttl,
+		entries: map[string]computeCacheEntry{},
+	}
+}
+
+func (c *computeCache) get(key string, now time.Time) (interface{}, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || !now.Before(entry.expires) {
+		return nil, false
+	}
+	computeCacheHitsCounter.Inc()
+
+	return entry.value, true
+}
+
+func (c *computeCache) set(key string, value interface{}, now time.Time) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.entries[key] = computeCacheEntry{value: value, expires: now.Add(c.ttl)}
+}
+
+func (c *computeCache) invalidate(keys ...string) {
+	c.Lock()
+	defer c.Unlock()
+
+	for _, key := range keys {
+		delete(c.entries, key)
+	}
+}
+
+// autoscalerCacheKey returns the cache key of the autoscaler of the managed instance group
+func autoscalerCacheKey(configItem MIGConfiguration) string {
+	return fmt.Sprintf("%v/%v%v/autoscalers/%v", configItem.GCloudProject, configItem.GCloudZone, configItem.GCloudRegion, configItem.InstanceGroupName)
+}
+
+// autoscalerReference is what's cached of an autoscaler
+type autoscalerReference struct {
+	name   string
+	target string
+}
+
+func (c *computeCache) setAutoscaler(configItem MIGConfiguration, autoscaler *Autoscaler, now time.Time) {
+	c.set(autoscalerCacheKey(configItem), autoscalerReference{name: autoscaler.Name, target: autoscaler.Target}, now)
+}
+
+// autoscalerName returns the name of the cached autoscaler of the managed instance group, if it targets the instance group manager with the self link
+func (c *computeCache) autoscalerName(configItem MIGConfiguration, instanceGroupManagerSelfLink string, now time.Time) (string, bool) {
+	value, ok := c.get(autoscalerCacheKey(configItem), now)
+	if !ok {
+		return "", false
+	}
+	reference := value.(autoscalerReference)
+	if !IsSameResource(reference.target, instanceGroupManagerSelfLink) {
+		return "", false
+	}
+	return reference.name, true
+}
+
+// invalidateOnNotFound forgets the cached autoscaler of the managed instance group if the error shows it or the instance group manager no longer exists, so it's searched for again in the next iteration
+func (c *computeCache) invalidateOnNotFound(configItem MIGConfiguration, err error) {
+	if isNotFound(err) {
+		c.invalidate(autoscalerCacheKey(configItem))
+	}
+}
+
+// isNotFound returns whether the error is a not found response of the compute api
+func isNotFound(err error) bool {
+	apiErr, ok := err.(*googleapi.Error)
+	return ok && apiErr.Code == http.StatusNotFound
+}
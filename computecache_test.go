@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	compute "google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+)
+
+func TestComputeCache(t *testing.T) {
+
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	configItem := MIGConfiguration{GCloudProject: "project-id", GCloudZone: "europe-west1-b", InstanceGroupName: "web"}
+
+	selfLink := "https://www.googleapis.com/compute/v1/projects/project-id/zones/europe-west1-b/instanceGroupManagers/web"
+	autoscaler := &Autoscaler{Autoscaler: &compute.Autoscaler{Name: "web-autoscaler", Target: selfLink}}
+
+	t.Run("ReturnsCachedAutoscalerNameWithinTTL", func(t *testing.T) {
+
+		cache := newComputeCache(5 * time.Minute)
+		cache.setAutoscaler(configItem, autoscaler, now)
+
+		// act
+		name, ok := cache.autoscalerName(configItem, selfLink, now.Add(4*time.Minute))
+
+		assert.True(t, ok)
+		assert.Equal(t, "web-autoscaler", name)
+	})
+
+	t.Run("ReturnsFalseAfterTTL", func(t *testing.T) {
+
+		cache := newComputeCache(5 * time.Minute)
+		cache.setAutoscaler(configItem, autoscaler, now)
+
+		// act
+		_, ok := cache.autoscalerName(configItem, selfLink, now.Add(5*time.Minute))
+
+		assert.False(t, ok)
+	})
+
+	t.Run("DoesNotCacheWithTTLOfZero", func(t *testing.T) {
+
+		cache := newComputeCache(0)
+		cache.setAutoscaler(configItem, autoscaler, now)
+
+		// act
+		_, ok := cache.autoscalerName(configItem, selfLink, now)
+
+		assert.False(t, ok)
+	})
+
+	t.Run("DoesNotMixUpMigsWithTheSameNameInOtherLocations", func(t *testing.T) {
+
+		cache := newComputeCache(5 * time.Minute)
+		cache.setAutoscaler(configItem, autoscaler, now)
+		otherConfigItem := MIGConfiguration{GCloudProject: "project-id", GCloudZone: "europe-west4-a", InstanceGroupName: "web"}
+
+		// act
+		_, ok := cache.autoscalerName(otherConfigItem, selfLink, now)
+
+		assert.False(t, ok)
+	})
+
+	t.Run("ReturnsFalseIfAutoscalerTargetsOtherInstanceGroupManager", func(t *testing.T) {
+
+		cache := newComputeCache(5 * time.Minute)
+		cache.setAutoscaler(configItem, autoscaler, now)
+
+		// act
+		_, ok := cache.autoscalerName(configItem, "https://www.googleapis.com/compute/v1/projects/project-id/zones/europe-west1-b/instanceGroupManagers/web-v2", now)
+
+		assert.False(t, ok)
+	})
+
+	t.Run("InvalidatesAutoscalerOnNotFound", func(t *testing.T) {
+
+		cache := newComputeCache(5 * time.Minute)
+		cache.setAutoscaler(configItem, autoscaler, now)
+
+		// act
+		cache.invalidateOnNotFound(configItem, &googleapi.Error{Code: http.StatusNotFound})
+
+		_, ok := cache.autoscalerName(configItem, selfLink, now)
+		assert.False(t, ok)
+	})
+
+	t.Run("KeepsCacheOnOtherErrors", func(t *testing.T) {
+
+		cache := newComputeCache(5 * time.Minute)
+		cache.setAutoscaler(configItem, autoscaler, now)
+
+		// act
+		cache.invalidateOnNotFound(configItem, errors.New("connection reset"))
+
+		_, ok := cache.autoscalerName(configItem, selfLink, now)
+		assert.True(t, ok)
+	})
+}
+
+func TestGetInstanceGroupManager(t *testing.T) {
+
+	t.Run("RetrievesCurrentTargetSizeEveryTimeWithComputeCacheTTL", func(t *testing.T) {
+
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/project-id/regions/europe-west1/instanceGroupManagers/web", r.URL.Path)
+			requests++
+			fmt.Fprintf(w, `{"name":"web","targetSize":%v}`, 10+requests)
+		}))
+		defer server.Close()
+
+		computeClient, _ := NewGAComputeClient(server.Client(), ComputeClientOptions{BasePath: server.URL + "/"})
+		scaler := NewMIGScaler(computeClient, nil, MIGScalerOptions{ComputeCacheTTL: time.Minute})
+		configItem := MIGConfiguration{GCloudProject: "project-id", GCloudRegion: "europe-west1", InstanceGroupName: "web"}
+
+		// act
+		_, err := scaler.getInstanceGroupManager(context.Background(), configItem)
+		assert.Nil(t, err)
+		instanceGroupManager, err := scaler.getInstanceGroupManager(context.Background(), configItem)
+
+		assert.Nil(t, err)
+		assert.Equal(t, int64(12), instanceGroupManager.TargetSize)
+		assert.Equal(t, 2, requests)
+	})
+}
+
+func TestFindAutoscalerWithComputeCache(t *testing.T) {
+
+	t.Run("RetrievesCachedAutoscalerByNameWithCurrentPolicy", func(t *testing.T) {
+
+		selfLink := "https://www.googleapis.com/compute/v1/projects/project-id/regions/europe-west1/instanceGroupManagers/web"
+		searches, gets := 0, 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/project-id/regions/europe-west1/autoscalers":
+				searches++
+				fmt.Fprintf(w, `{"items":[{"name":"web-autoscaler","target":"%v","autoscalingPolicy":{"minNumReplicas":3}}]}`, selfLink)
+			case "/project-id/regions/europe-west1/autoscalers/web-autoscaler":
+				gets++
+				fmt.Fprintf(w, `{"name":"web-autoscaler","target":"%v","autoscalingPolicy":{"minNumReplicas":5}}`, selfLink)
+			default:
+				t.Errorf("Unexpected request for %v", r.URL.Path)
+			}
+		}))
+		defer server.Close()
+
+		computeClient, _ := NewGAComputeClient(server.Client(), ComputeClientOptions{BasePath: server.URL + "/"})
+		scaler := NewMIGScaler(computeClient, nil, MIGScalerOptions{ComputeCacheTTL: time.Minute})
+		configItem := MIGConfiguration{GCloudProject: "project-id", GCloudRegion: "europe-west1", InstanceGroupName: "web"}
+		instanceGroupManager := &compute.InstanceGroupManager{Name: "web", SelfLink: selfLink}
+
+		// act
+		_, err := scaler.findAutoscaler(context.Background(), configItem, instanceGroupManager)
+		assert.Nil(t, err)
+		autoscaler, err := scaler.findAutoscaler(context.Background(), configItem, instanceGroupManager)
+
+		assert.Nil(t, err)
+		assert.Equal(t, int64(5), autoscaler.AutoscalingPolicy.MinNumReplicas)
+		assert.Equal(t, 1, searches)
+		assert.Equal(t, 1, gets)
+	})
+}
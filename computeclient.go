@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+
+	computebeta "google.golang.org/api/compute/v0.beta"
+	compute "google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+)
+
+// ComputeClient is the part of the compute api the scaler uses, for the zone or region of a managed instance group, so the scaler doesn't depend on a specific version of the compute client
+type ComputeClient interface {
+	GetInstanceGroupManager(ctx context.Context, configItem MIGConfiguration) (*compute.InstanceGroupManager, error)
+	ListInstanceGroupManagers(ctx context.Context, configItem MIGConfiguration) ([]*compute.InstanceGroupManager, error)
+	ListManagedInstances(ctx context.Context, configItem MIGConfiguration) ([]*compute.ManagedInstance, error)
+	GetDistributionZones(ctx context.Context, configItem MIGConfiguration) ([]string, error)
+	GetInstanceTemplate(ctx context.Context, project, name string) (*compute.InstanceTemplate, error)
+	GetAutoscaler(ctx context.Context, configItem MIGConfiguration, name string) (*Autoscaler, error)
+	ListAutoscalers(ctx context.Context, configItem MIGConfiguration, filter string) ([]*Autoscaler, error)
+	PatchAutoscaler(ctx context.Context, configItem MIGConfiguration, patch *compute.Autoscaler) (*compute.Operation, error)
+	PatchAutoscalerMode(ctx context.Context, configItem MIGConfiguration, name, mode string) (*compute.Operation, error)
+	GetOperation(ctx context.Context, configItem MIGConfiguration, name string) (*compute.Operation, error)
+
+	// WithClient returns a compute client for the same api, authenticated with another client
+	WithClient(client *http.Client) (ComputeClient, error)
+
+	// HTTPClient returns the authenticated client the compute client sends its requests with, for compute api calls it doesn't cover itself
+	HTTPClient() *http.Client
+}
+
+// Autoscaler is an autoscaler of the compute api, with the fields the ga compute client in use predates
+type Autoscaler struct {
+	*compute.Autoscaler
+
+	// Mode is the mode of the autoscaling policy: ON, OFF or ONLY_SCALE_OUT, or empty for autoscalers that predate it and are on
+	Mode string
+
+	// RecommendedSize is the number of instances the autoscaler calculated the managed instance group needs, within its min and max instances
+	RecommendedSize int64
+}
+
+// autoscalerFields holds the fields of an autoscaler response the ga compute client in use predates
+type autoscalerFields struct {
+	AutoscalingPolicy struct {
+		Mode string `json:"mode"`
+	} `json:"autoscalingPolicy"`
+	RecommendedSize int64 `json:"recommendedSize"`
+}
+
+func newAutoscaler(autoscaler *compute.Autoscaler, fields autoscalerFields) *Autoscaler {
+	return &Autoscaler{
+		Autoscaler:      autoscaler,
+		Mode:            fields.AutoscalingPolicy.Mode,
+		RecommendedSize: fields.RecommendedSize,
+	}
+}
+
+// ComputeClientOptions holds the settings of a compute client that apply regardless of the client it's authenticated with
+type ComputeClientOptions struct {
+	// BasePath replaces the urls of both the ga and the beta api if set
+	BasePath string
+
+	// Retry holds the backoff for compute api calls that are rate limited or fail with a server error
+	Retry ComputeRetryConfig
+
+	// RateLimiter is shared by all clients created with the options, so all compute api calls together stay within its rate; nil means no limit
+	RateLimiter *TokenBucket
+}
+
+// GAComputeClient implements ComputeClient with the ga compute api; only ListManagedInstances, for its pages, and GetDistributionZones, for the distribution policy, which the ga compute client in use lacks, call the beta api, while the autoscaler calls that need fields it lacks, like the mode, are sent as plain json requests to the ga api
+type GAComputeClient struct {
+	client      *http.Client
+	service     *compute.Service
+	betaService *computebeta.Service
+	options     ComputeClientOptions
+}
+
+// NewGAComputeClient returns a compute client authenticated with the client, that limits the rate of calls and retries rate limited and failed calls according to the options
+func NewGAComputeClient(client *http.Client, options ComputeClientOptions) (*GAComputeClient, error) {
+
+	// every retry waits for the rate limiter as well
+	client = newComputeRetryClient(newComputeRateLimitClient(client, options.RateLimiter), options.Retry)
+
+	service, err := compute.New(client)
+	if err != nil {
+		return nil, err
+	}
+	betaService, err := computebeta.New(client)
+	if err != nil {
+		return nil, err
+	}
+	if options.BasePath != "" {
+		service.BasePath = options.BasePath
+		betaService.BasePath = options.BasePath
+	}
+
+	return &GAComputeClient{client: client, service: service, betaService: betaService, options: options}, nil
+}
+
+// WithClient returns a compute client with the same options, authenticated with another client
+func (c *GAComputeClient) WithClient(client *http.Client) (ComputeClient, error) {
+	return NewGAComputeClient(client, c.options)
+}
+
+// HTTPClient returns the authenticated client including the rate limit and retries of the options
+func (c *GAComputeClient) HTTPClient() *http.Client {
+	return c.client
+}
+
+// GetInstanceGroupManager retrieves the regional or zonal instance group manager of the managed instance group
+func (c *GAComputeClient) GetInstanceGroupManager(ctx context.Context, configItem MIGConfiguration) (*compute.InstanceGroupManager, error) {
+	if configItem.GCloudRegion != "" {
+		return c.service.RegionInstanceGroupManagers.Get(configItem.GCloudProject, configItem.GCloudRegion, configItem.InstanceGroupName).Context(ctx).Do()
+	}
+	return c.service.InstanceGroupManagers.Get(configItem.GCloudProject, configItem.GCloudZone, configItem.InstanceGroupName).Context(ctx).Do()
+}
+
+// ListInstanceGroupManagers retrieves all instance group managers in the region or zone
+func (c *GAComputeClient) ListInstanceGroupManagers(ctx context.Context, configItem MIGConfiguration) (instanceGroupManagers []*compute.InstanceGroupManager, err error) {
+	if configItem.GCloudRegion != "" {
+		err = c.service.RegionInstanceGroupManagers.List(configItem.GCloudProject, configItem.GCloudRegion).Pages(ctx, func(page *compute.RegionInstanceGroupManagerList) error {
+			instanceGroupManagers = append(instanceGroupManagers, page.Items...)
+			return nil
+		})
+	} else {
+		err = c.service.InstanceGroupManagers.List(configItem.GCloudProject, configItem.GCloudZone).Pages(ctx, func(page *compute.InstanceGroupManagerList) error {
+			instanceGroupManagers = append(instanceGroupManagers, page.Items...)
+			return nil
+		})
+	}
+	return
+}
+
+// ListManagedInstances retrieves all instances of the regional or zonal managed instance group; it uses the beta api, since the regional response of the ga compute client in use has no page token, so instances beyond the first page would be missed
+func (c *GAComputeClient) ListManagedInstances(ctx context.Context, configItem MIGConfiguration) (managedInstances []*compute.ManagedInstance, err error) {
+	if configItem.GCloudRegion != "" {
+		err = c.betaService.RegionInstanceGroupManagers.ListManagedInstances(configItem.GCloudProject, configItem.GCloudRegion, configItem.InstanceGroupName).Pages(ctx, func(page *computebeta.RegionInstanceGroupManagersListInstancesResponse) error {
+			managedInstances = append(managedInstances, gaManagedInstances(page.ManagedInstances)...)
+			return nil
+		})
+	} else {
+		err = c.betaService.InstanceGroupManagers.ListManagedInstances(configItem.GCloudProject, configItem.GCloudZone, configItem.InstanceGroupName).Pages(ctx, func(page *computebeta.InstanceGroupManagersListManagedInstancesResponse) error {
+			managedInstances = append(managedInstances, gaManagedInstances(page.ManagedInstances)...)
+			return nil
+		})
+	}
+	return
+}
+
+// gaManagedInstances converts managed instances of the beta api to those of the ga api, with the fields the scaler uses
+func gaManagedInstances(betaManagedInstances []*computebeta.ManagedInstance) []*compute.ManagedInstance {
+	managedInstances := []*compute.ManagedInstance{}
+	for _, instance := range betaManagedInstances {
+		managedInstances = append(managedInstances, &compute.ManagedInstance{
+			Id:             instance.Id,
+			Instance:       instance.Instance,
+			InstanceStatus: instance.InstanceStatus,
+			CurrentAction:  instance.CurrentAction,
+		})
+	}
+	return managedInstances
+}
+
+// GetDistributionZones retrieves the zones a regional managed instance group distributes its instances over
+func (c *GAComputeClient) GetDistributionZones(ctx context.Context, configItem MIGConfiguration) ([]string, error) {
+
+	instanceGroupManager, err := c.betaService.RegionInstanceGroupManagers.Get(configItem.GCloudProject, configItem.GCloudRegion, configItem.InstanceGroupName).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	zones := []string{}
+	if instanceGroupManager.DistributionPolicy != nil {
+		for _, zone := range instanceGroupManager.DistributionPolicy.Zones {
+			zones = append(zones, path.Base(zone.Zone))
+		}
+	}
+
+	return zones, nil
+}
+
+// GetInstanceTemplate retrieves the global instance template by name
+func (c *GAComputeClient) GetInstanceTemplate(ctx context.Context, project, name string) (*compute.InstanceTemplate, error) {
+	return c.service.InstanceTemplates.Get(project, name).Context(ctx).Do()
+}
+
+// autoscalersURL returns the url of the regional or zonal autoscalers of the managed instance group, for the calls the ga compute client in use can't make
+func (c *GAComputeClient) autoscalersURL(configItem MIGConfiguration) string {
+	if configItem.GCloudRegion != "" {
+		return fmt.Sprintf("%v%v/regions/%v/autoscalers", c.service.BasePath, url.PathEscape(configItem.GCloudProject), url.PathEscape(configItem.GCloudRegion))
+	}
+	return fmt.Sprintf("%v%v/zones/%v/autoscalers", c.service.BasePath, url.PathEscape(configItem.GCloudProject), url.PathEscape(configItem.GCloudZone))
+}
+
+// GetAutoscaler retrieves the regional or zonal autoscaler by name
+func (c *GAComputeClient) GetAutoscaler(ctx context.Context, configItem MIGConfiguration, name string) (*Autoscaler, error) {
+
+	var autoscaler compute.Autoscaler
+	var fields autoscalerFields
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("%v/%v", c.autoscalersURL(configItem), url.PathEscape(name)), nil, &autoscaler, &fields); err != nil {
+		return nil, err
+	}
+
+	return newAutoscaler(&autoscaler, fields), nil
+}
+
+// ListAutoscalers retrieves the regional or zonal autoscalers matching the filter, of all pages
+func (c *GAComputeClient) ListAutoscalers(ctx context.Context, configItem MIGConfiguration, filter string) ([]*Autoscaler, error) {
+
+	autoscalers := []*Autoscaler{}
+	pageToken := ""
+	for {
+		listURL := fmt.Sprintf("%v?filter=%v", c.autoscalersURL(configItem), url.QueryEscape(filter))
+		if pageToken != "" {
+			listURL += fmt.Sprintf("&pageToken=%v", url.QueryEscape(pageToken))
+		}
+
+		var autoscalerList struct {
+			Items         []*compute.Autoscaler `json:"items"`
+			NextPageToken string                `json:"nextPageToken"`
+		}
+		var fieldsList struct {
+			Items []autoscalerFields `json:"items"`
+		}
+		if err := c.do(ctx, http.MethodGet, listURL, nil, &autoscalerList, &fieldsList); err != nil {
+			return nil, err
+		}
+
+		for i, autoscaler := range autoscalerList.Items {
+			autoscalers = append(autoscalers, newAutoscaler(autoscaler, fieldsList.Items[i]))
+		}
+
+		if autoscalerList.NextPageToken == "" {
+			return autoscalers, nil
+		}
+		pageToken = autoscalerList.NextPageToken
+	}
+}
+
+// PatchAutoscaler patches the regional or zonal autoscaler named in the patch with the fields set in it
+func (c *GAComputeClient) PatchAutoscaler(ctx context.Context, configItem MIGConfiguration, patch *compute.Autoscaler) (*compute.Operation, error) {
+	if configItem.GCloudRegion != "" {
+		return c.service.RegionAutoscalers.Patch(configItem.GCloudProject, configItem.GCloudRegion, patch).Autoscaler(patch.Name).Context(ctx).Do()
+	}
+	return c.service.Autoscalers.Patch(configItem.GCloudProject, configItem.GCloudZone, patch).Autoscaler(patch.Name).Context(ctx).Do()
+}
+
+// PatchAutoscalerMode sets the mode of the autoscaling policy of the regional or zonal autoscaler by name, leaving all other fields as they are
+func (c *GAComputeClient) PatchAutoscalerMode(ctx context.Context, configItem MIGConfiguration, name, mode string) (*compute.Operation, error) {
+
+	patch := map[string]interface{}{
+		"autoscalingPolicy": map[string]interface{}{
+			"mode": mode,
+		},
+	}
+
+	var operation compute.Operation
+	if err := c.do(ctx, http.MethodPatch, fmt.Sprintf("%v?autoscaler=%v", c.autoscalersURL(configItem), url.QueryEscape(name)), patch, &operation); err != nil {
+		return nil, err
+	}
+
+	return &operation, nil
+}
+
+// GetOperation retrieves the regional or zonal operation by name
+func (c *GAComputeClient) GetOperation(ctx context.Context, configItem MIGConfiguration, name string) (*compute.Operation, error) {
+	if configItem.GCloudRegion != "" {
+		return c.service.RegionOperations.Get(configItem.GCloudProject, configItem.GCloudRegion, name).Context(ctx).Do()
+	}
+	return c.service.ZoneOperations.Get(configItem.GCloudProject, configItem.GCloudZone, name).Context(ctx).Do()
+}
+
+// do sends a request with the json body, if any, to the compute api and decodes the response into all targets; errors are googleapi errors, as with the calls of the compute client
+func (c *GAComputeClient) do(ctx context.Context, method, url string, body interface{}, targets ...interface{}) error {
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	request, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(request.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := googleapi.CheckResponse(resp); err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	for _, target := range targets {
+		if err := json.Unmarshal(data, target); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
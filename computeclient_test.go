@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/googleapi"
+)
+
+func TestGAComputeClient(t *testing.T) {
+
+	t.Run("GetsDistributionZonesOfRegionalMigWithBetaApi", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/project-id/regions/europe-west1/instanceGroupManagers/web", r.URL.Path)
+			w.Write([]byte(`{"name":"web","distributionPolicy":{"zones":[{"zone":"https://www.googleapis.com/compute/beta/projects/project-id/zones/europe-west1-b"},{"zone":"https://www.googleapis.com/compute/beta/projects/project-id/zones/europe-west1-c"}]}}`))
+		}))
+		defer server.Close()
+
+		computeClient, _ := NewGAComputeClient(server.Client(), ComputeClientOptions{BasePath: server.URL + "/"})
+		configItem := MIGConfiguration{GCloudProject: "project-id", GCloudRegion: "europe-west1", InstanceGroupName: "web"}
+
+		// act
+		zones, err := computeClient.GetDistributionZones(context.Background(), configItem)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"europe-west1-b", "europe-west1-c"}, zones)
+	})
+
+	t.Run("ListsRegionalAutoscalersMatchingFilter", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/project-id/regions/europe-west1/autoscalers", r.URL.Path)
+			assert.Equal(t, "target eq web", r.URL.Query().Get("filter"))
+			w.Write([]byte(`{"items":[{"name":"web-autoscaler"}]}`))
+		}))
+		defer server.Close()
+
+		computeClient, _ := NewGAComputeClient(server.Client(), ComputeClientOptions{BasePath: server.URL + "/"})
+		configItem := MIGConfiguration{GCloudProject: "project-id", GCloudRegion: "europe-west1", InstanceGroupName: "web"}
+
+		// act
+		autoscalers, err := computeClient.ListAutoscalers(context.Background(), configItem, "target eq web")
+
+		assert.Nil(t, err)
+		if assert.Equal(t, 1, len(autoscalers)) {
+			assert.Equal(t, "web-autoscaler", autoscalers[0].Name)
+		}
+	})
+
+	t.Run("ListsInstanceGroupManagersOfAllPages", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/project-id/zones/europe-west1-b/instanceGroupManagers", r.URL.Path)
+			if r.URL.Query().Get("pageToken") == "" {
+				w.Write([]byte(`{"items":[{"name":"web"}],"nextPageToken":"page-2"}`))
+				return
+			}
+			w.Write([]byte(`{"items":[{"name":"api"}]}`))
+		}))
+		defer server.Close()
+
+		computeClient, _ := NewGAComputeClient(server.Client(), ComputeClientOptions{BasePath: server.URL + "/"})
+		configItem := MIGConfiguration{GCloudProject: "project-id", GCloudZone: "europe-west1-b"}
+
+		// act
+		instanceGroupManagers, err := computeClient.ListInstanceGroupManagers(context.Background(), configItem)
+
+		assert.Nil(t, err)
+		if assert.Equal(t, 2, len(instanceGroupManagers)) {
+			assert.Equal(t, "web", instanceGroupManagers[0].Name)
+			assert.Equal(t, "api", instanceGroupManagers[1].Name)
+		}
+	})
+
+	t.Run("ListsManagedInstancesOfRegionalMigOfAllPages", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/project-id/regions/europe-west1/instanceGroupManagers/web/listManagedInstances", r.URL.Path)
+			if r.URL.Query().Get("pageToken") == "" {
+				w.Write([]byte(`{"managedInstances":[{"instance":"https://www.googleapis.com/compute/beta/projects/project-id/zones/europe-west1-b/instances/web-1","instanceStatus":"RUNNING"}],"nextPageToken":"page-2"}`))
+				return
+			}
+			w.Write([]byte(`{"managedInstances":[{"instance":"https://www.googleapis.com/compute/beta/projects/project-id/zones/europe-west1-c/instances/web-2","instanceStatus":"PROVISIONING","currentAction":"CREATING"}]}`))
+		}))
+		defer server.Close()
+
+		computeClient, _ := NewGAComputeClient(server.Client(), ComputeClientOptions{BasePath: server.URL + "/"})
+		configItem := MIGConfiguration{GCloudProject: "project-id", GCloudRegion: "europe-west1", InstanceGroupName: "web"}
+
+		// act
+		managedInstances, err := computeClient.ListManagedInstances(context.Background(), configItem)
+
+		assert.Nil(t, err)
+		if assert.Equal(t, 2, len(managedInstances)) {
+			assert.Equal(t, "RUNNING", managedInstances[0].InstanceStatus)
+			assert.Equal(t, "CREATING", managedInstances[1].CurrentAction)
+		}
+	})
+
+	t.Run("KeepsBasePathForOtherClient", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/project-id/zones/europe-west1-b/instanceGroupManagers/web", r.URL.Path)
+			w.Write([]byte(`{"name":"web","targetSize":3}`))
+		}))
+		defer server.Close()
+
+		computeClient, _ := NewGAComputeClient(http.DefaultClient, ComputeClientOptions{BasePath: server.URL + "/"})
+		configItem := MIGConfiguration{GCloudProject: "project-id", GCloudZone: "europe-west1-b", InstanceGroupName: "web"}
+
+		// act
+		otherClient, err := computeClient.WithClient(server.Client())
+		assert.Nil(t, err)
+		instanceGroupManager, err := otherClient.GetInstanceGroupManager(context.Background(), configItem)
+
+		assert.Nil(t, err)
+		assert.Equal(t, int64(3), instanceGroupManager.TargetSize)
+	})
+}
+
+func TestGAComputeClientAutoscalers(t *testing.T) {
+
+	t.Run("GetsAutoscalerWithMode", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/project-id/zones/europe-west1-b/autoscalers/web-autoscaler", r.URL.Path)
+			w.Write([]byte(`{"name":"web-autoscaler","autoscalingPolicy":{"minNumReplicas":2,"maxNumReplicas":10,"mode":"OFF"}}`))
+		}))
+		defer server.Close()
+
+		computeClient, _ := NewGAComputeClient(server.Client(), ComputeClientOptions{BasePath: server.URL + "/"})
+		configItem := MIGConfiguration{GCloudProject: "project-id", GCloudZone: "europe-west1-b", InstanceGroupName: "web"}
+
+		// act
+		autoscaler, err := computeClient.GetAutoscaler(context.Background(), configItem, "web-autoscaler")
+
+		assert.Nil(t, err)
+		assert.Equal(t, int64(10), autoscaler.AutoscalingPolicy.MaxNumReplicas)
+		assert.Equal(t, "OFF", autoscaler.Mode)
+	})
+
+	t.Run("ListsAutoscalersWithRecommendedSizeAndStatusDetails", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"items":[{"name":"web-autoscaler","recommendedSize":4,"status":"ACTIVE","statusDetails":[{"type":"NOT_ENOUGH_QUOTA_AVAILABLE","message":"Quota CPUS exceeded"}]}]}`))
+		}))
+		defer server.Close()
+
+		computeClient, _ := NewGAComputeClient(server.Client(), ComputeClientOptions{BasePath: server.URL + "/"})
+		configItem := MIGConfiguration{GCloudProject: "project-id", GCloudZone: "europe-west1-b", InstanceGroupName: "web"}
+
+		// act
+		autoscalers, err := computeClient.ListAutoscalers(context.Background(), configItem, "target eq web")
+
+		assert.Nil(t, err)
+		if assert.Equal(t, 1, len(autoscalers)) {
+			assert.Equal(t, int64(4), autoscalers[0].RecommendedSize)
+			assert.Equal(t, map[string]string{"NOT_ENOUGH_QUOTA_AVAILABLE": "Quota CPUS exceeded"}, autoscalers[0].StatusDetails())
+		}
+	})
+
+	t.Run("ListsAutoscalersOfAllPages", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "target eq web", r.URL.Query().Get("filter"))
+			if r.URL.Query().Get("pageToken") == "" {
+				w.Write([]byte(`{"items":[{"name":"web-autoscaler","recommendedSize":4}],"nextPageToken":"page-2"}`))
+				return
+			}
+			assert.Equal(t, "page-2", r.URL.Query().Get("pageToken"))
+			w.Write([]byte(`{"items":[{"name":"web-autoscaler-2","recommendedSize":5}]}`))
+		}))
+		defer server.Close()
+
+		computeClient, _ := NewGAComputeClient(server.Client(), ComputeClientOptions{BasePath: server.URL + "/"})
+		configItem := MIGConfiguration{GCloudProject: "project-id", GCloudZone: "europe-west1-b", InstanceGroupName: "web"}
+
+		// act
+		autoscalers, err := computeClient.ListAutoscalers(context.Background(), configItem, "target eq web")
+
+		assert.Nil(t, err)
+		if assert.Equal(t, 2, len(autoscalers)) {
+			assert.Equal(t, "web-autoscaler-2", autoscalers[1].Name)
+			assert.Equal(t, int64(5), autoscalers[1].RecommendedSize)
+		}
+	})
+
+	t.Run("ReturnsGoogleApiErrorForMissingAutoscaler", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"code":404,"message":"The resource was not found"}}`))
+		}))
+		defer server.Close()
+
+		computeClient, _ := NewGAComputeClient(server.Client(), ComputeClientOptions{BasePath: server.URL + "/"})
+		configItem := MIGConfiguration{GCloudProject: "project-id", GCloudZone: "europe-west1-b", InstanceGroupName: "web"}
+
+		// act
+		_, err := computeClient.GetAutoscaler(context.Background(), configItem, "web-autoscaler")
+
+		if assert.IsType(t, &googleapi.Error{}, err) {
+			assert.Equal(t, http.StatusNotFound, err.(*googleapi.Error).Code)
+		}
+	})
+}
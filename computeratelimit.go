@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// TokenBucket limits the rate of compute api requests of all managed instance groups together; it holds up to burst tokens, refilled at rate per second, and every request takes one
+type TokenBucket struct {
+	sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket returns a full token bucket for the rate per second and burst, of at least 1
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// reserve takes a token and returns how long to wait until it would have been available; the tokens go negative while requests are waiting, so they're served in order
+func (b *TokenBucket) reserve(now time.Time) time.Duration {
+	b.Lock()
+	defer b.Unlock()
+
+	if now.After(b.last) {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// release returns a token that was reserved but not used
+func (b *TokenBucket) release() {
+	b.Lock()
+	defer b.Unlock()
+
+	b.tokens++
+}
+
+// Wait blocks until a token is available or the context is done
+func (b *TokenBucket) Wait(ctx context.Context) error {
+
+	wait := b.reserve(time.Now())
+	if wait <= 0 {
+		return nil
+	}
+	computeRateLimitWaitCounter.Add(wait.Seconds())
+
+	select {
+	case <-ctx.Done():
+		b.release()
+		return ctx.Err()
+	case <-time.After(wait):
+		return nil
+	}
+}
+
+// computeRateLimitTransport waits for a token of the shared bucket before sending every compute api request, including retries
+type computeRateLimitTransport struct {
+	base    http.RoundTripper
+	limiter *TokenBucket
+}
+
+// newComputeRateLimitClient returns a copy of the client that waits for the limiter before every request, or the client itself without a limiter
+func newComputeRateLimitClient(client *http.Client, limiter *TokenBucket) *http.Client {
+	if limiter == nil {
+		return client
+	}
+
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	rateLimitClient := *client
+	rateLimitClient.Transport = &computeRateLimitTransport{base: base, limiter: limiter}
+
+	return &rateLimitClient
+}
+
+func (t *computeRateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucket(t *testing.T) {
+
+	t.Run("AllowsBurstWithoutWaiting", func(t *testing.T) {
+
+		bucket := NewTokenBucket(1, 3)
+		now := bucket.last
+
+		// act
+		waits := []time.Duration{bucket.reserve(now), bucket.reserve(now), bucket.reserve(now)}
+
+		assert.Equal(t, []time.Duration{0, 0, 0}, waits)
+	})
+
+	t.Run("QueuesRequestsBeyondBurstAtRate", func(t *testing.T) {
+
+		bucket := NewTokenBucket(2, 1)
+		now := bucket.last
+		bucket.reserve(now)
+
+		// act
+		waits := []time.Duration{bucket.reserve(now), bucket.reserve(now)}
+
+		assert.Equal(t, []time.Duration{500 * time.Millisecond, time.Second}, waits)
+	})
+
+	t.Run("RefillsUpToBurst", func(t *testing.T) {
+
+		bucket := NewTokenBucket(10, 2)
+		now := bucket.last
+		bucket.reserve(now)
+		bucket.reserve(now)
+
+		// act
+		later := now.Add(time.Minute)
+		waits := []time.Duration{bucket.reserve(later), bucket.reserve(later), bucket.reserve(later)}
+
+		assert.Equal(t, []time.Duration{0, 0, 100 * time.Millisecond}, waits)
+	})
+
+	t.Run("ReturnsTokenIfContextIsDoneWhileWaiting", func(t *testing.T) {
+
+		bucket := NewTokenBucket(0.001, 1)
+		bucket.reserve(time.Now())
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+
+		// act
+		err := bucket.Wait(ctx)
+
+		assert.Equal(t, context.DeadlineExceeded, err)
+		assert.True(t, bucket.tokens > -1, "tokens %v", bucket.tokens)
+	})
+}
+
+func TestComputeRateLimitTransport(t *testing.T) {
+
+	t.Run("SharesLimiterWithClientsForOtherCredentials", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"name":"web"}`))
+		}))
+		defer server.Close()
+
+		limiter := NewTokenBucket(0.001, 1)
+		computeClient, _ := NewGAComputeClient(server.Client(), ComputeClientOptions{BasePath: server.URL + "/", RateLimiter: limiter})
+		otherClient, _ := computeClient.WithClient(server.Client())
+		configItem := MIGConfiguration{GCloudProject: "project-id", GCloudZone: "europe-west1-b", InstanceGroupName: "web"}
+
+		_, err := computeClient.GetInstanceGroupManager(context.Background(), configItem)
+		assert.Nil(t, err)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		// act
+		_, err = otherClient.GetInstanceGroupManager(ctx, configItem)
+
+		assert.NotNil(t, err)
+	})
+}
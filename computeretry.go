@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	rateLimitedRetryClass = "rate_limited"
+	serverErrorRetryClass = "server_error"
+)
+
+// ComputeRetryConfig holds the retry settings for compute api calls; without an initial or maximum backoff they wait 1 second growing up to 16 seconds
+type ComputeRetryConfig struct {
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// Backoff returns how long to wait before the retry after the attempt, counting from 0: exponentially growing up to the maximum with jitter in its upper half, or as long as a Retry-After header asks for
+func (c ComputeRetryConfig) Backoff(attempt int, retryAfter string, now time.Time) time.Duration {
+
+	if wait, ok := parseRetryAfter(retryAfter, now); ok {
+		return wait
+	}
+
+	initialBackoff := c.InitialBackoff
+	if initialBackoff <= 0 {
+		initialBackoff = time.Second
+	}
+	maxBackoff := c.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = 16 * time.Second
+	}
+
+	backoff := initialBackoff
+	for i := 0; i < attempt && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+
+	// waiting a random part of the upper half keeps all migs throttled at once from retrying at the same moment
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// parseRetryAfter returns the wait a Retry-After header asks for, either in seconds or until an http date
+func parseRetryAfter(retryAfter string, now time.Time) (time.Duration, bool) {
+	if retryAfter == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(retryAfter); err == nil {
+		if wait := date.Sub(now); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// ComputeRetryClass returns whether a compute api response is worth retrying: rate_limited for 429 or a 403 for exceeding a rate limit, server_error for 5xx, and empty otherwise; the body is left readable
+func ComputeRetryClass(resp *http.Response) string {
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return rateLimitedRetryClass
+	case resp.StatusCode >= 500:
+		return serverErrorRetryClass
+	case resp.StatusCode == http.StatusForbidden:
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			return ""
+		}
+
+		var errorResponse struct {
+			Error struct {
+				Errors []struct {
+					Reason string `json:"reason"`
+				} `json:"errors"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(body, &errorResponse) != nil {
+			return ""
+		}
+		for _, item := range errorResponse.Error.Errors {
+			if item.Reason == "rateLimitExceeded" || item.Reason == "userRateLimitExceeded" {
+				return rateLimitedRetryClass
+			}
+		}
+	}
+
+	return ""
+}
+
+// computeRetryTransport retries compute api requests that are rate limited or fail with a server error with exponential backoff, until the retries run out or the context of the request is done
+type computeRetryTransport struct {
+	base  http.RoundTripper
+	retry ComputeRetryConfig
+}
+
+// newComputeRetryClient returns a copy of the client that retries compute api requests, or the client itself without retries
+func newComputeRetryClient(client *http.Client, retry ComputeRetryConfig) *http.Client {
+	if retry.MaxRetries <= 0 {
+		return client
+	}
+
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	retryClient := *client
+	retryClient.Transport = &computeRetryTransport{base: base, retry: retry}
+
+	return &retryClient
+}
+
+func (t *computeRetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	for attempt := 0; ; attempt++ {
+
+		attemptReq := req
+		if attempt > 0 && req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.WithContext(req.Context())
+			attemptReq.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(attemptReq)
+		if err != nil {
+			return nil, err
+		}
+
+		class := ComputeRetryClass(resp)
+		if class == "" || attempt >= t.retry.MaxRetries || (req.Body != nil && req.GetBody == nil) {
+			return resp, nil
+		}
+
+		wait := t.retry.Backoff(attempt, resp.Header.Get("Retry-After"), time.Now())
+		resp.Body.Close()
+
+		log.Warn().Str("class", class).Msgf("Compute api request %v %v returned status code %v, retrying in %v", req.Method, req.URL.Path, resp.StatusCode, wait)
+		computeRetriesVector.WithLabelValues(class).Inc()
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+	}
+}
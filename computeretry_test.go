@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeRetryConfigBackoff(t *testing.T) {
+
+	now := time.Date(2024, 11, 29, 12, 0, 0, 0, time.UTC)
+
+	t.Run("DoublesWithEachAttemptWithJitterInUpperHalf", func(t *testing.T) {
+
+		config := ComputeRetryConfig{InitialBackoff: time.Second, MaxBackoff: time.Minute}
+
+		// act
+		backoff := config.Backoff(2, "", now)
+
+		assert.True(t, backoff >= 2*time.Second && backoff <= 4*time.Second, "backoff %v", backoff)
+	})
+
+	t.Run("CapsAtMaxBackoff", func(t *testing.T) {
+
+		config := ComputeRetryConfig{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+
+		// act
+		backoff := config.Backoff(10, "", now)
+
+		assert.True(t, backoff >= 2500*time.Millisecond && backoff <= 5*time.Second, "backoff %v", backoff)
+	})
+
+	t.Run("HonorsRetryAfterInSeconds", func(t *testing.T) {
+
+		config := ComputeRetryConfig{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+
+		// act
+		backoff := config.Backoff(0, "30", now)
+
+		assert.Equal(t, 30*time.Second, backoff)
+	})
+
+	t.Run("HonorsRetryAfterAsHttpDate", func(t *testing.T) {
+
+		config := ComputeRetryConfig{}
+
+		// act
+		backoff := config.Backoff(0, now.Add(10*time.Second).Format(http.TimeFormat), now)
+
+		assert.Equal(t, 10*time.Second, backoff)
+	})
+}
+
+func TestComputeRetryClass(t *testing.T) {
+
+	t.Run("ReturnsRateLimitedFor429", func(t *testing.T) {
+
+		// act
+		class := ComputeRetryClass(&http.Response{StatusCode: http.StatusTooManyRequests, Body: ioutil.NopCloser(strings.NewReader(""))})
+
+		assert.Equal(t, "rate_limited", class)
+	})
+
+	t.Run("ReturnsRateLimitedFor403WithRateLimitExceededAndKeepsBodyReadable", func(t *testing.T) {
+
+		body := `{"error":{"code":403,"errors":[{"reason":"rateLimitExceeded"}]}}`
+
+		resp := &http.Response{StatusCode: http.StatusForbidden, Body: ioutil.NopCloser(strings.NewReader(body))}
+
+		// act
+		class := ComputeRetryClass(resp)
+
+		assert.Equal(t, "rate_limited", class)
+		remaining, _ := ioutil.ReadAll(resp.Body)
+		assert.Equal(t, body, string(remaining))
+	})
+
+	t.Run("ReturnsEmptyFor403WithoutRateLimitReason", func(t *testing.T) {
+
+		// act
+		class := ComputeRetryClass(&http.Response{StatusCode: http.StatusForbidden, Body: ioutil.NopCloser(strings.NewReader(`{"error":{"code":403,"errors":[{"reason":"forbidden"}]}}`))})
+
+		assert.Equal(t, "", class)
+	})
+
+	t.Run("ReturnsServerErrorFor5xx", func(t *testing.T) {
+
+		// act
+		class := ComputeRetryClass(&http.Response{StatusCode: http.StatusServiceUnavailable, Body: ioutil.NopCloser(strings.NewReader(""))})
+
+		assert.Equal(t, "server_error", class)
+	})
+}
+
+func TestComputeRetryTransport(t *testing.T) {
+
+	t.Run("RetriesRateLimitedPatchWithSameBody", func(t *testing.T) {
+
+		attempts := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			body, _ := ioutil.ReadAll(r.Body)
+			assert.Contains(t, string(body), `"minNumReplicas":0`)
+			if attempts == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.Write([]byte(`{"name":"operation-1","status":"DONE"}`))
+		}))
+		defer server.Close()
+
+		retriesBefore := testutil.ToFloat64(computeRetriesVector.WithLabelValues("rate_limited"))
+		computeClient, _ := NewGAComputeClient(server.Client(), ComputeClientOptions{BasePath: server.URL + "/", Retry: ComputeRetryConfig{MaxRetries: 3, InitialBackoff: time.Millisecond}})
+		configItem := MIGConfiguration{GCloudProject: "project-id", GCloudZone: "europe-west1-b", InstanceGroupName: "web"}
+
+		// act
+		operation, err := computeClient.PatchAutoscaler(context.Background(), configItem, AutoscalerPatch("web-autoscaler", true, 0, false, 0))
+
+		assert.Nil(t, err)
+		assert.Equal(t, "operation-1", operation.Name)
+		assert.Equal(t, 2, attempts)
+		assert.Equal(t, retriesBefore+1, testutil.ToFloat64(computeRetriesVector.WithLabelValues("rate_limited")))
+	})
+
+	t.Run("ReturnsErrorAfterMaxRetries", func(t *testing.T) {
+
+		attempts := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		computeClient, _ := NewGAComputeClient(server.Client(), ComputeClientOptions{BasePath: server.URL + "/", Retry: ComputeRetryConfig{MaxRetries: 2, InitialBackoff: time.Millisecond}})
+		configItem := MIGConfiguration{GCloudProject: "project-id", GCloudZone: "europe-west1-b", InstanceGroupName: "web"}
+
+		// act
+		_, err := computeClient.GetInstanceGroupManager(context.Background(), configItem)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, 3, attempts)
+	})
+
+	t.Run("DoesNotRetryClientErrors", func(t *testing.T) {
+
+		attempts := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		computeClient, _ := NewGAComputeClient(server.Client(), ComputeClientOptions{BasePath: server.URL + "/", Retry: ComputeRetryConfig{MaxRetries: 2, InitialBackoff: time.Millisecond}})
+		configItem := MIGConfiguration{GCloudProject: "project-id", GCloudZone: "europe-west1-b", InstanceGroupName: "web"}
+
+		// act
+		_, err := computeClient.GetInstanceGroupManager(context.Background(), configItem)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, 1, attempts)
+	})
+}
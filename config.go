@@ -0,0 +1,676 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/ghodss/yaml"
+	"github.com/rs/zerolog/log"
+)
+
+// MIGConfiguration has all the config needed for a single managed instance group to be scaled
+type MIGConfiguration struct {
+	GCloudProject                string                   `json:"gcloudProject,omitempty"`
+	GCloudZone                   string                   `json:"gcloudZone,omitempty"`
+	GCloudRegion                 string                   `json:"gcloudRegion,omitempty"`
+	CredentialsFile              string                   `json:"credentialsFile,omitempty"`
+	CredentialsSecret            string                   `json:"credentialsSecret,omitempty"`
+	MetricSource                 string                   `json:"metricSource,omitempty"`
+	PrometheusURL                string                   `json:"prometheusUrl,omitempty"`
+	PrometheusReplicaAggregation string                   `json:"prometheusReplicaAggregation,omitempty"`
+	PrometheusUsername           string                   `json:"prometheusUsername,omitempty"`
+	PrometheusPassword           string                   `json:"prometheusPassword,omitempty"`
+	PrometheusBearerTokenFile    string                   `json:"prometheusBearerTokenFile,omitempty"`
+	PrometheusCAFile             string                   `json:"prometheusCaFile,omitempty"`
+	PrometheusCertFile           string                   `json:"prometheusCertFile,omitempty"`
+	PrometheusKeyFile            string                   `json:"prometheusKeyFile,omitempty"`
+	PrometheusInsecureSkipVerify bool                     `json:"prometheusInsecureSkipVerify,omitempty"`
+	PrometheusIAPAudience        string                   `json:"prometheusIapAudience,omitempty"`
+	PrometheusHeaders            map[string]string        `json:"prometheusHeaders,omitempty"`
+	PrometheusProxyURL           string                   `json:"prometheusProxyUrl,omitempty"`
+	PrometheusUsePOST            bool                     `json:"prometheusUsePost,omitempty"`
+	AWSRegion                    string                   `json:"awsRegion,omitempty"`
+	ElasticsearchURL             string                   `json:"elasticsearchUrl,omitempty"`
+	ElasticsearchIndex           string                   `json:"elasticsearchIndex,omitempty"`
+	ElasticsearchWindowSeconds   float64                  `json:"elasticsearchWindowSeconds,omitempty"`
+	LokiURL                      string                   `json:"lokiUrl,omitempty"`
+	KafkaBrokers                 string                   `json:"kafkaBrokers,omitempty"`
+	KafkaConsumerGroup           string                   `json:"kafkaConsumerGroup,omitempty"`
+	KafkaTopic                   string                   `json:"kafkaTopic,omitempty"`
+	RabbitMQURL                  string                   `json:"rabbitmqUrl,omitempty"`
+	RabbitMQVhost                string                   `json:"rabbitmqVhost,omitempty"`
+	RabbitMQQueue                string                   `json:"rabbitmqQueue,omitempty"`
+	RabbitMQMetric               string                   `json:"rabbitmqMetric,omitempty"`
+	PubSubProject                string                   `json:"pubsubProject,omitempty"`
+	PubSubSubscription           string                   `json:"pubsubSubscription,omitempty"`
+	CloudTasksProject            string                   `json:"cloudTasksProject,omitempty"`
+	CloudTasksLocation           string                   `json:"cloudTasksLocation,omitempty"`
+	CloudTasksQueue              string                   `json:"cloudTasksQueue,omitempty"`
+	CloudTasksMetric             string                   `json:"cloudTasksMetric,omitempty"`
+	BigQueryProject              string                   `json:"bigqueryProject,omitempty"`
+	BigQueryRefreshSeconds       int                      `json:"bigqueryRefreshSeconds,omitempty"`
+	SQLDriver                    string                   `json:"sqlDriver,omitempty"`
+	SQLDSN                       string                   `json:"sqlDsn,omitempty"`
+	HTTPURL                      string                   `json:"httpUrl,omitempty"`
+	ExecCommand                  []string                 `json:"execCommand,omitempty"`
+	ExecTimeoutSeconds           int                      `json:"execTimeoutSeconds,omitempty"`
+	Queries                      []map[string]interface{} `json:"queries,omitempty"`
+	QueryAggregation             string                   `json:"queryAggregation,omitempty"`
+	Weight                       *float64                 `json:"weight,omitempty"`
+	Fallbacks                    []map[string]interface{} `json:"fallbacks,omitempty"`
+	RequestRateQuery             string                   `json:"requestRateQuery,omitempty"`
+	RequestRateQueryTemplate     string                   `json:"requestRateQueryTemplate,omitempty"`
+	QueryVariables               map[string]string        `json:"queryVariables,omitempty"`
+	EvaluationWindows            []string                 `json:"evaluationWindows,omitempty"`
+	TrendQuery                   string                   `json:"trendQuery,omitempty"`
+	HistoricalOffsetSeconds      int                      `json:"historicalOffsetSeconds,omitempty"`
+	HistoricalLeadSeconds        int                      `json:"historicalLeadSeconds,omitempty"`
+	BootTimeLeadSeconds          int                      `json:"bootTimeLeadSeconds,omitempty"`
+	AnomalyFilterDeviations      float64                  `json:"anomalyFilterDeviations,omitempty"`
+	AnomalyFilterSamples         int                      `json:"anomalyFilterSamples,omitempty"`
+	SeriesSelector               map[string]string        `json:"seriesSelector,omitempty"`
+	QueryType                    string                   `json:"queryType,omitempty"`
+	RangeWindowSeconds           int                      `json:"rangeWindowSeconds,omitempty"`
+	RangeStepSeconds             int                      `json:"rangeStepSeconds,omitempty"`
+	RangeAggregation             string                   `json:"rangeAggregation,omitempty"`
+	QueryTimeoutSeconds          int                      `json:"queryTimeoutSeconds,omitempty"`
+	MaxMetricAgeSeconds          int                      `json:"maxMetricAgeSeconds,omitempty"`
+	MissingDataPolicy            string                   `json:"missingDataPolicy,omitempty"`
+	MissingDataFallbackRate      *float64                 `json:"missingDataFallbackRate,omitempty"`
+	InstanceGroupName            string                   `json:"instanceGroupName,omitempty"`
+	AutoscalerName               string                   `json:"autoscalerName,omitempty"`
+	AutoscalerModePolicy         string                   `json:"autoscalerModePolicy,omitempty"`
+	HoldScaleDownOnStatusErrors  bool                     `json:"holdScaleDownOnAutoscalerErrors,omitempty"`
+	MinimumNumberOfInstances     int                      `json:"minimumNumberOfInstances,omitempty"`
+	MaximumNumberOfInstances     int                      `json:"maximumNumberOfInstances,omitempty"`
+	MaxInstancesToSet            int                      `json:"maximumNumberOfInstancesToSet,omitempty"`
+	InstanceHourlyCost           float64                  `json:"instanceHourlyCost,omitempty"`
+	InstanceCostSkus             []InstanceCostSku        `json:"instanceCostSkus,omitempty"`
+	MaxHourlyCost                float64                  `json:"maxHourlyCost,omitempty"`
+	NumberOfRequestsPerInstance  float64                  `json:"numberOfRequestsPerInstance,omitempty"`
+	RequestsPerInstanceQuery     string                   `json:"numberOfRequestsPerInstanceQuery,omitempty"`
+	MinRequestsPerInstance       float64                  `json:"minNumberOfRequestsPerInstance,omitempty"`
+	MaxRequestsPerInstance       float64                  `json:"maxNumberOfRequestsPerInstance,omitempty"`
+	CapacityWeight               float64                  `json:"capacityWeight,omitempty"`
+	Rounding                     string                   `json:"rounding,omitempty"`
+	TargetExpression             string                   `json:"targetExpression,omitempty"`
+	FollowMIG                    string                   `json:"followMig,omitempty"`
+	UpstreamMIG                  string                   `json:"upstreamMig,omitempty"`
+	UpstreamGCloudZone           string                   `json:"upstreamGcloudZone,omitempty"`
+	UpstreamGCloudRegion         string                   `json:"upstreamGcloudRegion,omitempty"`
+	Ratio                        float64                  `json:"ratio,omitempty"`
+	NumberOfInstancesBelowTarget int                      `json:"numberOfInstancesBelowTarget,omitempty"`
+	TargetHeadroomPercent        float64                  `json:"targetHeadroomPercent,omitempty"`
+	SpotOverprovisionPercent     float64                  `json:"spotOverprovisionPercent,omitempty"`
+	SpotPreemptionRateQuery      string                   `json:"spotPreemptionRateQuery,omitempty"`
+	ZoneOutageCompensation       bool                     `json:"zoneOutageCompensation,omitempty"`
+	SLOQuery                     string                   `json:"sloQuery,omitempty"`
+	SLOThreshold                 float64                  `json:"sloThreshold,omitempty"`
+	SLOScaleUpPercent            float64                  `json:"sloScaleUpPercent,omitempty"`
+	HysteresisPercent            float64                  `json:"hysteresisPercent,omitempty"`
+	Timezone                     string                   `json:"timezone,omitempty"`
+	Holidays                     []string                 `json:"holidays,omitempty"`
+	Profiles                     MIGProfiles              `json:"profiles,omitempty"`
+	Schedules                    []ScheduleEntry          `json:"schedules,omitempty"`
+	UseScalingSchedules          bool                     `json:"useScalingSchedules,omitempty"`
+	ScaleDownConfirmations       int                      `json:"scaleDownConfirmations,omitempty"`
+	ScaleDownCooldownSeconds     int                      `json:"scaleDownCooldownSeconds,omitempty"`
+	MaxScaleDownStep             int                      `json:"maxScaleDownStep,omitempty"`
+	MaxScaleUpStep               int                      `json:"maxScaleUpStep,omitempty"`
+	MaxInstancesChangePerMinute  float64                  `json:"maxInstancesChangePerMinute,omitempty"`
+	MinChangeInstances           int                      `json:"minChangeInstances,omitempty"`
+	EnableSettingMinInstances    bool                     `json:"enableSettingMinInstances,omitempty"`
+	EnableSettingMaxInstances    bool                     `json:"enableSettingMaxInstances,omitempty"`
+	MaintenanceWindows           []MaintenanceWindow      `json:"maintenanceWindows,omitempty"`
+	Enabled                      *bool                    `json:"enabled,omitempty"`
+}
+
+// IsEnabled returns whether the managed instance group should be scaled at all; it defaults to true when enabled isn't set
+func (c *MIGConfiguration) IsEnabled() bool {
+	return c.Enabled == nil || *c.Enabled
+}
+
+// ScalesOnRequestRate returns whether the minimum number of instances is calculated from a request rate, rather than from another mig with followMig or upstreamMig
+func (c *MIGConfiguration) ScalesOnRequestRate() bool {
+	return c.FollowMIG == "" && c.UpstreamMIG == ""
+}
+
+// Config is the full managed instance group configuration
+type Config struct {
+	MIGs      []MIGConfiguration          `json:"migs,omitempty"`
+	Discovery []MIGDiscoveryConfiguration `json:"discovery,omitempty"`
+
+	// Revision identifies the version of the configuration in its source, if the source supports it
+	Revision string `json:"-"`
+}
+
+// ReadConfig reads and unmarshals the managed instance group configuration from the config source
+func ReadConfig(ctx context.Context, source ConfigSource) (config Config, err error) {
+
+	log.Debug().Msgf("Reading managed instance group configuration from %v...", source)
+
+	data, err := source.Read(ctx)
+	if err != nil {
+		return
+	}
+
+	config, err = UnmarshalConfig(data)
+	config.Revision = ConfigSourceRevision(source)
+
+	return
+}
+
+// MIGConfigFile is the structure of a configuration with a defaults section that all managed instance group entries and discovery rules inherit from
+type MIGConfigFile struct {
+	Version   int                         `json:"version,omitempty"`
+	Defaults  map[string]interface{}      `json:"defaults,omitempty"`
+	MIGs      []map[string]interface{}    `json:"migs,omitempty"`
+	Discovery []MIGDiscoveryConfiguration `json:"discovery,omitempty"`
+}
+
+// UnmarshalConfig unmarshals yaml or json managed instance group configuration of any supported version, either a plain array of entries or an object with version, defaults, migs and discovery rules
+func UnmarshalConfig(data []byte) (config Config, err error) {
+	return unmarshalConfig(data, true)
+}
+
+// unmarshalConfig unmarshals configuration like UnmarshalConfig; without expand it leaves ${ENV_VAR} placeholders and request rate query templates as they are, for configuration that's unmarshalled again after merging, so they're expanded and rendered only once
+func unmarshalConfig(data []byte, expand bool) (config Config, err error) {
+
+	// json is a subset of yaml, so converting to json handles both formats
+	jsonData, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return
+	}
+
+	var raw interface{}
+	if err = json.Unmarshal(jsonData, &raw); err != nil {
+		return
+	}
+
+	// expand ${ENV_VAR} placeholders in all string values
+	if expand {
+		raw, err = expandEnvVars(raw)
+		if err != nil {
+			return
+		}
+	}
+
+	// upgrade older configuration formats, including the plain array of entries, to the latest version
+	migrated, err := MigrateConfig(raw)
+	if err != nil {
+		return
+	}
+	jsonData, err = json.Marshal(migrated)
+	if err != nil {
+		return
+	}
+
+	var configFile MIGConfigFile
+	if err = json.Unmarshal(jsonData, &configFile); err != nil {
+		return
+	}
+
+	config.MIGs, err = applyMIGConfigDefaults(configFile)
+	if err != nil {
+		return
+	}
+
+	if expand {
+		if err = renderRequestRateQueries(config.MIGs); err != nil {
+			return
+		}
+	}
+
+	for _, discovery := range configFile.Discovery {
+		discovery.MIG = mergeMaps(configFile.Defaults, discovery.MIG)
+		config.Discovery = append(config.Discovery, discovery)
+	}
+
+	return
+}
+
+// QueryTemplateData is passed to request rate query templates when rendering them for a managed instance group
+type QueryTemplateData struct {
+	InstanceGroupName string
+	GCloudProject     string
+	GCloudZone        string
+	GCloudRegion      string
+	Variables         map[string]string
+
+	// Window is the evaluation window the query is rendered for, if evaluationWindows is set
+	Window string
+}
+
+// RenderRequestRateQuery sets the request rate query by rendering the request rate query template with the query variables, unless a request rate query is set explicitly; with evaluationWindows it's rendered for the first window
+func (c *MIGConfiguration) RenderRequestRateQuery() error {
+	window := ""
+	if len(c.EvaluationWindows) > 0 {
+		window = c.EvaluationWindows[0]
+	}
+	return c.renderRequestRateQuery(window)
+}
+
+func (c *MIGConfiguration) renderRequestRateQuery(window string) error {
+
+	if c.RequestRateQuery != "" || c.RequestRateQueryTemplate == "" {
+		return nil
+	}
+
+	// fail on variables missing from queryVariables instead of rendering <no value> into the query
+	queryTemplate, err := template.New("query").Option("missingkey=error").Parse(c.RequestRateQueryTemplate)
+	if err != nil {
+		return err
+	}
+
+	var query bytes.Buffer
+	err = queryTemplate.Execute(&query, QueryTemplateData{
+		InstanceGroupName: c.InstanceGroupName,
+		GCloudProject:     c.GCloudProject,
+		GCloudZone:        c.GCloudZone,
+		GCloudRegion:      c.GCloudRegion,
+		Variables:         c.QueryVariables,
+		Window:            window,
+	})
+	if err != nil {
+		return err
+	}
+
+	c.RequestRateQuery = query.String()
+
+	return nil
+}
+
+func renderRequestRateQueries(migConfigs []MIGConfiguration) error {
+	for i := range migConfigs {
+		if err := migConfigs[i].RenderRequestRateQuery(); err != nil {
+			return fmt.Errorf("Rendering request rate query template for mig %v failed: %v", migConfigs[i].InstanceGroupName, err)
+		}
+	}
+	return nil
+}
+
+var envVarPlaceholderRegex = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnvVars replaces ${ENV_VAR} placeholders in all string values of unmarshalled json with the value of the environment variable; unset variables result in an error
+func expandEnvVars(raw interface{}) (interface{}, error) {
+
+	switch value := raw.(type) {
+	case string:
+		var err error
+		expanded := envVarPlaceholderRegex.ReplaceAllStringFunc(value, func(placeholder string) string {
+			name := envVarPlaceholderRegex.FindStringSubmatch(placeholder)[1]
+			envValue, ok := os.LookupEnv(name)
+			if !ok && err == nil {
+				err = fmt.Errorf("Environment variable %v used in configuration is not set", name)
+			}
+			return envValue
+		})
+		return expanded, err
+
+	case []interface{}:
+		for i, item := range value {
+			expanded, err := expandEnvVars(item)
+			if err != nil {
+				return nil, err
+			}
+			value[i] = expanded
+		}
+		return value, nil
+
+	case map[string]interface{}:
+		for k, item := range value {
+			expanded, err := expandEnvVars(item)
+			if err != nil {
+				return nil, err
+			}
+			value[k] = expanded
+		}
+		return value, nil
+	}
+
+	return raw, nil
+}
+
+// applyMIGConfigDefaults merges each managed instance group entry on top of the defaults, so entries only have to set the fields they override
+func applyMIGConfigDefaults(configFile MIGConfigFile) (migConfigs []MIGConfiguration, err error) {
+
+	for _, mig := range configFile.MIGs {
+		mergedJSON, err := json.Marshal(mergeMaps(configFile.Defaults, mig))
+		if err != nil {
+			return migConfigs, err
+		}
+
+		var migConfig MIGConfiguration
+		if err = json.Unmarshal(mergedJSON, &migConfig); err != nil {
+			return migConfigs, err
+		}
+
+		migConfigs = append(migConfigs, migConfig)
+	}
+
+	return
+}
+
+// mergeMaps returns a new map with the values of overrides set on top of base; nested maps are merged recursively
+func mergeMaps(base, overrides map[string]interface{}) map[string]interface{} {
+
+	merged := map[string]interface{}{}
+	for k, v := range base {
+		merged[k] = v
+	}
+
+	for k, v := range overrides {
+		baseMap, baseIsMap := merged[k].(map[string]interface{})
+		overrideMap, overrideIsMap := v.(map[string]interface{})
+		if baseIsMap && overrideIsMap {
+			merged[k] = mergeMaps(baseMap, overrideMap)
+		} else {
+			merged[k] = v
+		}
+	}
+
+	return merged
+}
+
+// ValidationError describes a single invalid field in the configuration of a managed instance group
+type ValidationError struct {
+	Index             int
+	InstanceGroupName string
+	Field             string
+	Message           string
+
+	// File and Line locate the field in the configuration, if it's looked up with locateValidationErrors
+	File string
+	Line int
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%vmig[%v] (%v) %v: %v", e.location(), e.Index, e.InstanceGroupName, e.Field, e.Message)
+}
+
+// ValidationErrors bundles all validation errors found in a managed instance group configuration
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := []string{}
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return fmt.Sprintf("%v validation errors: %v", len(e), strings.Join(messages, "; "))
+}
+
+// Validate checks whether the configuration for a managed instance group is usable for scaling and returns an error for every invalid field
+func (c *MIGConfiguration) Validate() (errs []ValidationError) {
+
+	addError := func(field, message string) {
+		errs = append(errs, ValidationError{InstanceGroupName: c.InstanceGroupName, Field: field, Message: message})
+	}
+
+	if c.InstanceGroupName == "" {
+		addError("instanceGroupName", "is required")
+	}
+	if c.GCloudProject == "" {
+		addError("gcloudProject", "is required")
+	}
+	if c.GCloudZone == "" && c.GCloudRegion == "" {
+		addError("gcloudZone", "either gcloudZone or gcloudRegion is required")
+	}
+	if c.GCloudZone != "" && c.GCloudRegion != "" {
+		addError("gcloudZone", "gcloudZone and gcloudRegion are mutually exclusive")
+	}
+	if len(c.Queries) > 0 {
+		c.validateQueries(addError)
+	} else if c.ScalesOnRequestRate() {
+		c.validateMetricSource(addError)
+		c.validateFallbacks(addError)
+	}
+	if c.MinimumNumberOfInstances < 0 {
+		addError("minimumNumberOfInstances", "should be 0 or larger")
+	}
+	if c.MaximumNumberOfInstances < 0 {
+		addError("maximumNumberOfInstances", "should be 0 or larger")
+	}
+	if c.MaximumNumberOfInstances > 0 && c.MaximumNumberOfInstances < c.MinimumNumberOfInstances {
+		addError("maximumNumberOfInstances", "should be larger than or equal to minimumNumberOfInstances")
+	}
+	if c.MaxInstancesToSet < 0 {
+		addError("maximumNumberOfInstancesToSet", "should be 0 or larger")
+	}
+	if c.EnableSettingMaxInstances && c.MaxInstancesToSet <= 0 {
+		addError("maximumNumberOfInstancesToSet", "is required for enableSettingMaxInstances")
+	}
+	if c.MaxInstancesToSet > 0 && c.MaxInstancesToSet < c.MinimumNumberOfInstances {
+		addError("maximumNumberOfInstancesToSet", "should be larger than or equal to minimumNumberOfInstances")
+	}
+	if c.NumberOfRequestsPerInstance <= 0 && c.RequestsPerInstanceQuery == "" && c.ScalesOnRequestRate() {
+		addError("numberOfRequestsPerInstance", "should be larger than 0")
+	}
+	if c.Rounding != "" && c.Rounding != ceilRounding && c.Rounding != roundRounding && c.Rounding != floorRounding {
+		addError("rounding", fmt.Sprintf("should be one of %v", roundings))
+	}
+	if c.CapacityWeight < 0 {
+		addError("capacityWeight", "should be 0 or larger")
+	}
+	if c.NumberOfInstancesBelowTarget < 0 {
+		addError("numberOfInstancesBelowTarget", "should be 0 or larger")
+	}
+	if c.TargetHeadroomPercent < 0 || c.TargetHeadroomPercent >= 100 {
+		addError("targetHeadroomPercent", "should be 0 or larger and smaller than 100")
+	}
+	if c.TargetHeadroomPercent > 0 && c.NumberOfInstancesBelowTarget > 0 {
+		addError("targetHeadroomPercent", "targetHeadroomPercent and numberOfInstancesBelowTarget are mutually exclusive")
+	}
+	if c.AnomalyFilterDeviations < 0 {
+		addError("anomalyFilterDeviations", "should be 0 or larger")
+	}
+	if c.AnomalyFilterSamples != 0 && c.AnomalyFilterSamples < minAnomalyFilterSamples {
+		addError("anomalyFilterSamples", fmt.Sprintf("should be %v or larger", minAnomalyFilterSamples))
+	}
+	if c.BootTimeLeadSeconds < 0 {
+		addError("bootTimeLeadSeconds", "should be 0 or larger")
+	}
+	if c.ZoneOutageCompensation && c.GCloudRegion == "" {
+		addError("zoneOutageCompensation", "requires gcloudRegion")
+	}
+	if c.QueryTimeoutSeconds < 0 {
+		addError("queryTimeoutSeconds", "should be 0 or larger")
+	}
+	if c.ScaleDownConfirmations < 0 {
+		addError("scaleDownConfirmations", "should be 0 or larger")
+	}
+	if c.MinChangeInstances < 0 {
+		addError("minChangeInstances", "should be 0 or larger")
+	}
+	if c.ScaleDownCooldownSeconds < 0 {
+		addError("scaleDownCooldownSeconds", "should be 0 or larger")
+	}
+	if c.MaxScaleDownStep < 0 {
+		addError("maxScaleDownStep", "should be 0 or larger")
+	}
+	if c.MaxScaleUpStep < 0 {
+		addError("maxScaleUpStep", "should be 0 or larger")
+	}
+	if c.MaxInstancesChangePerMinute < 0 {
+		addError("maxInstancesChangePerMinute", "should be 0 or larger")
+	}
+	c.validateMissingDataPolicy(addError)
+	c.validateHysteresis(addError)
+	c.validateEvaluationWindows(addError)
+	c.validateHistoricalOffset(addError)
+	c.validateSchedules(addError)
+	c.validateScalingSchedules(addError)
+	c.validateMaintenanceWindows(addError)
+	c.validateProfiles(addError)
+	c.validateTargetExpression(addError)
+	c.validateFollowMIG(addError)
+	c.validateUpstreamMIG(addError)
+	c.validateCost(addError)
+	c.validateSpotOverprovision(addError)
+	c.validateSLO(addError)
+	c.validateRequestsPerInstanceQuery(addError)
+	c.validateCredentials(addError)
+	c.validateAutoscalerModePolicy(addError)
+
+	return
+}
+
+// validateMetricSource checks whether the metric source is supported and has the fields it needs
+func (c *MIGConfiguration) validateMetricSource(addError func(field, message string)) {
+	if validateMetricSource, ok := metricSourceValidators[c.MetricSourceName()]; ok {
+		validateMetricSource(c, addError)
+	} else {
+		addError("metricSource", fmt.Sprintf("metric source %v is not supported", c.MetricSource))
+	}
+}
+
+// ValidateMIGConfigs checks all managed instance group configurations and returns ValidationErrors if any of them is invalid
+func ValidateMIGConfigs(migConfigs []MIGConfiguration) error {
+
+	errs := ValidationErrors{}
+	instanceGroups := map[migStateKey]bool{}
+
+	for i, c := range migConfigs {
+		for _, err := range c.Validate() {
+			err.Index = i
+			errs = append(errs, err)
+		}
+
+		// migs with the same name in another project, zone or region are different migs
+		if c.InstanceGroupName != "" {
+			key := newMIGStateKey(c)
+			if instanceGroups[key] {
+				errs = append(errs, ValidationError{Index: i, InstanceGroupName: c.InstanceGroupName, Field: "instanceGroupName", Message: "is not unique"})
+			}
+			instanceGroups[key] = true
+		}
+	}
+
+	errs = append(errs, validateFollowedMIGs(migConfigs)...)
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// ValidateConfig checks all managed instance group configurations and discovery rules and returns ValidationErrors if any of them is invalid
+func ValidateConfig(config Config) error {
+
+	errs := ValidationErrors{}
+	if err, ok := ValidateMIGConfigs(config.MIGs).(ValidationErrors); ok {
+		errs = append(errs, err...)
+	}
+
+	for i, d := range config.Discovery {
+		for _, err := range d.Validate() {
+			err.Index = i
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// DiffMIGConfigs returns the project/location/name of the managed instance groups that were added, removed or changed between two configurations
+func DiffMIGConfigs(oldConfigs, newConfigs []MIGConfiguration) (added, removed, changed []string) {
+
+	oldConfigsByKey := map[migStateKey]MIGConfiguration{}
+	for _, c := range oldConfigs {
+		oldConfigsByKey[newMIGStateKey(c)] = c
+	}
+
+	newConfigsByKey := map[migStateKey]bool{}
+	for _, c := range newConfigs {
+		key := newMIGStateKey(c)
+		newConfigsByKey[key] = true
+
+		oldConfig, ok := oldConfigsByKey[key]
+		if !ok {
+			added = append(added, key.String())
+		} else if !reflect.DeepEqual(oldConfig, c) {
+			changed = append(changed, key.String())
+		}
+	}
+
+	for _, c := range oldConfigs {
+		if key := newMIGStateKey(c); !newConfigsByKey[key] {
+			removed = append(removed, key.String())
+		}
+	}
+
+	return
+}
+
+// MIGConfigStore holds the active managed instance group configuration and allows it to be swapped atomically while the main loop is running
+type MIGConfigStore struct {
+	mutex                sync.RWMutex
+	config               Config
+	discoveredMIGConfigs []MIGConfiguration
+
+	// ValidateQueries checks the queries of a reloaded configuration with their metric sources before it's swapped in, if set; it's set before the configuration is watched for changes
+	ValidateQueries func(ctx context.Context, migConfigs []MIGConfiguration) error
+}
+
+// NewMIGConfigStore returns a store initialized with the given configuration
+func NewMIGConfigStore(config Config) *MIGConfigStore {
+	return &MIGConfigStore{
+		config: config,
+	}
+}
+
+// Get returns the active managed instance group configuration, both explicitly configured and discovered; explicitly configured entries take precedence over discovered ones with the same project, location and name
+func (s *MIGConfigStore) Get() []MIGConfiguration {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	migConfigs := append([]MIGConfiguration{}, s.config.MIGs...)
+
+	configured := map[migStateKey]bool{}
+	for _, c := range s.config.MIGs {
+		configured[newMIGStateKey(c)] = true
+	}
+	for _, c := range s.discoveredMIGConfigs {
+		if !configured[newMIGStateKey(c)] {
+			migConfigs = append(migConfigs, c)
+		}
+	}
+
+	return migConfigs
+}
+
+// GetConfig returns the active configuration as loaded from the config source
+func (s *MIGConfigStore) GetConfig() Config {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.config
+}
+
+// Set replaces the active configuration
+func (s *MIGConfigStore) Set(config Config) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.config = config
+}
+
+// SetDiscovered replaces the managed instance groups found by discovery
+func (s *MIGConfigStore) SetDiscovered(migConfigs []MIGConfiguration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.discoveredMIGConfigs = migConfigs
+}
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnmarshalConfig(t *testing.T) {
+
+	t.Run("ReturnsConfigsFromJSON", func(t *testing.T) {
+
+		data := []byte("[{\"gcloudProject\":\"project-id\",\"gcloudRegion\":\"europe-west1\",\"requestRateQuery\":\"sum(rate(nginx_http_requests_total[10m]))\",\"instanceGroupName\":\"instance-group-name\",\"minimumNumberOfInstances\":3,\"numberOfRequestsPerInstance\":5.8,\"numberOfInstancesBelowTarget\":2,\"enableSettingMinInstances\":true}]")
+
+		// act
+		config, err := UnmarshalConfig(data)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(config.MIGs))
+		assert.Equal(t, "project-id", config.MIGs[0].GCloudProject)
+		assert.Equal(t, "europe-west1", config.MIGs[0].GCloudRegion)
+		assert.Equal(t, "instance-group-name", config.MIGs[0].InstanceGroupName)
+		assert.Equal(t, 3, config.MIGs[0].MinimumNumberOfInstances)
+		assert.Equal(t, 5.8, config.MIGs[0].NumberOfRequestsPerInstance)
+		assert.Equal(t, 2, config.MIGs[0].NumberOfInstancesBelowTarget)
+		assert.True(t, config.MIGs[0].EnableSettingMinInstances)
+	})
+
+	t.Run("ReturnsConfigsFromYAML", func(t *testing.T) {
+
+		data := []byte(`
+- gcloudProject: project-id
+  gcloudZone: europe-west1-b
+  requestRateQuery: sum(rate(nginx_http_requests_total[10m]))
+  instanceGroupName: instance-group-name
+  minimumNumberOfInstances: 3
+  numberOfRequestsPerInstance: 5.8
+`)
+
+		// act
+		config, err := UnmarshalConfig(data)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(config.MIGs))
+		assert.Equal(t, "europe-west1-b", config.MIGs[0].GCloudZone)
+		assert.Equal(t, "sum(rate(nginx_http_requests_total[10m]))", config.MIGs[0].RequestRateQuery)
+		assert.Equal(t, 5.8, config.MIGs[0].NumberOfRequestsPerInstance)
+		assert.False(t, config.MIGs[0].EnableSettingMinInstances)
+	})
+}
+
+func TestReadConfig(t *testing.T) {
+
+	t.Run("ReturnsConfigsFromStaticConfigSource", func(t *testing.T) {
+
+		source := &StaticConfigSource{Data: "[{\"instanceGroupName\":\"instance-group-name\"}]"}
+
+		// act
+		config, err := ReadConfig(context.Background(), source)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(config.MIGs))
+		assert.Equal(t, "instance-group-name", config.MIGs[0].InstanceGroupName)
+	})
+}
+
+func TestIsEnabled(t *testing.T) {
+
+	t.Run("ReturnsTrueIfEnabledIsNotSet", func(t *testing.T) {
+
+		configItem := MIGConfiguration{}
+
+		// act
+		enabled := configItem.IsEnabled()
+
+		assert.True(t, enabled)
+	})
+
+	t.Run("ReturnsFalseIfEnabledIsSetToFalse", func(t *testing.T) {
+
+		configs, err := UnmarshalConfig([]byte("- instanceGroupName: instance-group-name\n  enabled: false\n"))
+		assert.Nil(t, err)
+
+		// act
+		enabled := configs.MIGs[0].IsEnabled()
+
+		assert.False(t, enabled)
+	})
+}
+
+func TestDiffMIGConfigs(t *testing.T) {
+
+	t.Run("ReturnsAddedRemovedAndChangedInstanceGroups", func(t *testing.T) {
+
+		oldConfigs := []MIGConfiguration{
+			MIGConfiguration{GCloudProject: "project-id", GCloudRegion: "europe-west1", InstanceGroupName: "unchanged", MinimumNumberOfInstances: 1},
+			MIGConfiguration{GCloudProject: "project-id", GCloudRegion: "europe-west1", InstanceGroupName: "changed", MinimumNumberOfInstances: 1},
+			MIGConfiguration{GCloudProject: "project-id", GCloudRegion: "europe-west1", InstanceGroupName: "removed", MinimumNumberOfInstances: 1},
+		}
+		newConfigs := []MIGConfiguration{
+			MIGConfiguration{GCloudProject: "project-id", GCloudRegion: "europe-west1", InstanceGroupName: "unchanged", MinimumNumberOfInstances: 1},
+			MIGConfiguration{GCloudProject: "project-id", GCloudRegion: "europe-west1", InstanceGroupName: "changed", MinimumNumberOfInstances: 2},
+			MIGConfiguration{GCloudProject: "project-id", GCloudRegion: "europe-west1", InstanceGroupName: "added", MinimumNumberOfInstances: 1},
+		}
+
+		// act
+		added, removed, changed := DiffMIGConfigs(oldConfigs, newConfigs)
+
+		assert.Equal(t, []string{"project-id/europe-west1/added"}, added)
+		assert.Equal(t, []string{"project-id/europe-west1/removed"}, removed)
+		assert.Equal(t, []string{"project-id/europe-west1/changed"}, changed)
+	})
+
+	t.Run("ReturnsMIGMovedToAnotherProjectAsAddedAndRemoved", func(t *testing.T) {
+
+		oldConfigs := []MIGConfiguration{
+			MIGConfiguration{GCloudProject: "project-a", GCloudRegion: "europe-west1", InstanceGroupName: "web", MinimumNumberOfInstances: 1},
+		}
+		newConfigs := []MIGConfiguration{
+			MIGConfiguration{GCloudProject: "project-b", GCloudRegion: "europe-west1", InstanceGroupName: "web", MinimumNumberOfInstances: 1},
+		}
+
+		// act
+		added, removed, changed := DiffMIGConfigs(oldConfigs, newConfigs)
+
+		assert.Equal(t, []string{"project-b/europe-west1/web"}, added)
+		assert.Equal(t, []string{"project-a/europe-west1/web"}, removed)
+		assert.Equal(t, 0, len(changed))
+	})
+}
+
+func TestValidateMIGConfigs(t *testing.T) {
+
+	validConfig := MIGConfiguration{
+		GCloudProject:               "project-id",
+		GCloudRegion:                "europe-west1",
+		RequestRateQuery:            "sum(rate(nginx_http_requests_total[10m]))",
+		InstanceGroupName:           "instance-group-name",
+		NumberOfRequestsPerInstance: 5.8,
+	}
+
+	t.Run("ReturnsNilForValidConfig", func(t *testing.T) {
+
+		// act
+		err := ValidateMIGConfigs([]MIGConfiguration{validConfig})
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("ReturnsErrorPerInvalidField", func(t *testing.T) {
+
+		invalidConfig := validConfig
+		invalidConfig.GCloudProject = ""
+		invalidConfig.GCloudZone = "europe-west1-b"
+		invalidConfig.NumberOfRequestsPerInstance = 0
+
+		// act
+		err := ValidateMIGConfigs([]MIGConfiguration{validConfig, invalidConfig})
+
+		if assert.IsType(t, ValidationErrors{}, err) {
+			validationErrors := err.(ValidationErrors)
+			assert.Equal(t, 3, len(validationErrors))
+			assert.Equal(t, 1, validationErrors[0].Index)
+			assert.Equal(t, "gcloudProject", validationErrors[0].Field)
+			assert.Equal(t, "gcloudZone", validationErrors[1].Field)
+			assert.Equal(t, "numberOfRequestsPerInstance", validationErrors[2].Field)
+		}
+	})
+
+	t.Run("ReturnsErrorForMIGConfiguredTwiceInSameLocation", func(t *testing.T) {
+
+		// act
+		err := ValidateMIGConfigs([]MIGConfiguration{validConfig, validConfig})
+
+		if assert.IsType(t, ValidationErrors{}, err) {
+			validationErrors := err.(ValidationErrors)
+			assert.Equal(t, 1, len(validationErrors))
+			assert.Equal(t, 1, validationErrors[0].Index)
+			assert.Equal(t, "instanceGroupName", validationErrors[0].Field)
+			assert.Equal(t, "is not unique", validationErrors[0].Message)
+		}
+	})
+
+	t.Run("ReturnsNilForMIGsWithSameNameInOtherProjectsOrRegions", func(t *testing.T) {
+
+		otherProject := validConfig
+		otherProject.GCloudProject = "other-project-id"
+		otherRegion := validConfig
+		otherRegion.GCloudRegion = "europe-west4"
+
+		// act
+		err := ValidateMIGConfigs([]MIGConfiguration{validConfig, otherProject, otherRegion})
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("ReturnsErrorForUnparseableQuery", func(t *testing.T) {
+
+		invalidConfig := validConfig
+		invalidConfig.RequestRateQuery = "sum(rate(nginx_http_requests_total[10m])"
+
+		// act
+		err := ValidateMIGConfigs([]MIGConfiguration{invalidConfig})
+
+		if assert.IsType(t, ValidationErrors{}, err) {
+			assert.Equal(t, "requestRateQuery", err.(ValidationErrors)[0].Field)
+		}
+	})
+
+	t.Run("ReturnsErrorForInvalidQueryInQueries", func(t *testing.T) {
+
+		invalidConfig := validConfig
+		invalidConfig.Queries = []map[string]interface{}{
+			map[string]interface{}{"requestRateQuery": "sum(rate(websocket_connections[10m])"},
+		}
+
+		// act
+		err := ValidateMIGConfigs([]MIGConfiguration{invalidConfig})
+
+		if assert.IsType(t, ValidationErrors{}, err) {
+			assert.Equal(t, "queries[0].requestRateQuery", err.(ValidationErrors)[0].Field)
+		}
+	})
+
+	t.Run("ReturnsErrorForEnableSettingMaxInstancesWithoutMaximumNumberOfInstancesToSet", func(t *testing.T) {
+
+		invalidConfig := validConfig
+		invalidConfig.EnableSettingMaxInstances = true
+
+		// act
+		err := ValidateMIGConfigs([]MIGConfiguration{invalidConfig})
+
+		if assert.IsType(t, ValidationErrors{}, err) {
+			assert.Equal(t, "maximumNumberOfInstancesToSet", err.(ValidationErrors)[0].Field)
+			assert.Equal(t, "is required for enableSettingMaxInstances", err.(ValidationErrors)[0].Message)
+		}
+	})
+
+	t.Run("ReturnsErrorForMaximumNumberOfInstancesToSetBelowMinimumNumberOfInstances", func(t *testing.T) {
+
+		invalidConfig := validConfig
+		invalidConfig.MinimumNumberOfInstances = 5
+		invalidConfig.MaxInstancesToSet = 4
+		invalidConfig.EnableSettingMaxInstances = true
+
+		// act
+		err := ValidateMIGConfigs([]MIGConfiguration{invalidConfig})
+
+		if assert.IsType(t, ValidationErrors{}, err) {
+			assert.Equal(t, "maximumNumberOfInstancesToSet", err.(ValidationErrors)[0].Field)
+			assert.Equal(t, "should be larger than or equal to minimumNumberOfInstances", err.(ValidationErrors)[0].Message)
+		}
+	})
+
+	t.Run("ReturnsErrorForUnsupportedMetricSource", func(t *testing.T) {
+
+		invalidConfig := validConfig
+		invalidConfig.MetricSource = "graphite"
+
+		// act
+		err := ValidateMIGConfigs([]MIGConfiguration{invalidConfig})
+
+		if assert.IsType(t, ValidationErrors{}, err) {
+			assert.Equal(t, "metricSource", err.(ValidationErrors)[0].Field)
+		}
+	})
+
+	t.Run("ReturnsErrorForPrometheusProxyUrlWithoutHTTPSchemeOrHost", func(t *testing.T) {
+
+		for _, proxyURL := range []string{"proxy:3128", "socks5://proxy:1080", "http://", "/proxy"} {
+			invalidConfig := validConfig
+			invalidConfig.PrometheusProxyURL = proxyURL
+
+			// act
+			err := ValidateMIGConfigs([]MIGConfiguration{invalidConfig})
+
+			if assert.IsType(t, ValidationErrors{}, err, proxyURL) {
+				assert.Equal(t, "prometheusProxyUrl", err.(ValidationErrors)[0].Field)
+			}
+		}
+	})
+
+	t.Run("ReturnsNilForPrometheusProxyUrlWithHTTPSchemeAndHost", func(t *testing.T) {
+
+		validProxyConfig := validConfig
+		validProxyConfig.PrometheusProxyURL = "https://proxy:3128"
+
+		// act
+		err := ValidateMIGConfigs([]MIGConfiguration{validProxyConfig})
+
+		assert.Nil(t, err)
+	})
+}
+
+func TestUnmarshalConfigWithDefaults(t *testing.T) {
+
+	t.Run("AppliesDefaultsToEachEntry", func(t *testing.T) {
+
+		data := []byte(`
+defaults:
+  gcloudProject: project-id
+  gcloudRegion: europe-west1
+  numberOfRequestsPerInstance: 5.8
+  enableSettingMinInstances: true
+migs:
+- instanceGroupName: instance-group-a
+  requestRateQuery: sum(rate(nginx_http_requests_total{location="a"}[10m]))
+- instanceGroupName: instance-group-b
+  requestRateQuery: sum(rate(nginx_http_requests_total{location="b"}[10m]))
+  numberOfRequestsPerInstance: 10
+  enableSettingMinInstances: false
+`)
+
+		// act
+		config, err := UnmarshalConfig(data)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(config.MIGs))
+		assert.Equal(t, "project-id", config.MIGs[0].GCloudProject)
+		assert.Equal(t, "europe-west1", config.MIGs[0].GCloudRegion)
+		assert.Equal(t, 5.8, config.MIGs[0].NumberOfRequestsPerInstance)
+		assert.True(t, config.MIGs[0].EnableSettingMinInstances)
+		assert.Equal(t, "project-id", config.MIGs[1].GCloudProject)
+		assert.Equal(t, 10.0, config.MIGs[1].NumberOfRequestsPerInstance)
+		assert.False(t, config.MIGs[1].EnableSettingMinInstances)
+	})
+}
+
+func TestUnmarshalConfigWithEnvVars(t *testing.T) {
+
+	t.Run("ExpandsEnvVarPlaceholders", func(t *testing.T) {
+
+		os.Setenv("MIG_SCALER_TEST_PROJECT", "project-id")
+		os.Setenv("MIG_SCALER_TEST_LOCATION", "@applicationname")
+		defer os.Unsetenv("MIG_SCALER_TEST_PROJECT")
+		defer os.Unsetenv("MIG_SCALER_TEST_LOCATION")
+
+		data := []byte(`
+- gcloudProject: ${MIG_SCALER_TEST_PROJECT}
+  requestRateQuery: sum(rate(nginx_http_requests_total{host!~"^(?:[0-9.]+)$",location="${MIG_SCALER_TEST_LOCATION}"}[10m]))
+  instanceGroupName: instance-group-name
+`)
+
+		// act
+		config, err := UnmarshalConfig(data)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "project-id", config.MIGs[0].GCloudProject)
+		assert.Equal(t, "sum(rate(nginx_http_requests_total{host!~\"^(?:[0-9.]+)$\",location=\"@applicationname\"}[10m]))", config.MIGs[0].RequestRateQuery)
+	})
+
+	t.Run("ReturnsErrorForUnsetEnvVar", func(t *testing.T) {
+
+		data := []byte(`
+- gcloudProject: ${MIG_SCALER_TEST_UNSET}
+  instanceGroupName: instance-group-name
+`)
+
+		// act
+		_, err := UnmarshalConfig(data)
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestUnmarshalConfigWithQueryTemplates(t *testing.T) {
+
+	t.Run("RendersRequestRateQueryTemplateWithQueryVariables", func(t *testing.T) {
+
+		data := []byte(`
+defaults:
+  requestRateQueryTemplate: sum(rate(nginx_http_requests_total{location="{{ .Variables.location }}"}[10m]))
+migs:
+- instanceGroupName: instance-group-a
+  queryVariables:
+    location: "@a"
+- instanceGroupName: instance-group-b
+  requestRateQuery: sum(rate(nginx_http_requests_total{location="@b"}[5m]))
+`)
+
+		// act
+		config, err := UnmarshalConfig(data)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "sum(rate(nginx_http_requests_total{location=\"@a\"}[10m]))", config.MIGs[0].RequestRateQuery)
+		assert.Equal(t, "sum(rate(nginx_http_requests_total{location=\"@b\"}[5m]))", config.MIGs[1].RequestRateQuery)
+	})
+
+	t.Run("ReturnsErrorForMissingQueryVariable", func(t *testing.T) {
+
+		data := []byte(`
+- instanceGroupName: instance-group-a
+  requestRateQueryTemplate: sum(rate(nginx_http_requests_total{location="{{ .Variables.location }}"}[10m]))
+  queryVariables:
+    host: www.example.com
+`)
+
+		// act
+		_, err := UnmarshalConfig(data)
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestMIGConfigStoreGet(t *testing.T) {
+
+	t.Run("ReturnsDiscoveredMIGsUnlessConfiguredInSameProjectAndLocation", func(t *testing.T) {
+
+		store := NewMIGConfigStore(Config{MIGs: []MIGConfiguration{
+			MIGConfiguration{GCloudProject: "project-a", GCloudRegion: "europe-west1", InstanceGroupName: "web", MinimumNumberOfInstances: 3},
+		}})
+		store.SetDiscovered([]MIGConfiguration{
+			MIGConfiguration{GCloudProject: "project-a", GCloudRegion: "europe-west1", InstanceGroupName: "web", MinimumNumberOfInstances: 1},
+			MIGConfiguration{GCloudProject: "project-b", GCloudRegion: "europe-west1", InstanceGroupName: "web", MinimumNumberOfInstances: 1},
+		})
+
+		// act
+		migConfigs := store.Get()
+
+		if assert.Equal(t, 2, len(migConfigs)) {
+			assert.Equal(t, 3, migConfigs[0].MinimumNumberOfInstances)
+			assert.Equal(t, "project-b", migConfigs[1].GCloudProject)
+		}
+	})
+}
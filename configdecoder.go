@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/hashicorp/hcl"
+)
+
+// ConfigDecoder converts configuration in a specific format to json, so it can be unmarshalled like any other configuration
+type ConfigDecoder func(data []byte) ([]byte, error)
+
+// configDecoders maps config file extensions to the decoder for that format; json and yaml files are handled by the yaml unmarshaller directly and don't need a decoder
+var configDecoders = map[string]ConfigDecoder{
+	".toml": decodeTOMLConfig,
+	".hcl":  decodeHCLConfig,
+}
+
+// DecodeConfigFile converts the content of a config file to json if its extension has a registered decoder, and returns it as is otherwise
+func DecodeConfigFile(path string, data []byte) ([]byte, error) {
+
+	decoder, ok := configDecoders[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return data, nil
+	}
+
+	return decoder(data)
+}
+
+func decodeTOMLConfig(data []byte) ([]byte, error) {
+
+	var raw map[string]interface{}
+	if _, err := toml.Decode(string(data), &raw); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(raw)
+}
+
+func decodeHCLConfig(data []byte) ([]byte, error) {
+
+	var raw map[string]interface{}
+	if err := hcl.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	flattenHCLBlocks(raw, reflect.TypeOf(MIGConfigFile{}))
+
+	return json.Marshal(raw)
+}
+
+// migConfigMapType is the type of the maps in the configuration that hold managed instance group fields, like defaults, profiles and queries
+var migConfigMapType = reflect.TypeOf(map[string]interface{}{})
+
+// flattenHCLBlocks turns the single-element lists hcl decodes every block into back into objects where the configuration field of type t the block maps to is a struct or map; blocks of list fields, like a single schedules block, stay a list
+func flattenHCLBlocks(raw interface{}, t reflect.Type) interface{} {
+
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == migConfigMapType {
+		t = reflect.TypeOf(MIGConfiguration{})
+	}
+
+	switch value := raw.(type) {
+	case []map[string]interface{}:
+		if len(value) == 1 && t != nil && (t.Kind() == reflect.Struct || t.Kind() == reflect.Map) {
+			return flattenHCLBlocks(value[0], t)
+		}
+		items := []interface{}{}
+		for _, item := range value {
+			items = append(items, flattenHCLBlocks(item, hclElemType(t)))
+		}
+		return items
+
+	case []interface{}:
+		for i, item := range value {
+			value[i] = flattenHCLBlocks(item, hclElemType(t))
+		}
+		return value
+
+	case map[string]interface{}:
+		for k, v := range value {
+			value[k] = flattenHCLBlocks(v, hclFieldType(t, k))
+		}
+		return value
+	}
+
+	return raw
+}
+
+// hclElemType returns the type of the items of a list field, or nil if it isn't a list
+func hclElemType(t reflect.Type) reflect.Type {
+	if t == nil || (t.Kind() != reflect.Slice && t.Kind() != reflect.Array) {
+		return nil
+	}
+	return t.Elem()
+}
+
+// hclFieldType returns the type of the field with json name key of a struct, or of the values of a map, or nil if it's unknown
+func hclFieldType(t reflect.Type, key string) reflect.Type {
+
+	if t == nil {
+		return nil
+	}
+	if t.Kind() == reflect.Map {
+		return t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if strings.Split(field.Tag.Get("json"), ",")[0] == key {
+			return field.Type
+		}
+	}
+
+	return nil
+}
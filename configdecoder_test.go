@@ -0,0 +1,111 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeConfigFile(t *testing.T) {
+
+	t.Run("DecodesTOML", func(t *testing.T) {
+
+		data := []byte(`
+[defaults]
+gcloudProject = "project-id"
+numberOfRequestsPerInstance = 5.8
+
+[[migs]]
+instanceGroupName = "instance-group-a"
+
+[[migs]]
+instanceGroupName = "instance-group-b"
+numberOfRequestsPerInstance = 10.0
+`)
+
+		// act
+		jsonData, err := DecodeConfigFile("migs.toml", data)
+
+		assert.Nil(t, err)
+		config, err := UnmarshalConfig(jsonData)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(config.MIGs))
+		assert.Equal(t, "project-id", config.MIGs[0].GCloudProject)
+		assert.Equal(t, 5.8, config.MIGs[0].NumberOfRequestsPerInstance)
+		assert.Equal(t, "instance-group-b", config.MIGs[1].InstanceGroupName)
+		assert.Equal(t, 10.0, config.MIGs[1].NumberOfRequestsPerInstance)
+	})
+
+	t.Run("DecodesHCL", func(t *testing.T) {
+
+		data := []byte(`
+defaults {
+  gcloudProject = "project-id"
+  numberOfRequestsPerInstance = 5.8
+}
+
+migs {
+  instanceGroupName = "instance-group-a"
+}
+
+migs {
+  instanceGroupName = "instance-group-b"
+  numberOfRequestsPerInstance = 10.0
+}
+`)
+
+		// act
+		jsonData, err := DecodeConfigFile("migs.hcl", data)
+
+		assert.Nil(t, err)
+		config, err := UnmarshalConfig(jsonData)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(config.MIGs))
+		assert.Equal(t, "project-id", config.MIGs[0].GCloudProject)
+		assert.Equal(t, "instance-group-a", config.MIGs[0].InstanceGroupName)
+		assert.Equal(t, 10.0, config.MIGs[1].NumberOfRequestsPerInstance)
+	})
+
+	t.Run("KeepsSingleBlockOfListFieldAsList", func(t *testing.T) {
+
+		data := []byte(`
+migs {
+  instanceGroupName = "instance-group-a"
+
+  schedules {
+    name = "business-hours"
+    cron = "0 8 * * 1-5"
+    minimumNumberOfInstances = 10
+  }
+
+  profiles {
+    weekend {
+      minimumNumberOfInstances = 2
+    }
+  }
+}
+`)
+
+		// act
+		jsonData, err := DecodeConfigFile("migs.hcl", data)
+
+		assert.Nil(t, err)
+		config, err := UnmarshalConfig(jsonData)
+		assert.Nil(t, err)
+		if assert.Equal(t, 1, len(config.MIGs)) {
+			assert.Equal(t, []ScheduleEntry{{Name: "business-hours", Cron: "0 8 * * 1-5", MinimumNumberOfInstances: 10}}, config.MIGs[0].Schedules)
+			assert.Equal(t, MIGProfiles{"weekend": map[string]interface{}{"minimumNumberOfInstances": float64(2)}}, config.MIGs[0].Profiles)
+		}
+	})
+
+	t.Run("ReturnsYAMLAsIs", func(t *testing.T) {
+
+		data := []byte("- instanceGroupName: instance-group-a\n")
+
+		// act
+		decodedData, err := DecodeConfigFile("migs.yaml", data)
+
+		assert.Nil(t, err)
+		assert.Equal(t, data, decodedData)
+	})
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sync"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// GitConfigSource reads the configuration from a file in a git repository, so configuration changes go through the same review and audit trail as code
+type GitConfigSource struct {
+	RepoURL string
+	Branch  string
+	Path    string
+
+	mutex    sync.Mutex
+	revision string
+}
+
+// Read does a shallow clone of the repository in memory and returns the content of the config file at the head of the branch
+func (s *GitConfigSource) Read(ctx context.Context) ([]byte, error) {
+
+	cloneOptions := &git.CloneOptions{
+		URL:          s.RepoURL,
+		SingleBranch: true,
+		Depth:        1,
+	}
+	if s.Branch != "" {
+		cloneOptions.ReferenceName = plumbing.NewBranchReferenceName(s.Branch)
+	}
+
+	// config repositories are small, so a fresh shallow clone avoids having to deal with force pushes and local state
+	fs := memfs.New()
+	repository, err := git.CloneContext(ctx, memory.NewStorage(), fs, cloneOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := repository.Head()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := fs.Open(s.Path)
+	if err != nil {
+		return nil, fmt.Errorf("Opening %v in git repository %v failed: %v", s.Path, s.RepoURL, err)
+	}
+	defer file.Close()
+
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err = DecodeConfigFile(s.Path, data)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	s.revision = head.Hash().String()
+	s.mutex.Unlock()
+
+	return data, nil
+}
+
+// Revision returns the sha of the commit the configuration was last read from
+func (s *GitConfigSource) Revision() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.revision
+}
+
+func (s *GitConfigSource) String() string {
+	return fmt.Sprintf("git repository %v path %v", s.RepoURL, s.Path)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGitConfigSourceRead(t *testing.T) {
+
+	t.Run("ReturnsConfigFileAndRevisionFromRepository", func(t *testing.T) {
+
+		dir, _ := ioutil.TempDir("", "mig-config-repo")
+		defer os.RemoveAll(dir)
+
+		repository, _ := git.PlainInit(dir, false)
+		worktree, _ := repository.Worktree()
+		os.MkdirAll(filepath.Join(dir, "config"), 0755)
+		ioutil.WriteFile(filepath.Join(dir, "config", "migs.yaml"), []byte("- instanceGroupName: instance-group-name\n"), 0644)
+		worktree.Add("config/migs.yaml")
+		hash, _ := worktree.Commit("add config", &git.CommitOptions{Author: &object.Signature{Name: "test", Email: "test@estafette.io", When: time.Now()}})
+
+		source := &GitConfigSource{RepoURL: dir, Path: "config/migs.yaml"}
+
+		// act
+		config, err := ReadConfig(context.Background(), source)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(config.MIGs))
+		assert.Equal(t, "instance-group-name", config.MIGs[0].InstanceGroupName)
+		assert.Equal(t, hash.String(), config.Revision)
+	})
+}
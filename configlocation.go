@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// sourceFile is the content of a file the configuration is read from, to look up the lines of validation errors in
+type sourceFile struct {
+	name string
+	data []byte
+}
+
+// sourceFiles returns the files the configuration of the source is read from, before they're decoded or merged so their lines match what's in the repository; for sources other than files it's the configuration the source returned
+func sourceFiles(source ConfigSource, data []byte) (files []sourceFile) {
+
+	fileSource, ok := source.(*FileConfigSource)
+	if !ok {
+		return []sourceFile{{name: source.String(), data: data}}
+	}
+
+	paths := []string{fileSource.Path}
+	if fileSource.IsGlob() {
+		paths, _ = filepath.Glob(fileSource.Path)
+	}
+
+	for _, path := range paths {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		files = append(files, sourceFile{name: path, data: content})
+	}
+
+	return files
+}
+
+var (
+	// instanceGroupNameLineRegex matches a line with the instanceGroupName key in yaml, json, toml or hcl
+	instanceGroupNameLineRegex = regexp.MustCompile(`(^|[\s{,"'])instanceGroupName["']?\s*[:=]`)
+
+	// validationErrorKeyRegex matches the key of the last part of a validation error field, like requestRateQuery in queries[0].requestRateQuery
+	validationErrorKeyRegex = regexp.MustCompile(`([A-Za-z0-9_]+)(\[\d+\])?$`)
+)
+
+// locateValidationErrors sets the file and line of the validation errors of managed instance groups, to the line of the field within their entry or, if it isn't set there like when it's inherited from the defaults, to that of their instanceGroupName
+func locateValidationErrors(errs ValidationErrors, migConfigs []MIGConfiguration, files []sourceFile) {
+
+	fileLines := make([][]string, len(files))
+	for i, file := range files {
+		fileLines[i] = strings.Split(string(file.data), "\n")
+	}
+
+	for i, validationError := range errs {
+		if validationError.Index >= len(migConfigs) || migConfigs[validationError.Index].InstanceGroupName != validationError.InstanceGroupName || validationError.InstanceGroupName == "" {
+			continue
+		}
+
+		// managed instance groups with the same name are looked up in order, so the error for a duplicate points at the duplicate
+		occurrence := 0
+		for _, c := range migConfigs[:validationError.Index] {
+			if c.InstanceGroupName == validationError.InstanceGroupName {
+				occurrence++
+			}
+		}
+
+		nameRegex := regexp.MustCompile(`(^|[\s{,"'])instanceGroupName["']?\s*[:=]\s*["']?` + regexp.QuoteMeta(validationError.InstanceGroupName) + `(["'\s,}]|$)`)
+		key := validationErrorKeyRegex.FindStringSubmatch(validationError.Field)
+		if key == nil {
+			key = []string{"", "instanceGroupName"}
+		}
+		keyRegex := regexp.MustCompile(`(^|[\s{,"'])` + key[1] + `["']?\s*[:=]`)
+
+		for f, lines := range fileLines {
+			for l, line := range lines {
+				if !nameRegex.MatchString(line) {
+					continue
+				}
+				if occurrence > 0 {
+					occurrence--
+					continue
+				}
+
+				errs[i].File = files[f].name
+				errs[i].Line = l + 1
+				for fieldLine := l + 1; fieldLine < len(lines) && !instanceGroupNameLineRegex.MatchString(lines[fieldLine]); fieldLine++ {
+					if keyRegex.MatchString(lines[fieldLine]) {
+						errs[i].Line = fieldLine + 1
+						break
+					}
+				}
+				break
+			}
+			if errs[i].Line > 0 {
+				break
+			}
+		}
+	}
+}
+
+// location returns the file and line of the validation error to prefix its message with, or empty if it has none
+func (e ValidationError) location() string {
+	if e.Line == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%v:%v: ", e.File, e.Line)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocateValidationErrors(t *testing.T) {
+
+	data := []byte(`defaults:
+  gcloudProject: project-id
+migs:
+- instanceGroupName: web
+  numberOfRequestsPerInstance: -1
+  queries:
+  - requestRateQuery: sum(rate(requests_total[5m])
+- instanceGroupName: "api"
+  gcloudZone: europe-west1-b
+- instanceGroupName: web
+`)
+	migConfigs := []MIGConfiguration{{InstanceGroupName: "web"}, {InstanceGroupName: "api"}, {InstanceGroupName: "web"}}
+
+	t.Run("SetsLineOfFieldWithinEntry", func(t *testing.T) {
+
+		errs := ValidationErrors{
+			{Index: 0, InstanceGroupName: "web", Field: "numberOfRequestsPerInstance", Message: "should be larger than 0"},
+			{Index: 0, InstanceGroupName: "web", Field: "queries[0].requestRateQuery", Message: "unclosed '('"},
+		}
+
+		// act
+		locateValidationErrors(errs, migConfigs, []sourceFile{{name: "migs.yaml", data: data}})
+
+		assert.Equal(t, "migs.yaml", errs[0].File)
+		assert.Equal(t, 5, errs[0].Line)
+		assert.Equal(t, 7, errs[1].Line)
+		assert.Equal(t, "migs.yaml:5: mig[0] (web) numberOfRequestsPerInstance: should be larger than 0", errs[0].Error())
+	})
+
+	t.Run("SetsLineOfInstanceGroupNameForFieldThatIsNotInEntry", func(t *testing.T) {
+
+		errs := ValidationErrors{
+			{Index: 1, InstanceGroupName: "api", Field: "gcloudRegion", Message: "should not be set together with gcloudZone"},
+		}
+
+		// act
+		locateValidationErrors(errs, migConfigs, []sourceFile{{name: "migs.yaml", data: data}})
+
+		assert.Equal(t, 8, errs[0].Line)
+	})
+
+	t.Run("SetsLineOfDuplicateForSecondEntryWithSameName", func(t *testing.T) {
+
+		errs := ValidationErrors{
+			{Index: 2, InstanceGroupName: "web", Field: "instanceGroupName", Message: "is not unique"},
+		}
+
+		// act
+		locateValidationErrors(errs, migConfigs, []sourceFile{{name: "migs.yaml", data: data}})
+
+		assert.Equal(t, 10, errs[0].Line)
+	})
+
+	t.Run("LeavesErrorsOfDiscoveryRulesWithoutLine", func(t *testing.T) {
+
+		errs := ValidationErrors{
+			{Index: 0, InstanceGroupName: "discovery", Field: "labelSelector", Message: "is required"},
+		}
+
+		// act
+		locateValidationErrors(errs, migConfigs, []sourceFile{{name: "migs.yaml", data: data}})
+
+		assert.Equal(t, 0, errs[0].Line)
+		assert.Equal(t, "mig[0] (discovery) labelSelector: is required", errs[0].Error())
+	})
+}
+
+func TestSourceFiles(t *testing.T) {
+
+	t.Run("ReturnsEveryFileMatchingGlob", func(t *testing.T) {
+
+		dir, _ := ioutil.TempDir("", "mig-config")
+		defer os.RemoveAll(dir)
+		ioutil.WriteFile(filepath.Join(dir, "team-a.yaml"), []byte("- instanceGroupName: instance-group-a\n"), 0644)
+		ioutil.WriteFile(filepath.Join(dir, "team-b.yaml"), []byte("- instanceGroupName: instance-group-b\n"), 0644)
+
+		// act
+		files := sourceFiles(&FileConfigSource{Path: filepath.Join(dir, "*.yaml")}, nil)
+
+		if assert.Equal(t, 2, len(files)) {
+			assert.Equal(t, filepath.Join(dir, "team-a.yaml"), files[0].name)
+			assert.Equal(t, []byte("- instanceGroupName: instance-group-b\n"), files[1].data)
+		}
+	})
+
+	t.Run("ReturnsConfigurationOfOtherSources", func(t *testing.T) {
+
+		// act
+		files := sourceFiles(&StaticConfigSource{Data: "- instanceGroupName: instance-group-a\n"}, []byte("- instanceGroupName: instance-group-a\n"))
+
+		if assert.Equal(t, 1, len(files)) {
+			assert.Equal(t, []byte("- instanceGroupName: instance-group-a\n"), files[0].data)
+		}
+	})
+}
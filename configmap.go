@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const (
+	kubernetesServiceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	kubernetesServiceAccountCAPath    = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+)
+
+// ConfigMapConfigSource reads the configuration from a key in a Kubernetes ConfigMap, using the in-cluster service account of the pod
+type ConfigMapConfigSource struct {
+	client    *http.Client
+	apiURL    string
+	token     string
+	namespace string
+	name      string
+	key       string
+}
+
+// kubernetesConfigMap is used to unmarshal a ConfigMap from the Kubernetes api
+type kubernetesConfigMap struct {
+	Metadata struct {
+		Name            string `json:"name"`
+		Namespace       string `json:"namespace"`
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+	Data map[string]string `json:"data"`
+}
+
+// kubernetesWatchEvent is used to unmarshal the events streamed by a Kubernetes api watch
+type kubernetesWatchEvent struct {
+	Type   string              `json:"type"`
+	Object kubernetesConfigMap `json:"object"`
+}
+
+// NewConfigMapConfigSource returns a config source for a namespace/name ConfigMap using the in-cluster Kubernetes configuration
+func NewConfigMapConfigSource(namespacedName, key string) (*ConfigMapConfigSource, error) {
+
+	parts := strings.Split(namespacedName, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("Config configmap %v should be of the form namespace/name", namespacedName)
+	}
+
+	client, apiURL, token, err := newInClusterKubernetesClient()
+	if err != nil {
+		return nil, fmt.Errorf("Reading config from a configmap failed: %v", err)
+	}
+
+	return &ConfigMapConfigSource{
+		client:    client,
+		apiURL:    apiURL,
+		token:     token,
+		namespace: parts[0],
+		name:      parts[1],
+		key:       key,
+	}, nil
+}
+
+// newInClusterKubernetesClient returns a client trusting the ca of the Kubernetes api, the url of the api and the token of the service account of the pod
+func newInClusterKubernetesClient() (client *http.Client, apiURL, token string, err error) {
+
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, "", "", errors.New("The Kubernetes api is only available when running inside Kubernetes")
+	}
+
+	tokenData, err := ioutil.ReadFile(kubernetesServiceAccountTokenPath)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	ca, err := ioutil.ReadFile(kubernetesServiceAccountCAPath)
+	if err != nil {
+		return nil, "", "", err
+	}
+	certPool := x509.NewCertPool()
+	if !certPool.AppendCertsFromPEM(ca) {
+		return nil, "", "", fmt.Errorf("Parsing kubernetes ca certificate %v failed", kubernetesServiceAccountCAPath)
+	}
+
+	client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: certPool},
+		},
+	}
+
+	return client, fmt.Sprintf("https://%v:%v", host, port), strings.TrimSpace(string(tokenData)), nil
+}
+
+// Read fetches the ConfigMap and returns the value for the configured key
+func (s *ConfigMapConfigSource) Read(ctx context.Context) ([]byte, error) {
+
+	resp, err := s.get(ctx, fmt.Sprintf("/api/v1/namespaces/%v/configmaps/%v", s.namespace, s.name))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var configMap kubernetesConfigMap
+	if err = json.NewDecoder(resp.Body).Decode(&configMap); err != nil {
+		return nil, err
+	}
+
+	return s.getData(configMap)
+}
+
+// Watch streams the ConfigMap from the Kubernetes api and calls onChange with the value for the configured key on every update; it returns when the watch ends
+func (s *ConfigMapConfigSource) Watch(ctx context.Context, onChange func(data []byte)) error {
+
+	query := url.Values{}
+	query.Set("watch", "true")
+	query.Set("fieldSelector", fmt.Sprintf("metadata.name=%v", s.name))
+
+	resp, err := s.get(ctx, fmt.Sprintf("/api/v1/namespaces/%v/configmaps?%v", s.namespace, query.Encode()))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var event kubernetesWatchEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return err
+		}
+
+		if event.Type != "ADDED" && event.Type != "MODIFIED" {
+			continue
+		}
+
+		data, err := s.getData(event.Object)
+		if err != nil {
+			return err
+		}
+
+		onChange(data)
+	}
+
+	return scanner.Err()
+}
+
+func (s *ConfigMapConfigSource) String() string {
+	return fmt.Sprintf("configmap %v/%v key %v", s.namespace, s.name, s.key)
+}
+
+func (s *ConfigMapConfigSource) get(ctx context.Context, path string) (*http.Response, error) {
+
+	req, err := http.NewRequest("GET", s.apiURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.token)
+
+	resp, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("Requesting %v from kubernetes api failed with status code %v: %v", path, resp.StatusCode, string(body))
+	}
+
+	return resp, nil
+}
+
+func (s *ConfigMapConfigSource) getData(configMap kubernetesConfigMap) ([]byte, error) {
+
+	data, ok := configMap.Data[s.key]
+	if !ok {
+		return nil, fmt.Errorf("Configmap %v/%v has no key %v", s.namespace, s.name, s.key)
+	}
+
+	return []byte(data), nil
+}
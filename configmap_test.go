@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigMapConfigSourceRead(t *testing.T) {
+
+	t.Run("ReturnsValueOfKeyWithServiceAccountToken", func(t *testing.T) {
+
+		var path, authorization string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			authorization = r.Header.Get("Authorization")
+			w.Write([]byte(`{"metadata":{"name":"mig-config","namespace":"estafette","resourceVersion":"12"},"data":{"config.yaml":"- instanceGroupName: instance-group-name\n"}}`))
+		}))
+		defer server.Close()
+
+		source := &ConfigMapConfigSource{client: server.Client(), apiURL: server.URL, token: "token", namespace: "estafette", name: "mig-config", key: "config.yaml"}
+
+		// act
+		data, err := source.Read(context.Background())
+
+		assert.Nil(t, err)
+		assert.Equal(t, "/api/v1/namespaces/estafette/configmaps/mig-config", path)
+		assert.Equal(t, "Bearer token", authorization)
+		assert.Equal(t, "- instanceGroupName: instance-group-name\n", string(data))
+	})
+
+	t.Run("ReturnsErrorIfConfigMapHasNoKey", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"metadata":{"name":"mig-config","namespace":"estafette"},"data":{"other.yaml":""}}`))
+		}))
+		defer server.Close()
+
+		source := &ConfigMapConfigSource{client: server.Client(), apiURL: server.URL, token: "token", namespace: "estafette", name: "mig-config", key: "config.yaml"}
+
+		// act
+		_, err := source.Read(context.Background())
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("ReturnsErrorWithResponseForStatusOtherThanOK", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"kind":"Status","message":"configmaps \"mig-config\" is forbidden"}`))
+		}))
+		defer server.Close()
+
+		source := &ConfigMapConfigSource{client: server.Client(), apiURL: server.URL, token: "token", namespace: "estafette", name: "mig-config", key: "config.yaml"}
+
+		// act
+		_, err := source.Read(context.Background())
+
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "status code 403")
+			assert.Contains(t, err.Error(), "is forbidden")
+		}
+	})
+}
+
+func TestConfigMapConfigSourceWatch(t *testing.T) {
+
+	t.Run("CallsOnChangeForAddedAndModifiedEvents", func(t *testing.T) {
+
+		var query string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			query = r.URL.RawQuery
+			for _, event := range []string{"ADDED", "DELETED", "MODIFIED"} {
+				fmt.Fprintf(w, `{"type":"%v","object":{"metadata":{"name":"mig-config","namespace":"estafette"},"data":{"config.yaml":"%v"}}}`+"\n", event, event)
+			}
+		}))
+		defer server.Close()
+
+		source := &ConfigMapConfigSource{client: server.Client(), apiURL: server.URL, token: "token", namespace: "estafette", name: "mig-config", key: "config.yaml"}
+		changes := []string{}
+
+		// act
+		err := source.Watch(context.Background(), func(data []byte) {
+			changes = append(changes, string(data))
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, "fieldSelector=metadata.name%3Dmig-config&watch=true", query)
+		assert.Equal(t, []string{"ADDED", "MODIFIED"}, changes)
+	})
+
+	t.Run("ReturnsErrorForEventThatIsNotJSON", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("not json\n"))
+		}))
+		defer server.Close()
+
+		source := &ConfigMapConfigSource{client: server.Client(), apiURL: server.URL, token: "token", namespace: "estafette", name: "mig-config", key: "config.yaml"}
+
+		// act
+		err := source.Watch(context.Background(), func(data []byte) {})
+
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// legacyConfigVersion is the version of the original configuration format, a plain array of managed instance group entries
+	legacyConfigVersion = 1
+
+	// latestConfigVersion is the version of the configuration format as unmarshalled into MIGConfigFile
+	latestConfigVersion = 2
+)
+
+// ConfigMigration upgrades raw configuration from one version of the schema to the next
+type ConfigMigration func(raw interface{}) (interface{}, error)
+
+// configMigrations maps a configuration version to the migration upgrading it to the next version; a change to the configuration format adds a migration here instead of breaking existing configuration
+var configMigrations = map[int]ConfigMigration{
+	1: migrateConfigFromV1,
+}
+
+// MigrateConfig upgrades raw configuration of any supported version to the latest version, so only the latest format has to be unmarshalled
+func MigrateConfig(raw interface{}) (migrated map[string]interface{}, err error) {
+
+	version, err := configVersion(raw)
+	if err != nil {
+		return
+	}
+	if version > latestConfigVersion {
+		return nil, fmt.Errorf("Managed instance group configuration version %v is newer than the latest supported version %v", version, latestConfigVersion)
+	}
+
+	for v := version; v < latestConfigVersion; v++ {
+		migration, ok := configMigrations[v]
+		if !ok {
+			return nil, fmt.Errorf("No migration from managed instance group configuration version %v exists", v)
+		}
+		if raw, err = migration(raw); err != nil {
+			return nil, fmt.Errorf("Migrating managed instance group configuration from version %v failed: %v", v, err)
+		}
+		log.Debug().Msgf("Migrated managed instance group configuration from version %v to %v", v, v+1)
+	}
+
+	migrated, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Managed instance group configuration version %v should be an object", latestConfigVersion)
+	}
+	migrated["version"] = latestConfigVersion
+
+	return
+}
+
+// configVersion returns the version of raw configuration; a plain array is the legacy format and an object without version predates versioning, so it's the first object version
+func configVersion(raw interface{}) (int, error) {
+
+	switch value := raw.(type) {
+	case []interface{}:
+		return legacyConfigVersion, nil
+
+	case map[string]interface{}:
+		version, ok := value["version"]
+		if !ok {
+			return latestConfigVersion, nil
+		}
+		// numbers are unmarshalled from json as float64
+		number, ok := version.(float64)
+		if !ok || number != float64(int(number)) || number < legacyConfigVersion {
+			return 0, fmt.Errorf("Managed instance group configuration version %v is invalid", version)
+		}
+		if number == legacyConfigVersion {
+			return 0, fmt.Errorf("Managed instance group configuration version %v is a plain array of entries, not an object", legacyConfigVersion)
+		}
+		return int(number), nil
+	}
+
+	return 0, fmt.Errorf("Managed instance group configuration should be either an array of entries or an object with defaults, migs and discovery")
+}
+
+// migrateConfigFromV1 wraps a plain array of managed instance group entries in an object
+func migrateConfigFromV1(raw interface{}) (interface{}, error) {
+	return map[string]interface{}{
+		"migs": raw,
+	}, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrateConfig(t *testing.T) {
+
+	t.Run("WrapsLegacyArrayInLatestVersionObject", func(t *testing.T) {
+
+		raw := []interface{}{
+			map[string]interface{}{"instanceGroupName": "instance-group-name"},
+		}
+
+		// act
+		migrated, err := MigrateConfig(raw)
+
+		assert.Nil(t, err)
+		assert.Equal(t, latestConfigVersion, migrated["version"])
+		assert.Equal(t, raw, migrated["migs"])
+	})
+
+	t.Run("ReturnsErrorForObjectWithLegacyVersion", func(t *testing.T) {
+
+		raw := map[string]interface{}{"version": float64(1)}
+
+		// act
+		_, err := MigrateConfig(raw)
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("ReturnsObjectWithoutVersionAsLatestVersion", func(t *testing.T) {
+
+		raw := map[string]interface{}{
+			"defaults": map[string]interface{}{"gcloudProject": "project-id"},
+		}
+
+		// act
+		migrated, err := MigrateConfig(raw)
+
+		assert.Nil(t, err)
+		assert.Equal(t, latestConfigVersion, migrated["version"])
+		assert.Equal(t, raw["defaults"], migrated["defaults"])
+	})
+
+	t.Run("ReturnsErrorForNewerVersion", func(t *testing.T) {
+
+		raw := map[string]interface{}{"version": float64(latestConfigVersion + 1)}
+
+		// act
+		_, err := MigrateConfig(raw)
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("ReturnsErrorForInvalidVersion", func(t *testing.T) {
+
+		raw := map[string]interface{}{"version": "two"}
+
+		// act
+		_, err := MigrateConfig(raw)
+
+		assert.NotNil(t, err)
+	})
+}
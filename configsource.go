@@ -0,0 +1,404 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/sethgrid/pester"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/storage/v1"
+)
+
+// ConfigSource retrieves the raw yaml or json managed instance group configuration from wherever it's stored
+type ConfigSource interface {
+	Read(ctx context.Context) ([]byte, error)
+	String() string
+}
+
+// RevisionedConfigSource is a config source that can tell which revision of the configuration it read last
+type RevisionedConfigSource interface {
+	Revision() string
+}
+
+// ConfigSourceRevision returns the revision of the configuration last read from the source, or an empty string if the source doesn't keep track of revisions
+func ConfigSourceRevision(source ConfigSource) string {
+	if revisioned, ok := source.(RevisionedConfigSource); ok {
+		return revisioned.Revision()
+	}
+	return ""
+}
+
+// ConfigSourceOptions holds the flags that determine which config source to use
+type ConfigSourceOptions struct {
+	ConfigFile         string
+	MIGConfig          string
+	ConfigGCSURL       string
+	ConfigSecret       string
+	ConfigConfigMap    string
+	ConfigConfigMapKey string
+	ConfigURL          string
+	ConfigGitRepo      string
+	ConfigGitBranch    string
+	ConfigGitPath      string
+}
+
+// NewConfigSource returns the config source for the given options; remote sources take precedence over a config file, which takes precedence over the MIG_CONFIG envvar
+func NewConfigSource(ctx context.Context, options ConfigSourceOptions) (ConfigSource, error) {
+
+	if options.ConfigConfigMap != "" {
+		return NewConfigMapConfigSource(options.ConfigConfigMap, options.ConfigConfigMapKey)
+	}
+
+	if options.ConfigGitRepo != "" {
+		if options.ConfigGitPath == "" {
+			return nil, errors.New("Config git path should be set when reading configuration from a git repository")
+		}
+		return &GitConfigSource{RepoURL: options.ConfigGitRepo, Branch: options.ConfigGitBranch, Path: options.ConfigGitPath}, nil
+	}
+
+	if options.ConfigURL != "" {
+		return &HTTPConfigSource{URL: options.ConfigURL}, nil
+	}
+
+	if options.ConfigSecret != "" {
+		return NewSecretManagerConfigSource(ctx, options.ConfigSecret)
+	}
+
+	if options.ConfigGCSURL != "" {
+		return NewGCSConfigSource(ctx, options.ConfigGCSURL)
+	}
+
+	if options.ConfigFile == "-" {
+		return &StdinConfigSource{Stdin: os.Stdin}, nil
+	}
+
+	if options.ConfigFile != "" {
+		return &FileConfigSource{Path: options.ConfigFile}, nil
+	}
+
+	if options.MIGConfig != "" {
+		return &StaticConfigSource{Data: options.MIGConfig}, nil
+	}
+
+	return nil, errors.New("No managed instance group configuration has been provided, set either --config-git-repo, --config-configmap, --config-url, --config-secret, --config-gcs-url, --config-file or --mig-config")
+}
+
+// FileConfigSource reads the configuration from a file on disk; the path can be a glob pattern to merge multiple files
+type FileConfigSource struct {
+	Path string
+}
+
+// Read returns the content of the config file, or a json array with the merged entries of all files when the path is a glob pattern
+func (s *FileConfigSource) Read(ctx context.Context) ([]byte, error) {
+
+	if !s.IsGlob() {
+		return readConfigFile(s.Path)
+	}
+
+	paths, err := filepath.Glob(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("No config files match %v", s.Path)
+	}
+
+	mergedConfig := Config{}
+	pathsByInstanceGroup := map[migStateKey]string{}
+	for _, path := range paths {
+		data, err := readConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		// placeholders are expanded when the merged configuration is unmarshalled
+		config, err := unmarshalConfig(data, false)
+		if err != nil {
+			return nil, fmt.Errorf("Unmarshalling config file %v failed: %v", path, err)
+		}
+
+		for _, c := range config.MIGs {
+			key := newMIGStateKey(c)
+			if otherPath, ok := pathsByInstanceGroup[key]; ok {
+				return nil, fmt.Errorf("Managed instance group %v is configured in both %v and %v", key, otherPath, path)
+			}
+			pathsByInstanceGroup[key] = path
+			mergedConfig.MIGs = append(mergedConfig.MIGs, c)
+		}
+		mergedConfig.Discovery = append(mergedConfig.Discovery, config.Discovery...)
+	}
+
+	return json.Marshal(mergedConfig)
+}
+
+// readConfigFile reads a config file and converts it to json if its format requires a decoder
+func readConfigFile(path string) ([]byte, error) {
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeConfigFile(path, data)
+}
+
+// IsGlob returns true if the path is a glob pattern instead of a single file
+func (s *FileConfigSource) IsGlob() bool {
+	return strings.ContainsAny(s.Path, "*?[")
+}
+
+// Matches returns true if a file path is part of the configuration
+func (s *FileConfigSource) Matches(path string) bool {
+	if s.IsGlob() {
+		matched, _ := filepath.Match(filepath.Clean(s.Path), filepath.Clean(path))
+		return matched
+	}
+	return filepath.Clean(path) == filepath.Clean(s.Path)
+}
+
+func (s *FileConfigSource) String() string {
+	return fmt.Sprintf("file %v", s.Path)
+}
+
+// StdinConfigSource reads the configuration from stdin; since stdin can only be read once, later reads return the same configuration
+type StdinConfigSource struct {
+	Stdin io.Reader
+
+	once sync.Once
+	data []byte
+	err  error
+}
+
+// Read returns the configuration piped into stdin
+func (s *StdinConfigSource) Read(ctx context.Context) ([]byte, error) {
+	s.once.Do(func() {
+		s.data, s.err = ioutil.ReadAll(s.Stdin)
+	})
+	return s.data, s.err
+}
+
+func (s *StdinConfigSource) String() string {
+	return "stdin"
+}
+
+// StaticConfigSource returns configuration that was passed in directly, like the MIG_CONFIG envvar
+type StaticConfigSource struct {
+	Data string
+}
+
+// Read returns the static configuration
+func (s *StaticConfigSource) Read(ctx context.Context) ([]byte, error) {
+	return []byte(s.Data), nil
+}
+
+func (s *StaticConfigSource) String() string {
+	return "mig-config flag"
+}
+
+// GCSConfigSource downloads the configuration from a Google Cloud Storage object
+type GCSConfigSource struct {
+	service *storage.Service
+	bucket  string
+	object  string
+}
+
+// NewGCSConfigSource returns a config source for a gs://bucket/path url, using the default google credentials
+func NewGCSConfigSource(ctx context.Context, gcsURL string) (*GCSConfigSource, error) {
+
+	bucket, object, err := parseGCSURL(gcsURL)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := google.DefaultClient(ctx, storage.DevstorageReadOnlyScope)
+	if err != nil {
+		return nil, err
+	}
+
+	service, err := storage.New(client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GCSConfigSource{
+		service: service,
+		bucket:  bucket,
+		object:  object,
+	}, nil
+}
+
+// Read downloads the config object
+func (s *GCSConfigSource) Read(ctx context.Context) ([]byte, error) {
+
+	resp, err := s.service.Objects.Get(s.bucket, s.object).Context(ctx).Download()
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+func (s *GCSConfigSource) String() string {
+	return fmt.Sprintf("gs://%v/%v", s.bucket, s.object)
+}
+
+// SecretManagerConfigSource fetches the configuration from a Google Secret Manager secret version
+type SecretManagerConfigSource struct {
+	client   *http.Client
+	name     string
+	basePath string
+}
+
+// NewSecretManagerConfigSource returns a config source for a projects/x/secrets/y/versions/z secret version name, using the default google credentials
+func NewSecretManagerConfigSource(ctx context.Context, name string) (*SecretManagerConfigSource, error) {
+
+	if !secretVersionNameRegex.MatchString(name) {
+		return nil, fmt.Errorf("Config secret %v should be of the form projects/<project>/secrets/<secret>/versions/<version>", name)
+	}
+
+	client, err := google.DefaultClient(ctx, secretManagerScope)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SecretManagerConfigSource{
+		client:   client,
+		name:     name,
+		basePath: secretManagerBasePath,
+	}, nil
+}
+
+const (
+	secretManagerScope = "https://www.googleapis.com/auth/cloud-platform"
+
+	// secretManagerBasePath is the url of the secret manager api the secret version name is appended to
+	secretManagerBasePath = "https://secretmanager.googleapis.com/v1/"
+)
+
+var secretVersionNameRegex = regexp.MustCompile(`^projects/[^/]+/secrets/[^/]+/versions/[^/]+$`)
+
+// secretManagerAccessResponse is used to unmarshal the response of the secret manager access endpoint
+type secretManagerAccessResponse struct {
+	Name    string `json:"name"`
+	Payload struct {
+		Data string `json:"data"`
+	} `json:"payload"`
+}
+
+// Read accesses the secret version and returns its decoded payload
+func (s *SecretManagerConfigSource) Read(ctx context.Context) ([]byte, error) {
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("%v%v:access", s.basePath, s.name), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Accessing secret %v failed with status code %v: %v", s.name, resp.StatusCode, string(body))
+	}
+
+	var accessResponse secretManagerAccessResponse
+	if err = json.Unmarshal(body, &accessResponse); err != nil {
+		return nil, err
+	}
+
+	return base64.StdEncoding.DecodeString(accessResponse.Payload.Data)
+}
+
+func (s *SecretManagerConfigSource) String() string {
+	return fmt.Sprintf("secret %v", s.name)
+}
+
+// HTTPConfigSource fetches the configuration from an http endpoint; it sends the ETag of the last response along, so an unchanged configuration doesn't have to be transferred again
+type HTTPConfigSource struct {
+	URL string
+
+	mutex    sync.Mutex
+	etag     string
+	lastData []byte
+}
+
+// Read fetches the configuration, or returns the previously fetched configuration if the endpoint responds it's not modified
+func (s *HTTPConfigSource) Read(ctx context.Context) ([]byte, error) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	req, err := http.NewRequest("GET", s.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.etag != "" {
+		req.Header.Set("If-None-Match", s.etag)
+	}
+
+	resp, err := pester.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return s.lastData, nil
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Fetching config from %v failed with status code %v: %v", s.URL, resp.StatusCode, string(body))
+	}
+
+	s.etag = resp.Header.Get("ETag")
+	s.lastData = body
+
+	return body, nil
+}
+
+func (s *HTTPConfigSource) String() string {
+	return s.URL
+}
+
+// parseGCSURL splits a gs://bucket/path url into bucket and object name
+func parseGCSURL(gcsURL string) (bucket, object string, err error) {
+
+	u, err := url.Parse(gcsURL)
+	if err != nil {
+		return
+	}
+
+	bucket = u.Host
+	object = strings.TrimPrefix(u.Path, "/")
+
+	if u.Scheme != "gs" || bucket == "" || object == "" {
+		return "", "", fmt.Errorf("Config gcs url %v should be of the form gs://bucket/path", gcsURL)
+	}
+
+	return
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewConfigSource(t *testing.T) {
+
+	t.Run("ReturnsFileConfigSourceIfConfigFileIsSet", func(t *testing.T) {
+
+		// act
+		source, err := NewConfigSource(context.Background(), ConfigSourceOptions{ConfigFile: "migs.yaml", MIGConfig: "[]"})
+
+		assert.Nil(t, err)
+		assert.IsType(t, &FileConfigSource{}, source)
+	})
+
+	t.Run("FallsBackToStaticConfigSourceIfConfigFileIsEmpty", func(t *testing.T) {
+
+		// act
+		source, err := NewConfigSource(context.Background(), ConfigSourceOptions{MIGConfig: "[]"})
+
+		assert.Nil(t, err)
+		assert.IsType(t, &StaticConfigSource{}, source)
+	})
+
+	t.Run("ReturnsErrorIfNoConfigIsProvided", func(t *testing.T) {
+
+		// act
+		_, err := NewConfigSource(context.Background(), ConfigSourceOptions{})
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestParseGCSURL(t *testing.T) {
+
+	t.Run("ReturnsBucketAndObject", func(t *testing.T) {
+
+		// act
+		bucket, object, err := parseGCSURL("gs://my-bucket/path/to/migs.yaml")
+
+		assert.Nil(t, err)
+		assert.Equal(t, "my-bucket", bucket)
+		assert.Equal(t, "path/to/migs.yaml", object)
+	})
+
+	t.Run("ReturnsErrorForNonGCSURL", func(t *testing.T) {
+
+		// act
+		_, _, err := parseGCSURL("https://my-bucket/path/to/migs.yaml")
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestFileConfigSourceRead(t *testing.T) {
+
+	t.Run("MergesAllFilesMatchingGlob", func(t *testing.T) {
+
+		dir, _ := ioutil.TempDir("", "mig-config")
+		defer os.RemoveAll(dir)
+		ioutil.WriteFile(filepath.Join(dir, "team-a.yaml"), []byte("- instanceGroupName: instance-group-a\n"), 0644)
+		ioutil.WriteFile(filepath.Join(dir, "team-b.yaml"), []byte("defaults:\n  gcloudProject: project-b\nmigs:\n- instanceGroupName: instance-group-b\n"), 0644)
+
+		source := &FileConfigSource{Path: filepath.Join(dir, "*.yaml")}
+
+		// act
+		config, err := ReadConfig(context.Background(), source)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(config.MIGs))
+		assert.Equal(t, "instance-group-a", config.MIGs[0].InstanceGroupName)
+		assert.Equal(t, "instance-group-b", config.MIGs[1].InstanceGroupName)
+		assert.Equal(t, "project-b", config.MIGs[1].GCloudProject)
+	})
+
+	t.Run("MergesMIGsWithSameNameInOtherProjects", func(t *testing.T) {
+
+		dir, _ := ioutil.TempDir("", "mig-config")
+		defer os.RemoveAll(dir)
+		ioutil.WriteFile(filepath.Join(dir, "team-a.yaml"), []byte("- instanceGroupName: web\n  gcloudProject: project-a\n"), 0644)
+		ioutil.WriteFile(filepath.Join(dir, "team-b.yaml"), []byte("- instanceGroupName: web\n  gcloudProject: project-b\n"), 0644)
+
+		source := &FileConfigSource{Path: filepath.Join(dir, "*.yaml")}
+
+		// act
+		config, err := ReadConfig(context.Background(), source)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(config.MIGs))
+	})
+
+	t.Run("ReturnsErrorForMIGConfiguredInTwoFiles", func(t *testing.T) {
+
+		dir, _ := ioutil.TempDir("", "mig-config")
+		defer os.RemoveAll(dir)
+		ioutil.WriteFile(filepath.Join(dir, "team-a.yaml"), []byte("- instanceGroupName: web\n  gcloudProject: project-a\n"), 0644)
+		ioutil.WriteFile(filepath.Join(dir, "team-b.yaml"), []byte("defaults:\n  gcloudProject: project-a\nmigs:\n- instanceGroupName: web\n"), 0644)
+
+		source := &FileConfigSource{Path: filepath.Join(dir, "*.yaml")}
+
+		// act
+		_, err := source.Read(context.Background())
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("ExpandsEnvVarPlaceholdersOnce", func(t *testing.T) {
+
+		os.Setenv("MIG_CONFIG_TEST_PROJECT", "project-${NOT_A_PLACEHOLDER}")
+		defer os.Unsetenv("MIG_CONFIG_TEST_PROJECT")
+
+		dir, _ := ioutil.TempDir("", "mig-config")
+		defer os.RemoveAll(dir)
+		ioutil.WriteFile(filepath.Join(dir, "team-a.yaml"), []byte("- instanceGroupName: instance-group-a\n  gcloudProject: ${MIG_CONFIG_TEST_PROJECT}\n"), 0644)
+
+		source := &FileConfigSource{Path: filepath.Join(dir, "*.yaml")}
+
+		// act
+		config, err := ReadConfig(context.Background(), source)
+
+		assert.Nil(t, err)
+		if assert.Equal(t, 1, len(config.MIGs)) {
+			assert.Equal(t, "project-${NOT_A_PLACEHOLDER}", config.MIGs[0].GCloudProject)
+		}
+	})
+
+	t.Run("ReturnsErrorForDuplicateInstanceGroupNames", func(t *testing.T) {
+
+		dir, _ := ioutil.TempDir("", "mig-config")
+		defer os.RemoveAll(dir)
+		ioutil.WriteFile(filepath.Join(dir, "team-a.yaml"), []byte("- instanceGroupName: instance-group-a\n"), 0644)
+		ioutil.WriteFile(filepath.Join(dir, "team-b.yaml"), []byte("- instanceGroupName: instance-group-a\n"), 0644)
+
+		source := &FileConfigSource{Path: filepath.Join(dir, "*.yaml")}
+
+		// act
+		_, err := source.Read(context.Background())
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestNewConfigMapConfigSource(t *testing.T) {
+
+	t.Run("ReturnsErrorIfNameIsNotNamespaced", func(t *testing.T) {
+
+		// act
+		_, err := NewConfigMapConfigSource("mig-config", "config.yaml")
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestNewSecretManagerConfigSource(t *testing.T) {
+
+	t.Run("ReturnsErrorIfNameIsNotASecretVersion", func(t *testing.T) {
+
+		// act
+		_, err := NewSecretManagerConfigSource(context.Background(), "projects/project-id/secrets/mig-config")
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestSecretManagerConfigSourceRead(t *testing.T) {
+
+	t.Run("ReturnsDecodedPayloadOfSecretVersion", func(t *testing.T) {
+
+		var path string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			w.Write([]byte(`{"name":"projects/123/secrets/mig-config/versions/4","payload":{"data":"W3siaW5zdGFuY2VHcm91cE5hbWUiOiJpbnN0YW5jZS1ncm91cC1uYW1lIn1d"}}`))
+		}))
+		defer server.Close()
+
+		source := &SecretManagerConfigSource{client: server.Client(), name: "projects/project-id/secrets/mig-config/versions/latest", basePath: server.URL + "/"}
+
+		// act
+		data, err := source.Read(context.Background())
+
+		assert.Nil(t, err)
+		assert.Equal(t, "/projects/project-id/secrets/mig-config/versions/latest:access", path)
+		assert.Equal(t, "[{\"instanceGroupName\":\"instance-group-name\"}]", string(data))
+	})
+
+	t.Run("ReturnsErrorWithResponseForStatusOtherThanOK", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error":{"code":403,"message":"Permission denied","status":"PERMISSION_DENIED"}}`))
+		}))
+		defer server.Close()
+
+		source := &SecretManagerConfigSource{client: server.Client(), name: "projects/project-id/secrets/mig-config/versions/latest", basePath: server.URL + "/"}
+
+		// act
+		_, err := source.Read(context.Background())
+
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "status code 403")
+			assert.Contains(t, err.Error(), "Permission denied")
+		}
+	})
+
+	t.Run("ReturnsErrorForPayloadThatIsNotBase64", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"name":"projects/123/secrets/mig-config/versions/4","payload":{"data":"not base64!"}}`))
+		}))
+		defer server.Close()
+
+		source := &SecretManagerConfigSource{client: server.Client(), name: "projects/project-id/secrets/mig-config/versions/latest", basePath: server.URL + "/"}
+
+		// act
+		_, err := source.Read(context.Background())
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestHTTPConfigSourceRead(t *testing.T) {
+
+	t.Run("ReturnsLastFetchedConfigIfNotModified", func(t *testing.T) {
+
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if r.Header.Get("If-None-Match") == "\"v1\"" {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", "\"v1\"")
+			w.Write([]byte("[{\"instanceGroupName\":\"instance-group-name\"}]"))
+		}))
+		defer server.Close()
+
+		source := &HTTPConfigSource{URL: server.URL}
+
+		// act
+		firstData, firstErr := source.Read(context.Background())
+		secondData, secondErr := source.Read(context.Background())
+
+		assert.Nil(t, firstErr)
+		assert.Nil(t, secondErr)
+		assert.Equal(t, 2, requests)
+		assert.Equal(t, "[{\"instanceGroupName\":\"instance-group-name\"}]", string(firstData))
+		assert.Equal(t, firstData, secondData)
+	})
+}
+
+func TestStdinConfigSourceRead(t *testing.T) {
+
+	t.Run("ReturnsSameConfigOnEveryRead", func(t *testing.T) {
+
+		source := &StdinConfigSource{Stdin: strings.NewReader("[{\"instanceGroupName\":\"instance-group-name\"}]")}
+
+		// act
+		firstData, firstErr := source.Read(context.Background())
+		secondData, secondErr := source.Read(context.Background())
+
+		assert.Nil(t, firstErr)
+		assert.Nil(t, secondErr)
+		assert.Equal(t, "[{\"instanceGroupName\":\"instance-group-name\"}]", string(firstData))
+		assert.Equal(t, firstData, secondData)
+	})
+}
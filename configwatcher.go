@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog/log"
+)
+
+// ReloadMIGConfigs re-reads and validates the managed instance group configuration and swaps it into the store if valid
+func ReloadMIGConfigs(ctx context.Context, source ConfigSource, store *MIGConfigStore) error {
+
+	config, err := ReadConfig(ctx, source)
+	if err != nil {
+		return err
+	}
+
+	return applyConfig(ctx, config, store)
+}
+
+// applyConfig validates the managed instance group configuration, including its queries if the store validates them, and swaps it into the store if valid
+func applyConfig(ctx context.Context, config Config, store *MIGConfigStore) error {
+
+	if err := ValidateConfig(config); err != nil {
+		return err
+	}
+	if store.ValidateQueries != nil {
+		if err := store.ValidateQueries(ctx, config.MIGs); err != nil {
+			return err
+		}
+	}
+
+	added, removed, changed := DiffMIGConfigs(store.GetConfig().MIGs, config.MIGs)
+
+	store.Set(config)
+
+	log.Info().
+		Strs("added", added).
+		Strs("removed", removed).
+		Strs("changed", changed).
+		Str("revision", config.Revision).
+		Msgf("Reloaded configuration for %v managed instance groups and %v discovery rules", len(config.MIGs), len(config.Discovery))
+
+	return nil
+}
+
+// HandleReloadSignals reloads the managed instance group configuration whenever a SIGHUP is received; it blocks forever
+func HandleReloadSignals(ctx context.Context, source ConfigSource, store *MIGConfigStore) {
+
+	reloadSignals := make(chan os.Signal, 1)
+	signal.Notify(reloadSignals, syscall.SIGHUP)
+
+	for range reloadSignals {
+		log.Info().Msg("Received SIGHUP, reloading configuration...")
+
+		if err := ReloadMIGConfigs(ctx, source, store); err != nil {
+			log.Error().Err(err).Msg("Reloading configuration failed, keeping active configuration")
+		}
+	}
+}
+
+// PollMIGConfigs re-reads the managed instance group configuration on an interval and reloads it when its content has changed; it blocks forever
+func PollMIGConfigs(ctx context.Context, source ConfigSource, store *MIGConfigStore, interval time.Duration) {
+
+	log.Info().Msgf("Polling %v for configuration changes every %v...", source, interval)
+
+	lastData, _ := source.Read(ctx)
+
+	for {
+		time.Sleep(interval)
+
+		data, err := source.Read(ctx)
+		if err != nil {
+			log.Error().Err(err).Msgf("Polling %v for configuration changes failed", source)
+			continue
+		}
+		if bytes.Equal(data, lastData) {
+			continue
+		}
+
+		log.Info().Msgf("Configuration in %v changed, reloading...", source)
+
+		config, err := UnmarshalConfig(data)
+		if err == nil {
+			config.Revision = ConfigSourceRevision(source)
+			err = applyConfig(ctx, config, store)
+		}
+		if err != nil {
+			log.Error().Err(err).Msgf("Reloading configuration from %v failed, keeping active configuration", source)
+			continue
+		}
+
+		lastData = data
+	}
+}
+
+// WatchMIGConfigFile reloads the managed instance group configuration whenever the config file changes; it blocks until the watcher fails
+func WatchMIGConfigFile(ctx context.Context, source *FileConfigSource, store *MIGConfigStore) error {
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	// watch the directory instead of the file itself, so renames by editors and atomic replaces are picked up as well
+	configFileDir := filepath.Dir(source.Path)
+	if err = watcher.Add(configFileDir); err != nil {
+		return err
+	}
+
+	log.Info().Msgf("Watching config %v for changes...", source)
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if !source.Matches(event.Name) {
+				continue
+			}
+			// removing one of multiple files matching a glob changes the configuration as well
+			relevantOps := fsnotify.Write | fsnotify.Create
+			if source.IsGlob() {
+				relevantOps |= fsnotify.Remove | fsnotify.Rename
+			}
+			if event.Op&relevantOps == 0 {
+				continue
+			}
+
+			log.Info().Msgf("Config %v changed, reloading...", source)
+
+			if err := ReloadMIGConfigs(ctx, source, store); err != nil {
+				log.Error().Err(err).Msgf("Reloading config %v failed, keeping active configuration", source)
+			}
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Error().Err(err).Msgf("Watching config %v failed", source)
+		}
+	}
+}
+
+// WatchMIGConfigMap reloads the managed instance group configuration whenever the ConfigMap is updated; it re-establishes the watch when it ends and blocks forever
+func WatchMIGConfigMap(ctx context.Context, source *ConfigMapConfigSource, store *MIGConfigStore) {
+
+	log.Info().Msgf("Watching config %v for changes...", source)
+
+	var lastData []byte
+	for {
+		err := source.Watch(ctx, func(data []byte) {
+			if bytes.Equal(data, lastData) {
+				return
+			}
+
+			log.Info().Msgf("Config %v changed, reloading...", source)
+
+			config, err := UnmarshalConfig(data)
+			if err == nil {
+				err = applyConfig(ctx, config, store)
+			}
+			if err != nil {
+				log.Error().Err(err).Msgf("Reloading config %v failed, keeping active configuration", source)
+				return
+			}
+
+			lastData = data
+		})
+		if err != nil {
+			log.Error().Err(err).Msgf("Watching config %v failed", source)
+		}
+
+		// back off a little before re-establishing the watch
+		time.Sleep(time.Duration(applyJitter(10)) * time.Second)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyConfig(t *testing.T) {
+
+	validConfig := Config{MIGs: []MIGConfiguration{{
+		GCloudProject:               "project-id",
+		GCloudZone:                  "europe-west1-b",
+		InstanceGroupName:           "instance-group-name",
+		RequestRateQuery:            "sum(rate(nginx_http_requests_total[10m]))",
+		NumberOfRequestsPerInstance: 10,
+	}}}
+
+	t.Run("KeepsActiveConfigurationIfQueriesAreRejected", func(t *testing.T) {
+
+		store := NewMIGConfigStore(Config{})
+		store.ValidateQueries = func(ctx context.Context, migConfigs []MIGConfiguration) error {
+			return errors.New("1:43: parse error: unexpected end of input")
+		}
+
+		// act
+		err := applyConfig(context.Background(), validConfig, store)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, 0, len(store.Get()))
+	})
+
+	t.Run("SwapsInConfigurationIfQueriesAreAccepted", func(t *testing.T) {
+
+		store := NewMIGConfigStore(Config{})
+		validated := []MIGConfiguration{}
+		store.ValidateQueries = func(ctx context.Context, migConfigs []MIGConfiguration) error {
+			validated = migConfigs
+			return nil
+		}
+
+		// act
+		err := applyConfig(context.Background(), validConfig, store)
+
+		assert.Nil(t, err)
+		assert.Equal(t, validConfig.MIGs, validated)
+		assert.Equal(t, validConfig.MIGs, store.Get())
+	})
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/rs/zerolog/log"
+)
+
+// InstanceCostSku is a sku of the billing catalog an instance of a managed instance group is billed for, like its vcpus or memory, with the quantity of it per instance
+type InstanceCostSku struct {
+	SkuID    string  `json:"skuId,omitempty"`
+	Quantity float64 `json:"quantity,omitempty"`
+}
+
+// instanceHourlyCost returns the estimated hourly cost of a single instance of the managed instance group, from instanceHourlyCost or the billing catalog prices of instanceCostSkus, or false if it isn't known
+func (s *MIGScaler) instanceHourlyCost(configItem MIGConfiguration) (float64, bool) {
+
+	if configItem.InstanceHourlyCost > 0 {
+		return configItem.InstanceHourlyCost, true
+	}
+	if len(configItem.InstanceCostSkus) == 0 {
+		return 0, false
+	}
+	if s.options.BillingCatalog == nil {
+		log.Warn().Msgf("Mig %v has instanceCostSkus, but no billing catalog is configured", configItem.InstanceGroupName)
+		return 0, false
+	}
+
+	hourlyCost, ok := s.options.BillingCatalog.HourlyCost(configItem.InstanceCostSkus)
+	if !ok {
+		log.Warn().Msgf("Prices of the instanceCostSkus of mig %v aren't in the billing catalog (yet)", configItem.InstanceGroupName)
+	}
+
+	return hourlyCost, ok
+}
+
+// applyCostCeilings refuses to raise the minimum number of instances beyond the number of instances that fit in maxHourlyCost of the managed instance group, or in what's left of the global --max-hourly-cost after the minimums of all other migs; a minimum that already exceeds the budget, because it was lowered, isn't lowered for it
+func (s *MIGScaler) applyCostCeilings(configItem MIGConfiguration, minimumNumberOfInstances int) int {
+
+	hourlyCost, ok := s.instanceHourlyCost(configItem)
+	if !ok || hourlyCost <= 0 {
+		return minimumNumberOfInstances
+	}
+	s.setInstanceHourlyCost(configItem, hourlyCost)
+
+	limit, ceiling := math.MaxInt32, ""
+	if configItem.MaxHourlyCost > 0 {
+		limit, ceiling = int(math.Floor(configItem.MaxHourlyCost/hourlyCost)), "mig"
+	}
+	if s.options.MaxHourlyCost > 0 {
+		globalLimit := int(math.Floor((s.options.MaxHourlyCost - s.hourlyCostOfOtherMIGs(configItem)) / hourlyCost))
+		if globalLimit < limit {
+			limit, ceiling = globalLimit, "global"
+		}
+	}
+	if limit < 0 {
+		limit = 0
+	}
+
+	if minimumNumberOfInstances <= limit {
+		estimatedHourlyCostVector.WithLabelValues(configItem.InstanceGroupName).Set(float64(minimumNumberOfInstances) * hourlyCost)
+		return minimumNumberOfInstances
+	}
+
+	capped := limit
+	if applied, _, ok := s.appliedMinimumNumberOfInstances(configItem); ok && applied > capped {
+		capped = applied
+	}
+	if capped > minimumNumberOfInstances {
+		capped = minimumNumberOfInstances
+	}
+
+	log.Warn().Msgf("Minimum number of instances %v for mig %v exceeds the %v hourly cost ceiling at %v per instance, capping it at %v", minimumNumberOfInstances, configItem.InstanceGroupName, ceiling, hourlyCost, capped)
+	costCappedVector.WithLabelValues(configItem.InstanceGroupName, ceiling).Inc()
+	estimatedHourlyCostVector.WithLabelValues(configItem.InstanceGroupName).Set(float64(capped) * hourlyCost)
+
+	return capped
+}
+
+// validateCost checks whether the costs are positive, instanceHourlyCost and instanceCostSkus are mutually exclusive, and maxHourlyCost has the cost of an instance to divide by
+func (c *MIGConfiguration) validateCost(addError func(field, message string)) {
+
+	if c.InstanceHourlyCost < 0 {
+		addError("instanceHourlyCost", "should be 0 or larger")
+	}
+	if c.MaxHourlyCost < 0 {
+		addError("maxHourlyCost", "should be 0 or larger")
+	}
+	if c.InstanceHourlyCost > 0 && len(c.InstanceCostSkus) > 0 {
+		addError("instanceHourlyCost", "instanceHourlyCost and instanceCostSkus are mutually exclusive")
+	}
+	if c.MaxHourlyCost > 0 && c.InstanceHourlyCost <= 0 && len(c.InstanceCostSkus) == 0 {
+		addError("maxHourlyCost", "requires instanceHourlyCost or instanceCostSkus")
+	}
+
+	for i, sku := range c.InstanceCostSkus {
+		if sku.SkuID == "" {
+			addError(fmt.Sprintf("instanceCostSkus[%v].skuId", i), "is required")
+		}
+		if sku.Quantity <= 0 {
+			addError(fmt.Sprintf("instanceCostSkus[%v].quantity", i), "should be larger than 0")
+		}
+	}
+}
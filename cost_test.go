@@ -0,0 +1,85 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyCostCeilings(t *testing.T) {
+
+	t.Run("ReturnsMinimumWithinMIGCeiling", func(t *testing.T) {
+
+		configItem := MIGConfiguration{InstanceGroupName: "cost-within", InstanceHourlyCost: 0.5, MaxHourlyCost: 10}
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+
+		// act
+		minimumNumberOfInstances := scaler.applyCostCeilings(configItem, 20)
+
+		assert.Equal(t, 20, minimumNumberOfInstances)
+		assert.Equal(t, 10.0, testutil.ToFloat64(estimatedHourlyCostVector.WithLabelValues("cost-within")))
+	})
+
+	t.Run("CapsMinimumAtMIGCeilingAndCountsIt", func(t *testing.T) {
+
+		configItem := MIGConfiguration{InstanceGroupName: "cost-mig", InstanceHourlyCost: 0.5, MaxHourlyCost: 10}
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+
+		// act
+		minimumNumberOfInstances := scaler.applyCostCeilings(configItem, 30)
+
+		assert.Equal(t, 20, minimumNumberOfInstances)
+		assert.Equal(t, 1.0, testutil.ToFloat64(costCappedVector.WithLabelValues("cost-mig", "mig")))
+	})
+
+	t.Run("DoesNotLowerMinimumThatAlreadyExceedsCeiling", func(t *testing.T) {
+
+		configItem := MIGConfiguration{InstanceGroupName: "cost-lowered", InstanceHourlyCost: 0.5, MaxHourlyCost: 10}
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+		scaler.setAppliedMinimumNumberOfInstances(configItem, 25, time.Now())
+
+		// act
+		minimums := []int{scaler.applyCostCeilings(configItem, 30), scaler.applyCostCeilings(configItem, 22), scaler.applyCostCeilings(configItem, 15)}
+
+		assert.Equal(t, []int{25, 22, 15}, minimums)
+	})
+
+	t.Run("CapsMinimumAtWhatIsLeftOfGlobalCeiling", func(t *testing.T) {
+
+		configItem := MIGConfiguration{InstanceGroupName: "cost-global", InstanceHourlyCost: 0.5}
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{MaxHourlyCost: 20})
+		otherConfigItem := MIGConfiguration{InstanceGroupName: "cost-other"}
+		scaler.setInstanceHourlyCost(otherConfigItem, 1.5)
+		scaler.setAppliedMinimumNumberOfInstances(otherConfigItem, 10, time.Now())
+
+		// act
+		minimumNumberOfInstances := scaler.applyCostCeilings(configItem, 30)
+
+		assert.Equal(t, 10, minimumNumberOfInstances)
+		assert.Equal(t, 1.0, testutil.ToFloat64(costCappedVector.WithLabelValues("cost-global", "global")))
+	})
+
+	t.Run("UsesBillingCatalogPricesOfInstanceCostSkus", func(t *testing.T) {
+
+		configItem := MIGConfiguration{InstanceGroupName: "cost-skus", InstanceCostSkus: []InstanceCostSku{{SkuID: "CP-CORE", Quantity: 4}}, MaxHourlyCost: 1}
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{BillingCatalog: &BillingCatalog{prices: map[string]float64{"CP-CORE": 0.025}}})
+
+		// act
+		minimumNumberOfInstances := scaler.applyCostCeilings(configItem, 30)
+
+		assert.Equal(t, 10, minimumNumberOfInstances)
+	})
+
+	t.Run("ReturnsMinimumIfCostIsUnknown", func(t *testing.T) {
+
+		configItem := MIGConfiguration{InstanceGroupName: "cost-unknown", InstanceCostSkus: []InstanceCostSku{{SkuID: "CP-CORE", Quantity: 4}}, MaxHourlyCost: 1}
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+
+		// act
+		minimumNumberOfInstances := scaler.applyCostCeilings(configItem, 30)
+
+		assert.Equal(t, 30, minimumNumberOfInstances)
+	})
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/oauth2/google"
+	bigquery "google.golang.org/api/bigquery/v2"
+	compute "google.golang.org/api/compute/v1"
+	monitoring "google.golang.org/api/monitoring/v3"
+)
+
+// googleTokenInfoURL returns the scopes granted to an access token
+const googleTokenInfoURL = "https://oauth2.googleapis.com/tokeninfo"
+
+// cloudBillingReadonlyScope is the narrow scope to read the Cloud Billing catalog with
+const cloudBillingReadonlyScope = "https://www.googleapis.com/auth/cloud-billing.readonly"
+
+// OAuthScopes holds the oauth scope to request for each google api the scaler uses
+type OAuthScopes struct {
+	Compute    string
+	Monitoring string
+	BigQuery   string
+	Billing    string
+}
+
+// NewOAuthScopes returns cloud-platform for all apis, or if narrow the narrowest scope each api needs, with compute.readonly for the compute api if the scaler never updates autoscalers
+func NewOAuthScopes(narrow, disableAllUpdates bool) OAuthScopes {
+
+	if !narrow {
+		return OAuthScopes{
+			Compute:    compute.CloudPlatformScope,
+			Monitoring: compute.CloudPlatformScope,
+			BigQuery:   compute.CloudPlatformScope,
+			Billing:    compute.CloudPlatformScope,
+		}
+	}
+
+	scopes := OAuthScopes{
+		Compute:    compute.ComputeScope,
+		Monitoring: monitoring.MonitoringReadScope,
+		BigQuery:   bigquery.BigqueryScope,
+		Billing:    cloudBillingReadonlyScope,
+	}
+	if disableAllUpdates {
+		scopes.Compute = compute.ComputeReadonlyScope
+	}
+
+	return scopes
+}
+
+// Used returns the distinct scopes of the apis the scaler uses, which includes the billing api only if the billing catalog is retrieved
+func (s OAuthScopes) Used(billing bool) (scopes []string) {
+
+	candidates := []string{s.Compute, s.Monitoring, s.BigQuery}
+	if billing {
+		candidates = append(candidates, s.Billing)
+	}
+
+	seen := map[string]bool{}
+	for _, candidate := range candidates {
+		if !seen[candidate] {
+			seen[candidate] = true
+			scopes = append(scopes, candidate)
+		}
+	}
+
+	return scopes
+}
+
+// credentialsRefreshInterval is how long the service account key of a managed instance group is used before it's read again, so a rotated key is picked up without a restart
+const credentialsRefreshInterval = 5 * time.Minute
+
+// migClient holds the authenticated client and compute client for the service account key of one or more managed instance groups
+type migClient struct {
+	client        *http.Client
+	computeClient ComputeClient
+
+	// keyChecksum is the checksum of the service account key the client was created with, and readAt when that key was last read
+	keyChecksum [sha256.Size]byte
+	readAt      time.Time
+}
+
+// HasOwnCredentials returns whether the managed instance group uses its own service account key instead of the default google credentials
+func (c *MIGConfiguration) HasOwnCredentials() bool {
+	return c.CredentialsFile != "" || c.CredentialsSecret != ""
+}
+
+// credentialsKey identifies the service account key of the managed instance group, so migs sharing one also share their client
+func (c *MIGConfiguration) credentialsKey() string {
+	if c.CredentialsSecret != "" {
+		return "secret:" + c.CredentialsSecret
+	}
+	return "file:" + c.CredentialsFile
+}
+
+// readServiceAccountKey reads the service account key of the managed instance group from credentialsFile, or from the credentialsSecret secret version using the default google credentials
+func (c *MIGConfiguration) readServiceAccountKey(ctx context.Context) ([]byte, error) {
+	if c.CredentialsSecret != "" {
+		source, err := NewSecretManagerConfigSource(ctx, c.CredentialsSecret)
+		if err != nil {
+			return nil, err
+		}
+		return source.Read(ctx)
+	}
+	return ioutil.ReadFile(c.CredentialsFile)
+}
+
+// clientFor returns the compute client and authenticated client for the managed instance group; migs with credentialsFile or credentialsSecret get one authenticated with that service account key, and all others the default one. The key is read the first time it's needed and again every credentialsRefreshInterval, creating a new client if it changed; reading it happens without holding the lock on all clients, so a slow Secret Manager doesn't hold up migs with other keys
+func (s *MIGScaler) clientFor(ctx context.Context, configItem MIGConfiguration) (*migClient, error) {
+
+	if !configItem.HasOwnCredentials() {
+		return &migClient{computeClient: s.computeClient}, nil
+	}
+
+	key := configItem.credentialsKey()
+	s.clientsMu.Lock()
+	client, ok := s.clients[key]
+	s.clientsMu.Unlock()
+	if ok && time.Since(client.readAt) < credentialsRefreshInterval {
+		return client, nil
+	}
+
+	data, err := configItem.readServiceAccountKey(ctx)
+	if err != nil {
+		if ok {
+			log.Warn().Err(err).Msgf("Reading service account key for mig %v failed, using the one read at %v", configItem.InstanceGroupName, client.readAt.Format(time.RFC3339))
+			return client, nil
+		}
+		return nil, err
+	}
+
+	checksum := sha256.Sum256(data)
+	if ok && checksum == client.keyChecksum {
+		// the client keeps its access token if the key didn't change
+		client = &migClient{client: client.client, computeClient: client.computeClient, keyChecksum: checksum, readAt: time.Now()}
+	} else {
+		if client, err = s.newMIGClient(data); err != nil {
+			return nil, err
+		}
+		client.keyChecksum, client.readAt = checksum, time.Now()
+	}
+
+	s.clientsMu.Lock()
+	s.clients[key] = client
+	s.clientsMu.Unlock()
+
+	return client, nil
+}
+
+// newMIGClient returns a client authenticated with the service account key
+func (s *MIGScaler) newMIGClient(data []byte) (*migClient, error) {
+
+	scope := s.options.ComputeScope
+	if scope == "" {
+		scope = compute.CloudPlatformScope
+	}
+	jwtConfig, err := google.JWTConfigFromJSON(data, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	// the client refreshes its token with the context it's created with, so it can't be the one of a single iteration
+	httpClient := jwtConfig.Client(context.Background())
+	computeClient, err := s.computeClient.WithClient(httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	return &migClient{client: computeClient.HTTPClient(), computeClient: computeClient}, nil
+}
+
+// computeClientFor returns the compute client to manage the managed instance group with
+func (s *MIGScaler) computeClientFor(ctx context.Context, configItem MIGConfiguration) (ComputeClient, error) {
+	client, err := s.clientFor(ctx, configItem)
+	if err != nil {
+		return nil, err
+	}
+	return client.computeClient, nil
+}
+
+// scalingSchedulesFor returns the client to manage the scaling schedules of the autoscaler of the managed instance group with
+func (s *MIGScaler) scalingSchedulesFor(ctx context.Context, configItem MIGConfiguration) (*ScalingSchedulesClient, error) {
+	if !configItem.HasOwnCredentials() {
+		return s.options.ScalingSchedules, nil
+	}
+	client, err := s.clientFor(ctx, configItem)
+	if err != nil {
+		return nil, err
+	}
+	return &ScalingSchedulesClient{BasePath: s.options.ScalingSchedules.BasePath, client: client.client}, nil
+}
+
+// validateCredentials checks whether credentialsFile and credentialsSecret are mutually exclusive and credentialsSecret is a secret version
+func (c *MIGConfiguration) validateCredentials(addError func(field, message string)) {
+	if c.CredentialsFile != "" && c.CredentialsSecret != "" {
+		addError("credentialsFile", "credentialsFile and credentialsSecret are mutually exclusive")
+	}
+	if c.CredentialsSecret != "" && !secretVersionNameRegex.MatchString(c.CredentialsSecret) {
+		addError("credentialsSecret", "should be of the form projects/<project>/secrets/<secret>/versions/<version>")
+	}
+}
+
+// CheckServiceAccountKeyFile checks whether the file is a service account key that's granted an access token for all scopes, so a wrong or revoked key fails at startup instead of at the first api call
+func CheckServiceAccountKeyFile(ctx context.Context, keyFile string, scopes ...string) error {
+
+	data, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return fmt.Errorf("Reading service account key %v failed: %v", keyFile, err)
+	}
+
+	var key struct {
+		Type string `json:"type"`
+	}
+	if err = json.Unmarshal(data, &key); err != nil {
+		return fmt.Errorf("Service account key %v is not valid json: %v", keyFile, err)
+	}
+	if key.Type != "service_account" {
+		return fmt.Errorf("%v is not a service account key, its type is %q", keyFile, key.Type)
+	}
+
+	jwtConfig, err := google.JWTConfigFromJSON(data, scopes...)
+	if err != nil {
+		return fmt.Errorf("Service account key %v is invalid: %v", keyFile, err)
+	}
+	token, err := jwtConfig.TokenSource(ctx).Token()
+	if err != nil {
+		return fmt.Errorf("Retrieving an access token with service account key %v failed: %v", keyFile, err)
+	}
+
+	if err = checkTokenScopes(ctx, googleTokenInfoURL, token.AccessToken, scopes); err != nil {
+		return fmt.Errorf("Service account key %v can't be used: %v", keyFile, err)
+	}
+
+	return nil
+}
+
+// checkTokenScopes checks whether the access token is granted all scopes, according to the token info endpoint
+func checkTokenScopes(ctx context.Context, tokenInfoURL, accessToken string, scopes []string) error {
+
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%v?access_token=%v", tokenInfoURL, url.QueryEscape(accessToken)), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(request.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Token info endpoint returned status code %v: %v", resp.StatusCode, string(body))
+	}
+
+	var tokenInfo struct {
+		Scope string `json:"scope"`
+	}
+	if err = json.Unmarshal(body, &tokenInfo); err != nil {
+		return err
+	}
+
+	granted := map[string]bool{}
+	for _, scope := range strings.Fields(tokenInfo.Scope) {
+		granted[scope] = true
+	}
+	missing := []string{}
+	for _, scope := range scopes {
+		if !granted[scope] {
+			missing = append(missing, scope)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("Its access token lacks the required scopes %v", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
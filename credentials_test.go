@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientFor(t *testing.T) {
+
+	t.Run("AuthenticatesComputeApiCallsWithCredentialsFile", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/token" {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"access_token":"project-b-token","token_type":"Bearer","expires_in":3600}`))
+				return
+			}
+			assert.Equal(t, "/project-b/zones/europe-west1-b/instanceGroupManagers/web", r.URL.Path)
+			assert.Equal(t, "Bearer project-b-token", r.Header.Get("Authorization"))
+			w.Write([]byte(`{"name":"web","targetSize":4}`))
+		}))
+		defer server.Close()
+
+		keyFile := writeServiceAccountKey(t, server.URL+"/token")
+		defer os.Remove(keyFile)
+
+		computeClient, _ := NewGAComputeClient(http.DefaultClient, ComputeClientOptions{BasePath: server.URL + "/"})
+		scaler := NewMIGScaler(computeClient, nil, MIGScalerOptions{})
+		configItem := MIGConfiguration{GCloudProject: "project-b", GCloudZone: "europe-west1-b", InstanceGroupName: "web", CredentialsFile: keyFile}
+
+		// act
+		instanceGroupManager, err := scaler.getInstanceGroupManager(context.Background(), configItem)
+
+		assert.Nil(t, err)
+		assert.Equal(t, int64(4), instanceGroupManager.TargetSize)
+	})
+
+	t.Run("ReusesClientForMigsWithTheSameCredentials", func(t *testing.T) {
+
+		keyFile := writeServiceAccountKey(t, "")
+		defer os.Remove(keyFile)
+
+		computeClient, _ := NewGAComputeClient(http.DefaultClient, ComputeClientOptions{})
+		scaler := NewMIGScaler(computeClient, nil, MIGScalerOptions{})
+
+		// act
+		webClient, err := scaler.clientFor(context.Background(), MIGConfiguration{InstanceGroupName: "web", CredentialsFile: keyFile})
+		assert.Nil(t, err)
+		apiClient, err := scaler.clientFor(context.Background(), MIGConfiguration{InstanceGroupName: "api", CredentialsFile: keyFile})
+		assert.Nil(t, err)
+
+		assert.True(t, webClient == apiClient)
+		assert.False(t, webClient.computeClient == ComputeClient(computeClient))
+	})
+
+	t.Run("CreatesNewClientWhenKeyIsRotated", func(t *testing.T) {
+
+		keyFile := writeServiceAccountKey(t, "")
+		defer os.Remove(keyFile)
+		rotatedKeyFile := writeServiceAccountKey(t, "")
+		defer os.Remove(rotatedKeyFile)
+
+		computeClient, _ := NewGAComputeClient(http.DefaultClient, ComputeClientOptions{})
+		scaler := NewMIGScaler(computeClient, nil, MIGScalerOptions{})
+		configItem := MIGConfiguration{InstanceGroupName: "web", CredentialsFile: keyFile}
+		client, err := scaler.clientFor(context.Background(), configItem)
+		assert.Nil(t, err)
+		rotatedKey, _ := ioutil.ReadFile(rotatedKeyFile)
+		ioutil.WriteFile(keyFile, rotatedKey, 0600)
+		client.readAt = client.readAt.Add(-credentialsRefreshInterval)
+
+		// act
+		rotatedClient, err := scaler.clientFor(context.Background(), configItem)
+
+		assert.Nil(t, err)
+		assert.False(t, client.computeClient == rotatedClient.computeClient)
+	})
+
+	t.Run("KeepsClientWhenRereadKeyIsUnchanged", func(t *testing.T) {
+
+		keyFile := writeServiceAccountKey(t, "")
+		defer os.Remove(keyFile)
+
+		computeClient, _ := NewGAComputeClient(http.DefaultClient, ComputeClientOptions{})
+		scaler := NewMIGScaler(computeClient, nil, MIGScalerOptions{})
+		configItem := MIGConfiguration{InstanceGroupName: "web", CredentialsFile: keyFile}
+		client, err := scaler.clientFor(context.Background(), configItem)
+		assert.Nil(t, err)
+		client.readAt = client.readAt.Add(-credentialsRefreshInterval)
+
+		// act
+		rereadClient, err := scaler.clientFor(context.Background(), configItem)
+
+		assert.Nil(t, err)
+		assert.True(t, client.computeClient == rereadClient.computeClient)
+		assert.True(t, rereadClient.readAt.After(client.readAt))
+	})
+
+	t.Run("KeepsClientWhenRereadingKeyFails", func(t *testing.T) {
+
+		keyFile := writeServiceAccountKey(t, "")
+
+		computeClient, _ := NewGAComputeClient(http.DefaultClient, ComputeClientOptions{})
+		scaler := NewMIGScaler(computeClient, nil, MIGScalerOptions{})
+		configItem := MIGConfiguration{InstanceGroupName: "web", CredentialsFile: keyFile}
+		client, err := scaler.clientFor(context.Background(), configItem)
+		assert.Nil(t, err)
+		os.Remove(keyFile)
+		client.readAt = client.readAt.Add(-credentialsRefreshInterval)
+
+		// act
+		rereadClient, err := scaler.clientFor(context.Background(), configItem)
+
+		assert.Nil(t, err)
+		assert.True(t, client == rereadClient)
+	})
+
+	t.Run("ReturnsDefaultComputeClientForMigWithoutOwnCredentials", func(t *testing.T) {
+
+		computeClient, _ := NewGAComputeClient(http.DefaultClient, ComputeClientOptions{})
+		scaler := NewMIGScaler(computeClient, nil, MIGScalerOptions{})
+
+		// act
+		client, err := scaler.clientFor(context.Background(), MIGConfiguration{InstanceGroupName: "web"})
+
+		assert.Nil(t, err)
+		assert.True(t, client.computeClient == ComputeClient(computeClient))
+	})
+}
+
+func TestCheckServiceAccountKeyFile(t *testing.T) {
+
+	t.Run("ReturnsErrorForKeyThatIsNoServiceAccountKey", func(t *testing.T) {
+
+		keyFile, _ := ioutil.TempFile("", "key")
+		defer os.Remove(keyFile.Name())
+		keyFile.Write([]byte(`{"type":"authorized_user","client_id":"id","client_secret":"secret","refresh_token":"token"}`))
+		keyFile.Close()
+
+		// act
+		err := CheckServiceAccountKeyFile(context.Background(), keyFile.Name(), "https://www.googleapis.com/auth/cloud-platform")
+
+		assert.EqualError(t, err, keyFile.Name()+` is not a service account key, its type is "authorized_user"`)
+	})
+
+	t.Run("ReturnsErrorIfNoAccessTokenIsGranted", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant","error_description":"Invalid JWT Signature."}`))
+		}))
+		defer server.Close()
+
+		keyFile := writeServiceAccountKey(t, server.URL)
+		defer os.Remove(keyFile)
+
+		// act
+		err := CheckServiceAccountKeyFile(context.Background(), keyFile, "https://www.googleapis.com/auth/cloud-platform")
+
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "Retrieving an access token with service account key "+keyFile+" failed")
+		}
+	})
+}
+
+func TestCheckTokenScopes(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "access-token", r.URL.Query().Get("access_token"))
+		w.Write([]byte(`{"scope":"https://www.googleapis.com/auth/compute.readonly https://www.googleapis.com/auth/monitoring.read","expires_in":"3599"}`))
+	}))
+	defer server.Close()
+
+	t.Run("ReturnsNilIfAllScopesAreGranted", func(t *testing.T) {
+
+		// act
+		err := checkTokenScopes(context.Background(), server.URL, "access-token", []string{"https://www.googleapis.com/auth/compute.readonly"})
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("ReturnsErrorWithScopesThatAreNotGranted", func(t *testing.T) {
+
+		// act
+		err := checkTokenScopes(context.Background(), server.URL, "access-token", []string{"https://www.googleapis.com/auth/compute.readonly", "https://www.googleapis.com/auth/compute"})
+
+		assert.EqualError(t, err, "Its access token lacks the required scopes https://www.googleapis.com/auth/compute")
+	})
+}
+
+func TestNewOAuthScopes(t *testing.T) {
+
+	t.Run("ReturnsCloudPlatformScopeForAllApisByDefault", func(t *testing.T) {
+
+		// act
+		scopes := NewOAuthScopes(false, true)
+
+		assert.Equal(t, OAuthScopes{
+			Compute:    "https://www.googleapis.com/auth/cloud-platform",
+			Monitoring: "https://www.googleapis.com/auth/cloud-platform",
+			BigQuery:   "https://www.googleapis.com/auth/cloud-platform",
+			Billing:    "https://www.googleapis.com/auth/cloud-platform",
+		}, scopes)
+	})
+
+	t.Run("ReturnsNarrowScopePerApiIfNarrow", func(t *testing.T) {
+
+		// act
+		scopes := NewOAuthScopes(true, false)
+
+		assert.Equal(t, OAuthScopes{
+			Compute:    "https://www.googleapis.com/auth/compute",
+			Monitoring: "https://www.googleapis.com/auth/monitoring.read",
+			BigQuery:   "https://www.googleapis.com/auth/bigquery",
+			Billing:    "https://www.googleapis.com/auth/cloud-billing.readonly",
+		}, scopes)
+	})
+
+	t.Run("ReturnsComputeReadonlyScopeIfNarrowAndAllUpdatesAreDisabled", func(t *testing.T) {
+
+		// act
+		scopes := NewOAuthScopes(true, true)
+
+		assert.Equal(t, "https://www.googleapis.com/auth/compute.readonly", scopes.Compute)
+	})
+}
+
+func TestUsedOAuthScopes(t *testing.T) {
+
+	t.Run("ReturnsScopeOfEveryApiIncludingBillingIfUsed", func(t *testing.T) {
+
+		// act
+		scopes := NewOAuthScopes(true, false).Used(true)
+
+		assert.Equal(t, []string{
+			"https://www.googleapis.com/auth/compute",
+			"https://www.googleapis.com/auth/monitoring.read",
+			"https://www.googleapis.com/auth/bigquery",
+			"https://www.googleapis.com/auth/cloud-billing.readonly",
+		}, scopes)
+	})
+
+	t.Run("LeavesOutBillingScopeIfNotUsed", func(t *testing.T) {
+
+		// act
+		scopes := NewOAuthScopes(true, false).Used(false)
+
+		assert.Equal(t, 3, len(scopes))
+		assert.NotContains(t, scopes, "https://www.googleapis.com/auth/cloud-billing.readonly")
+	})
+
+	t.Run("ReturnsCloudPlatformScopeOnceIfNotNarrow", func(t *testing.T) {
+
+		// act
+		scopes := NewOAuthScopes(false, false).Used(true)
+
+		assert.Equal(t, []string{"https://www.googleapis.com/auth/cloud-platform"}, scopes)
+	})
+}
+
+func TestValidateCredentials(t *testing.T) {
+
+	validConfig := MIGConfiguration{
+		GCloudProject:     "project-id",
+		GCloudRegion:      "europe-west1",
+		InstanceGroupName: "backend",
+		UpstreamMIG:       "frontend",
+		Ratio:             0.5,
+		CredentialsSecret: "projects/project-id/secrets/mig-scaler-key/versions/latest",
+	}
+
+	t.Run("ReturnsNilForCredentialsSecret", func(t *testing.T) {
+
+		// act
+		err := ValidateMIGConfigs([]MIGConfiguration{validConfig})
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("ReturnsErrorForCredentialsFileAndCredentialsSecret", func(t *testing.T) {
+
+		invalidConfig := validConfig
+		invalidConfig.CredentialsFile = "/secrets/project-b/key.json"
+
+		// act
+		err := ValidateMIGConfigs([]MIGConfiguration{invalidConfig})
+
+		if assert.IsType(t, ValidationErrors{}, err) {
+			assert.Equal(t, "credentialsFile", err.(ValidationErrors)[0].Field)
+		}
+	})
+
+	t.Run("ReturnsErrorForCredentialsSecretThatIsNoSecretVersion", func(t *testing.T) {
+
+		invalidConfig := validConfig
+		invalidConfig.CredentialsSecret = "mig-scaler-key"
+
+		// act
+		err := ValidateMIGConfigs([]MIGConfiguration{invalidConfig})
+
+		if assert.IsType(t, ValidationErrors{}, err) {
+			assert.Equal(t, "credentialsSecret", err.(ValidationErrors)[0].Field)
+		}
+	})
+}
+
+// writeServiceAccountKey writes a service account key with a new private key to a temporary file and returns its path
+func writeServiceAccountKey(t *testing.T, tokenURI string) string {
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	key, _ := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "mig-scaler@project-b.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})),
+		"token_uri":    tokenURI,
+	})
+	keyFile, _ := ioutil.TempFile("", "key")
+	keyFile.Write(key)
+	keyFile.Close()
+
+	return keyFile.Name()
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronExpression is a parsed standard 5 field cron expression: minute, hour, day of month, month and day of week
+type CronExpression struct {
+	minutes     map[int]bool
+	hours       map[int]bool
+	daysOfMonth map[int]bool
+	months      map[int]bool
+	daysOfWeek  map[int]bool
+
+	// like cron, when both day of month and day of week are restricted a time matches if either matches
+	daysOfMonthRestricted bool
+	daysOfWeekRestricted  bool
+}
+
+// ParseCronExpression parses a cron expression like `*/15 8-18 * * 1-5`; every field can be `*`, a number, a range, a comma separated list of those, and have a `/step`
+func ParseCronExpression(expression string) (cron CronExpression, err error) {
+
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return cron, fmt.Errorf("Cron expression %v should have 5 fields, but has %v", expression, len(fields))
+	}
+
+	if cron.minutes, err = parseCronField(fields[0], 0, 59); err != nil {
+		return cron, fmt.Errorf("Minute field of cron expression %v is invalid: %v", expression, err)
+	}
+	if cron.hours, err = parseCronField(fields[1], 0, 23); err != nil {
+		return cron, fmt.Errorf("Hour field of cron expression %v is invalid: %v", expression, err)
+	}
+	if cron.daysOfMonth, err = parseCronField(fields[2], 1, 31); err != nil {
+		return cron, fmt.Errorf("Day of month field of cron expression %v is invalid: %v", expression, err)
+	}
+	if cron.months, err = parseCronField(fields[3], 1, 12); err != nil {
+		return cron, fmt.Errorf("Month field of cron expression %v is invalid: %v", expression, err)
+	}
+	// day of week allows 7 for sunday as well
+	if cron.daysOfWeek, err = parseCronField(fields[4], 0, 7); err != nil {
+		return cron, fmt.Errorf("Day of week field of cron expression %v is invalid: %v", expression, err)
+	}
+	if cron.daysOfWeek[7] {
+		cron.daysOfWeek[0] = true
+	}
+
+	cron.daysOfMonthRestricted = !strings.HasPrefix(fields[2], "*")
+	cron.daysOfWeekRestricted = !strings.HasPrefix(fields[4], "*")
+
+	return cron, nil
+}
+
+// Matches returns whether the minute the time falls in matches the cron expression
+func (c CronExpression) Matches(t time.Time) bool {
+
+	if !c.minutes[t.Minute()] || !c.hours[t.Hour()] || !c.months[int(t.Month())] {
+		return false
+	}
+
+	dayOfMonth, dayOfWeek := c.daysOfMonth[t.Day()], c.daysOfWeek[int(t.Weekday())]
+	if c.daysOfMonthRestricted && c.daysOfWeekRestricted {
+		return dayOfMonth || dayOfWeek
+	}
+
+	return dayOfMonth && dayOfWeek
+}
+
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+
+	values := map[int]bool{}
+
+	for _, part := range strings.Split(field, ",") {
+
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return nil, fmt.Errorf("step %v should be a number larger than 0", part[i+1:])
+			}
+			part = part[:i]
+		}
+
+		start, end := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if start, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("%v is not a number", bounds[0])
+			}
+			if end, err = strconv.Atoi(bounds[1]); err != nil {
+				return nil, fmt.Errorf("%v is not a number", bounds[1])
+			}
+		default:
+			value, err := strconv.Atoi(part)
+			if err != nil {
+				return nil, fmt.Errorf("%v is not a number", part)
+			}
+			start, end = value, value
+			// like cron, a single value with a step runs until the end of the range
+			if step > 1 {
+				end = max
+			}
+		}
+
+		if start < min || end > max || start > end {
+			return nil, fmt.Errorf("%v should be within %v-%v", part, min, max)
+		}
+
+		for value := start; value <= end; value += step {
+			values[value] = true
+		}
+	}
+
+	return values, nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCronExpression(t *testing.T) {
+
+	t.Run("MatchesRangesListsAndSteps", func(t *testing.T) {
+
+		cron, err := ParseCronExpression("*/15 8-18 * * 1-5")
+		assert.Nil(t, err)
+
+		// act
+		matchesMondayMorning := cron.Matches(time.Date(2020, 6, 1, 8, 30, 0, 0, time.UTC))
+		matchesMondayOffStep := cron.Matches(time.Date(2020, 6, 1, 8, 31, 0, 0, time.UTC))
+		matchesSaturday := cron.Matches(time.Date(2020, 6, 6, 8, 30, 0, 0, time.UTC))
+
+		assert.True(t, matchesMondayMorning)
+		assert.False(t, matchesMondayOffStep)
+		assert.False(t, matchesSaturday)
+	})
+
+	t.Run("MatchesEitherDayOfMonthOrDayOfWeekIfBothAreRestricted", func(t *testing.T) {
+
+		cron, err := ParseCronExpression("0 12 1 * 7")
+		assert.Nil(t, err)
+
+		// act
+		matchesFirstOfMonth := cron.Matches(time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC))
+		matchesSunday := cron.Matches(time.Date(2020, 6, 7, 12, 0, 0, 0, time.UTC))
+		matchesOtherDay := cron.Matches(time.Date(2020, 6, 8, 12, 0, 0, 0, time.UTC))
+
+		assert.True(t, matchesFirstOfMonth)
+		assert.True(t, matchesSunday)
+		assert.False(t, matchesOtherDay)
+	})
+
+	t.Run("ReturnsErrorForInvalidExpression", func(t *testing.T) {
+
+		for _, expression := range []string{"* * * *", "60 * * * *", "* 5-2 * * *", "*/0 * * * *", "a * * * *"} {
+
+			// act
+			_, err := ParseCronExpression(expression)
+
+			assert.NotNil(t, err, expression)
+		}
+	})
+}
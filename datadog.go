@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/sethgrid/pester"
+)
+
+// DatadogMetricSource retrieves request rates by executing metrics queries against the Datadog api
+type DatadogMetricSource struct {
+	APIURL string
+	APIKey string
+	AppKey string
+}
+
+// DatadogQueryResponse is used to unmarshal the response of a Datadog metrics query
+type DatadogQueryResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Series []struct {
+		// Pointlist holds [timestamp, value] pairs in ascending time order; value is null for gaps
+		Pointlist [][]*float64 `json:"pointlist"`
+	} `json:"series"`
+}
+
+// GetRequestRate executes the request rate query over the last 5 minutes and returns the most recent value
+func (s *DatadogMetricSource) GetRequestRate(ctx context.Context, configItem MIGConfiguration) (float64, error) {
+
+	if s.APIKey == "" || s.AppKey == "" {
+		return 0, errors.New("Querying Datadog requires --datadog-api-key and --datadog-app-key")
+	}
+
+	now := time.Now()
+	queryURL := fmt.Sprintf("%v/api/v1/query?from=%v&to=%v&query=%v", s.APIURL, now.Add(-5*time.Minute).Unix(), now.Unix(), url.QueryEscape(configItem.RequestRateQuery))
+
+	request, err := http.NewRequest(http.MethodGet, queryURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("DD-API-KEY", s.APIKey)
+	request.Header.Set("DD-APPLICATION-KEY", s.AppKey)
+
+	resp, err := pester.Do(request.WithContext(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("Executing datadog query failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("Reading datadog query response body failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("Datadog query returned status code %v: %v", resp.StatusCode, string(body))
+	}
+
+	var queryResponse DatadogQueryResponse
+	if err = json.Unmarshal(body, &queryResponse); err != nil {
+		return 0, fmt.Errorf("Unmarshalling datadog query response body failed: %v", err)
+	}
+
+	return queryResponse.GetRequestRate()
+}
+
+// GetRequestRate returns the most recent non-null value of the first series
+func (r *DatadogQueryResponse) GetRequestRate() (float64, error) {
+
+	if r.Status == "error" {
+		return 0, fmt.Errorf("Datadog query failed: %v", r.Error)
+	}
+	if len(r.Series) == 0 {
+		return 0, errEmptyResponse
+	}
+
+	pointlist := r.Series[0].Pointlist
+	for i := len(pointlist) - 1; i >= 0; i-- {
+		if len(pointlist[i]) == 2 && pointlist[i][1] != nil {
+			return *pointlist[i][1], nil
+		}
+	}
+
+	return 0, errEmptyResponse
+}
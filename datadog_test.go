@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDatadogMetricSourceGetRequestRate(t *testing.T) {
+
+	t.Run("ReturnsMostRecentNonNullValueOfFirstSeries", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "api-key", r.Header.Get("DD-API-KEY"))
+			assert.Equal(t, "app-key", r.Header.Get("DD-APPLICATION-KEY"))
+			assert.Equal(t, "sum:nginx.requests{app:a}.as_rate()", r.URL.Query().Get("query"))
+			w.Write([]byte(`{"status":"ok","series":[{"pointlist":[[1513161100000,180.1],[1513161148000,225.4],[1513161160000,null]]}]}`))
+		}))
+		defer server.Close()
+
+		source := &DatadogMetricSource{APIURL: server.URL, APIKey: "api-key", AppKey: "app-key"}
+
+		// act
+		requestRate, err := source.GetRequestRate(context.Background(), MIGConfiguration{RequestRateQuery: "sum:nginx.requests{app:a}.as_rate()"})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 225.4, requestRate)
+	})
+
+	t.Run("ReturnsErrorWithoutKeys", func(t *testing.T) {
+
+		source := &DatadogMetricSource{APIURL: "https://api.datadoghq.com"}
+
+		// act
+		_, err := source.GetRequestRate(context.Background(), MIGConfiguration{RequestRateQuery: "sum:nginx.requests{*}"})
+
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// MIGDiscoveryConfiguration describes how to find managed instance groups to scale by the labels on their instance template, instead of listing them explicitly
+type MIGDiscoveryConfiguration struct {
+	GCloudProject            string                 `json:"gcloudProject,omitempty"`
+	GCloudZone               string                 `json:"gcloudZone,omitempty"`
+	GCloudRegion             string                 `json:"gcloudRegion,omitempty"`
+	LabelSelector            string                 `json:"labelSelector,omitempty"`
+	RequestRateQueryTemplate string                 `json:"requestRateQueryTemplate,omitempty"`
+	MIG                      map[string]interface{} `json:"mig,omitempty"`
+}
+
+// Validate checks whether the discovery rule is usable and returns an error for every invalid field
+func (d *MIGDiscoveryConfiguration) Validate() (errs []ValidationError) {
+
+	addError := func(field, message string) {
+		errs = append(errs, ValidationError{InstanceGroupName: "discovery", Field: field, Message: message})
+	}
+
+	if d.GCloudProject == "" {
+		addError("gcloudProject", "is required")
+	}
+	if d.GCloudZone == "" && d.GCloudRegion == "" {
+		addError("gcloudZone", "either gcloudZone or gcloudRegion is required")
+	}
+	if d.GCloudZone != "" && d.GCloudRegion != "" {
+		addError("gcloudZone", "gcloudZone and gcloudRegion are mutually exclusive")
+	}
+	if _, err := ParseLabelSelector(d.LabelSelector); err != nil {
+		addError("labelSelector", err.Error())
+	}
+	if d.RequestRateQueryTemplate == "" {
+		addError("requestRateQueryTemplate", "is required")
+	} else if _, err := template.New("query").Parse(d.RequestRateQueryTemplate); err != nil {
+		addError("requestRateQueryTemplate", err.Error())
+	}
+
+	return
+}
+
+// ParseLabelSelector parses a label selector of the form key=value,key2=value2 into a map; a key without value only requires the label to exist
+func ParseLabelSelector(selector string) (map[string]string, error) {
+
+	labels := map[string]string{}
+	for _, requirement := range strings.Split(selector, ",") {
+		requirement = strings.TrimSpace(requirement)
+		if requirement == "" {
+			continue
+		}
+
+		parts := strings.SplitN(requirement, "=", 2)
+		key := strings.TrimSpace(parts[0])
+		if key == "" {
+			return nil, fmt.Errorf("label selector requirement %q has no key", requirement)
+		}
+
+		labels[key] = ""
+		if len(parts) == 2 {
+			labels[key] = strings.TrimSpace(parts[1])
+		}
+	}
+
+	if len(labels) == 0 {
+		return nil, fmt.Errorf("label selector %q has no requirements", selector)
+	}
+
+	return labels, nil
+}
+
+// MatchesLabelSelector returns true if the labels satisfy all requirements of the parsed label selector
+func MatchesLabelSelector(labels, selector map[string]string) bool {
+	for key, value := range selector {
+		labelValue, ok := labels[key]
+		if !ok || (value != "" && labelValue != value) {
+			return false
+		}
+	}
+	return true
+}
+
+// RunMIGDiscovery periodically discovers managed instance groups for the discovery rules in the active configuration and stores them; it blocks forever
+func RunMIGDiscovery(ctx context.Context, computeClient ComputeClient, store *MIGConfigStore, interval time.Duration) {
+
+	for {
+		rules := store.GetConfig().Discovery
+		if len(rules) > 0 {
+			migConfigs, err := DiscoverMIGs(ctx, computeClient, rules)
+			if err != nil {
+				log.Error().Err(err).Msg("Discovering managed instance groups failed, keeping previously discovered managed instance groups")
+			} else {
+				log.Info().Msgf("Discovered %v managed instance groups for %v discovery rules", len(migConfigs), len(rules))
+				store.SetDiscovered(migConfigs)
+			}
+		} else {
+			store.SetDiscovered(nil)
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+// DiscoverMIGs lists the managed instance groups for every discovery rule and returns configuration for the ones whose instance template matches the label selector
+func DiscoverMIGs(ctx context.Context, computeClient ComputeClient, rules []MIGDiscoveryConfiguration) (migConfigs []MIGConfiguration, err error) {
+
+	for _, rule := range rules {
+
+		selector, err := ParseLabelSelector(rule.LabelSelector)
+		if err != nil {
+			return migConfigs, err
+		}
+
+		instanceGroupManagers, err := computeClient.ListInstanceGroupManagers(ctx, MIGConfiguration{GCloudProject: rule.GCloudProject, GCloudZone: rule.GCloudZone, GCloudRegion: rule.GCloudRegion})
+		if err != nil {
+			return migConfigs, err
+		}
+
+		// instance groups managers often share templates, so only retrieve each template once
+		templateLabels := map[string]map[string]string{}
+
+		for _, instanceGroupManager := range instanceGroupManagers {
+
+			templateName := path.Base(instanceGroupManager.InstanceTemplate)
+			labels, ok := templateLabels[templateName]
+			if !ok {
+				instanceTemplate, err := computeClient.GetInstanceTemplate(ctx, rule.GCloudProject, templateName)
+				if err != nil {
+					return migConfigs, err
+				}
+				if instanceTemplate.Properties != nil {
+					labels = instanceTemplate.Properties.Labels
+				}
+				templateLabels[templateName] = labels
+			}
+
+			if !MatchesLabelSelector(labels, selector) {
+				continue
+			}
+
+			migConfig, err := rule.render(instanceGroupManager.Name)
+			if err != nil {
+				return migConfigs, err
+			}
+
+			if errs := migConfig.Validate(); len(errs) > 0 {
+				log.Warn().Msgf("Skipping discovered managed instance group %v, its configuration is invalid: %v", migConfig.InstanceGroupName, ValidationErrors(errs))
+				continue
+			}
+
+			migConfigs = append(migConfigs, migConfig)
+		}
+	}
+
+	return
+}
+
+// render returns the configuration for a discovered managed instance group, with the settings of the discovery rule and the query template rendered for it
+func (d *MIGDiscoveryConfiguration) render(instanceGroupName string) (migConfig MIGConfiguration, err error) {
+
+	merged := mergeMaps(d.MIG, map[string]interface{}{
+		"instanceGroupName":        instanceGroupName,
+		"gcloudProject":            d.GCloudProject,
+		"requestRateQueryTemplate": d.RequestRateQueryTemplate,
+	})
+
+	// the location and query template of the discovery rule replace any inherited from the defaults
+	delete(merged, "requestRateQuery")
+	delete(merged, "gcloudZone")
+	delete(merged, "gcloudRegion")
+	if d.GCloudRegion != "" {
+		merged["gcloudRegion"] = d.GCloudRegion
+	} else {
+		merged["gcloudZone"] = d.GCloudZone
+	}
+
+	migJSON, err := json.Marshal(merged)
+	if err != nil {
+		return
+	}
+
+	if err = json.Unmarshal(migJSON, &migConfig); err != nil {
+		return
+	}
+
+	err = migConfig.RenderRequestRateQuery()
+
+	return
+}
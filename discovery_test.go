@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLabelSelector(t *testing.T) {
+
+	t.Run("ReturnsRequirementsAsMap", func(t *testing.T) {
+
+		// act
+		selector, err := ParseLabelSelector("autoscale-by-rps=true, team")
+
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]string{"autoscale-by-rps": "true", "team": ""}, selector)
+	})
+
+	t.Run("ReturnsErrorForEmptySelector", func(t *testing.T) {
+
+		// act
+		_, err := ParseLabelSelector("")
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestMatchesLabelSelector(t *testing.T) {
+
+	selector := map[string]string{"autoscale-by-rps": "true", "team": ""}
+
+	t.Run("ReturnsTrueIfAllRequirementsAreMet", func(t *testing.T) {
+
+		// act
+		matches := MatchesLabelSelector(map[string]string{"autoscale-by-rps": "true", "team": "search", "other": "x"}, selector)
+
+		assert.True(t, matches)
+	})
+
+	t.Run("ReturnsFalseIfValueDiffers", func(t *testing.T) {
+
+		// act
+		matches := MatchesLabelSelector(map[string]string{"autoscale-by-rps": "false", "team": "search"}, selector)
+
+		assert.False(t, matches)
+	})
+
+	t.Run("ReturnsFalseIfLabelIsMissing", func(t *testing.T) {
+
+		// act
+		matches := MatchesLabelSelector(map[string]string{"autoscale-by-rps": "true"}, selector)
+
+		assert.False(t, matches)
+	})
+}
+
+func TestMIGDiscoveryConfigurationRender(t *testing.T) {
+
+	t.Run("ReturnsConfigWithRenderedQueryAndRuleLocation", func(t *testing.T) {
+
+		rule := MIGDiscoveryConfiguration{
+			GCloudProject:            "project-id",
+			GCloudRegion:             "europe-west1",
+			RequestRateQueryTemplate: "sum(rate(nginx_http_requests_total{location=\"@{{ .InstanceGroupName }}\"}[10m]))",
+			MIG: map[string]interface{}{
+				"gcloudZone":                  "europe-west1-b",
+				"numberOfRequestsPerInstance": 5.8,
+			},
+		}
+		// act
+		migConfig, err := rule.render("instance-group-name")
+
+		assert.Nil(t, err)
+		assert.Equal(t, "instance-group-name", migConfig.InstanceGroupName)
+		assert.Equal(t, "project-id", migConfig.GCloudProject)
+		assert.Equal(t, "europe-west1", migConfig.GCloudRegion)
+		assert.Equal(t, "", migConfig.GCloudZone)
+		assert.Equal(t, 5.8, migConfig.NumberOfRequestsPerInstance)
+		assert.Equal(t, "sum(rate(nginx_http_requests_total{location=\"@instance-group-name\"}[10m]))", migConfig.RequestRateQuery)
+	})
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/sethgrid/pester"
+)
+
+// defaultElasticsearchWindowSeconds is the window the count of an elasticsearch query is divided by when elasticsearchWindowSeconds isn't set; it matches a query filtering on the last 10 minutes
+const defaultElasticsearchWindowSeconds = 600
+
+// errElasticsearchHitCountIsLowerBound is returned for a hit count with relation gte, which elasticsearch returns once it stops counting at track_total_hits
+var errElasticsearchHitCountIsLowerBound = errors.New("Elasticsearch hit count is a lower bound of the actual number of hits")
+
+// ElasticsearchMetricSource retrieves request rates by running search queries against elasticsearch and dividing the resulting count by the window the query covers
+type ElasticsearchMetricSource struct {
+	// ElasticsearchURL is the default cluster, used for managed instance groups that don't set elasticsearchUrl
+	ElasticsearchURL string
+}
+
+// ElasticsearchSearchResponse is used to unmarshal the response of an elasticsearch search query
+type ElasticsearchSearchResponse struct {
+	Hits struct {
+		// Total is a number before elasticsearch 7 and an object with value and relation since
+		Total json.RawMessage `json:"total"`
+	} `json:"hits"`
+	Aggregations map[string]struct {
+		Value *float64 `json:"value"`
+	} `json:"aggregations"`
+}
+
+// GetRequestRate runs the request rate query as search body against the elasticsearch index of the managed instance group and returns the count per second
+func (s *ElasticsearchMetricSource) GetRequestRate(ctx context.Context, configItem MIGConfiguration) (float64, error) {
+
+	elasticsearchURL := s.ElasticsearchURL
+	if configItem.ElasticsearchURL != "" {
+		elasticsearchURL = configItem.ElasticsearchURL
+	}
+	searchURL := fmt.Sprintf("%v/_search", strings.TrimSuffix(elasticsearchURL, "/"))
+	if configItem.ElasticsearchIndex != "" {
+		searchURL = fmt.Sprintf("%v/%v/_search", strings.TrimSuffix(elasticsearchURL, "/"), configItem.ElasticsearchIndex)
+	}
+
+	count, err := searchCount(ctx, searchURL, configItem.RequestRateQuery)
+	if err == errElasticsearchHitCountIsLowerBound {
+		// elasticsearch 7 and later stop counting at 10000 hits by default, so the query is run again counting all of them
+		query, ok := trackTotalHits(configItem.RequestRateQuery)
+		if !ok {
+			return 0, fmt.Errorf("Elasticsearch query counts at most track_total_hits hits: %v", err)
+		}
+		count, err = searchCount(ctx, searchURL, query)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	windowSeconds := configItem.ElasticsearchWindowSeconds
+	if windowSeconds <= 0 {
+		windowSeconds = defaultElasticsearchWindowSeconds
+	}
+
+	return count / windowSeconds, nil
+}
+
+// searchCount runs the query as search body and returns the count of the response
+func searchCount(ctx context.Context, searchURL, query string) (float64, error) {
+
+	request, err := http.NewRequest(http.MethodPost, searchURL, bytes.NewReader([]byte(query)))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	resp, err := pester.Do(request.WithContext(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("Executing elasticsearch query failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("Reading elasticsearch query response body failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("Elasticsearch query returned status code %v: %v", resp.StatusCode, string(body))
+	}
+
+	var searchResponse ElasticsearchSearchResponse
+	if err = json.Unmarshal(body, &searchResponse); err != nil {
+		return 0, fmt.Errorf("Unmarshalling elasticsearch query response body failed: %v", err)
+	}
+
+	return searchResponse.GetCount()
+}
+
+// trackTotalHits returns the query with track_total_hits set to true, unless the query sets it itself
+func trackTotalHits(query string) (string, bool) {
+
+	var body map[string]interface{}
+	if err := json.Unmarshal([]byte(query), &body); err != nil {
+		return "", false
+	}
+	if _, ok := body["track_total_hits"]; ok {
+		return "", false
+	}
+	body["track_total_hits"] = true
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return "", false
+	}
+
+	return string(data), true
+}
+
+// GetCount returns the value of the single aggregation in the response, or the total number of hits if the query has no aggregations; it returns errElasticsearchHitCountIsLowerBound if elasticsearch stopped counting hits
+func (r *ElasticsearchSearchResponse) GetCount() (float64, error) {
+
+	if len(r.Aggregations) > 0 {
+		if len(r.Aggregations) != 1 {
+			return 0, fmt.Errorf("Elasticsearch query should have a single aggregation, but has %v", len(r.Aggregations))
+		}
+		for name, aggregation := range r.Aggregations {
+			if aggregation.Value == nil {
+				return 0, fmt.Errorf("Elasticsearch aggregation %v has no value", name)
+			}
+			return *aggregation.Value, nil
+		}
+	}
+
+	var total float64
+	if err := json.Unmarshal(r.Hits.Total, &total); err == nil {
+		return total, nil
+	}
+
+	var totalObject struct {
+		Value    float64 `json:"value"`
+		Relation string  `json:"relation"`
+	}
+	if err := json.Unmarshal(r.Hits.Total, &totalObject); err != nil {
+		return 0, fmt.Errorf("Elasticsearch response has no hit count: %v", err)
+	}
+	if totalObject.Relation == "gte" {
+		return 0, errElasticsearchHitCountIsLowerBound
+	}
+
+	return totalObject.Value, nil
+}
+
+// ValidateJSONQuery checks whether a query that is sent as request body is valid json
+func ValidateJSONQuery(query string) error {
+	var body interface{}
+	return json.Unmarshal([]byte(query), &body)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestElasticsearchMetricSourceGetRequestRate(t *testing.T) {
+
+	query := `{"size":0,"query":{"range":{"@timestamp":{"gte":"now-10m"}}}}`
+
+	t.Run("ReturnsHitCountPerSecondOfWindow", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			assert.Equal(t, "/access-logs-*/_search", r.URL.Path)
+			assert.Equal(t, query, string(body))
+			w.Write([]byte(`{"hits":{"total":{"value":6000,"relation":"eq"}}}`))
+		}))
+		defer server.Close()
+
+		source := &ElasticsearchMetricSource{ElasticsearchURL: server.URL}
+
+		// act
+		requestRate, err := source.GetRequestRate(context.Background(), MIGConfiguration{RequestRateQuery: query, ElasticsearchIndex: "access-logs-*"})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 10.0, requestRate)
+	})
+
+	t.Run("ReturnsAggregationValuePerSecondOfConfiguredWindow", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"hits":{"total":12000},"aggregations":{"requests":{"value":3000}}}`))
+		}))
+		defer server.Close()
+
+		source := &ElasticsearchMetricSource{ElasticsearchURL: server.URL}
+
+		// act
+		requestRate, err := source.GetRequestRate(context.Background(), MIGConfiguration{RequestRateQuery: query, ElasticsearchWindowSeconds: 300})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 10.0, requestRate)
+	})
+
+	t.Run("RunsQueryAgainTrackingTotalHitsIfCountIsLowerBound", func(t *testing.T) {
+
+		var bodies []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			bodies = append(bodies, string(body))
+			if len(bodies) == 1 {
+				w.Write([]byte(`{"hits":{"total":{"value":10000,"relation":"gte"}}}`))
+				return
+			}
+			w.Write([]byte(`{"hits":{"total":{"value":30000,"relation":"eq"}}}`))
+		}))
+		defer server.Close()
+
+		source := &ElasticsearchMetricSource{ElasticsearchURL: server.URL}
+
+		// act
+		requestRate, err := source.GetRequestRate(context.Background(), MIGConfiguration{RequestRateQuery: query})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 50.0, requestRate)
+		if assert.Equal(t, 2, len(bodies)) {
+			assert.Contains(t, bodies[1], `"track_total_hits":true`)
+		}
+	})
+
+	t.Run("ReturnsErrorIfCountIsLowerBoundOfQuerySettingTrackTotalHits", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"hits":{"total":{"value":10000,"relation":"gte"}}}`))
+		}))
+		defer server.Close()
+
+		source := &ElasticsearchMetricSource{ElasticsearchURL: server.URL}
+
+		// act
+		_, err := source.GetRequestRate(context.Background(), MIGConfiguration{RequestRateQuery: `{"size":0,"track_total_hits":10000}`})
+
+		assert.NotNil(t, err)
+	})
+}
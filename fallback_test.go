@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type failingMetricSource struct{}
+
+func (s *failingMetricSource) GetRequestRate(ctx context.Context, configItem MIGConfiguration) (float64, error) {
+	return 0, errors.New("Empty response")
+}
+
+func TestGetRequestRateWithFallbacks(t *testing.T) {
+
+	metricSources := map[string]MetricSource{
+		"prometheus":      &failingMetricSource{},
+		"cloudmonitoring": &fakeMetricSource{requestRates: map[string]float64{"fetch https_lb_rule": 225.4}},
+	}
+
+	t.Run("ReturnsRequestRateOfFirstSucceedingFallback", func(t *testing.T) {
+
+		configItem := MIGConfiguration{
+			RequestRateQuery: "sum(rate(nginx_http_requests_total[10m]))",
+			Fallbacks: []map[string]interface{}{
+				map[string]interface{}{"metricSource": "prometheus", "prometheusUrl": "http://prometheus-replica"},
+				map[string]interface{}{"metricSource": "cloudmonitoring", "requestRateQuery": "fetch https_lb_rule"},
+			},
+		}
+
+		// act
+		requestRate, err := getRequestRateWithFallbacks(context.Background(), metricSources, configItem)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 225.4, requestRate)
+	})
+
+	t.Run("ReturnsErrorIfAllFallbacksFail", func(t *testing.T) {
+
+		configItem := MIGConfiguration{
+			RequestRateQuery: "sum(rate(nginx_http_requests_total[10m]))",
+			Fallbacks: []map[string]interface{}{
+				map[string]interface{}{"prometheusUrl": "http://prometheus-replica"},
+			},
+		}
+
+		// act
+		_, err := getRequestRateWithFallbacks(context.Background(), metricSources, configItem)
+
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// followMIGTarget derives the minimum number of instances of a mig with followMig from the target the followed mig last calculated, multiplied by ratio and rounded like any other target
+func (s *MIGScaler) followMIGTarget(configItem MIGConfiguration) (int, error) {
+
+	leaderTarget, err := s.followedTargetMinimumNumberOfInstances(configItem)
+	if err != nil {
+		return 0, err
+	}
+
+	return configItem.ratioTarget(float64(leaderTarget)), nil
+}
+
+// ratioTarget returns ratio times the number of instances of another mig, rounded like any other target and raised to minimumNumberOfInstances if it's lower
+func (c *MIGConfiguration) ratioTarget(numberOfInstances float64) int {
+
+	minimumNumberOfInstances := c.RoundTarget(numberOfInstances * c.Ratio)
+	if minimumNumberOfInstances < c.MinimumNumberOfInstances {
+		minimumNumberOfInstances = c.MinimumNumberOfInstances
+	}
+
+	return minimumNumberOfInstances
+}
+
+// OrderByFollowMIG returns the managed instance group configurations with the migs that follow another mig last, so the target they follow is calculated earlier in the same iteration
+func OrderByFollowMIG(migConfigs []MIGConfiguration) []MIGConfiguration {
+
+	ordered := make([]MIGConfiguration, len(migConfigs))
+	copy(ordered, migConfigs)
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].FollowMIG == "" && ordered[j].FollowMIG != ""
+	})
+
+	return ordered
+}
+
+// validateFollowMIG checks whether followMig and ratio are set together, and followMig isn't combined with a query of its own
+func (c *MIGConfiguration) validateFollowMIG(addError func(field, message string)) {
+
+	if c.FollowMIG == "" {
+		if c.Ratio != 0 && c.UpstreamMIG == "" {
+			addError("ratio", "requires followMig or upstreamMig")
+		}
+		return
+	}
+
+	if c.Ratio <= 0 {
+		addError("ratio", "should be larger than 0")
+	}
+	if c.FollowMIG == c.InstanceGroupName {
+		addError("followMig", "can't be the mig itself")
+	}
+	if len(c.Queries) > 0 {
+		addError("followMig", "followMig and queries are mutually exclusive")
+	}
+}
+
+// validateFollowedMIGs checks whether every followMig refers to a configured mig that doesn't follow another mig itself
+func validateFollowedMIGs(migConfigs []MIGConfiguration) (errs ValidationErrors) {
+
+	followers := map[string]bool{}
+	for _, c := range migConfigs {
+		followers[c.InstanceGroupName] = c.FollowMIG != ""
+	}
+
+	for i, c := range migConfigs {
+		if c.FollowMIG == "" || c.FollowMIG == c.InstanceGroupName {
+			continue
+		}
+		following, ok := followers[c.FollowMIG]
+		if !ok {
+			errs = append(errs, ValidationError{Index: i, InstanceGroupName: c.InstanceGroupName, Field: "followMig", Message: fmt.Sprintf("mig %v is not configured", c.FollowMIG)})
+		} else if following {
+			errs = append(errs, ValidationError{Index: i, InstanceGroupName: c.InstanceGroupName, Field: "followMig", Message: fmt.Sprintf("mig %v follows another mig itself", c.FollowMIG)})
+		}
+	}
+
+	return
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFollowMIGTarget(t *testing.T) {
+
+	t.Run("ReturnsRatioOfFollowedMIGTargetRoundedUp", func(t *testing.T) {
+
+		configItem := MIGConfiguration{InstanceGroupName: "sidecar", FollowMIG: "primary", Ratio: 0.25}
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+		scaler.setTargetMinimumNumberOfInstances(MIGConfiguration{InstanceGroupName: "primary"}, 13)
+
+		// act
+		minimumNumberOfInstances, err := scaler.followMIGTarget(configItem)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 4, minimumNumberOfInstances)
+	})
+
+	t.Run("ReturnsMinimumNumberOfInstancesIfRatioOfFollowedMIGTargetIsLower", func(t *testing.T) {
+
+		configItem := MIGConfiguration{InstanceGroupName: "sidecar", FollowMIG: "primary", Ratio: 0.25, MinimumNumberOfInstances: 3}
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+		scaler.setTargetMinimumNumberOfInstances(MIGConfiguration{InstanceGroupName: "primary"}, 4)
+
+		// act
+		minimumNumberOfInstances, err := scaler.followMIGTarget(configItem)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 3, minimumNumberOfInstances)
+	})
+
+	t.Run("FollowsMIGWithThatNameInSameProjectAndRegion", func(t *testing.T) {
+
+		configItem := MIGConfiguration{GCloudProject: "project-a", GCloudRegion: "europe-west1", InstanceGroupName: "sidecar", FollowMIG: "primary", Ratio: 1}
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+		scaler.setTargetMinimumNumberOfInstances(MIGConfiguration{GCloudProject: "project-a", GCloudRegion: "europe-west1", InstanceGroupName: "primary"}, 8)
+		scaler.setTargetMinimumNumberOfInstances(MIGConfiguration{GCloudProject: "project-b", GCloudRegion: "europe-west1", InstanceGroupName: "primary"}, 30)
+
+		// act
+		minimumNumberOfInstances, err := scaler.followMIGTarget(configItem)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 8, minimumNumberOfInstances)
+	})
+
+	t.Run("ReturnsErrorIfFollowedMIGIsAmbiguous", func(t *testing.T) {
+
+		configItem := MIGConfiguration{GCloudProject: "project-a", GCloudRegion: "europe-west1", InstanceGroupName: "sidecar", FollowMIG: "primary", Ratio: 1}
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+		scaler.setTargetMinimumNumberOfInstances(MIGConfiguration{GCloudProject: "project-b", GCloudRegion: "europe-west1", InstanceGroupName: "primary"}, 8)
+		scaler.setTargetMinimumNumberOfInstances(MIGConfiguration{GCloudProject: "project-c", GCloudRegion: "europe-west1", InstanceGroupName: "primary"}, 30)
+
+		// act
+		_, err := scaler.followMIGTarget(configItem)
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("ReturnsErrorIfFollowedMIGHasNoTargetYet", func(t *testing.T) {
+
+		configItem := MIGConfiguration{InstanceGroupName: "sidecar", FollowMIG: "primary", Ratio: 0.25}
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+
+		// act
+		_, err := scaler.followMIGTarget(configItem)
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestOrderByFollowMIG(t *testing.T) {
+
+	t.Run("MovesFollowingMIGsAfterOtherMIGs", func(t *testing.T) {
+
+		migConfigs := []MIGConfiguration{
+			{InstanceGroupName: "sidecar", FollowMIG: "primary"},
+			{InstanceGroupName: "primary"},
+			{InstanceGroupName: "cache", FollowMIG: "primary"},
+			{InstanceGroupName: "worker"},
+		}
+
+		// act
+		ordered := OrderByFollowMIG(migConfigs)
+
+		names := []string{}
+		for _, c := range ordered {
+			names = append(names, c.InstanceGroupName)
+		}
+		assert.Equal(t, []string{"primary", "worker", "sidecar", "cache"}, names)
+		assert.Equal(t, "sidecar", migConfigs[0].InstanceGroupName)
+	})
+}
+
+func TestValidateFollowMIG(t *testing.T) {
+
+	primaryConfig := MIGConfiguration{
+		GCloudProject:               "project-id",
+		GCloudRegion:                "europe-west1",
+		RequestRateQuery:            "sum(rate(nginx_http_requests_total[10m]))",
+		InstanceGroupName:           "primary",
+		NumberOfRequestsPerInstance: 5.8,
+	}
+	followerConfig := MIGConfiguration{
+		GCloudProject:     "project-id",
+		GCloudRegion:      "europe-west1",
+		InstanceGroupName: "sidecar",
+		FollowMIG:         "primary",
+		Ratio:             0.25,
+	}
+
+	t.Run("ReturnsNilForFollowerWithoutQuery", func(t *testing.T) {
+
+		// act
+		err := ValidateMIGConfigs([]MIGConfiguration{primaryConfig, followerConfig})
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("ReturnsErrorForFollowerWithoutRatio", func(t *testing.T) {
+
+		invalidConfig := followerConfig
+		invalidConfig.Ratio = 0
+
+		// act
+		err := ValidateMIGConfigs([]MIGConfiguration{primaryConfig, invalidConfig})
+
+		if assert.IsType(t, ValidationErrors{}, err) {
+			assert.Equal(t, "ratio", err.(ValidationErrors)[0].Field)
+		}
+	})
+
+	t.Run("ReturnsErrorForRatioWithoutFollowMIG", func(t *testing.T) {
+
+		invalidConfig := primaryConfig
+		invalidConfig.Ratio = 0.5
+
+		// act
+		err := ValidateMIGConfigs([]MIGConfiguration{invalidConfig})
+
+		if assert.IsType(t, ValidationErrors{}, err) {
+			assert.Equal(t, "ratio", err.(ValidationErrors)[0].Field)
+		}
+	})
+
+	t.Run("ReturnsErrorForFollowedMIGThatIsNotConfigured", func(t *testing.T) {
+
+		// act
+		err := ValidateMIGConfigs([]MIGConfiguration{followerConfig})
+
+		if assert.IsType(t, ValidationErrors{}, err) {
+			assert.Equal(t, "followMig", err.(ValidationErrors)[0].Field)
+			assert.Equal(t, "mig primary is not configured", err.(ValidationErrors)[0].Message)
+		}
+	})
+
+	t.Run("ReturnsErrorForFollowedMIGThatFollowsAnotherMIG", func(t *testing.T) {
+
+		chainedConfig := followerConfig
+		chainedConfig.InstanceGroupName = "cache"
+		chainedConfig.FollowMIG = "sidecar"
+
+		// act
+		err := ValidateMIGConfigs([]MIGConfiguration{primaryConfig, followerConfig, chainedConfig})
+
+		if assert.IsType(t, ValidationErrors{}, err) {
+			assert.Equal(t, 2, err.(ValidationErrors)[0].Index)
+			assert.Equal(t, "mig sidecar follows another mig itself", err.(ValidationErrors)[0].Message)
+		}
+	})
+}
@@ -4,17 +4,29 @@ go 1.12
 
 require (
 	cloud.google.com/go v0.0.0-20171212232625-22fb6a34557c // indirect
+	github.com/BurntSushi/toml v0.3.1
+	github.com/Shopify/sarama v1.27.2
 	github.com/alecthomas/assert v0.0.0-20170929043011-405dbfeb8e38 // indirect
 	github.com/alecthomas/colour v0.0.0-20160524082231-60882d9e2721 // indirect
 	github.com/alecthomas/kingpin v2.2.5+incompatible
 	github.com/alecthomas/repr v0.0.0-20181024024818-d37bc2a10ba1 // indirect
+	github.com/aws/aws-sdk-go v1.35.0
 	github.com/estafette/estafette-foundation v0.0.32
+	github.com/fsnotify/fsnotify v1.4.7
+	github.com/ghodss/yaml v1.0.0
+	github.com/go-git/go-billy/v5 v5.0.0
+	github.com/go-git/go-git/v5 v5.2.0
+	github.com/go-sql-driver/mysql v1.5.0
+	github.com/golang/protobuf v1.2.0
+	github.com/golang/snappy v0.0.1
+	github.com/hashicorp/hcl v1.0.0
+	github.com/lib/pq v1.8.0
 	github.com/mattn/go-isatty v0.0.6 // indirect
 	github.com/prometheus/client_golang v0.9.2
 	github.com/rs/zerolog v1.15.0
-	github.com/sergi/go-diff v1.0.0 // indirect
 	github.com/sethgrid/pester v0.0.0-20171127025028-760f8913c048
-	github.com/stretchr/testify v1.2.2
+	github.com/stretchr/testify v1.6.1
+	github.com/tidwall/gjson v1.6.1
 	golang.org/x/oauth2 v0.0.0-20171212205436-00dc70155e4c
 	google.golang.org/api v0.0.0-20171220000333-cf39d2072c48
 	google.golang.org/appengine v0.0.0-20171212223047-5bee14b453b4 // indirect
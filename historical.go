@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// pointInTimeMetricSources are the metric sources that evaluate their queries at the time set with withQueryTime, which the historical request rate needs
+var pointInTimeMetricSources = map[string]bool{
+	prometheusMetricSource: true,
+}
+
+type queryTimeContextKey struct{}
+
+// withQueryTime returns a context for which prometheus queries are evaluated at the given time instead of now
+func withQueryTime(ctx context.Context, t time.Time) context.Context {
+	return context.WithValue(ctx, queryTimeContextKey{}, t)
+}
+
+// queryTime returns the time queries should be evaluated at, and whether it's been set explicitly instead of defaulting to now
+func queryTime(ctx context.Context) (time.Time, bool) {
+	if t, ok := ctx.Value(queryTimeContextKey{}).(time.Time); ok {
+		return t, true
+	}
+	return time.Now(), false
+}
+
+// HistoricalQueryTime returns the time to evaluate the request rate query at for the historical request rate: historicalOffsetSeconds ago, plus historicalLeadSeconds to look ahead in the past traffic pattern
+func (c *MIGConfiguration) HistoricalQueryTime(now time.Time) time.Time {
+	return now.Add(-time.Duration(c.HistoricalOffsetSeconds) * time.Second).Add(time.Duration(c.HistoricalLeadSeconds) * time.Second)
+}
+
+// applyHistoricalRequestRate evaluates the request rate query at the same moment in the past, for example a week ago, and returns the historical request rate if it's higher than the current one, so predictable daily or weekly peaks are provisioned for before they happen; failing historical queries are ignored
+func (s *MIGScaler) applyHistoricalRequestRate(ctx context.Context, configItem MIGConfiguration, requestRate float64) float64 {
+
+	if configItem.HistoricalOffsetSeconds <= 0 {
+		return requestRate
+	}
+
+	historicalRequestRate, err := s.getCurrentRequestRate(withQueryTime(ctx, configItem.HistoricalQueryTime(time.Now())), configItem)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Retrieving historical request rate for mig %v failed, using current request rate", configItem.InstanceGroupName)
+		return requestRate
+	}
+
+	if historicalRequestRate <= requestRate {
+		return requestRate
+	}
+
+	log.Info().Msgf("Historical request rate for mig %v is higher, using %v instead of %v", configItem.InstanceGroupName, historicalRequestRate, requestRate)
+
+	return historicalRequestRate
+}
+
+// validateHistoricalOffset checks the historical look back settings
+func (c *MIGConfiguration) validateHistoricalOffset(addError func(field, message string)) {
+	if c.HistoricalOffsetSeconds < 0 {
+		addError("historicalOffsetSeconds", "should be 0 or larger")
+	}
+	if c.HistoricalLeadSeconds < 0 || (c.HistoricalOffsetSeconds > 0 && c.HistoricalLeadSeconds >= c.HistoricalOffsetSeconds) {
+		addError("historicalLeadSeconds", "should be 0 or larger and smaller than historicalOffsetSeconds")
+	}
+	if c.HistoricalOffsetSeconds > 0 {
+		for _, metricSourceName := range c.requestRateMetricSourceNames() {
+			if !pointInTimeMetricSources[metricSourceName] {
+				addError("historicalOffsetSeconds", fmt.Sprintf("is not supported by metric source %v, which can't evaluate queries in the past", metricSourceName))
+			}
+		}
+	}
+}
+
+// requestRateMetricSourceNames returns the distinct metric sources the request rate can be retrieved from, of the queries and fallbacks included; invalid queries and fallbacks are left to their own validation
+func (c *MIGConfiguration) requestRateMetricSourceNames() (metricSourceNames []string) {
+
+	configs := []MIGConfiguration{*c}
+	if len(c.Queries) > 0 {
+		configs, _ = c.QueryConfigs()
+	}
+
+	seen := map[string]bool{}
+	add := func(metricSourceName string) {
+		if !seen[metricSourceName] {
+			seen[metricSourceName] = true
+			metricSourceNames = append(metricSourceNames, metricSourceName)
+		}
+	}
+	for _, config := range configs {
+		add(config.MetricSourceName())
+		fallbackConfigs, _ := config.FallbackConfigs()
+		for _, fallbackConfig := range fallbackConfigs {
+			add(fallbackConfig.MetricSourceName())
+		}
+	}
+
+	return
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyHistoricalRequestRate(t *testing.T) {
+
+	weekAgo := time.Now().Add(-7 * 24 * time.Hour)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if queryTime := r.FormValue("time"); queryTime != "" {
+			evaluationTime, _ := strconv.ParseInt(queryTime, 10, 64)
+			assert.InDelta(t, weekAgo.Add(10*time.Minute).Unix(), evaluationTime, 5)
+			writePrometheusVector(w, r, evaluationTime, "300")
+			return
+		}
+		writePrometheusVector(w, r, time.Now().Unix(), "100")
+	}))
+	defer server.Close()
+
+	scaler := NewMIGScaler(nil, map[string]MetricSource{"prometheus": &PrometheusMetricSource{Defaults: PrometheusEndpoint{URL: server.URL}}}, MIGScalerOptions{})
+
+	t.Run("ReturnsHistoricalRequestRateIfHigher", func(t *testing.T) {
+
+		configItem := MIGConfiguration{RequestRateQuery: "sum(up)", HistoricalOffsetSeconds: 604800, HistoricalLeadSeconds: 600}
+
+		// act
+		requestRate, err := scaler.getRequestRate(context.Background(), configItem)
+
+		assert.Nil(t, err)
+		assert.Equal(t, float64(300), requestRate)
+	})
+
+	t.Run("ReturnsCurrentRequestRateWithoutHistoricalOffset", func(t *testing.T) {
+
+		configItem := MIGConfiguration{RequestRateQuery: "sum(up)"}
+
+		// act
+		requestRate, err := scaler.getRequestRate(context.Background(), configItem)
+
+		assert.Nil(t, err)
+		assert.Equal(t, float64(100), requestRate)
+	})
+}
+
+func TestValidateHistoricalOffset(t *testing.T) {
+
+	t.Run("ReturnsErrorForMetricSourceThatCantQueryInThePast", func(t *testing.T) {
+
+		configItem := MIGConfiguration{MetricSource: "datadog", RequestRateQuery: "sum:requests{*}", HistoricalOffsetSeconds: 604800}
+		fields := []string{}
+
+		// act
+		configItem.validateHistoricalOffset(func(field, message string) {
+			fields = append(fields, field)
+		})
+
+		assert.Equal(t, []string{"historicalOffsetSeconds"}, fields)
+	})
+
+	t.Run("ReturnsErrorForFallbackToMetricSourceThatCantQueryInThePast", func(t *testing.T) {
+
+		configItem := MIGConfiguration{RequestRateQuery: "sum(up)", HistoricalOffsetSeconds: 604800, Fallbacks: []map[string]interface{}{{"metricSource": "cloudmonitoring"}}}
+		fields := []string{}
+
+		// act
+		configItem.validateHistoricalOffset(func(field, message string) {
+			fields = append(fields, field)
+		})
+
+		assert.Equal(t, []string{"historicalOffsetSeconds"}, fields)
+	})
+
+	t.Run("ReturnsNoErrorForPrometheusQueries", func(t *testing.T) {
+
+		configItem := MIGConfiguration{HistoricalOffsetSeconds: 604800, Queries: []map[string]interface{}{{"requestRateQuery": "sum(up)"}, {"metricSource": "prometheus", "requestRateQuery": "sum(down)"}}}
+		fields := []string{}
+
+		// act
+		configItem.validateHistoricalOffset(func(field, message string) {
+			fields = append(fields, field)
+		})
+
+		assert.Equal(t, []string{}, fields)
+	})
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/sethgrid/pester"
+	"github.com/tidwall/gjson"
+)
+
+// HTTPJSONMetricSource retrieves request rates from arbitrary json endpoints, like internal capacity apis, by extracting a number with a gjson path
+type HTTPJSONMetricSource struct{}
+
+// GetRequestRate gets httpUrl and returns the number at the gjson path in requestRateQuery, like data.services.#(name=="api").rps
+func (s *HTTPJSONMetricSource) GetRequestRate(ctx context.Context, configItem MIGConfiguration) (float64, error) {
+
+	request, err := http.NewRequest(http.MethodGet, configItem.HTTPURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Accept", "application/json")
+
+	resp, err := pester.Do(request.WithContext(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("Retrieving %v failed: %v", configItem.HTTPURL, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("Reading %v response body failed: %v", configItem.HTTPURL, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("%v returned status code %v: %v", configItem.HTTPURL, resp.StatusCode, string(body))
+	}
+
+	return ExtractJSONValue(body, configItem.RequestRateQuery)
+}
+
+// ExtractJSONValue returns the number at the gjson path in a json document; numbers formatted as string are accepted as well
+func ExtractJSONValue(body []byte, path string) (float64, error) {
+
+	if !gjson.ValidBytes(body) {
+		return 0, fmt.Errorf("Response is not valid json")
+	}
+
+	result := gjson.GetBytes(body, path)
+	switch result.Type {
+	case gjson.Number:
+		return result.Num, nil
+	case gjson.String:
+		var value float64
+		if _, err := fmt.Sscanf(result.Str, "%g", &value); err == nil {
+			return value, nil
+		}
+	case gjson.Null:
+		if !result.Exists() {
+			return 0, MissingDataError{Reason: fmt.Sprintf("Path %v doesn't exist in response", path)}
+		}
+		return 0, MissingDataError{Reason: fmt.Sprintf("Value at path %v is null", path)}
+	}
+
+	return 0, fmt.Errorf("Value %v at path %v is not a number", result.Raw, path)
+}
+
+// validateHTTPJSONConfig checks the fields required for the http metric source
+func validateHTTPJSONConfig(c *MIGConfiguration, addError func(field, message string)) {
+	requireRequestRateQuery(nil)(c, addError)
+
+	if c.HTTPURL == "" {
+		addError("httpUrl", "is required for metricSource http")
+	}
+}
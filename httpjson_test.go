@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPJSONMetricSourceGetRequestRate(t *testing.T) {
+
+	t.Run("ReturnsNumberAtPath", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"data":{"services":[{"name":"web","rps":12.5},{"name":"api","rps":225.4}]}}`))
+		}))
+		defer server.Close()
+
+		source := &HTTPJSONMetricSource{}
+
+		// act
+		requestRate, err := source.GetRequestRate(context.Background(), MIGConfiguration{HTTPURL: server.URL, RequestRateQuery: `data.services.#(name=="api").rps`})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 225.4, requestRate)
+	})
+}
+
+func TestExtractJSONValue(t *testing.T) {
+
+	t.Run("ReturnsNumberFormattedAsString", func(t *testing.T) {
+
+		// act
+		value, err := ExtractJSONValue([]byte(`{"rps":"225.4"}`), "rps")
+
+		assert.Nil(t, err)
+		assert.Equal(t, 225.4, value)
+	})
+
+	t.Run("ReturnsErrorForMissingPath", func(t *testing.T) {
+
+		// act
+		_, err := ExtractJSONValue([]byte(`{"rps":225.4}`), "requests")
+
+		assert.True(t, IsMissingData(err))
+	})
+
+	t.Run("ReturnsMissingDataErrorForNullValue", func(t *testing.T) {
+
+		// act
+		_, err := ExtractJSONValue([]byte(`{"rps":null}`), "rps")
+
+		assert.True(t, IsMissingData(err))
+	})
+
+	t.Run("ReturnsErrorForNonNumericValue", func(t *testing.T) {
+
+		// act
+		_, err := ExtractJSONValue([]byte(`{"rps":{"value":225.4}}`), "rps")
+
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,30 @@
+package main
+
+// applyHysteresis keeps the previously applied minimum number of instances as long as calculate would also return it for a request rate within hysteresisPercent of the current one, so a request rate hovering around an instance boundary doesn't flip the minimum between n and n+1 every iteration
+func (s *MIGScaler) applyHysteresis(configItem MIGConfiguration, requestRate float64, minimumNumberOfInstances int, calculate func(requestRate float64) int) int {
+
+	if configItem.HysteresisPercent <= 0 {
+		return minimumNumberOfInstances
+	}
+
+	applied, _, ok := s.appliedMinimumNumberOfInstances(configItem)
+	if !ok || applied == minimumNumberOfInstances {
+		return minimumNumberOfInstances
+	}
+
+	deadband := configItem.HysteresisPercent / 100
+	lower := calculate(requestRate * (1 - deadband))
+	upper := calculate(requestRate * (1 + deadband))
+	if applied < lower || applied > upper {
+		return minimumNumberOfInstances
+	}
+
+	return applied
+}
+
+// validateHysteresis checks whether hysteresisPercent is a percentage the request rate can be lowered by
+func (c *MIGConfiguration) validateHysteresis(addError func(field, message string)) {
+	if c.HysteresisPercent < 0 || c.HysteresisPercent >= 100 {
+		addError("hysteresisPercent", "should be 0 or larger and smaller than 100")
+	}
+}
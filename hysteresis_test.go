@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyHysteresis(t *testing.T) {
+
+	configItem := MIGConfiguration{InstanceGroupName: "instance-group-name", NumberOfRequestsPerInstance: 10, HysteresisPercent: 10}
+	calculate := func(requestRate float64) int {
+		return CalculateMinimumNumberOfInstances(configItem, requestRate)
+	}
+
+	t.Run("KeepsAppliedMinimumWithinDeadband", func(t *testing.T) {
+
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+		scaler.setAppliedMinimumNumberOfInstances(configItem, 10, time.Now())
+
+		// act
+		minimums := []int{}
+		for _, requestRate := range []float64{102, 97, 108, 91} {
+			minimums = append(minimums, scaler.applyHysteresis(configItem, requestRate, calculate(requestRate), calculate))
+		}
+
+		assert.Equal(t, []int{10, 10, 10, 10}, minimums)
+	})
+
+	t.Run("ReturnsCalculatedMinimumOutsideDeadband", func(t *testing.T) {
+
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+		scaler.setAppliedMinimumNumberOfInstances(configItem, 10, time.Now())
+
+		// act
+		up := scaler.applyHysteresis(configItem, 115, calculate(115), calculate)
+		down := scaler.applyHysteresis(configItem, 80, calculate(80), calculate)
+
+		assert.Equal(t, 12, up)
+		assert.Equal(t, 8, down)
+	})
+
+	t.Run("ReturnsCalculatedMinimumWithoutHysteresisPercent", func(t *testing.T) {
+
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+		scaler.setAppliedMinimumNumberOfInstances(configItem, 10, time.Now())
+
+		// act
+		minimumNumberOfInstances := scaler.applyHysteresis(MIGConfiguration{InstanceGroupName: "instance-group-name", NumberOfRequestsPerInstance: 10}, 102, 11, calculate)
+
+		assert.Equal(t, 11, minimumNumberOfInstances)
+	})
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2/google"
+	"golang.org/x/oauth2/jws"
+)
+
+const (
+	metadataIdentityURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/identity?audience=%v&format=full"
+
+	// idTokenTimeout is the maximum time for retrieving an id token, so a slow token endpoint or metadata server fails the prometheus query instead of stalling it
+	idTokenTimeout = 10 * time.Second
+)
+
+// idTokens caches oidc id tokens per audience until shortly before they expire; the lock is only held to read and store tokens, not while retrieving them
+var idTokens = struct {
+	sync.Mutex
+	tokens map[string]idToken
+}{tokens: map[string]idToken{}}
+
+type idToken struct {
+	token  string
+	expiry time.Time
+}
+
+// GetIDToken returns a google signed oidc id token for the audience, like the oauth client id of an identity-aware proxy; it uses the service account key in GOOGLE_APPLICATION_CREDENTIALS if set and the metadata server otherwise
+func GetIDToken(ctx context.Context, audience string) (string, error) {
+
+	idTokens.Lock()
+	cached, ok := idTokens.tokens[audience]
+	idTokens.Unlock()
+	if ok && time.Now().Add(5*time.Minute).Before(cached.expiry) {
+		return cached.token, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, idTokenTimeout)
+	defer cancel()
+
+	var token string
+	var err error
+	if keyFile := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); keyFile != "" {
+		token, err = getIDTokenWithServiceAccountKey(ctx, keyFile, audience)
+	} else {
+		token, err = getIDTokenFromMetadataServer(ctx, audience)
+	}
+	if err != nil {
+		return "", fmt.Errorf("Retrieving id token for audience %v failed: %v", audience, err)
+	}
+
+	claimSet, err := jws.Decode(token)
+	if err != nil {
+		return "", fmt.Errorf("Decoding id token for audience %v failed: %v", audience, err)
+	}
+
+	idTokens.Lock()
+	idTokens.tokens[audience] = idToken{token: token, expiry: time.Unix(claimSet.Exp, 0)}
+	idTokens.Unlock()
+
+	return token, nil
+}
+
+func getIDTokenFromMetadataServer(ctx context.Context, audience string) (string, error) {
+
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf(metadataIdentityURL, url.QueryEscape(audience)), nil)
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Metadata-Flavor", "Google")
+
+	resp, err := http.DefaultClient.Do(request.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Metadata server returned status code %v: %v", resp.StatusCode, string(body))
+	}
+
+	return strings.TrimSpace(string(body)), nil
+}
+
+// getIDTokenWithServiceAccountKey signs a jwt with target_audience using the service account key and exchanges it for an id token; the jwt config of the oauth2 version in use can't add target_audience to its claims, so only its key parsing and signing are used
+func getIDTokenWithServiceAccountKey(ctx context.Context, keyFile, audience string) (string, error) {
+
+	data, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return "", err
+	}
+
+	config, err := google.JWTConfigFromJSON(data)
+	if err != nil {
+		return "", err
+	}
+
+	privateKey, err := parseRSAPrivateKey(config.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+
+	assertion, err := jws.Encode(&jws.Header{Algorithm: "RS256", Typ: "JWT", KeyID: config.PrivateKeyID}, &jws.ClaimSet{
+		Iss:           config.Email,
+		Sub:           config.Email,
+		Aud:           config.TokenURL,
+		PrivateClaims: map[string]interface{}{"target_audience": audience},
+	}, privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, config.TokenURL, strings.NewReader(url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}.Encode()))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(request.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Token endpoint returned status code %v: %v", resp.StatusCode, string(body))
+	}
+
+	var tokenResponse struct {
+		IDToken string `json:"id_token"`
+	}
+	if err = json.Unmarshal(body, &tokenResponse); err != nil {
+		return "", err
+	}
+	if tokenResponse.IDToken == "" {
+		return "", errors.New("Token endpoint returned no id token")
+	}
+
+	return tokenResponse.IDToken, nil
+}
+
+// parseRSAPrivateKey parses the pem encoded pkcs8 or pkcs1 private key of a service account key
+func parseRSAPrivateKey(privateKey []byte) (*rsa.PrivateKey, error) {
+
+	block, _ := pem.Decode(privateKey)
+	if block == nil {
+		return nil, errors.New("Private key is not pem encoded")
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	rsaKey, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("Private key is not an rsa key")
+	}
+
+	return rsaKey, nil
+}
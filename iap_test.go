@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetIDToken(t *testing.T) {
+
+	t.Run("ExchangesSignedJWTForIDTokenWithServiceAccountKey", func(t *testing.T) {
+
+		expiry := time.Now().Add(time.Hour).Unix()
+		payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp":%v}`, expiry)))
+		idToken := "header." + payload + ".signature"
+
+		var assertion string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assertion = r.FormValue("assertion")
+			w.Write([]byte(`{"id_token":"` + idToken + `"}`))
+		}))
+		defer server.Close()
+
+		privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		assert.Nil(t, err)
+		key, _ := json.Marshal(map[string]string{
+			"type":         "service_account",
+			"client_email": "mig-scaler@project-id.iam.gserviceaccount.com",
+			"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})),
+			"token_uri":    server.URL,
+		})
+		keyFile, _ := ioutil.TempFile("", "key")
+		defer os.Remove(keyFile.Name())
+		keyFile.Write(key)
+		keyFile.Close()
+
+		os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", keyFile.Name())
+		defer os.Unsetenv("GOOGLE_APPLICATION_CREDENTIALS")
+
+		// act
+		token, err := GetIDToken(context.Background(), "iap-client-id.apps.googleusercontent.com")
+
+		assert.Nil(t, err)
+		assert.Equal(t, idToken, token)
+		assert.Equal(t, time.Unix(expiry, 0), idTokens.tokens["iap-client-id.apps.googleusercontent.com"].expiry)
+
+		claims, _ := base64.RawURLEncoding.DecodeString(strings.Split(assertion, ".")[1])
+		assert.Contains(t, string(claims), `"target_audience":"iap-client-id.apps.googleusercontent.com"`)
+	})
+
+	t.Run("ReturnsErrorWhenTokenEndpointDoesNotRespondInTime", func(t *testing.T) {
+
+		done := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-done
+		}))
+		defer server.Close()
+		defer close(done)
+
+		privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		assert.Nil(t, err)
+		key, _ := json.Marshal(map[string]string{
+			"type":         "service_account",
+			"client_email": "mig-scaler@project-id.iam.gserviceaccount.com",
+			"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})),
+			"token_uri":    server.URL,
+		})
+		keyFile, _ := ioutil.TempFile("", "key")
+		defer os.Remove(keyFile.Name())
+		keyFile.Write(key)
+		keyFile.Close()
+
+		os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", keyFile.Name())
+		defer os.Unsetenv("GOOGLE_APPLICATION_CREDENTIALS")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		// act
+		_, err = GetIDToken(ctx, "slow-client-id.apps.googleusercontent.com")
+
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Shopify/sarama"
+)
+
+// KafkaMetricSource uses the lag of a kafka consumer group as scaling signal, so worker instance groups scale with their backlog instead of a request rate
+type KafkaMetricSource struct {
+	// Brokers is a comma separated list of the default brokers, used for managed instance groups that don't set kafkaBrokers
+	Brokers string
+}
+
+// GetRequestRate returns the total lag of the consumer group over all partitions of its topics, or of kafkaTopic only if set
+func (s *KafkaMetricSource) GetRequestRate(ctx context.Context, configItem MIGConfiguration) (float64, error) {
+
+	brokers := s.Brokers
+	if configItem.KafkaBrokers != "" {
+		brokers = configItem.KafkaBrokers
+	}
+	if brokers == "" {
+		return 0, errors.New("Querying kafka requires --kafka-brokers or kafkaBrokers")
+	}
+
+	config := sarama.NewConfig()
+	config.ClientID = "estafette-gcloud-mig-scaler"
+	// fetching the offsets of all partitions of a consumer group at once requires kafka 0.10.2 or newer
+	config.Version = sarama.V0_10_2_0
+
+	client, err := sarama.NewClient(strings.Split(brokers, ","), config)
+	if err != nil {
+		return 0, fmt.Errorf("Connecting to kafka brokers %v failed: %v", brokers, err)
+	}
+	defer client.Close()
+
+	coordinator, err := client.Coordinator(configItem.KafkaConsumerGroup)
+	if err != nil {
+		return 0, fmt.Errorf("Retrieving coordinator for consumer group %v failed: %v", configItem.KafkaConsumerGroup, err)
+	}
+
+	offsetFetchResponse, err := coordinator.FetchOffset(&sarama.OffsetFetchRequest{
+		Version:       2,
+		ConsumerGroup: configItem.KafkaConsumerGroup,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("Fetching offsets for consumer group %v failed: %v", configItem.KafkaConsumerGroup, err)
+	}
+	if offsetFetchResponse.Err != sarama.ErrNoError {
+		return 0, fmt.Errorf("Fetching offsets for consumer group %v failed: %v", configItem.KafkaConsumerGroup, offsetFetchResponse.Err)
+	}
+
+	committedOffsets := map[string]map[int32]int64{}
+	newestOffsets := map[string]map[int32]int64{}
+	for topic, blocks := range offsetFetchResponse.Blocks {
+		if configItem.KafkaTopic != "" && topic != configItem.KafkaTopic {
+			continue
+		}
+		committedOffsets[topic] = map[int32]int64{}
+		newestOffsets[topic] = map[int32]int64{}
+		for partition, block := range blocks {
+			newestOffset, err := client.GetOffset(topic, partition, sarama.OffsetNewest)
+			if err != nil {
+				return 0, fmt.Errorf("Retrieving newest offset for topic %v partition %v failed: %v", topic, partition, err)
+			}
+			committedOffsets[topic][partition] = block.Offset
+			newestOffsets[topic][partition] = newestOffset
+		}
+	}
+
+	if len(committedOffsets) == 0 {
+		return 0, fmt.Errorf("Consumer group %v has no committed offsets", configItem.KafkaConsumerGroup)
+	}
+
+	return CalculateConsumerGroupLag(committedOffsets, newestOffsets), nil
+}
+
+// CalculateConsumerGroupLag sums the difference between the newest and committed offset of every partition; partitions without committed offset are skipped since their lag is unknown
+func CalculateConsumerGroupLag(committedOffsets, newestOffsets map[string]map[int32]int64) float64 {
+
+	lag := int64(0)
+	for topic, partitions := range committedOffsets {
+		for partition, committedOffset := range partitions {
+			if committedOffset < 0 {
+				continue
+			}
+			newestOffset, ok := newestOffsets[topic][partition]
+			if !ok || newestOffset <= committedOffset {
+				continue
+			}
+			lag += newestOffset - committedOffset
+		}
+	}
+
+	return float64(lag)
+}
+
+// validateKafkaConfig checks the fields required for the kafka metric source, which reads a consumer group's lag instead of executing requestRateQuery
+func validateKafkaConfig(c *MIGConfiguration, addError func(field, message string)) {
+	if c.KafkaConsumerGroup == "" {
+		addError("kafkaConsumerGroup", "is required for metricSource kafka")
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculateConsumerGroupLag(t *testing.T) {
+
+	t.Run("ReturnsSumOfLagOverAllPartitions", func(t *testing.T) {
+
+		committedOffsets := map[string]map[int32]int64{
+			"orders":   map[int32]int64{0: 100, 1: 200},
+			"payments": map[int32]int64{0: 50},
+		}
+		newestOffsets := map[string]map[int32]int64{
+			"orders":   map[int32]int64{0: 150, 1: 200},
+			"payments": map[int32]int64{0: 75},
+		}
+
+		// act
+		lag := CalculateConsumerGroupLag(committedOffsets, newestOffsets)
+
+		assert.Equal(t, 75.0, lag)
+	})
+
+	t.Run("SkipsPartitionsWithoutCommittedOffset", func(t *testing.T) {
+
+		committedOffsets := map[string]map[int32]int64{
+			"orders": map[int32]int64{0: -1, 1: 10},
+		}
+		newestOffsets := map[string]map[int32]int64{
+			"orders": map[int32]int64{0: 1000, 1: 20},
+		}
+
+		// act
+		lag := CalculateConsumerGroupLag(committedOffsets, newestOffsets)
+
+		assert.Equal(t, 10.0, lag)
+	})
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	kubernetesServiceAccountNamespacePath = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+	// kubernetesEventTimeout is the maximum time for creating an event, so an unavailable Kubernetes api doesn't hold up scaling
+	kubernetesEventTimeout = 5 * time.Second
+
+	normalKubernetesEventType  = "Normal"
+	warningKubernetesEventType = "Warning"
+)
+
+// KubernetesEventRecorder creates Kubernetes events on the pod of the scaler, so scaling incidents show up in kubectl describe and event exporters
+type KubernetesEventRecorder struct {
+	client    *http.Client
+	apiURL    string
+	token     string
+	namespace string
+	podName   string
+}
+
+// kubernetesEvent is used to marshal a core/v1 Event for the Kubernetes api
+type kubernetesEvent struct {
+	Metadata struct {
+		GenerateName string `json:"generateName"`
+		Namespace    string `json:"namespace"`
+	} `json:"metadata"`
+	InvolvedObject struct {
+		APIVersion string `json:"apiVersion"`
+		Kind       string `json:"kind"`
+		Namespace  string `json:"namespace"`
+		Name       string `json:"name"`
+	} `json:"involvedObject"`
+	Reason         string `json:"reason"`
+	Message        string `json:"message"`
+	Type           string `json:"type"`
+	Count          int    `json:"count"`
+	FirstTimestamp string `json:"firstTimestamp"`
+	LastTimestamp  string `json:"lastTimestamp"`
+	Source         struct {
+		Component string `json:"component"`
+	} `json:"source"`
+}
+
+// NewKubernetesEventRecorder returns an event recorder using the in-cluster Kubernetes configuration; the pod is taken from the POD_NAME and POD_NAMESPACE envvars, defaulting to the hostname and the namespace of the service account
+func NewKubernetesEventRecorder() (*KubernetesEventRecorder, error) {
+
+	client, apiURL, token, err := newInClusterKubernetesClient()
+	if err != nil {
+		return nil, fmt.Errorf("Creating kubernetes events failed: %v", err)
+	}
+
+	namespace := os.Getenv("POD_NAMESPACE")
+	if namespace == "" {
+		data, err := ioutil.ReadFile(kubernetesServiceAccountNamespacePath)
+		if err != nil {
+			return nil, err
+		}
+		namespace = strings.TrimSpace(string(data))
+	}
+
+	podName := os.Getenv("POD_NAME")
+	if podName == "" {
+		if podName, err = os.Hostname(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &KubernetesEventRecorder{
+		client:    client,
+		apiURL:    apiURL,
+		token:     token,
+		namespace: namespace,
+		podName:   podName,
+	}, nil
+}
+
+// Record creates an event of the type, Normal or Warning, on the pod of the scaler with a timeout of its own, independent of the compute api calls of the iteration; failures are only logged, since events are informational
+func (r *KubernetesEventRecorder) Record(eventType, reason, message string) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), kubernetesEventTimeout)
+	defer cancel()
+
+	if err := r.create(ctx, eventType, reason, message); err != nil {
+		log.Warn().Err(err).Msgf("Creating kubernetes event %v failed", reason)
+	}
+}
+
+func (r *KubernetesEventRecorder) create(ctx context.Context, eventType, reason, message string) error {
+
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	var event kubernetesEvent
+	event.Metadata.GenerateName = r.podName + "."
+	event.Metadata.Namespace = r.namespace
+	event.InvolvedObject.APIVersion = "v1"
+	event.InvolvedObject.Kind = "Pod"
+	event.InvolvedObject.Namespace = r.namespace
+	event.InvolvedObject.Name = r.podName
+	event.Reason = reason
+	event.Message = message
+	event.Type = eventType
+	event.Count = 1
+	event.FirstTimestamp = now
+	event.LastTimestamp = now
+	event.Source.Component = app
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%v/api/v1/namespaces/%v/events", r.apiURL, r.namespace), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+r.token)
+	request.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.client.Do(request.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("Kubernetes api returned status code %v: %v", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
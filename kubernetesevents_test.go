@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKubernetesEventRecorder(t *testing.T) {
+
+	t.Run("CreatesEventOnPodOfScaler", func(t *testing.T) {
+
+		var path, authorization string
+		var event kubernetesEvent
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			authorization = r.Header.Get("Authorization")
+			json.NewDecoder(r.Body).Decode(&event)
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer server.Close()
+
+		recorder := &KubernetesEventRecorder{client: server.Client(), apiURL: server.URL, token: "token", namespace: "estafette", podName: "estafette-gcloud-mig-scaler-0"}
+
+		// act
+		err := recorder.create(context.Background(), warningKubernetesEventType, "ZoneOutageCompensation", "Zones europe-west1-c of mig web have no running instances")
+
+		assert.Nil(t, err)
+		assert.Equal(t, "/api/v1/namespaces/estafette/events", path)
+		assert.Equal(t, "Bearer token", authorization)
+		assert.Equal(t, "Pod", event.InvolvedObject.Kind)
+		assert.Equal(t, "estafette-gcloud-mig-scaler-0", event.InvolvedObject.Name)
+		assert.Equal(t, "Warning", event.Type)
+		assert.Equal(t, "ZoneOutageCompensation", event.Reason)
+	})
+
+	t.Run("ReturnsErrorIfKubernetesApiRejectsEvent", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		}))
+		defer server.Close()
+
+		recorder := &KubernetesEventRecorder{client: server.Client(), apiURL: server.URL, namespace: "estafette", podName: "estafette-gcloud-mig-scaler-0"}
+
+		// act
+		err := recorder.create(context.Background(), normalKubernetesEventType, "ZoneOutageCompensationEnded", "All zones of mig web have running instances again")
+
+		assert.NotNil(t, err)
+	})
+}
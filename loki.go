@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// LokiMetricSource retrieves request rates from log lines by executing LogQL metric queries against grafana loki
+type LokiMetricSource struct {
+	// LokiURL is the default server, used for managed instance groups that don't set lokiUrl
+	LokiURL string
+}
+
+// GetRequestRate executes the request rate query as LogQL instant query; loki's query api returns results in the same format as prometheus
+func (s *LokiMetricSource) GetRequestRate(ctx context.Context, configItem MIGConfiguration) (float64, error) {
+
+	lokiURL := s.LokiURL
+	if configItem.LokiURL != "" {
+		lokiURL = configItem.LokiURL
+	}
+	queryURL := fmt.Sprintf("%v/loki/api/v1/query?query=%v", lokiURL, url.QueryEscape(configItem.RequestRateQuery))
+
+	return executePrometheusInstantQuery(ctx, PrometheusEndpoint{URL: lokiURL}, queryURL)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLokiMetricSourceGetRequestRate(t *testing.T) {
+
+	t.Run("ReturnsValueOfLogQLQuery", func(t *testing.T) {
+
+		query := `sum(rate({app="a"} |= "GET" [5m]))`
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/loki/api/v1/query", r.URL.Path)
+			assert.Equal(t, query, r.URL.Query().Get("query"))
+			w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1513161148.757,"225.4"]}]}}`))
+		}))
+		defer server.Close()
+
+		source := &LokiMetricSource{LokiURL: "http://unused"}
+
+		// act
+		requestRate, err := source.GetRequestRate(context.Background(), MIGConfiguration{RequestRateQuery: query, LokiURL: server.URL})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 225.4, requestRate)
+	})
+}
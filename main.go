@@ -2,13 +2,9 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"math"
 	"math/rand"
 	"net/http"
-	"net/url"
 	"os"
 	"os/signal"
 	"runtime"
@@ -16,31 +12,18 @@ import (
 	"syscall"
 	"time"
 
+	// the image has no zoneinfo, so embed it for the timezones of schedules
+	_ "time/tzdata"
+
 	"github.com/alecthomas/kingpin"
 	foundation "github.com/estafette/estafette-foundation"
 	"github.com/rs/zerolog/log"
-	"github.com/sethgrid/pester"
 	"golang.org/x/oauth2/google"
-	computebeta "google.golang.org/api/compute/v0.beta"
-	compute "google.golang.org/api/compute/v1"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-// MIGConfiguration has all the config needed for a single managed instance group to be scaled
-type MIGConfiguration struct {
-	GCloudProject                string  `json:"gcloudProject,omitempty"`
-	GCloudZone                   string  `json:"gcloudZone,omitempty"`
-	GCloudRegion                 string  `json:"gcloudRegion,omitempty"`
-	RequestRateQuery             string  `json:"requestRateQuery,omitempty"`
-	InstanceGroupName            string  `json:"instanceGroupName,omitempty"`
-	MinimumNumberOfInstances     int     `json:"minimumNumberOfInstances,omitempty"`
-	NumberOfRequestsPerInstance  float64 `json:"numberOfRequestsPerInstance,omitempty"`
-	NumberOfInstancesBelowTarget int     `json:"numberOfInstancesBelowTarget,omitempty"`
-	EnableSettingMinInstances    bool    `json:"enableSettingMinInstances,omitempty"`
-}
-
 var (
 	appgroup  string
 	app       string
@@ -52,11 +35,82 @@ var (
 )
 
 var (
+	// commands
+	runCommand      = kingpin.Command("run", "Scale the configured managed instance groups.").Default()
+	validateCommand = kingpin.Command("validate", "Validate the managed instance group configuration and exit.")
+
 	// flags
+	settingsFile             = kingpin.Flag("settings-file", "Path to a yaml file mapping flag names to values; command line flags and envvars take precedence over it.").Envar("SETTINGS_FILE").String()
 	prometheusMetricsAddress = kingpin.Flag("metrics-listen-address", "The address to listen on for Prometheus metrics requests.").Envar("PROMETHEUS_METRICS_PORT").Default(":9101").String()
 	prometheusMetricsPath    = kingpin.Flag("metrics-path", "The path to listen for Prometheus metrics requests.").Envar("PROMETHEUS_METRICS_PATH").Default("/metrics").String()
-	prometheusURL            = kingpin.Flag("prometheus-url", "The url to the Prometheus server).").Envar("PROMETHEUS_URL").String()
+	prometheusURL            = kingpin.Flag("prometheus-url", "The url to the Prometheus server, or a comma separated list of urls to query all replicas of a highly available pair; can be overridden per managed instance group with prometheusUrl.").Envar("PROMETHEUS_URL").String()
+	prometheusReplicaAggr    = kingpin.Flag("prometheus-replica-aggregation", "How to combine the values of multiple Prometheus replicas: max takes the highest value of the replicas that responded, quorum requires more than half of them to respond; can be overridden per managed instance group with prometheusReplicaAggregation.").Envar("PROMETHEUS_REPLICA_AGGREGATION").Default("max").Enum("max", "quorum")
+	prometheusUsername       = kingpin.Flag("prometheus-username", "The username for basic auth on Prometheus queries; can be overridden per managed instance group with prometheusUsername.").Envar("PROMETHEUS_USERNAME").String()
+	prometheusPassword       = kingpin.Flag("prometheus-password", "The password for basic auth on Prometheus queries; can be overridden per managed instance group with prometheusPassword.").Envar("PROMETHEUS_PASSWORD").String()
+	prometheusBearerToken    = kingpin.Flag("prometheus-bearer-token-file", "Path to a file with a bearer token for Prometheus queries; can be overridden per managed instance group with prometheusBearerTokenFile.").Envar("PROMETHEUS_BEARER_TOKEN_FILE").String()
+	prometheusCAFile         = kingpin.Flag("prometheus-ca-file", "Path to a pem encoded ca bundle to verify the Prometheus server certificate with; can be overridden per managed instance group with prometheusCaFile.").Envar("PROMETHEUS_CA_FILE").String()
+	prometheusCertFile       = kingpin.Flag("prometheus-cert-file", "Path to a pem encoded client certificate for mutual tls with Prometheus; can be overridden per managed instance group with prometheusCertFile.").Envar("PROMETHEUS_CERT_FILE").String()
+	prometheusKeyFile        = kingpin.Flag("prometheus-key-file", "Path to the pem encoded key of the client certificate; can be overridden per managed instance group with prometheusKeyFile.").Envar("PROMETHEUS_KEY_FILE").String()
+	prometheusInsecure       = kingpin.Flag("prometheus-insecure-skip-verify", "Don't verify the Prometheus server certificate; only use this for testing.").Envar("PROMETHEUS_INSECURE_SKIP_VERIFY").Bool()
+	prometheusIAPAudience    = kingpin.Flag("prometheus-iap-audience", "The oauth client id of the Identity-Aware Proxy protecting Prometheus, to attach an id token for; can be overridden per managed instance group with prometheusIapAudience.").Envar("PROMETHEUS_IAP_AUDIENCE").String()
+	prometheusProxyURL       = kingpin.Flag("prometheus-proxy-url", "The url of an http(s) proxy to reach Prometheus through, independent of the proxy for the Google apis; without it the HTTP_PROXY, HTTPS_PROXY and NO_PROXY envvars apply. Can be overridden per managed instance group with prometheusProxyUrl.").Envar("PROMETHEUS_PROXY_URL").String()
+	prometheusUsePOST        = kingpin.Flag("prometheus-use-post", "Send Prometheus queries as post form body instead of in the url, for queries exceeding url length limits; without it queries answered with 414 URI Too Long are resent as post. Can be enabled per managed instance group with prometheusUsePost.").Envar("PROMETHEUS_USE_POST").Bool()
+	prometheusMaxResponse    = kingpin.Flag("prometheus-max-response-size", "The maximum size of a Prometheus query response; larger responses are aborted, so a query returning millions of series can't run the scaler out of memory.").Envar("PROMETHEUS_MAX_RESPONSE_SIZE").Default("10MB").Bytes()
+	prometheusMaxRetries     = kingpin.Flag("prometheus-max-retries", "The number of attempts for a Prometheus request that fails or returns a 5xx status code.").Envar("PROMETHEUS_MAX_RETRIES").Default("3").Int()
+	prometheusBackoff        = kingpin.Flag("prometheus-backoff", "The backoff between attempts of Prometheus requests: constant waits 1 second, linear and exponential grow with each attempt, optionally with jitter.").Envar("PROMETHEUS_BACKOFF").Default("constant").Enum("constant", "linear", "linear-jitter", "exponential", "exponential-jitter")
+	prometheusConcurrency    = kingpin.Flag("prometheus-concurrency", "The number of identical Prometheus requests sent in parallel, of which the first response is used.").Envar("PROMETHEUS_CONCURRENCY").Default("1").Int()
+	prometheusAttemptTimeout = kingpin.Flag("prometheus-attempt-timeout", "The maximum time for a single attempt of a Prometheus request; 0 means only --query-timeout applies.").Envar("PROMETHEUS_ATTEMPT_TIMEOUT").Default("0s").Duration()
+	prometheusHeaders        = kingpin.Flag("prometheus-header", "A header as name=value to send with every Prometheus query, like X-Scope-OrgID=tenant for Cortex, Mimir or Thanos; can be repeated and extended per managed instance group with prometheusHeaders.").Envar("PROMETHEUS_HEADERS").StringMap()
+	validateQueriesOnStartup = kingpin.Flag("validate-queries-on-startup", "Check the syntax of all Prometheus request rate queries with the format_query endpoint of Prometheus 2.38 and newer on startup and on every configuration reload, and refuse to start or keep the active configuration if any is invalid.").Envar("VALIDATE_QUERIES_ON_STARTUP").Default("true").Bool()
 	migConfig                = kingpin.Flag("mig-config", "A json array of configuration for all managed instance groups, the Prometheus query to fetch request rate with, the target requests per instance.").Envar("MIG_CONFIG").String()
+	configFile               = kingpin.Flag("config-file", "Path to a yaml or json file with the configuration for all managed instance groups; takes precedence over --mig-config.").Envar("CONFIG_FILE").String()
+	configGCSURL             = kingpin.Flag("config-gcs-url", "A gs://bucket/path url to a yaml or json file with the configuration for all managed instance groups; takes precedence over --config-file.").Envar("CONFIG_GCS_URL").String()
+	configSecret             = kingpin.Flag("config-secret", "A projects/x/secrets/y/versions/z Secret Manager secret version holding the configuration for all managed instance groups; takes precedence over --config-gcs-url.").Envar("CONFIG_SECRET").String()
+	configConfigMap          = kingpin.Flag("config-configmap", "A namespace/name Kubernetes ConfigMap holding the configuration for all managed instance groups, watched for changes; takes precedence over --config-secret.").Envar("CONFIG_CONFIGMAP").String()
+	configConfigMapKey       = kingpin.Flag("config-configmap-key", "The key in the Kubernetes ConfigMap holding the configuration.").Envar("CONFIG_CONFIGMAP_KEY").Default("config.yaml").String()
+	discoveryInterval        = kingpin.Flag("discovery-interval", "The interval at which managed instance groups are discovered for the discovery rules in the configuration.").Envar("DISCOVERY_INTERVAL").Default("5m").Duration()
+	configURL                = kingpin.Flag("config-url", "An http(s) url serving the configuration for all managed instance groups, polled for changes; takes precedence over --config-secret.").Envar("CONFIG_URL").String()
+	configGitRepo            = kingpin.Flag("config-git-repo", "The url of a git repository holding the configuration for all managed instance groups, polled for changes; takes precedence over --config-configmap.").Envar("CONFIG_GIT_REPO").String()
+	configGitBranch          = kingpin.Flag("config-git-branch", "The branch of the git repository to read the configuration from; defaults to the remote head.").Envar("CONFIG_GIT_BRANCH").String()
+	configGitPath            = kingpin.Flag("config-git-path", "The path of the config file inside the git repository.").Envar("CONFIG_GIT_PATH").String()
+	configPollInterval       = kingpin.Flag("config-poll-interval", "The interval at which remote configuration is polled for changes.").Envar("CONFIG_POLL_INTERVAL").Default("1m").Duration()
+	datadogAPIURL            = kingpin.Flag("datadog-api-url", "The url of the Datadog api for your Datadog site.").Envar("DATADOG_API_URL").Default("https://api.datadoghq.com").String()
+	datadogAPIKey            = kingpin.Flag("datadog-api-key", "The Datadog api key, for managed instance groups with metricSource datadog.").Envar("DATADOG_API_KEY").String()
+	datadogAppKey            = kingpin.Flag("datadog-app-key", "The Datadog application key, for managed instance groups with metricSource datadog.").Envar("DATADOG_APP_KEY").String()
+	newRelicInsightsURL      = kingpin.Flag("newrelic-insights-url", "The url of the New Relic Insights query api.").Envar("NEWRELIC_INSIGHTS_URL").Default("https://insights-api.newrelic.com").String()
+	newRelicAccountID        = kingpin.Flag("newrelic-account-id", "The New Relic account id, for managed instance groups with metricSource newrelic.").Envar("NEWRELIC_ACCOUNT_ID").String()
+	newRelicAPIKey           = kingpin.Flag("newrelic-api-key", "The New Relic Insights query key, for managed instance groups with metricSource newrelic.").Envar("NEWRELIC_API_KEY").String()
+	elasticsearchURL         = kingpin.Flag("elasticsearch-url", "The url to the Elasticsearch cluster; can be overridden per managed instance group with elasticsearchUrl.").Envar("ELASTICSEARCH_URL").String()
+	lokiURL                  = kingpin.Flag("loki-url", "The url to the Grafana Loki server; can be overridden per managed instance group with lokiUrl.").Envar("LOKI_URL").String()
+	kafkaBrokers             = kingpin.Flag("kafka-brokers", "A comma separated list of Kafka brokers; can be overridden per managed instance group with kafkaBrokers.").Envar("KAFKA_BROKERS").String()
+	rabbitMQURL              = kingpin.Flag("rabbitmq-url", "The url to the RabbitMQ management api; can be overridden per managed instance group with rabbitmqUrl.").Envar("RABBITMQ_URL").String()
+	rabbitMQUsername         = kingpin.Flag("rabbitmq-username", "The username for the RabbitMQ management api.").Envar("RABBITMQ_USERNAME").String()
+	rabbitMQPassword         = kingpin.Flag("rabbitmq-password", "The password for the RabbitMQ management api.").Envar("RABBITMQ_PASSWORD").String()
+	execAllowedCommands      = kingpin.Flag("exec-allowed-commands", "A command managed instance groups with metricSource exec are allowed to run in execCommand, like /usr/local/bin/queue-depth; can be repeated. Configuration with any other command is rejected.").Envar("EXEC_ALLOWED_COMMANDS").Strings()
+	disableAllUpdates        = kingpin.Flag("disable-all-updates", "Keep collecting and exporting metrics, but never update any autoscaler.").Envar("DISABLE_ALL_UPDATES").Bool()
+	calendarURL              = kingpin.Flag("calendar-url", "The url of an iCalendar feed, like the secret address in ical format of a Google Calendar, whose events tagged with mig:<instance group name>=<minimum> raise the minimum number of instances for their duration.").Envar("CALENDAR_URL").String()
+	calendarRefresh          = kingpin.Flag("calendar-refresh-interval", "The interval at which the calendar feed is retrieved again.").Envar("CALENDAR_REFRESH_INTERVAL").Default("5m").Duration()
+	remoteWriteURL           = kingpin.Flag("remote-write-url", "The url of a Prometheus remote write endpoint to push the request rate, target and applied minimum number of instances of every scaling decision to, so they're queryable even if scrapes of the metrics endpoint have gaps.").Envar("REMOTE_WRITE_URL").String()
+	remoteWriteHeaders       = kingpin.Flag("remote-write-header", "A header as name=value to send with every remote write, like X-Scope-OrgID=tenant for Cortex or Mimir; can be repeated.").Envar("REMOTE_WRITE_HEADERS").StringMap()
+	remoteWriteUsername      = kingpin.Flag("remote-write-username", "The username for basic auth on the remote write endpoint.").Envar("REMOTE_WRITE_USERNAME").String()
+	remoteWritePassword      = kingpin.Flag("remote-write-password", "The password for basic auth on the remote write endpoint.").Envar("REMOTE_WRITE_PASSWORD").String()
+	remoteWriteBearerToken   = kingpin.Flag("remote-write-bearer-token-file", "Path to a file with a bearer token for the remote write endpoint, read for every write.").Envar("REMOTE_WRITE_BEARER_TOKEN_FILE").String()
+	kubernetesEvents         = kingpin.Flag("kubernetes-events", "Create Kubernetes events on the pod of the scaler when zone outage compensation starts or ends; requires permission to create events in its namespace.").Envar("KUBERNETES_EVENTS").Default("false").Bool()
+	maxHourlyCost            = kingpin.Flag("max-hourly-cost", "The maximum estimated hourly cost in usd of the minimum number of instances of all managed instance groups with instanceHourlyCost or instanceCostSkus together; minimums aren't raised beyond it. 0 means no ceiling.").Envar("MAX_HOURLY_COST").Default("0").Float64()
+	billingCatalogService    = kingpin.Flag("billing-catalog-service", "The id of the Cloud Billing catalog service to retrieve the prices of instanceCostSkus from, like 6F81-5844-456A for Compute Engine.").Envar("BILLING_CATALOG_SERVICE").String()
+	billingCatalogRefresh    = kingpin.Flag("billing-catalog-refresh-interval", "The interval at which the prices of the billing catalog are retrieved again.").Envar("BILLING_CATALOG_REFRESH_INTERVAL").Default("24h").Duration()
+	queryTimeout             = kingpin.Flag("query-timeout", "The maximum time for retrieving the request rate of a managed instance group, including fallbacks and retries; can be overridden per managed instance group with queryTimeoutSeconds.").Envar("QUERY_TIMEOUT").Default("30s").Duration()
+	computeTimeout           = kingpin.Flag("compute-timeout", "The maximum time for the compute api calls to retrieve and update the autoscaler of a managed instance group, including waiting for the update operation to complete.").Envar("COMPUTE_TIMEOUT").Default("30s").Duration()
+	narrowOAuthScopes        = kingpin.Flag("narrow-oauth-scopes", "Request the narrowest oauth scope for every Google api instead of cloud-platform, like compute for the compute api, or compute.readonly with --disable-all-updates.").Envar("NARROW_OAUTH_SCOPES").Default("false").Bool()
+	googleCredentialsFile    = kingpin.Flag("google-application-credentials-file", "A json service account key to authenticate with all Google apis instead of the application default credentials; it's checked at startup.").Envar("GOOGLE_APPLICATION_CREDENTIALS_FILE").String()
+	computeCacheTTL          = kingpin.Flag("compute-cache-ttl", "How long the autoscaler found for a managed instance group is retrieved by name before it's searched for again. 0 means it's searched for every iteration.").Envar("COMPUTE_CACHE_TTL").Default("0s").Duration()
+	computeMaxRetries        = kingpin.Flag("compute-max-retries", "The number of retries of a compute api request that's rate limited or fails with a 5xx status code, within --compute-timeout; 0 disables retries.").Envar("COMPUTE_MAX_RETRIES").Default("3").Int()
+	computeRetryBackoff      = kingpin.Flag("compute-retry-backoff", "The wait before the first retry of a compute api request, doubling with each retry and randomized by up to half; a Retry-After header in the response takes precedence.").Envar("COMPUTE_RETRY_BACKOFF").Default("1s").Duration()
+	computeRetryMaxBackoff   = kingpin.Flag("compute-retry-max-backoff", "The maximum wait between retries of a compute api request.").Envar("COMPUTE_RETRY_MAX_BACKOFF").Default("16s").Duration()
+	computeRateLimit         = kingpin.Flag("compute-rate-limit", "The maximum number of compute api requests per second of all managed instance groups together, including retries; requests beyond it wait their turn. 0 means no limit.").Envar("COMPUTE_RATE_LIMIT").Default("0").Float64()
+	computeRateLimitBurst    = kingpin.Flag("compute-rate-limit-burst", "The number of compute api requests that can be sent at once before --compute-rate-limit applies.").Envar("COMPUTE_RATE_LIMIT_BURST").Default("10").Int()
+	computeConcurrency       = kingpin.Flag("compute-concurrency", "The number of projects and zones or regions whose instance group managers are listed in parallel at the start of every iteration, instead of retrieving them one by one for managed instance groups sharing a zone or region. 0 disables listing them.").Envar("COMPUTE_CONCURRENCY").Default("4").Int()
+	operationPollInterval    = kingpin.Flag("operation-poll-interval", "The interval at which an autoscaler update operation is checked for completion.").Envar("OPERATION_POLL_INTERVAL").Default("2s").Duration()
 
 	// seed random number
 	r = rand.New(rand.NewSource(time.Now().UnixNano()))
@@ -78,24 +132,204 @@ var (
 		Name: "estafette_gcloud_mig_scaler_request_rate",
 		Help: "The request rate used for setting minimum number of instances per managed instance group as set by this application.",
 	}, []string{"mig"})
+
+	// create counter for tracking queries that weren't executed because an identical query was already executed in the same iteration
+	queryCacheHitsCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "estafette_gcloud_mig_scaler_query_cache_hits_total",
+		Help: "The number of queries served from the responses of identical queries executed earlier in the same iteration.",
+	})
+
+	// create counter for tracking autoscalers that were retrieved by their cached name instead of searched for
+	computeCacheHitsCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "estafette_gcloud_mig_scaler_compute_cache_hits_total",
+		Help: "The number of autoscalers retrieved by their cached name instead of searched for with a list of all autoscalers in their location.",
+	})
+
+	// create counter for tracking retried compute api requests per error class
+	computeRetriesVector = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "estafette_gcloud_mig_scaler_compute_request_retries_total",
+		Help: "The number of compute api requests that were retried by error class: rate_limited for 429 and rate limit exceeded errors, server_error for 5xx.",
+	}, []string{"class"})
+
+	// create counter for tracking time compute api requests waited for the rate limiter
+	computeRateLimitWaitCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "estafette_gcloud_mig_scaler_compute_rate_limit_wait_seconds_total",
+		Help: "The total time compute api requests waited for --compute-rate-limit.",
+	})
+
+	// create counter for tracking retried prometheus requests
+	prometheusRetriesCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "estafette_gcloud_mig_scaler_prometheus_request_retries_total",
+		Help: "The number of Prometheus request attempts that failed and were retried.",
+	})
+
+	// create counter for tracking prometheus responses aborted for exceeding the maximum response size
+	prometheusResponsesTooLargeCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "estafette_gcloud_mig_scaler_prometheus_responses_too_large_total",
+		Help: "The number of Prometheus query responses that were aborted because they exceeded the maximum response size.",
+	})
+
+	// create counter for tracking calculations capped at the maximum number of instances per managed instance group
+	maxInstancesClampedVector = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "estafette_gcloud_mig_scaler_max_instances_clamped_total",
+		Help: "The number of times the calculated minimum number of instances per managed instance group exceeded maximumNumberOfInstances and was capped.",
+	}, []string{"mig"})
+
+	// create counter for tracking minimums capped at the max instances of the autoscaler per managed instance group
+	autoscalerMaxClampedVector = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "estafette_gcloud_mig_scaler_autoscaler_max_clamped_total",
+		Help: "The number of times the minimum number of instances per managed instance group exceeded the max instances of its autoscaler and was capped.",
+	}, []string{"mig"})
+
+	// create counter for tracking the outcome of autoscaler update operations per managed instance group
+	autoscalerUpdatesVector = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "estafette_gcloud_mig_scaler_autoscaler_updates_total",
+		Help: "The number of autoscaler update operations per managed instance group by result: succeeded, failed or unknown if they didn't complete within --compute-timeout.",
+	}, []string{"mig", "result"})
+
+	// create gauge for tracking the mode of the autoscaler per managed instance group
+	autoscalerModeVector = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "estafette_gcloud_mig_scaler_autoscaler_mode",
+		Help: "The mode of the autoscaler per managed instance group, 1 for its current mode and 0 for all others; min instances have no effect in mode OFF and don't scale in with ONLY_SCALE_OUT.",
+	}, []string{"mig", "mode"})
+
+	// create gauge for tracking the recommended size of the autoscaler per managed instance group
+	autoscalerRecommendedSizeVector = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "estafette_gcloud_mig_scaler_autoscaler_recommended_size",
+		Help: "The number of instances the autoscaler of the managed instance group calculated it needs, within its min and max instances.",
+	}, []string{"mig"})
+
+	// create gauge for tracking the status details the autoscaler reports per managed instance group
+	autoscalerStatusDetailsVector = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "estafette_gcloud_mig_scaler_autoscaler_status_details",
+		Help: "1 for every type of status detail the autoscaler of the managed instance group currently reports, like NOT_ENOUGH_QUOTA_AVAILABLE or ALL_INSTANCES_UNHEALTHY.",
+	}, []string{"mig", "type"})
+
+	// create counter for tracking scale downs held while the autoscaler reports errors per managed instance group
+	scaleDownsHeldVector = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "estafette_gcloud_mig_scaler_autoscaler_errors_scale_downs_held_total",
+		Help: "The number of times lowering the min instances of a managed instance group with holdScaleDownOnAutoscalerErrors was held because its autoscaler reported errors.",
+	}, []string{"mig"})
+
+	// create counter for tracking request rates replaced by the anomaly filter per managed instance group
+	anomaliesFilteredVector = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "estafette_gcloud_mig_scaler_anomalies_filtered_total",
+		Help: "The number of request rates per managed instance group that deviated too much from the recent median and were replaced by it.",
+	}, []string{"mig"})
+
+	// create gauge and counter for tracking the estimated hourly cost and the cost ceilings capping the minimum per managed instance group
+	estimatedHourlyCostVector = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "estafette_gcloud_mig_scaler_estimated_hourly_cost",
+		Help: "The estimated hourly cost in usd of the minimum number of instances per managed instance group.",
+	}, []string{"mig"})
+	costCappedVector = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "estafette_gcloud_mig_scaler_cost_capped_total",
+		Help: "The number of times the minimum number of instances per managed instance group wasn't raised because of the mig or global hourly cost ceiling.",
+	}, []string{"mig", "ceiling"})
+
+	// create gauge for tracking whether zone outage compensation is active per managed instance group
+	zoneOutageCompensationVector = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "estafette_gcloud_mig_scaler_zone_outage_compensation_active",
+		Help: "Whether the minimum number of instances per regional managed instance group is raised because one or more of its zones have no running instances.",
+	}, []string{"mig"})
+
+	// create gauge for tracking whether the slo query exceeds its threshold per managed instance group
+	sloBreachedVector = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "estafette_gcloud_mig_scaler_slo_breached",
+		Help: "Whether the slo query per managed instance group exceeds sloThreshold, raising the minimum number of instances by sloScaleUpPercent.",
+	}, []string{"mig"})
+
+	// create gauge for tracking the number of requests per instance retrieved with numberOfRequestsPerInstanceQuery per managed instance group
+	requestsPerInstanceVector = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "estafette_gcloud_mig_scaler_requests_per_instance",
+		Help: "The number of requests per instance per managed instance group retrieved with numberOfRequestsPerInstanceQuery, within its bounds.",
+	}, []string{"mig"})
 )
 
 func init() {
 	prometheus.MustRegister(minInstancesVector)
 	prometheus.MustRegister(actualInstancesVector)
 	prometheus.MustRegister(requestRateVector)
+	prometheus.MustRegister(queryCacheHitsCounter)
+	prometheus.MustRegister(computeCacheHitsCounter)
+	prometheus.MustRegister(computeRetriesVector)
+	prometheus.MustRegister(computeRateLimitWaitCounter)
+	prometheus.MustRegister(prometheusRetriesCounter)
+	prometheus.MustRegister(prometheusResponsesTooLargeCounter)
+	prometheus.MustRegister(maxInstancesClampedVector)
+	prometheus.MustRegister(autoscalerMaxClampedVector)
+	prometheus.MustRegister(autoscalerUpdatesVector)
+	prometheus.MustRegister(autoscalerModeVector)
+	prometheus.MustRegister(autoscalerRecommendedSizeVector)
+	prometheus.MustRegister(autoscalerStatusDetailsVector)
+	prometheus.MustRegister(scaleDownsHeldVector)
+	prometheus.MustRegister(anomaliesFilteredVector)
+	prometheus.MustRegister(estimatedHourlyCostVector)
+	prometheus.MustRegister(costCappedVector)
+	prometheus.MustRegister(zoneOutageCompensationVector)
+	prometheus.MustRegister(sloBreachedVector)
+	prometheus.MustRegister(requestsPerInstanceVector)
 }
 
 func main() {
 
 	// parse command line parameters
-	kingpin.Parse()
+	command := kingpin.Parse()
+
+	// apply flags from the settings file that weren't set on the command line or via their envvar
+	if *settingsFile != "" {
+		if err := ApplySettingsFile(kingpin.CommandLine, os.Args[1:], *settingsFile); err != nil {
+			kingpin.Fatalf("Applying settings file failed: %v", err)
+		}
+	}
+
+	ctx := context.Background()
+
+	// validating configuration uses the allowed commands, so they're set before any configuration is read
+	allowedExecCommands = *execAllowedCommands
+
+	oauthScopes := NewOAuthScopes(*narrowOAuthScopes, *disableAllUpdates)
+
+	if *googleCredentialsFile != "" {
+		if err := CheckServiceAccountKeyFile(ctx, *googleCredentialsFile, oauthScopes.Used(*billingCatalogService != "")...); err != nil {
+			log.Fatal().Err(err).Msg("Checking --google-application-credentials-file failed")
+		}
+
+		// the default credentials of all google clients and the service account key for iap id tokens come from this envvar
+		os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", *googleCredentialsFile)
+	}
+
+	configSource, err := NewConfigSource(ctx, ConfigSourceOptions{
+		ConfigFile:         *configFile,
+		MIGConfig:          *migConfig,
+		ConfigGCSURL:       *configGCSURL,
+		ConfigSecret:       *configSecret,
+		ConfigConfigMap:    *configConfigMap,
+		ConfigConfigMapKey: *configConfigMapKey,
+		ConfigURL:          *configURL,
+		ConfigGitRepo:      *configGitRepo,
+		ConfigGitBranch:    *configGitBranch,
+		ConfigGitPath:      *configGitPath,
+	})
+	if err != nil {
+		if command == validateCommand.FullCommand() {
+			fmt.Printf("Creating config source failed: %v\n", err)
+			os.Exit(1)
+		}
+		log.Fatal().Err(err).Msg("Creating config source failed")
+	}
+
+	if command == validateCommand.FullCommand() {
+		os.Exit(validate(ctx, configSource))
+	}
 
 	// init log format from envvar ESTAFETTE_LOG_FORMAT
 	foundation.InitLoggingFromEnv(appgroup, app, version, branch, revision, buildDate)
 
+	LogEffectiveSettings(kingpin.CommandLine)
+
 	// define channel and wait group to gracefully shutdown the application
-	gracefulShutdown := make(chan os.Signal)
+	gracefulShutdown := make(chan os.Signal, 1)
 	signal.Notify(gracefulShutdown, syscall.SIGTERM, syscall.SIGINT)
 	waitGroup := &sync.WaitGroup{}
 
@@ -112,22 +346,170 @@ func main() {
 		}
 	}()
 
-	var migConfigs []MIGConfiguration
+	config, err := ReadConfig(ctx, configSource)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Reading managed instance group configuration failed")
+	}
+	if err = ValidateConfig(config); err != nil {
+		log.Fatal().Err(err).Msg("Validating managed instance group configuration failed")
+	}
+	migConfigStore := NewMIGConfigStore(config)
 
-	if err := json.Unmarshal([]byte(*migConfig), &migConfigs); err != nil {
-		// couldn't deserialize, setting to default struct
-		log.Fatal().Err(err).Msg("Unmarshalling migConfig failed")
+	// apis requesting the same scope share a client
+	googleClients := map[string]*http.Client{}
+	googleClient := func(scope string) *http.Client {
+		if client, ok := googleClients[scope]; ok {
+			return client
+		}
+		client, err := google.DefaultClient(ctx, scope)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("Creating google cloud client with scope %v failed", scope)
+		}
+		googleClients[scope] = client
+		return client
+	}
+	client := googleClient(oauthScopes.Compute)
+
+	computeClientOptions := ComputeClientOptions{
+		Retry: ComputeRetryConfig{
+			MaxRetries:     *computeMaxRetries,
+			InitialBackoff: *computeRetryBackoff,
+			MaxBackoff:     *computeRetryMaxBackoff,
+		},
+	}
+	if *computeRateLimit > 0 {
+		computeClientOptions.RateLimiter = NewTokenBucket(*computeRateLimit, *computeRateLimitBurst)
 	}
 
-	ctx := context.Background()
-	client, err := google.DefaultClient(ctx, compute.CloudPlatformScope)
+	computeClient, err := NewGAComputeClient(client, computeClientOptions)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Creating google cloud client failed")
+		log.Fatal().Err(err).Msg("Creating google cloud service failed")
 	}
 
-	computeService, err := computebeta.New(client)
+	// discover managed instance groups by the labels of their instance template
+	go RunMIGDiscovery(ctx, computeClient, migConfigStore, *discoveryInterval)
+
+	cloudMonitoring, err := NewCloudMonitoringMetricSource(googleClient(oauthScopes.Monitoring))
 	if err != nil {
-		log.Fatal().Err(err).Msg("Creating google cloud service failed")
+		log.Fatal().Err(err).Msg("Creating google cloud monitoring service failed")
+	}
+
+	bigQuery, err := NewBigQueryMetricSource(googleClient(oauthScopes.BigQuery))
+	if err != nil {
+		log.Fatal().Err(err).Msg("Creating google bigquery service failed")
+	}
+
+	prometheusEndpoint := PrometheusEndpoint{
+		URL:                *prometheusURL,
+		ReplicaAggregation: *prometheusReplicaAggr,
+		Username:           *prometheusUsername,
+		Password:           *prometheusPassword,
+		BearerTokenFile:    *prometheusBearerToken,
+		TLS: PrometheusTLSConfig{
+			CAFile:             *prometheusCAFile,
+			CertFile:           *prometheusCertFile,
+			KeyFile:            *prometheusKeyFile,
+			InsecureSkipVerify: *prometheusInsecure,
+		},
+		IAPAudience:      *prometheusIAPAudience,
+		Headers:          *prometheusHeaders,
+		ProxyURL:         *prometheusProxyURL,
+		UsePOST:          *prometheusUsePOST,
+		MaxResponseBytes: int64(*prometheusMaxResponse),
+		Retry: PrometheusRetryConfig{
+			MaxRetries:     *prometheusMaxRetries,
+			Backoff:        *prometheusBackoff,
+			Concurrency:    *prometheusConcurrency,
+			AttemptTimeout: *prometheusAttemptTimeout,
+		},
+	}
+
+	prometheusSource := &PrometheusMetricSource{Defaults: prometheusEndpoint}
+	if *validateQueriesOnStartup {
+		validateQueries := func(ctx context.Context, migConfigs []MIGConfiguration) error {
+			unvalidated, err := prometheusSource.ValidateQueries(ctx, migConfigs)
+			logUnvalidatedQueries(unvalidated)
+			return err
+		}
+		if err := validateQueries(ctx, config.MIGs); err != nil {
+			log.Fatal().Err(err).Msg("Validating request rate queries with prometheus failed")
+		}
+		migConfigStore.ValidateQueries = validateQueries
+	}
+
+	// reload the configuration on SIGHUP
+	go HandleReloadSignals(ctx, configSource, migConfigStore)
+
+	// reload the configuration when it changes
+	switch source := configSource.(type) {
+	case *FileConfigSource:
+		go func() {
+			if err := WatchMIGConfigFile(ctx, source, migConfigStore); err != nil {
+				log.Error().Err(err).Msgf("Watching config %v failed, configuration changes won't be picked up until restart", source)
+			}
+		}()
+	case *ConfigMapConfigSource:
+		go WatchMIGConfigMap(ctx, source, migConfigStore)
+	case *GCSConfigSource, *SecretManagerConfigSource, *HTTPConfigSource, *GitConfigSource:
+		go PollMIGConfigs(ctx, source, migConfigStore, *configPollInterval)
+	}
+
+	metricSources := map[string]MetricSource{
+		prometheusMetricSource:      prometheusSource,
+		cloudMonitoringMetricSource: cloudMonitoring,
+		datadogMetricSource:         &DatadogMetricSource{APIURL: *datadogAPIURL, APIKey: *datadogAPIKey, AppKey: *datadogAppKey},
+		cloudWatchMetricSource:      NewCloudWatchMetricSource(),
+		newRelicMetricSource:        &NewRelicMetricSource{InsightsURL: *newRelicInsightsURL, AccountID: *newRelicAccountID, APIKey: *newRelicAPIKey},
+		elasticsearchMetricSource:   &ElasticsearchMetricSource{ElasticsearchURL: *elasticsearchURL},
+		lokiMetricSource:            &LokiMetricSource{LokiURL: *lokiURL},
+		kafkaMetricSource:           &KafkaMetricSource{Brokers: *kafkaBrokers},
+		rabbitMQMetricSource:        &RabbitMQMetricSource{ManagementURL: *rabbitMQURL, Username: *rabbitMQUsername, Password: *rabbitMQPassword},
+		pubSubMetricSource:          &PubSubMetricSource{CloudMonitoring: cloudMonitoring},
+		cloudTasksMetricSource:      &CloudTasksMetricSource{CloudMonitoring: cloudMonitoring},
+		bigQueryMetricSource:        bigQuery,
+		sqlMetricSource:             NewSQLMetricSource(),
+		httpJSONMetricSource:        &HTTPJSONMetricSource{},
+		execMetricSource:            &ExecMetricSource{},
+	}
+
+	migScalerOptions := MIGScalerOptions{
+		DisableAllUpdates:  *disableAllUpdates,
+		QueryTimeout:       *queryTimeout,
+		ComputeTimeout:     *computeTimeout,
+		OperationPoll:      *operationPollInterval,
+		ComputeConcurrency: *computeConcurrency,
+		ComputeCacheTTL:    *computeCacheTTL,
+		ComputeScope:       oauthScopes.Compute,
+		MaxHourlyCost:      *maxHourlyCost,
+		ScalingSchedules:   NewScalingSchedulesClient(computeClient.HTTPClient()),
+	}
+	if *remoteWriteURL != "" {
+		remoteWriter := NewRemoteWriter(*remoteWriteURL)
+		remoteWriter.Headers = *remoteWriteHeaders
+		remoteWriter.Username = *remoteWriteUsername
+		remoteWriter.Password = *remoteWritePassword
+		remoteWriter.BearerTokenFile = *remoteWriteBearerToken
+		migScalerOptions.RemoteWriter = remoteWriter
+	}
+	if *kubernetesEvents {
+		eventRecorder, err := NewKubernetesEventRecorder()
+		if err != nil {
+			log.Fatal().Err(err).Msg("Creating kubernetes event recorder failed")
+		}
+		migScalerOptions.EventRecorder = eventRecorder
+	}
+	if *calendarURL != "" {
+		migScalerOptions.Calendar = NewCalendar(*calendarURL)
+		go RunCalendarRefresh(ctx, migScalerOptions.Calendar, *calendarRefresh)
+	}
+	if *billingCatalogService != "" {
+		migScalerOptions.BillingCatalog = NewBillingCatalog(googleClient(oauthScopes.Billing), *billingCatalogService)
+		go RunBillingCatalogRefresh(ctx, migScalerOptions.BillingCatalog, *billingCatalogRefresh)
+	}
+
+	migScaler := NewMIGScaler(computeClient, metricSources, migScalerOptions)
+	if *disableAllUpdates {
+		log.Warn().Msg("All autoscaler updates are disabled, only metrics are collected and exported")
 	}
 
 	// update minimum instances
@@ -135,135 +517,22 @@ func main() {
 		// loop indefinitely
 		for {
 			// loop through configs
-			for _, configItem := range migConfigs {
-
-				log.Info().Msgf("Retrieving data for managed instance group %v scaling...", configItem.InstanceGroupName)
-
-				// get request rate with prometheus query
-				// https://prometheus-production.travix.com/api/v1/query?query=sum%28rate%28nginx_http_requests_total%7Bhost%21~%22%5E%28%3F%3A%5B0-9.%5D%2B%29%24%22%2Clocation%3D%22%40searchfareapi_gcloud%22%7D%5B10m%5D%29%29%20by%20%28location%29
-				prometheusQueryURL := fmt.Sprintf("%v/api/v1/query?query=%v", *prometheusURL, url.QueryEscape(configItem.RequestRateQuery))
-				resp, err := pester.Get(prometheusQueryURL)
-				if err != nil {
-					log.Error().Err(err).Msgf("Executing prometheus query for mig %v failed", configItem.InstanceGroupName)
-					continue
-				}
-
-				defer resp.Body.Close()
-
-				body, err := ioutil.ReadAll(resp.Body)
-				if err != nil {
-					log.Error().Err(err).Msgf("Reading prometheus query (%v) response body for mig %v failed", prometheusQueryURL, configItem.InstanceGroupName)
-					continue
-				}
-
-				queryResponse, err := UnmarshalPrometheusQueryResponse(body)
-				if err != nil {
-					log.Error().Err(err).Msgf("Unmarshalling prometheus query (%v) response body for mig %v failed", prometheusQueryURL, configItem.InstanceGroupName)
-					continue
-				}
-
-				requestRate, err := queryResponse.GetRequestRate()
-				if err != nil {
-					log.Error().Err(err).Msgf("Retrieving request rate from query (%v) response body for mig %v failed", prometheusQueryURL, configItem.InstanceGroupName)
-					continue
-				}
-
-				// calculate target # of instances
-				targetNumberOfInstances := int(math.Ceil(requestRate / configItem.NumberOfRequestsPerInstance))
-
-				// substract number of instances below target
-				minimumNumberOfInstances := targetNumberOfInstances - configItem.NumberOfInstancesBelowTarget
-
-				// ensure minimumNumberOfInstances is larger than MinimumNumberOfInstances from the config
-				if minimumNumberOfInstances < configItem.MinimumNumberOfInstances {
-					minimumNumberOfInstances = configItem.MinimumNumberOfInstances
-				}
-
-				// get actual number of instances
-				var instanceGroupManager *computebeta.InstanceGroupManager
-				if configItem.GCloudRegion != "" {
-					instanceGroupManager, err = computeService.RegionInstanceGroupManagers.Get(configItem.GCloudProject, configItem.GCloudRegion, configItem.InstanceGroupName).Context(ctx).Do()
-				} else if configItem.GCloudZone != "" {
-					instanceGroupManager, err = computeService.InstanceGroupManagers.Get(configItem.GCloudProject, configItem.GCloudZone, configItem.InstanceGroupName).Context(ctx).Do()
-				}
-				if err != nil {
-					log.Error().Err(err).Msgf("Retrieving instance group manager %v failed", configItem.InstanceGroupName)
-					continue
-				}
-				migTargetSize := instanceGroupManager.TargetSize
-
-				log.Info().Msgf("Setting data for managed instance group %v in prometheus (min: %v, actual: %v, source request rate:%v)...", configItem.InstanceGroupName, minimumNumberOfInstances, migTargetSize, requestRate)
-
-				// set prometheus gauge values
-				minInstancesVector.WithLabelValues(configItem.InstanceGroupName).Set(float64(minimumNumberOfInstances))
-				actualInstancesVector.WithLabelValues(configItem.InstanceGroupName).Set(float64(migTargetSize))
-				requestRateVector.WithLabelValues(configItem.InstanceGroupName).Set(requestRate)
-
-				// set min instances on managed instance group
-				if configItem.EnableSettingMinInstances {
-
-					// retrieve autoscaler
-					filter := fmt.Sprintf("target eq %v", instanceGroupManager.SelfLink)
-
-					if configItem.GCloudRegion != "" {
-						autoscalerList, err := computeService.RegionAutoscalers.List(configItem.GCloudProject, configItem.GCloudRegion).Filter(filter).Context(ctx).Do()
-
-						if err != nil {
-							log.Error().Err(err).Msgf("Retrieving autoscaler %v failed", configItem.InstanceGroupName)
-							continue
-						}
-
-						if len(autoscalerList.Items) != 1 {
-							log.Warn().Msgf("An incorrect number of %v autoscalers for mig %v were retrieved", len(autoscalerList.Items), configItem.InstanceGroupName)
-							continue
-						}
-
-						autoScaler := autoscalerList.Items[0]
-
-						// update autoscaler
-						if autoScaler.AutoscalingPolicy.MinNumReplicas != int64(minimumNumberOfInstances) {
-							autoScaler.AutoscalingPolicy.MinNumReplicas = int64(minimumNumberOfInstances)
-							operation, err := computeService.RegionAutoscalers.Update(configItem.GCloudProject, configItem.GCloudRegion, autoScaler).Context(ctx).Do()
-							if err != nil {
-								log.Error().Err(err).Msgf("Updating autoscaler %v failed", configItem.InstanceGroupName)
-								continue
-							}
-
-							log.Info().Interface("operation", *operation).Msgf("Updated autoscaler for mig %v to min instances %v", configItem.InstanceGroupName, minimumNumberOfInstances)
-						} else {
-							log.Info().Msgf("Skipped updating autoscaler for mig %v, min instances is already at %v", configItem.InstanceGroupName, minimumNumberOfInstances)
-						}
-
-					} else if configItem.GCloudZone != "" {
-						autoscalerList, err := computeService.Autoscalers.List(configItem.GCloudProject, configItem.GCloudZone).Filter(filter).Context(ctx).Do()
-
-						if err != nil {
-							log.Error().Err(err).Msgf("Retrieving autoscaler %v failed", configItem.InstanceGroupName)
-							continue
-						}
-
-						if len(autoscalerList.Items) != 1 {
-							log.Warn().Msgf("An incorrect number of %v autoscalers for mig %v were retrieved", len(autoscalerList.Items), configItem.InstanceGroupName)
-							continue
-						}
-
-						autoScaler := autoscalerList.Items[0]
-
-						// update autoscaler
-						if autoScaler.AutoscalingPolicy.MinNumReplicas != int64(minimumNumberOfInstances) {
-							autoScaler.AutoscalingPolicy.MinNumReplicas = int64(minimumNumberOfInstances)
-							operation, err := computeService.Autoscalers.Update(configItem.GCloudProject, configItem.GCloudZone, autoScaler).Context(ctx).Do()
-							if err != nil {
-								log.Error().Err(err).Msgf("Updating autoscaler %v failed", configItem.InstanceGroupName)
-								continue
-							}
-
-							log.Info().Interface("operation", *operation).Msgf("Updated autoscaler for mig %v to min instances %v", configItem.InstanceGroupName, minimumNumberOfInstances)
-						} else {
-							log.Info().Msgf("Skipped updating autoscaler for mig %v, min instances is already at %v", configItem.InstanceGroupName, minimumNumberOfInstances)
-						}
-					}
-				}
+			// the revision of the configuration is logged with every scaling decision, so changes can be traced back to their source
+			configRevision := migConfigStore.GetConfig().Revision
+
+			// identical queries of multiple managed instance groups are executed once per iteration
+			iterationCtx := WithQueryCache(ctx)
+
+			// instance group managers in the same zone or region are listed at once instead of retrieved one by one
+			configItems := migConfigStore.Get()
+			iterationCtx = migScaler.PrefetchInstanceGroupManagers(iterationCtx, configItems)
+
+			// migs removed from the configuration or disabled are forgotten, so their last minimum doesn't count towards --max-hourly-cost
+			migScaler.RetainStates(configItems)
+
+			// migs following another mig are scaled last, so they follow the target of this iteration
+			for _, configItem := range OrderByFollowMIG(configItems) {
+				migScaler.Scale(iterationCtx, configItem, configRevision)
 			}
 
 			// sleep random time between 60s +- 25%
@@ -278,10 +547,41 @@ func main() {
 		Msgf("Received signal %v. Waiting on running tasks to finish...", signalReceived)
 
 	waitGroup.Wait()
+	if migScalerOptions.RemoteWriter != nil {
+		migScalerOptions.RemoteWriter.Wait()
+	}
 
 	log.Info().Msg("Shutting down...")
 }
 
+// validate prints all errors found in the managed instance group configuration and returns the exit code to use
+func validate(ctx context.Context, configSource ConfigSource) int {
+
+	data, err := configSource.Read(ctx)
+	if err != nil {
+		fmt.Printf("Reading managed instance group configuration failed: %v\n", err)
+		return 1
+	}
+	config, err := UnmarshalConfig(data)
+	if err != nil {
+		fmt.Printf("Reading managed instance group configuration failed: %v\n", err)
+		return 1
+	}
+
+	err = ValidateConfig(config)
+	if validationErrors, ok := err.(ValidationErrors); ok {
+		locateValidationErrors(validationErrors, config.MIGs, sourceFiles(configSource, data))
+		for _, validationError := range validationErrors {
+			fmt.Println(validationError.Error())
+		}
+		fmt.Printf("Configuration for %v managed instance groups and %v discovery rules has %v errors\n", len(config.MIGs), len(config.Discovery), len(validationErrors))
+		return 1
+	}
+
+	fmt.Printf("Configuration for %v managed instance groups and %v discovery rules is valid\n", len(config.MIGs), len(config.Discovery))
+	return 0
+}
+
 func applyJitter(input int) (output int) {
 
 	deviation := int(0.25 * float64(input))
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// MaintenanceWindow is a period during which the autoscaler of a managed instance group isn't updated; either a one-off period between start and end, or every minute matching a cron expression
+type MaintenanceWindow struct {
+	Start    string `json:"start,omitempty"`
+	End      string `json:"end,omitempty"`
+	Cron     string `json:"cron,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// Active returns whether the time falls within the maintenance window
+func (w MaintenanceWindow) Active(c *MIGConfiguration, now time.Time) bool {
+
+	if w.Cron != "" {
+		cron, err := ParseCronExpression(w.Cron)
+		if err != nil {
+			return false
+		}
+		location, err := w.location(c)
+		if err != nil {
+			return false
+		}
+		return cron.Matches(now.In(location))
+	}
+
+	start, err := time.Parse(time.RFC3339, w.Start)
+	if err != nil {
+		return false
+	}
+	end, err := time.Parse(time.RFC3339, w.End)
+	if err != nil {
+		return false
+	}
+
+	return !now.Before(start) && now.Before(end)
+}
+
+// location returns the timezone the cron expression of the maintenance window is in, which defaults to the timezone of the managed instance group
+func (w MaintenanceWindow) location(c *MIGConfiguration) (*time.Location, error) {
+	if w.Timezone != "" {
+		return loadTimezone(w.Timezone)
+	}
+	return c.Location()
+}
+
+// InMaintenanceWindow returns whether any of the maintenance windows of the managed instance group is active
+func (c *MIGConfiguration) InMaintenanceWindow(now time.Time) bool {
+	for _, window := range c.MaintenanceWindows {
+		if window.Active(c, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateMaintenanceWindows checks that every maintenance window has either a valid cron expression or a valid start and end
+func (c *MIGConfiguration) validateMaintenanceWindows(addError func(field, message string)) {
+	for i, window := range c.MaintenanceWindows {
+		field := fmt.Sprintf("maintenanceWindows[%v]", i)
+
+		if window.Cron != "" {
+			if window.Start != "" || window.End != "" {
+				addError(field, "cron and start/end are mutually exclusive")
+			}
+			if _, err := ParseCronExpression(window.Cron); err != nil {
+				addError(field+".cron", err.Error())
+			}
+			if _, err := loadTimezone(window.Timezone); err != nil {
+				addError(field+".timezone", err.Error())
+			}
+			continue
+		}
+
+		start, err := time.Parse(time.RFC3339, window.Start)
+		if err != nil {
+			addError(field+".start", "should be a RFC3339 time, like 2020-06-05T18:00:00Z")
+			continue
+		}
+		end, err := time.Parse(time.RFC3339, window.End)
+		if err != nil {
+			addError(field+".end", "should be a RFC3339 time, like 2020-06-05T21:00:00Z")
+			continue
+		}
+		if !end.After(start) {
+			addError(field+".end", "should be after start")
+		}
+	}
+}
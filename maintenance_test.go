@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInMaintenanceWindow(t *testing.T) {
+
+	configItem := MIGConfiguration{
+		Timezone: "Europe/Amsterdam",
+		MaintenanceWindows: []MaintenanceWindow{
+			MaintenanceWindow{Start: "2020-06-05T18:00:00Z", End: "2020-06-05T21:00:00Z"},
+			MaintenanceWindow{Cron: "* 2-3 * * 0"},
+		},
+	}
+
+	t.Run("ReturnsTrueBetweenStartAndEnd", func(t *testing.T) {
+
+		// act
+		inMaintenanceWindow := configItem.InMaintenanceWindow(time.Date(2020, 6, 5, 20, 59, 0, 0, time.UTC))
+
+		assert.True(t, inMaintenanceWindow)
+	})
+
+	t.Run("ReturnsTrueForMatchingCronInTimezone", func(t *testing.T) {
+
+		// act
+		inMaintenanceWindow := configItem.InMaintenanceWindow(time.Date(2020, 6, 7, 0, 30, 0, 0, time.UTC))
+
+		assert.True(t, inMaintenanceWindow)
+	})
+
+	t.Run("ReturnsFalseOutsideWindows", func(t *testing.T) {
+
+		// act
+		inMaintenanceWindow := configItem.InMaintenanceWindow(time.Date(2020, 6, 5, 21, 0, 0, 0, time.UTC))
+
+		assert.False(t, inMaintenanceWindow)
+	})
+
+	t.Run("ReturnsTrueForMatchingCronInTimezoneOfWindow", func(t *testing.T) {
+
+		configItem := MIGConfiguration{
+			Timezone:           "Europe/Amsterdam",
+			MaintenanceWindows: []MaintenanceWindow{MaintenanceWindow{Cron: "* 2-3 * * 0", Timezone: "UTC"}},
+		}
+
+		// act
+		inMaintenanceWindow := configItem.InMaintenanceWindow(time.Date(2020, 6, 7, 2, 30, 0, 0, time.UTC))
+
+		assert.True(t, inMaintenanceWindow)
+	})
+}
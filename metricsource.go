@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	prometheusMetricSource      = "prometheus"
+	cloudMonitoringMetricSource = "cloudmonitoring"
+	datadogMetricSource         = "datadog"
+	cloudWatchMetricSource      = "cloudwatch"
+	newRelicMetricSource        = "newrelic"
+	elasticsearchMetricSource   = "elasticsearch"
+	lokiMetricSource            = "loki"
+	kafkaMetricSource           = "kafka"
+	rabbitMQMetricSource        = "rabbitmq"
+	pubSubMetricSource          = "pubsub"
+	cloudTasksMetricSource      = "cloudtasks"
+	bigQueryMetricSource        = "bigquery"
+	sqlMetricSource             = "sql"
+	httpJSONMetricSource        = "http"
+	execMetricSource            = "exec"
+)
+
+// MetricSource retrieves the current request rate for a managed instance group by executing its request rate query
+type MetricSource interface {
+	GetRequestRate(ctx context.Context, configItem MIGConfiguration) (float64, error)
+}
+
+// metricSourceValidators maps the supported metricSource values to a check of the configuration fields the source needs
+var metricSourceValidators = map[string]func(c *MIGConfiguration, addError func(field, message string)){
+	prometheusMetricSource:      validatePrometheusConfig,
+	cloudMonitoringMetricSource: requireRequestRateQuery(nil),
+	datadogMetricSource:         requireRequestRateQuery(nil),
+	cloudWatchMetricSource:      requireRequestRateQuery(nil),
+	newRelicMetricSource:        requireRequestRateQuery(nil),
+	elasticsearchMetricSource:   requireRequestRateQuery(ValidateJSONQuery),
+	lokiMetricSource:            requireRequestRateQuery(CheckPromQLBrackets),
+	kafkaMetricSource:           validateKafkaConfig,
+	rabbitMQMetricSource:        validateRabbitMQConfig,
+	pubSubMetricSource:          validatePubSubConfig,
+	cloudTasksMetricSource:      validateCloudTasksConfig,
+	bigQueryMetricSource:        requireRequestRateQuery(nil),
+	sqlMetricSource:             validateSQLConfig,
+	httpJSONMetricSource:        validateHTTPJSONConfig,
+	execMetricSource:            validateExecConfig,
+}
+
+// requireRequestRateQuery returns a validator for sources that execute requestRateQuery, checking its syntax with validateQuery if set
+func requireRequestRateQuery(validateQuery func(query string) error) func(c *MIGConfiguration, addError func(field, message string)) {
+	return func(c *MIGConfiguration, addError func(field, message string)) {
+		if c.RequestRateQuery == "" {
+			addError("requestRateQuery", "is required")
+		} else if validateQuery != nil {
+			if err := validateQuery(c.RequestRateQuery); err != nil {
+				addError("requestRateQuery", err.Error())
+			}
+		}
+	}
+}
+
+// MetricSourceName returns the name of the metric source to use for the managed instance group; it defaults to prometheus when metricSource isn't set
+func (c *MIGConfiguration) MetricSourceName() string {
+	if c.MetricSource == "" {
+		return prometheusMetricSource
+	}
+	return c.MetricSource
+}
+
+// GetMetricSource returns the metric source the managed instance group's request rate query should be executed against
+func GetMetricSource(metricSources map[string]MetricSource, configItem MIGConfiguration) (MetricSource, error) {
+	metricSource, ok := metricSources[configItem.MetricSourceName()]
+	if !ok {
+		return nil, fmt.Errorf("Metric source %v is not available", configItem.MetricSourceName())
+	}
+	return metricSource, nil
+}
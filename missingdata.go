@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+const (
+	// holdLastValueMissingDataPolicy uses the last request rate retrieved for the managed instance group
+	holdLastValueMissingDataPolicy = "holdLastValue"
+
+	// useConfiguredMinimumMissingDataPolicy sets the minimum number of instances to minimumNumberOfInstances
+	useConfiguredMinimumMissingDataPolicy = "useConfiguredMinimum"
+
+	// useFallbackRateMissingDataPolicy uses missingDataFallbackRate as request rate
+	useFallbackRateMissingDataPolicy = "useFallbackRate"
+)
+
+// missingDataPolicies are the supported values for missingDataPolicy
+var missingDataPolicies = []string{holdLastValueMissingDataPolicy, useConfiguredMinimumMissingDataPolicy, useFallbackRateMissingDataPolicy}
+
+// MissingDataError is returned when a metric source responds, but without a usable value, like an empty result, a NaN or Inf value or a stale sample
+type MissingDataError struct {
+	Reason string
+}
+
+func (e MissingDataError) Error() string {
+	return e.Reason
+}
+
+// errEmptyResponse is returned by metric sources for a query without results
+var errEmptyResponse = MissingDataError{Reason: "Empty response"}
+
+// IsMissingData returns whether the error, or any error it wraps, is a MissingDataError
+func IsMissingData(err error) bool {
+	var missingDataError MissingDataError
+	return errors.As(err, &missingDataError)
+}
+
+// checkRequestRateValue returns a MissingDataError for NaN and Inf request rates
+func checkRequestRateValue(requestRate float64) error {
+	if math.IsNaN(requestRate) || math.IsInf(requestRate, 0) {
+		return MissingDataError{Reason: fmt.Sprintf("Request rate %v is not a number", requestRate)}
+	}
+	return nil
+}
+
+// getRequestRateForMissingData returns the request rate to use according to missingDataPolicy when the request rate query returned no usable value
+func (s *MIGScaler) getRequestRateForMissingData(configItem MIGConfiguration, missingDataErr error) (float64, error) {
+
+	switch configItem.MissingDataPolicy {
+	case holdLastValueMissingDataPolicy:
+		if requestRate, ok := s.lastRequestRate(configItem); ok {
+			return requestRate, nil
+		}
+		return 0, fmt.Errorf("%v and no earlier request rate to hold", missingDataErr)
+
+	case useConfiguredMinimumMissingDataPolicy:
+		return 0, nil
+
+	case useFallbackRateMissingDataPolicy:
+		return *configItem.MissingDataFallbackRate, nil
+	}
+
+	return 0, missingDataErr
+}
+
+// validateMissingDataPolicy checks missingDataPolicy and the fallback rate it needs
+func (c *MIGConfiguration) validateMissingDataPolicy(addError func(field, message string)) {
+
+	switch c.MissingDataPolicy {
+	case "", holdLastValueMissingDataPolicy, useConfiguredMinimumMissingDataPolicy:
+	case useFallbackRateMissingDataPolicy:
+		if c.MissingDataFallbackRate == nil {
+			addError("missingDataFallbackRate", "is required for missingDataPolicy useFallbackRate")
+		} else if *c.MissingDataFallbackRate < 0 {
+			addError("missingDataFallbackRate", "should be 0 or larger")
+		}
+	default:
+		addError("missingDataPolicy", fmt.Sprintf("should be one of %v", missingDataPolicies))
+	}
+}
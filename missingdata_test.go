@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsMissingData(t *testing.T) {
+
+	t.Run("ReturnsTrueForWrappedEmptyResponse", func(t *testing.T) {
+
+		err := fmt.Errorf("Fallback 0 failed: %w", errEmptyResponse)
+
+		// act
+		missingData := IsMissingData(err)
+
+		assert.True(t, missingData)
+	})
+
+	t.Run("ReturnsFalseForOtherErrors", func(t *testing.T) {
+
+		// act
+		missingData := IsMissingData(errors.New("connection refused"))
+
+		assert.False(t, missingData)
+	})
+
+	t.Run("ReturnsTrueForNaNRequestRate", func(t *testing.T) {
+
+		metricSources := map[string]MetricSource{
+			"prometheus": &fakeMetricSource{requestRates: map[string]float64{"api": math.NaN()}},
+		}
+
+		// act
+		_, err := getSingleRequestRate(context.Background(), metricSources, MIGConfiguration{RequestRateQuery: "api"})
+
+		assert.True(t, IsMissingData(err))
+	})
+}
+
+func TestGetRequestRateForMissingData(t *testing.T) {
+
+	t.Run("ReturnsLastRequestRateForHoldLastValue", func(t *testing.T) {
+
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+		scaler.setLastRequestRate(MIGConfiguration{InstanceGroupName: "instance-group-name"}, 120)
+
+		// act
+		requestRate, err := scaler.getRequestRateForMissingData(MIGConfiguration{InstanceGroupName: "instance-group-name", MissingDataPolicy: "holdLastValue"}, errEmptyResponse)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 120.0, requestRate)
+	})
+
+	t.Run("ReturnsErrorForHoldLastValueWithoutEarlierRequestRate", func(t *testing.T) {
+
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+
+		// act
+		_, err := scaler.getRequestRateForMissingData(MIGConfiguration{InstanceGroupName: "instance-group-name", MissingDataPolicy: "holdLastValue"}, errEmptyResponse)
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("ReturnsFallbackRateForUseFallbackRate", func(t *testing.T) {
+
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+
+		// act
+		fallbackRate := 250.0
+		requestRate, err := scaler.getRequestRateForMissingData(MIGConfiguration{MissingDataPolicy: "useFallbackRate", MissingDataFallbackRate: &fallbackRate}, errEmptyResponse)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 250.0, requestRate)
+	})
+}
+
+func TestValidateMissingDataPolicy(t *testing.T) {
+
+	t.Run("ReturnsErrorForUseFallbackRateWithoutFallbackRate", func(t *testing.T) {
+
+		configItem := MIGConfiguration{MissingDataPolicy: "useFallbackRate"}
+		fields := []string{}
+
+		// act
+		configItem.validateMissingDataPolicy(func(field, message string) {
+			fields = append(fields, field)
+		})
+
+		assert.Equal(t, []string{"missingDataFallbackRate"}, fields)
+	})
+
+	t.Run("ReturnsNilForUseFallbackRateWithFallbackRateOfZero", func(t *testing.T) {
+
+		fallbackRate := 0.0
+		configItem := MIGConfiguration{MissingDataPolicy: "useFallbackRate", MissingDataFallbackRate: &fallbackRate}
+		fields := []string{}
+
+		// act
+		configItem.validateMissingDataPolicy(func(field, message string) {
+			fields = append(fields, field)
+		})
+
+		assert.Equal(t, []string{}, fields)
+	})
+}
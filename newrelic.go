@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/sethgrid/pester"
+)
+
+// NewRelicMetricSource retrieves request rates by executing NRQL queries against the New Relic Insights query api
+type NewRelicMetricSource struct {
+	InsightsURL string
+	AccountID   string
+	APIKey      string
+}
+
+// NewRelicQueryResponse is used to unmarshal the response of an NRQL query; every result is an object with the name of the aggregate function as key
+type NewRelicQueryResponse struct {
+	Results []map[string]interface{} `json:"results"`
+	Error   string                   `json:"error"`
+}
+
+// GetRequestRate executes the request rate query as NRQL, like SELECT rate(count(*), 1 second) FROM Transaction WHERE appName = 'a' SINCE 10 minutes ago
+func (s *NewRelicMetricSource) GetRequestRate(ctx context.Context, configItem MIGConfiguration) (float64, error) {
+
+	if s.AccountID == "" || s.APIKey == "" {
+		return 0, errors.New("Querying New Relic requires --newrelic-account-id and --newrelic-api-key")
+	}
+
+	queryURL := fmt.Sprintf("%v/v1/accounts/%v/query?nrql=%v", s.InsightsURL, s.AccountID, url.QueryEscape(configItem.RequestRateQuery))
+
+	request, err := http.NewRequest(http.MethodGet, queryURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Accept", "application/json")
+	request.Header.Set("X-Query-Key", s.APIKey)
+
+	resp, err := pester.Do(request.WithContext(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("Executing new relic query failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("Reading new relic query response body failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("New relic query returned status code %v: %v", resp.StatusCode, string(body))
+	}
+
+	var queryResponse NewRelicQueryResponse
+	if err = json.Unmarshal(body, &queryResponse); err != nil {
+		return 0, fmt.Errorf("Unmarshalling new relic query response body failed: %v", err)
+	}
+
+	return queryResponse.GetRequestRate()
+}
+
+// GetRequestRate returns the single numeric value of the first result
+func (r *NewRelicQueryResponse) GetRequestRate() (float64, error) {
+
+	if r.Error != "" {
+		return 0, fmt.Errorf("New relic query failed: %v", r.Error)
+	}
+	if len(r.Results) == 0 {
+		return 0, errEmptyResponse
+	}
+
+	values := []float64{}
+	for _, value := range r.Results[0] {
+		if number, ok := value.(float64); ok {
+			values = append(values, number)
+		}
+	}
+	if len(values) != 1 {
+		return 0, fmt.Errorf("New relic query should select exactly one numeric value, but returned %v", len(values))
+	}
+
+	return values[0], nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRelicMetricSourceGetRequestRate(t *testing.T) {
+
+	t.Run("ReturnsNumericValueOfFirstResult", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/accounts/12345/query", r.URL.Path)
+			assert.Equal(t, "api-key", r.Header.Get("X-Query-Key"))
+			assert.Equal(t, "SELECT rate(count(*), 1 second) FROM Transaction SINCE 10 minutes ago", r.URL.Query().Get("nrql"))
+			w.Write([]byte(`{"results":[{"result":225.4}]}`))
+		}))
+		defer server.Close()
+
+		source := &NewRelicMetricSource{InsightsURL: server.URL, AccountID: "12345", APIKey: "api-key"}
+
+		// act
+		requestRate, err := source.GetRequestRate(context.Background(), MIGConfiguration{RequestRateQuery: "SELECT rate(count(*), 1 second) FROM Transaction SINCE 10 minutes ago"})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 225.4, requestRate)
+	})
+
+	t.Run("ReturnsErrorIfResultHasMultipleValues", func(t *testing.T) {
+
+		queryResponse := NewRelicQueryResponse{
+			Results: []map[string]interface{}{
+				map[string]interface{}{"count": 100.0, "average": 2.5},
+			},
+		}
+
+		// act
+		_, err := queryResponse.GetRequestRate()
+
+		assert.NotNil(t, err)
+	})
+}
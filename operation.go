@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	compute "google.golang.org/api/compute/v1"
+)
+
+const (
+	operationDoneStatus = "DONE"
+
+	succeededOperationResult = "succeeded"
+	failedOperationResult    = "failed"
+	unknownOperationResult   = "unknown"
+)
+
+// waitForOperation polls the zonal or regional operation of the managed instance group until it's done or the context is cancelled
+func (s *MIGScaler) waitForOperation(ctx context.Context, configItem MIGConfiguration, operation *compute.Operation) (*compute.Operation, error) {
+
+	computeClient, err := s.computeClientFor(ctx, configItem)
+	if err != nil {
+		return nil, err
+	}
+
+	pollInterval := s.options.OperationPoll
+	if pollInterval <= 0 {
+		pollInterval = 2 * time.Second
+	}
+
+	for operation.Status != operationDoneStatus {
+		select {
+		case <-ctx.Done():
+			return operation, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+
+		operation, err = computeClient.GetOperation(ctx, configItem, operation.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return operation, nil
+}
+
+// OperationError returns the errors a done operation failed with combined in a single error, or nil if it succeeded
+func OperationError(operation *compute.Operation) error {
+
+	if operation.Error == nil || len(operation.Error.Errors) == 0 {
+		return nil
+	}
+
+	messages := []string{}
+	for _, e := range operation.Error.Errors {
+		messages = append(messages, fmt.Sprintf("%v: %v", e.Code, e.Message))
+	}
+
+	return fmt.Errorf("Operation %v failed: %v", operation.Name, strings.Join(messages, "; "))
+}
+
+// awaitAutoscalerUpdate waits for the update operation of the autoscaler to complete, logs its warnings and errors and counts its result; it returns whether the update succeeded
+func (s *MIGScaler) awaitAutoscalerUpdate(ctx context.Context, configItem MIGConfiguration, operation *compute.Operation) bool {
+
+	operation, err := s.waitForOperation(ctx, configItem, operation)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Waiting for autoscaler update of mig %v to complete failed, its result is unknown", configItem.InstanceGroupName)
+		autoscalerUpdatesVector.WithLabelValues(configItem.InstanceGroupName, unknownOperationResult).Inc()
+		return false
+	}
+
+	for _, warning := range operation.Warnings {
+		log.Warn().Str("code", warning.Code).Msgf("Autoscaler update of mig %v completed with warning: %v", configItem.InstanceGroupName, warning.Message)
+	}
+
+	if err := OperationError(operation); err != nil {
+		log.Error().Err(err).Msgf("Updating autoscaler %v failed", configItem.InstanceGroupName)
+		autoscalerUpdatesVector.WithLabelValues(configItem.InstanceGroupName, failedOperationResult).Inc()
+		return false
+	}
+
+	autoscalerUpdatesVector.WithLabelValues(configItem.InstanceGroupName, succeededOperationResult).Inc()
+
+	return true
+}
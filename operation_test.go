@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	compute "google.golang.org/api/compute/v1"
+)
+
+func TestOperationError(t *testing.T) {
+
+	t.Run("ReturnsNilIfOperationHasNoErrors", func(t *testing.T) {
+
+		// act
+		err := OperationError(&compute.Operation{Name: "operation-1", Status: "DONE"})
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("ReturnsAllErrorsOfOperation", func(t *testing.T) {
+
+		operation := &compute.Operation{
+			Name:   "operation-1",
+			Status: "DONE",
+			Error: &compute.OperationError{
+				Errors: []*compute.OperationErrorErrors{
+					{Code: "INVALID_FIELD_VALUE", Message: "Invalid value for field 'resource.autoscalingPolicy.minNumReplicas'"},
+					{Code: "QUOTA_EXCEEDED", Message: "Quota 'CPUS' exceeded"},
+				},
+			},
+		}
+
+		// act
+		err := OperationError(operation)
+
+		assert.EqualError(t, err, "Operation operation-1 failed: INVALID_FIELD_VALUE: Invalid value for field 'resource.autoscalingPolicy.minNumReplicas'; QUOTA_EXCEEDED: Quota 'CPUS' exceeded")
+	})
+}
+
+func TestWaitForOperation(t *testing.T) {
+
+	t.Run("PollsRegionalOperationUntilDone", func(t *testing.T) {
+
+		polls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/project-id/regions/europe-west1/operations/operation-1", r.URL.Path)
+			polls++
+			if polls < 3 {
+				w.Write([]byte(`{"name":"operation-1","status":"RUNNING"}`))
+				return
+			}
+			w.Write([]byte(`{"name":"operation-1","status":"DONE"}`))
+		}))
+		defer server.Close()
+
+		computeClient, _ := NewGAComputeClient(server.Client(), ComputeClientOptions{BasePath: server.URL + "/"})
+		scaler := NewMIGScaler(computeClient, nil, MIGScalerOptions{OperationPoll: time.Millisecond})
+		configItem := MIGConfiguration{GCloudProject: "project-id", GCloudRegion: "europe-west1"}
+
+		// act
+		operation, err := scaler.waitForOperation(context.Background(), configItem, &compute.Operation{Name: "operation-1", Status: "PENDING"})
+
+		assert.Nil(t, err)
+		assert.Equal(t, "DONE", operation.Status)
+		assert.Equal(t, 3, polls)
+	})
+
+	t.Run("ReturnsErrorIfContextIsCancelledBeforeOperationIsDone", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"name":"operation-1","status":"RUNNING"}`))
+		}))
+		defer server.Close()
+
+		computeClient, _ := NewGAComputeClient(server.Client(), ComputeClientOptions{BasePath: server.URL + "/"})
+		scaler := NewMIGScaler(computeClient, nil, MIGScalerOptions{OperationPoll: time.Millisecond})
+		configItem := MIGConfiguration{GCloudProject: "project-id", GCloudZone: "europe-west1-b"}
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		// act
+		_, err := scaler.waitForOperation(ctx, configItem, &compute.Operation{Name: "operation-1", Status: "PENDING"})
+
+		assert.NotNil(t, err)
+	})
+}
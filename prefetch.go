@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	compute "google.golang.org/api/compute/v1"
+)
+
+type prefetchedInstanceGroupManagersContextKey struct{}
+
+// instanceGroupManagerLocation is a project and zone or region whose instance group managers are listed at once, with the credentials of the managed instance groups in it
+type instanceGroupManagerLocation struct {
+	configItem MIGConfiguration
+	names      map[string]bool
+}
+
+// groupByLocation groups the managed instance groups, and the upstream migs they follow, by project, zone or region and credentials
+func (s *MIGScaler) groupByLocation(configItems []MIGConfiguration) map[string]*instanceGroupManagerLocation {
+
+	locations := map[string]*instanceGroupManagerLocation{}
+	add := func(configItem MIGConfiguration) {
+		key := configItem.GCloudProject + "/" + configItem.GCloudZone + configItem.GCloudRegion
+		if configItem.HasOwnCredentials() {
+			key += "/" + configItem.credentialsKey()
+		}
+
+		location, ok := locations[key]
+		if !ok {
+			location = &instanceGroupManagerLocation{
+				configItem: MIGConfiguration{
+					GCloudProject:     configItem.GCloudProject,
+					GCloudZone:        configItem.GCloudZone,
+					GCloudRegion:      configItem.GCloudRegion,
+					CredentialsFile:   configItem.CredentialsFile,
+					CredentialsSecret: configItem.CredentialsSecret,
+				},
+				names: map[string]bool{},
+			}
+			locations[key] = location
+		}
+		location.names[configItem.InstanceGroupName] = true
+	}
+
+	for _, configItem := range configItems {
+		if !configItem.IsEnabled() {
+			continue
+		}
+		add(configItem)
+		if configItem.UpstreamMIG != "" {
+			add(configItem.UpstreamConfig())
+		}
+	}
+
+	return locations
+}
+
+// PrefetchInstanceGroupManagers lists the instance group managers of every location with more than one of the managed instance groups at once, for up to ComputeConcurrency locations in parallel, and returns a context holding them for the rest of the iteration; migs in other locations, or in locations that fail to list, are retrieved one by one as before
+func (s *MIGScaler) PrefetchInstanceGroupManagers(ctx context.Context, configItems []MIGConfiguration) context.Context {
+
+	if s.options.ComputeConcurrency <= 0 {
+		return ctx
+	}
+
+	prefetched := map[string]*compute.InstanceGroupManager{}
+	var prefetchedMu sync.Mutex
+
+	var waitGroup sync.WaitGroup
+	semaphore := make(chan struct{}, s.options.ComputeConcurrency)
+
+	for _, location := range s.groupByLocation(configItems) {
+
+		// a single mig is retrieved just as well by itself, without listing all others in its location
+		if len(location.names) < 2 {
+			continue
+		}
+
+		waitGroup.Add(1)
+		semaphore <- struct{}{}
+		go func(location *instanceGroupManagerLocation) {
+			defer waitGroup.Done()
+			defer func() { <-semaphore }()
+
+			instanceGroupManagers, err := s.listInstanceGroupManagers(ctx, location.configItem)
+			if err != nil {
+				log.Warn().Err(err).Msgf("Listing instance group managers in %v%v of project %v failed, retrieving them one by one", location.configItem.GCloudZone, location.configItem.GCloudRegion, location.configItem.GCloudProject)
+				return
+			}
+
+			prefetchedMu.Lock()
+			defer prefetchedMu.Unlock()
+
+			for _, instanceGroupManager := range instanceGroupManagers {
+				if !location.names[instanceGroupManager.Name] {
+					continue
+				}
+				configItem := location.configItem
+				configItem.InstanceGroupName = instanceGroupManager.Name
+
+				prefetched[instanceGroupManagerKey(configItem)] = instanceGroupManager
+			}
+		}(location)
+	}
+
+	waitGroup.Wait()
+
+	log.Debug().Msgf("Prefetched %v instance group managers", len(prefetched))
+
+	return context.WithValue(ctx, prefetchedInstanceGroupManagersContextKey{}, prefetched)
+}
+
+// listInstanceGroupManagers lists all instance group managers in the location within the compute timeout
+func (s *MIGScaler) listInstanceGroupManagers(ctx context.Context, location MIGConfiguration) ([]*compute.InstanceGroupManager, error) {
+
+	ctx, cancel := withTimeout(ctx, s.options.ComputeTimeout)
+	defer cancel()
+
+	computeClient, err := s.computeClientFor(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+
+	return computeClient.ListInstanceGroupManagers(ctx, location)
+}
+
+// prefetchedInstanceGroupManager returns the instance group manager of the managed instance group if it was prefetched in this iteration
+func prefetchedInstanceGroupManager(ctx context.Context, configItem MIGConfiguration) (*compute.InstanceGroupManager, bool) {
+
+	prefetched, ok := ctx.Value(prefetchedInstanceGroupManagersContextKey{}).(map[string]*compute.InstanceGroupManager)
+	if !ok {
+		return nil, false
+	}
+
+	instanceGroupManager, ok := prefetched[instanceGroupManagerKey(configItem)]
+
+	return instanceGroupManager, ok
+}
+
+// instanceGroupManagerKey identifies the instance group manager of the managed instance group by project, zone or region and name
+func instanceGroupManagerKey(configItem MIGConfiguration) string {
+	return fmt.Sprintf("%v/%v%v/instanceGroupManagers/%v", configItem.GCloudProject, configItem.GCloudZone, configItem.GCloudRegion, configItem.InstanceGroupName)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupByLocation(t *testing.T) {
+
+	t.Run("GroupsByProjectZoneOrRegionAndCredentialsIncludingUpstreamMigs", func(t *testing.T) {
+
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+		disabled := false
+		configItems := []MIGConfiguration{
+			{GCloudProject: "project-id", GCloudZone: "europe-west1-b", InstanceGroupName: "web"},
+			{GCloudProject: "project-id", GCloudZone: "europe-west1-b", InstanceGroupName: "api", UpstreamMIG: "gateway"},
+			{GCloudProject: "project-id", GCloudZone: "europe-west1-b", InstanceGroupName: "batch", CredentialsFile: "/secrets/batch.json"},
+			{GCloudProject: "project-id", GCloudRegion: "europe-west1", InstanceGroupName: "web"},
+			{GCloudProject: "project-id", GCloudZone: "europe-west1-b", InstanceGroupName: "legacy", Enabled: &disabled},
+		}
+
+		// act
+		locations := scaler.groupByLocation(configItems)
+
+		if assert.Equal(t, 3, len(locations)) {
+			assert.Equal(t, map[string]bool{"web": true, "api": true, "gateway": true}, locations["project-id/europe-west1-b"].names)
+			assert.Equal(t, map[string]bool{"batch": true}, locations["project-id/europe-west1-b/file:/secrets/batch.json"].names)
+			assert.Equal(t, "/secrets/batch.json", locations["project-id/europe-west1-b/file:/secrets/batch.json"].configItem.CredentialsFile)
+			assert.Equal(t, map[string]bool{"web": true}, locations["project-id/europe-west1"].names)
+		}
+	})
+}
+
+func TestPrefetchInstanceGroupManagers(t *testing.T) {
+
+	configItems := []MIGConfiguration{
+		{GCloudProject: "project-id", GCloudZone: "europe-west1-b", InstanceGroupName: "web"},
+		{GCloudProject: "project-id", GCloudZone: "europe-west1-b", InstanceGroupName: "api"},
+		{GCloudProject: "project-id", GCloudRegion: "europe-west1", InstanceGroupName: "batch"},
+	}
+
+	t.Run("ListsLocationsWithMultipleMigsOnceAndServesTheirInstanceGroupManagers", func(t *testing.T) {
+
+		var requestsMu sync.Mutex
+		requests := []string{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestsMu.Lock()
+			requests = append(requests, r.URL.Path)
+			requestsMu.Unlock()
+			w.Write([]byte(`{"items":[{"name":"web","targetSize":3},{"name":"api","targetSize":5},{"name":"other","targetSize":1}]}`))
+		}))
+		defer server.Close()
+
+		computeClient, _ := NewGAComputeClient(server.Client(), ComputeClientOptions{BasePath: server.URL + "/"})
+		scaler := NewMIGScaler(computeClient, nil, MIGScalerOptions{ComputeConcurrency: 2})
+
+		// act
+		ctx := scaler.PrefetchInstanceGroupManagers(context.Background(), configItems)
+
+		webInstanceGroupManager, err := scaler.getInstanceGroupManager(ctx, configItems[0])
+		assert.Nil(t, err)
+		apiInstanceGroupManager, err := scaler.getInstanceGroupManager(ctx, configItems[1])
+		assert.Nil(t, err)
+
+		assert.Equal(t, int64(3), webInstanceGroupManager.TargetSize)
+		assert.Equal(t, int64(5), apiInstanceGroupManager.TargetSize)
+		assert.Equal(t, []string{"/project-id/zones/europe-west1-b/instanceGroupManagers"}, requests)
+	})
+
+	t.Run("ReturnsContextUnchangedWithoutComputeConcurrency", func(t *testing.T) {
+
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+		ctx := context.Background()
+
+		// act
+		prefetchCtx := scaler.PrefetchInstanceGroupManagers(ctx, configItems)
+
+		assert.Equal(t, ctx, prefetchCtx)
+	})
+
+	t.Run("RetrievesMigsOneByOneIfListingFails", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/project-id/zones/europe-west1-b/instanceGroupManagers" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`{"name":"web","targetSize":3}`))
+		}))
+		defer server.Close()
+
+		computeClient, _ := NewGAComputeClient(server.Client(), ComputeClientOptions{BasePath: server.URL + "/"})
+		scaler := NewMIGScaler(computeClient, nil, MIGScalerOptions{ComputeConcurrency: 2})
+
+		// act
+		ctx := scaler.PrefetchInstanceGroupManagers(context.Background(), configItems)
+		instanceGroupManager, err := scaler.getInstanceGroupManager(ctx, configItems[0])
+
+		assert.Nil(t, err)
+		assert.Equal(t, int64(3), instanceGroupManager.TargetSize)
+	})
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	weekdayProfile = "weekday"
+	weekendProfile = "weekend"
+	holidayProfile = "holiday"
+
+	holidayDateLayout = "2006-01-02"
+)
+
+// MIGProfiles are the fields to override per profile name
+type MIGProfiles map[string]map[string]interface{}
+
+// profileNames are the supported keys of profiles
+var profileNames = []string{weekdayProfile, weekendProfile, holidayProfile}
+
+// ProfileName returns the profile for the day the time falls on in the timezone of the managed instance group: holiday for dates in holidays, weekend for saturdays and sundays and weekday otherwise
+func (c *MIGConfiguration) ProfileName(now time.Time) string {
+
+	location, err := c.Location()
+	if err != nil {
+		location = time.UTC
+	}
+	local := now.In(location)
+
+	for _, holiday := range c.Holidays {
+		if holiday == local.Format(holidayDateLayout) {
+			return holidayProfile
+		}
+	}
+
+	if local.Weekday() == time.Saturday || local.Weekday() == time.Sunday {
+		return weekendProfile
+	}
+
+	return weekdayProfile
+}
+
+// ProfileConfig returns the configuration with the fields of the profile for the day the time falls on applied on top of it, or the configuration itself if it has no such profile
+func (c *MIGConfiguration) ProfileConfig(now time.Time) (MIGConfiguration, error) {
+
+	profileName := c.ProfileName(now)
+	profile, ok := c.Profiles[profileName]
+	if !ok {
+		return *c, nil
+	}
+
+	log.Debug().Msgf("Using %v profile for mig %v", profileName, c.InstanceGroupName)
+
+	base := *c
+	base.Profiles = nil
+
+	return base.merge(profile)
+}
+
+// validateProfiles checks the holiday dates, and the minimum number of instances, also against the maximums, and number of requests per instance of every profile
+func (c *MIGConfiguration) validateProfiles(addError func(field, message string)) {
+
+	for i, holiday := range c.Holidays {
+		if _, err := time.Parse(holidayDateLayout, holiday); err != nil {
+			addError(fmt.Sprintf("holidays[%v]", i), "should be a date like 2020-12-25")
+		}
+	}
+
+	for name, profile := range c.Profiles {
+		field := fmt.Sprintf("profiles.%v", name)
+
+		supported := false
+		for _, profileName := range profileNames {
+			supported = supported || name == profileName
+		}
+		if !supported {
+			addError(field, fmt.Sprintf("should be one of %v", profileNames))
+			continue
+		}
+
+		base := *c
+		base.Profiles = nil
+		profileConfig, err := base.merge(profile)
+		if err != nil {
+			addError(field, err.Error())
+			continue
+		}
+		if profileConfig.MinimumNumberOfInstances < 0 {
+			addError(field+".minimumNumberOfInstances", "should be 0 or larger")
+		}
+		if profileConfig.MaximumNumberOfInstances > 0 && profileConfig.MaximumNumberOfInstances < profileConfig.MinimumNumberOfInstances {
+			addError(field+".minimumNumberOfInstances", "should be smaller than or equal to maximumNumberOfInstances")
+		}
+		if profileConfig.MaxInstancesToSet > 0 && profileConfig.MaxInstancesToSet < profileConfig.MinimumNumberOfInstances {
+			addError(field+".minimumNumberOfInstances", "should be smaller than or equal to maximumNumberOfInstancesToSet")
+		}
+		if profileConfig.NumberOfRequestsPerInstance <= 0 && profileConfig.RequestsPerInstanceQuery == "" && profileConfig.ScalesOnRequestRate() {
+			addError(field+".numberOfRequestsPerInstance", "should be larger than 0")
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfileConfig(t *testing.T) {
+
+	configItem := MIGConfiguration{
+		InstanceGroupName:           "instance-group-name",
+		Timezone:                    "Europe/Amsterdam",
+		MinimumNumberOfInstances:    5,
+		NumberOfRequestsPerInstance: 10,
+		Holidays:                    []string{"2020-12-25"},
+		Profiles: MIGProfiles{
+			"weekend": map[string]interface{}{"numberOfRequestsPerInstance": 15, "minimumNumberOfInstances": 2},
+			"holiday": map[string]interface{}{"minimumNumberOfInstances": 1},
+		},
+	}
+
+	t.Run("UsesWeekendProfileOnSaturdayInTimezone", func(t *testing.T) {
+
+		// act
+		profileConfig, err := configItem.ProfileConfig(time.Date(2020, 6, 5, 23, 30, 0, 0, time.UTC))
+
+		assert.Nil(t, err)
+		assert.Equal(t, float64(15), profileConfig.NumberOfRequestsPerInstance)
+		assert.Equal(t, 2, profileConfig.MinimumNumberOfInstances)
+	})
+
+	t.Run("UsesHolidayProfileOnHolidayDate", func(t *testing.T) {
+
+		// act
+		profileConfig, err := configItem.ProfileConfig(time.Date(2020, 12, 25, 12, 0, 0, 0, time.UTC))
+
+		assert.Nil(t, err)
+		assert.Equal(t, float64(10), profileConfig.NumberOfRequestsPerInstance)
+		assert.Equal(t, 1, profileConfig.MinimumNumberOfInstances)
+	})
+
+	t.Run("ReturnsConfigurationItselfWithoutMatchingProfile", func(t *testing.T) {
+
+		// act
+		profileConfig, err := configItem.ProfileConfig(time.Date(2020, 6, 5, 12, 0, 0, 0, time.UTC))
+
+		assert.Nil(t, err)
+		assert.Equal(t, configItem, profileConfig)
+	})
+}
+
+func TestValidateProfiles(t *testing.T) {
+
+	t.Run("ReturnsErrorForProfileMinimumAboveMaximums", func(t *testing.T) {
+
+		configItem := MIGConfiguration{
+			MinimumNumberOfInstances:    5,
+			MaximumNumberOfInstances:    20,
+			MaxInstancesToSet:           15,
+			NumberOfRequestsPerInstance: 10,
+			Profiles: MIGProfiles{
+				"weekday": map[string]interface{}{"minimumNumberOfInstances": 10},
+				"weekend": map[string]interface{}{"minimumNumberOfInstances": 18},
+				"holiday": map[string]interface{}{"minimumNumberOfInstances": 25},
+			},
+		}
+		messages := []string{}
+
+		// act
+		configItem.validateProfiles(func(field, message string) {
+			messages = append(messages, field+" "+message)
+		})
+
+		assert.ElementsMatch(t, []string{
+			"profiles.weekend.minimumNumberOfInstances should be smaller than or equal to maximumNumberOfInstancesToSet",
+			"profiles.holiday.minimumNumberOfInstances should be smaller than or equal to maximumNumberOfInstances",
+			"profiles.holiday.minimumNumberOfInstances should be smaller than or equal to maximumNumberOfInstancesToSet",
+		}, messages)
+	})
+}
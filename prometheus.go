@@ -1,30 +1,253 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
 	"strconv"
-	"errors"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/rs/zerolog/log"
 )
 
+const (
+	// maxReplicaAggregation takes the highest value of the replicas that responded, so a replica with gaps in its data doesn't lower the request rate
+	maxReplicaAggregation = "max"
+
+	// quorumReplicaAggregation takes the highest value as well, but only if more than half of the replicas responded
+	quorumReplicaAggregation = "quorum"
+)
+
+// result types of the prometheus query api
+const (
+	scalarResultType = "scalar"
+	stringResultType = "string"
+	vectorResultType = "vector"
+	matrixResultType = "matrix"
+)
+
+// PrometheusMetricSource retrieves request rates by executing PromQL queries against a prometheus server
+type PrometheusMetricSource struct {
+	// Defaults are the connection settings used for managed instance groups that don't override them
+	Defaults PrometheusEndpoint
+}
+
+// GetRequestRate executes the request rate query for a managed instance group against prometheus
+func (s *PrometheusMetricSource) GetRequestRate(ctx context.Context, configItem MIGConfiguration) (requestRate float64, err error) {
+
+	// get request rate with prometheus query
+	// https://prometheus-production.travix.com/api/v1/query?query=sum%28rate%28nginx_http_requests_total%7Bhost%21~%22%5E%28%3F%3A%5B0-9.%5D%2B%29%24%22%2Clocation%3D%22%40searchfareapi_gcloud%22%7D%5B10m%5D%29%29%20by%20%28location%29
+	endpoint := s.endpoint(configItem)
+
+	replicaURLs := endpoint.replicaURLs()
+	if len(replicaURLs) <= 1 {
+		return executePrometheusQuery(ctx, endpoint, endpoint.URL, configItem)
+	}
+
+	return s.getReplicatedRequestRate(ctx, endpoint, replicaURLs, configItem)
+}
+
+// executePrometheusQuery executes the request rate query of a managed instance group against a single prometheus server, as an instant query or as a range query if queryType is range
+func executePrometheusQuery(ctx context.Context, endpoint PrometheusEndpoint, prometheusURL string, configItem MIGConfiguration) (float64, error) {
+
+	// queries for historical request rates are evaluated in the past instead of now
+	evaluationTime, historical := queryTime(ctx)
+
+	instantQueryURL := func(query string) string {
+		queryURL := fmt.Sprintf("%v/api/v1/query?query=%v", prometheusURL, url.QueryEscape(query))
+		if historical {
+			queryURL += fmt.Sprintf("&time=%v", evaluationTime.Unix())
+		}
+		return queryURL
+	}
+	prometheusQueryURL := instantQueryURL(configItem.RequestRateQuery)
+	if configItem.QueryType == rangeQueryType {
+		end := evaluationTime
+		start := end.Add(-time.Duration(configItem.RangeWindow()) * time.Second)
+		prometheusQueryURL = fmt.Sprintf("%v/api/v1/query_range?query=%v&start=%v&end=%v&step=%v", prometheusURL, url.QueryEscape(configItem.RequestRateQuery), start.Unix(), end.Unix(), configItem.RangeStep())
+	}
+
+	// identical queries of managed instance groups, like a shared query they select their series from, are only executed once per iteration
+	key := fmt.Sprintf("%v|%v|%v|%v|%v|%v", endpoint, prometheusURL, configItem.QueryType, configItem.RangeWindow(), configItem.RangeStep(), configItem.RequestRateQuery)
+	if historical {
+		key += fmt.Sprintf("|%v", evaluationTime.Unix())
+	}
+	queryResponse, err := cachedPrometheusQuery(ctx, key, func() (PrometheusQueryResponse, error) {
+		return executePrometheusQueryRequest(ctx, endpoint, prometheusQueryURL)
+	})
+	if err == nil && len(configItem.SeriesSelector) > 0 {
+		queryResponse, err = queryResponse.SelectSeries(configItem.SeriesSelector)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if configItem.MaxMetricAge() > 0 {
+		var sampleTime time.Time
+		if configItem.QueryType != rangeQueryType && queryResponse.Data.ResultType == vectorResultType {
+			// the samples of an instant vector carry the evaluation time instead of when they were scraped, so the age is taken from the timestamps of the series the query selects
+			sampleTime, err = getPrometheusSeriesSampleTime(ctx, endpoint, key, instantQueryURL, configItem, queryResponse.Data.Result[0].Metric)
+			if err != nil {
+				return 0, err
+			}
+		} else {
+			// empty responses are left to the request rate conversion to report
+			sampleTime, _ = queryResponse.LatestSampleTime()
+		}
+		if !sampleTime.IsZero() {
+			if err = checkSampleAge(sampleTime, configItem.MaxMetricAge(), evaluationTime); err != nil {
+				return 0, fmt.Errorf("Query (%v) returned stale data: %w", prometheusQueryURL, err)
+			}
+		}
+	}
+
+	// instant queries returning a range vector, like subqueries, use their latest sample unless rangeAggregation is set
+	if configItem.QueryType != rangeQueryType && (queryResponse.Data.ResultType != matrixResultType || configItem.RangeAggregation == "") {
+		requestRate, err := queryResponse.GetRequestRate()
+		if err != nil {
+			return 0, fmt.Errorf("Retrieving request rate from query (%v) response body failed: %w", prometheusQueryURL, err)
+		}
+		return requestRate, nil
+	}
+
+	values, err := queryResponse.GetRangeValues()
+	if err != nil {
+		return 0, fmt.Errorf("Retrieving values from range query (%v) response body failed: %w", prometheusQueryURL, err)
+	}
+
+	return AggregateRangeValues(values, configItem.RangeAggregation)
+}
+
+// getReplicatedRequestRate executes the request rate query against all replicas of a highly available prometheus concurrently and combines their values with the replica aggregation
+func (s *PrometheusMetricSource) getReplicatedRequestRate(ctx context.Context, endpoint PrometheusEndpoint, replicaURLs []string, configItem MIGConfiguration) (requestRate float64, err error) {
+
+	requestRates := make([]float64, len(replicaURLs))
+	errs := make([]error, len(replicaURLs))
+
+	var wg sync.WaitGroup
+	for i, replicaURL := range replicaURLs {
+		wg.Add(1)
+		go func(i int, replicaURL string) {
+			defer wg.Done()
+			requestRates[i], errs[i] = executePrometheusQuery(ctx, endpoint, replicaURL, configItem)
+		}(i, replicaURL)
+	}
+	wg.Wait()
+
+	responded := 0
+	for i := range replicaURLs {
+		if errs[i] != nil {
+			log.Warn().Err(errs[i]).Msgf("Querying prometheus replica %v for mig %v failed", replicaURLs[i], configItem.InstanceGroupName)
+			continue
+		}
+		if responded == 0 || requestRates[i] > requestRate {
+			requestRate = requestRates[i]
+		}
+		responded++
+	}
+
+	if responded == 0 {
+		return 0, fmt.Errorf("Querying all %v prometheus replicas failed, the first error was: %w", len(replicaURLs), errs[0])
+	}
+	if endpoint.ReplicaAggregation == quorumReplicaAggregation && responded*2 <= len(replicaURLs) {
+		return 0, fmt.Errorf("Only %v of %v prometheus replicas responded, which is not a quorum", responded, len(replicaURLs))
+	}
+
+	return requestRate, nil
+}
+
+// executePrometheusInstantQuery executes an instant query against an api compatible with the prometheus query api and returns the value of the first result
+func executePrometheusInstantQuery(ctx context.Context, endpoint PrometheusEndpoint, queryURL string) (requestRate float64, err error) {
+
+	queryResponse, err := cachedPrometheusQuery(ctx, fmt.Sprintf("%v|%v", endpoint, queryURL), func() (PrometheusQueryResponse, error) {
+		return executePrometheusQueryRequest(ctx, endpoint, queryURL)
+	})
+	if err != nil {
+		return
+	}
+
+	requestRate, err = queryResponse.GetRequestRate()
+	if err != nil {
+		return requestRate, fmt.Errorf("Retrieving request rate from query (%v) response body failed: %w", queryURL, err)
+	}
+
+	return
+}
+
+// executePrometheusQueryRequest sends a query to an api compatible with the prometheus query api and unmarshals its response
+func executePrometheusQueryRequest(ctx context.Context, endpoint PrometheusEndpoint, queryURL string) (queryResponse PrometheusQueryResponse, err error) {
+
+	resp, err := endpoint.query(ctx, queryURL)
+	if err != nil {
+		return queryResponse, fmt.Errorf("Executing prometheus query failed: %v", err)
+	}
+
+	defer resp.Body.Close()
+
+	body, err := endpoint.readResponseBody(resp)
+	if err != nil {
+		return queryResponse, fmt.Errorf("Reading prometheus query (%v) response body failed: %v", queryURL, err)
+	}
+
+	queryResponse, err = UnmarshalPrometheusQueryResponse(body)
+	if err != nil {
+		return queryResponse, fmt.Errorf("Unmarshalling prometheus query (%v) response body failed: %v", queryURL, err)
+	}
+
+	return
+}
+
 // PrometheusQueryResponseDataResult is used to unmarshal the response from a prometheus query
 // {"metric":{"location":"@searchfareapi_gcloud"},"value":[1513161148.757,"225.4068155675859"]}
 type PrometheusQueryResponseDataResult struct {
-	Metric interface{}   `json:"metric"`
-	Value  []interface{} `json:"value"`
+	Metric map[string]string `json:"metric"`
+	Value  []interface{}     `json:"value"`
+
+	// Values holds the samples of a series in a range query response
+	Values [][]interface{} `json:"values,omitempty"`
 }
 
 // PrometheusQueryResponseData is used to unmarshal the response from a prometheus query
 type PrometheusQueryResponseData struct {
 	ResultType string                              `json:"resultType"`
 	Result     []PrometheusQueryResponseDataResult `json:"result"`
+
+	// Scalar holds the timestamp and value of a scalar result, which isn't a list of series
+	Scalar []interface{} `json:"-"`
+}
+
+// UnmarshalJSON unmarshals the result as a single sample for scalar and string results and as a list of series otherwise
+func (d *PrometheusQueryResponseData) UnmarshalJSON(data []byte) error {
+
+	var raw struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	d.ResultType = raw.ResultType
+	if len(raw.Result) == 0 {
+		return nil
+	}
+	if raw.ResultType == scalarResultType || raw.ResultType == stringResultType {
+		return json.Unmarshal(raw.Result, &d.Scalar)
+	}
+
+	return json.Unmarshal(raw.Result, &d.Result)
 }
 
 // PrometheusQueryResponse is used to unmarshal the response from a prometheus query
 type PrometheusQueryResponse struct {
-	Status string                      `json:"status"`
-	Data   PrometheusQueryResponseData `json:"data"`
+	Status    string                      `json:"status"`
+	Data      PrometheusQueryResponseData `json:"data"`
+	ErrorType string                      `json:"errorType,omitempty"`
+	Error     string                      `json:"error,omitempty"`
 }
 
 //sum(rate(nginx_http_requests_total{host!~"^(?:[0-9.]+)$",location="@searchfareapi_gcloud"}[10m])) by (location)
@@ -43,12 +266,274 @@ func UnmarshalPrometheusQueryResponse(responseBody []byte) (queryResponse Promet
 	return
 }
 
-// GetRequestRate converts the string value into a float64
+// GetRequestRate converts the string value into a float64; for a vector result it's the value of the first series, for a matrix result the latest sample of the first series
 func (pqr *PrometheusQueryResponse) GetRequestRate() (f float64, err error) {
-	if len(pqr.Data.Result) > 0 {
-		f, err = strconv.ParseFloat(pqr.Data.Result[0].Value[1].(string), 64)
-	} else {
-		err = errors.New("Empty response")
+	switch pqr.Data.ResultType {
+	case scalarResultType:
+		return parsePrometheusSample(pqr.Data.Scalar)
+
+	case "", vectorResultType:
+		if len(pqr.Data.Result) == 0 {
+			return 0, errEmptyResponse
+		}
+		return parsePrometheusSample(pqr.Data.Result[0].Value)
+
+	case matrixResultType:
+		if len(pqr.Data.Result) == 0 || len(pqr.Data.Result[0].Values) == 0 {
+			return 0, errEmptyResponse
+		}
+		values := pqr.Data.Result[0].Values
+		return parsePrometheusSample(values[len(values)-1])
+	}
+
+	return 0, fmt.Errorf("Result type %v is not supported, the query should return a scalar, vector or matrix", pqr.Data.ResultType)
+}
+
+// SelectSeries returns the response with only the series that have all labels of the selector, for queries returning a series per managed instance group
+func (pqr PrometheusQueryResponse) SelectSeries(selector map[string]string) (PrometheusQueryResponse, error) {
+
+	if pqr.Data.ResultType != vectorResultType && pqr.Data.ResultType != matrixResultType {
+		return pqr, fmt.Errorf("Selecting series from result type %v is not supported", pqr.Data.ResultType)
+	}
+
+	result := []PrometheusQueryResponseDataResult{}
+	for _, series := range pqr.Data.Result {
+		matches := true
+		for label, value := range selector {
+			if series.Metric[label] != value {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			result = append(result, series)
+		}
+	}
+	if len(result) == 0 {
+		return pqr, MissingDataError{Reason: fmt.Sprintf("None of the %v series matches selector %v", len(pqr.Data.Result), selector)}
+	}
+
+	pqr.Data.Result = result
+	return pqr, nil
+}
+
+// LatestSampleTime returns the timestamp of the sample the request rate is taken from, or of the latest sample for a matrix result; for instant queries that's the evaluation time of the query
+func (pqr *PrometheusQueryResponse) LatestSampleTime() (time.Time, error) {
+
+	var sample []interface{}
+	switch pqr.Data.ResultType {
+	case scalarResultType:
+		sample = pqr.Data.Scalar
+	case "", vectorResultType:
+		if len(pqr.Data.Result) > 0 {
+			sample = pqr.Data.Result[0].Value
+		}
+	case matrixResultType:
+		if len(pqr.Data.Result) > 0 && len(pqr.Data.Result[0].Values) > 0 {
+			sample = pqr.Data.Result[0].Values[len(pqr.Data.Result[0].Values)-1]
+		}
+	}
+
+	if len(sample) != 2 {
+		return time.Time{}, errEmptyResponse
+	}
+	timestamp, ok := sample[0].(float64)
+	if !ok {
+		return time.Time{}, fmt.Errorf("Sample timestamp %v is not a number", sample[0])
+	}
+
+	return time.Unix(0, int64(timestamp*float64(time.Second))), nil
+}
+
+// getPrometheusSeriesSampleTime returns when the oldest of the series selectors in the request rate query was last scraped or recorded; a query that is a selector itself, like a recording rule, uses the timestamp() of the series the request rate is taken from, otherwise it's the max(timestamp()) of each selector, per seriesSelector labels if the raw series carry them. A query without selectors, like vector(1), returns a zero time
+func getPrometheusSeriesSampleTime(ctx context.Context, endpoint PrometheusEndpoint, key string, instantQueryURL func(string) string, configItem MIGConfiguration, labels map[string]string) (sampleTime time.Time, err error) {
+
+	selectors := PromQLSeriesSelectors(configItem.RequestRateQuery)
+	if len(selectors) == 1 && selectors[0] == strings.TrimSpace(configItem.RequestRateQuery) {
+		// timestamp() drops the metric name, but keeps all other labels
+		selector := map[string]string{}
+		for label, value := range labels {
+			if label != "__name__" {
+				selector[label] = value
+			}
+		}
+		return getPrometheusSampleTime(ctx, endpoint, key, instantQueryURL(fmt.Sprintf("timestamp(%v)", configItem.RequestRateQuery)), selector)
+	}
+
+	groupBy := ""
+	if len(configItem.SeriesSelector) > 0 {
+		selectorLabels := make([]string, 0, len(configItem.SeriesSelector))
+		for label := range configItem.SeriesSelector {
+			selectorLabels = append(selectorLabels, label)
+		}
+		sort.Strings(selectorLabels)
+		groupBy = fmt.Sprintf(" by (%v) ", strings.Join(selectorLabels, ", "))
+	}
+
+	for _, selector := range selectors {
+		selectorSampleTime, err := getPrometheusSampleTime(ctx, endpoint, key, instantQueryURL(fmt.Sprintf("max%v(timestamp(%v))", groupBy, selector)), configItem.SeriesSelector)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if sampleTime.IsZero() || selectorSampleTime.Before(sampleTime) {
+			sampleTime = selectorSampleTime
+		}
+	}
+
+	return sampleTime, nil
+}
+
+// getPrometheusSampleTime executes a query returning sample timestamps and returns the one of the series matching the selector; series that don't carry any of the selector labels match as well, so labels added by the request rate query itself don't fail the check
+func getPrometheusSampleTime(ctx context.Context, endpoint PrometheusEndpoint, key, timestampQueryURL string, selector map[string]string) (time.Time, error) {
+
+	timestampResponse, err := cachedPrometheusQuery(ctx, key+"|"+timestampQueryURL, func() (PrometheusQueryResponse, error) {
+		return executePrometheusQueryRequest(ctx, endpoint, timestampQueryURL)
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Retrieving sample time with query (%v) failed: %w", timestampQueryURL, err)
+	}
+
+	if len(timestampResponse.Data.Result) == 0 {
+		return time.Time{}, MissingDataError{Reason: fmt.Sprintf("Query (%v) returned no samples within the lookback window", timestampQueryURL)}
+	}
+
+	if len(selector) > 0 && carriesAnyLabel(timestampResponse, selector) {
+		timestampResponse, err = timestampResponse.SelectSeries(selector)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("Retrieving sample time with query (%v) failed: %w", timestampQueryURL, err)
+		}
+	}
+
+	timestamp, err := timestampResponse.GetRequestRate()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Retrieving sample time with query (%v) failed: %w", timestampQueryURL, err)
+	}
+
+	return time.Unix(0, int64(timestamp*float64(time.Second))), nil
+}
+
+// carriesAnyLabel returns whether any series of the response has one of the labels of the selector
+func carriesAnyLabel(pqr PrometheusQueryResponse, selector map[string]string) bool {
+	for _, series := range pqr.Data.Result {
+		for label := range selector {
+			if _, ok := series.Metric[label]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkSampleAge returns an error if the sample time is older than maxAge at the time the query was evaluated for; a maxAge of 0 or less disables the check
+func checkSampleAge(sampleTime time.Time, maxAge time.Duration, evaluationTime time.Time) error {
+
+	if maxAge <= 0 {
+		return nil
+	}
+
+	if age := evaluationTime.Sub(sampleTime); age > maxAge {
+		return MissingDataError{Reason: fmt.Sprintf("the latest sample is %v old, which is more than the maximum of %v", age.Round(time.Second), maxAge)}
+	}
+
+	return nil
+}
+
+// MaxMetricAge returns how old the latest sample of a prometheus query may be before it's treated as missing data; 0, when maxMetricAgeSeconds isn't set, disables the check
+func (c *MIGConfiguration) MaxMetricAge() time.Duration {
+	if c.MaxMetricAgeSeconds <= 0 {
+		return 0
+	}
+	return time.Duration(c.MaxMetricAgeSeconds) * time.Second
+}
+
+// GetRangeValues converts the string values of the first series in a range query response into float64s
+func (pqr *PrometheusQueryResponse) GetRangeValues() (values []float64, err error) {
+	if len(pqr.Data.Result) == 0 || len(pqr.Data.Result[0].Values) == 0 {
+		return nil, errEmptyResponse
+	}
+	for _, sample := range pqr.Data.Result[0].Values {
+		value, err := parsePrometheusSample(sample)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
 	}
 	return
-}
\ No newline at end of file
+}
+
+// parsePrometheusSample converts the value of a [timestamp, "value"] sample into a float64
+func parsePrometheusSample(sample []interface{}) (float64, error) {
+	if len(sample) != 2 {
+		return 0, fmt.Errorf("Sample %v is not a timestamp and value pair", sample)
+	}
+	stringValue, ok := sample[1].(string)
+	if !ok {
+		return 0, fmt.Errorf("Sample value %v is not a string", sample[1])
+	}
+	return strconv.ParseFloat(stringValue, 64)
+}
+
+// validatePrometheusConfig checks the request rate query, range query settings and replica aggregation of a managed instance group using prometheus
+func validatePrometheusConfig(c *MIGConfiguration, addError func(field, message string)) {
+	requireRequestRateQuery(CheckPromQLBrackets)(c, addError)
+
+	switch c.PrometheusReplicaAggregation {
+	case "", maxReplicaAggregation, quorumReplicaAggregation:
+	default:
+		addError("prometheusReplicaAggregation", fmt.Sprintf("should be one of %v", []string{maxReplicaAggregation, quorumReplicaAggregation}))
+	}
+
+	if c.PrometheusProxyURL != "" {
+		if proxyURL, err := url.Parse(c.PrometheusProxyURL); err != nil {
+			addError("prometheusProxyUrl", err.Error())
+		} else if (proxyURL.Scheme != "http" && proxyURL.Scheme != "https") || proxyURL.Host == "" {
+			addError("prometheusProxyUrl", "should be an http or https url with a host, like http://proxy:3128")
+		}
+	}
+
+	c.validateRangeQuery(addError)
+}
+
+// CheckPromQLBrackets checks a PromQL query for unbalanced brackets and unterminated strings; it doesn't parse PromQL, so other syntax errors are only caught by ValidateQueries with the format_query endpoint of prometheus
+func CheckPromQLBrackets(query string) error {
+
+	closingBrackets := map[rune]rune{')': '(', ']': '[', '}': '{'}
+	openBrackets := []rune{}
+	var quote rune
+	escaped := false
+
+	for i, c := range query {
+		if quote != 0 {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\' && quote != '`':
+				escaped = true
+			case c == quote:
+				quote = 0
+			}
+			continue
+		}
+
+		switch c {
+		case '"', '\'', '`':
+			quote = c
+		case '(', '[', '{':
+			openBrackets = append(openBrackets, c)
+		case ')', ']', '}':
+			if len(openBrackets) == 0 || openBrackets[len(openBrackets)-1] != closingBrackets[c] {
+				return fmt.Errorf("unexpected %q at position %v", c, i)
+			}
+			openBrackets = openBrackets[:len(openBrackets)-1]
+		}
+	}
+
+	if quote != 0 {
+		return fmt.Errorf("unterminated string starting with %q", quote)
+	}
+	if len(openBrackets) > 0 {
+		return fmt.Errorf("unclosed %q", openBrackets[len(openBrackets)-1])
+	}
+
+	return nil
+}
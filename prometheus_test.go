@@ -1,7 +1,13 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -46,4 +52,289 @@ func TestGetRequestRate(t *testing.T) {
 		assert.Nil(t, err)
 		assert.Equal(t, 225.4068155675859, floatValue)
 	})
+
+	t.Run("ReturnsValueOfScalarResult", func(t *testing.T) {
+
+		queryResponse, err := UnmarshalPrometheusQueryResponse([]byte(`{"status":"success","data":{"resultType":"scalar","result":[1513161148.757,"42"]}}`))
+		assert.Nil(t, err)
+
+		// act
+		floatValue, err := queryResponse.GetRequestRate()
+
+		assert.Nil(t, err)
+		assert.Equal(t, 42.0, floatValue)
+	})
+
+	t.Run("ReturnsLatestSampleOfMatrixResult", func(t *testing.T) {
+
+		queryResponse, err := UnmarshalPrometheusQueryResponse([]byte(`{"status":"success","data":{"resultType":"matrix","result":[{"metric":{},"values":[[1513161088,"100"],[1513161148,"150"]]}]}}`))
+		assert.Nil(t, err)
+
+		// act
+		floatValue, err := queryResponse.GetRequestRate()
+
+		assert.Nil(t, err)
+		assert.Equal(t, 150.0, floatValue)
+	})
+
+	t.Run("ReturnsErrorForStringResult", func(t *testing.T) {
+
+		queryResponse, err := UnmarshalPrometheusQueryResponse([]byte(`{"status":"success","data":{"resultType":"string","result":[1513161148.757,"hello"]}}`))
+		assert.Nil(t, err)
+
+		// act
+		_, err = queryResponse.GetRequestRate()
+
+		assert.NotNil(t, err)
+	})
+}
+
+// writePrometheusVector responds with a vector of a single series with the value sampled at the time, or with the time itself to queries for the timestamp() of series
+func writePrometheusVector(w http.ResponseWriter, r *http.Request, sampleTime int64, value string) {
+	if strings.Contains(r.FormValue("query"), "timestamp(") {
+		value = fmt.Sprint(sampleTime)
+	}
+	fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[%v,"%v"]}]}}`, time.Now().Unix(), value)
+}
+
+func TestPrometheusMetricSourceRangeQuery(t *testing.T) {
+
+	t.Run("ReturnsPercentileOfRangeQuerySamples", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/v1/query_range", r.URL.Path)
+			assert.Equal(t, "30", r.URL.Query().Get("step"))
+			now := time.Now().Unix()
+			fmt.Fprintf(w, `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{},"values":[[%v,"100"],[%v,"300"],[%v,"200"]]}]}}`, now-60, now-30, now)
+		}))
+		defer server.Close()
+
+		source := &PrometheusMetricSource{Defaults: PrometheusEndpoint{URL: server.URL}}
+
+		// act
+		requestRate, err := source.GetRequestRate(context.Background(), MIGConfiguration{RequestRateQuery: "sum(up)", QueryType: "range", RangeStepSeconds: 30, RangeAggregation: "p50"})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 200.0, requestRate)
+	})
+}
+
+func TestPrometheusMetricSourceSampleAge(t *testing.T) {
+
+	t.Run("ReturnsErrorIfSeriesOfAggregatedRateWereScrapedLongAgo", func(t *testing.T) {
+
+		queries := []string{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			queries = append(queries, r.FormValue("query"))
+			writePrometheusVector(w, r, time.Now().Add(-15*time.Minute).Unix(), "100")
+		}))
+		defer server.Close()
+
+		source := &PrometheusMetricSource{Defaults: PrometheusEndpoint{URL: server.URL}}
+
+		// act
+		_, err := source.GetRequestRate(context.Background(), MIGConfiguration{RequestRateQuery: "sum(rate(http_requests_total[5m]))", MaxMetricAgeSeconds: 600})
+
+		assert.NotNil(t, err)
+		assert.True(t, IsMissingData(err))
+		assert.Equal(t, []string{"sum(rate(http_requests_total[5m]))", "max(timestamp(http_requests_total))"}, queries)
+	})
+
+	t.Run("ReturnsErrorIfSelectedSeriesOfAggregationHaveNoSamplesWithinLookbackWindow", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			now := time.Now().Unix()
+			if r.FormValue("query") == "max by (location) (timestamp(http_requests_total))" {
+				fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"location":"@b"},"value":[%v,"%v"]}]}}`, now, now)
+				return
+			}
+			fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"location":"@a"},"value":[%v,"100"]},{"metric":{"location":"@b"},"value":[%v,"200"]}]}}`, now, now)
+		}))
+		defer server.Close()
+
+		source := &PrometheusMetricSource{Defaults: PrometheusEndpoint{URL: server.URL}}
+		query := "sum by (location) (rate(http_requests_total[10m]))"
+
+		// act
+		_, errA := source.GetRequestRate(context.Background(), MIGConfiguration{RequestRateQuery: query, SeriesSelector: map[string]string{"location": "@a"}, MaxMetricAgeSeconds: 600})
+		requestRateB, errB := source.GetRequestRate(context.Background(), MIGConfiguration{RequestRateQuery: query, SeriesSelector: map[string]string{"location": "@b"}, MaxMetricAgeSeconds: 600})
+
+		assert.NotNil(t, errA)
+		assert.True(t, IsMissingData(errA))
+		assert.Nil(t, errB)
+		assert.Equal(t, 200.0, requestRateB)
+	})
+
+	t.Run("ReturnsErrorIfSelectedSeriesOfInstantQueryWasRecordedLongAgo", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			now := time.Now().Unix()
+			if r.FormValue("query") == "timestamp(job:requests:rate5m)" {
+				// the recording rule for location @b stopped 15 minutes ago, while its last value is still returned within the lookback window
+				fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"location":"@a"},"value":[%v,"%v"]},{"metric":{"location":"@b"},"value":[%v,"%v"]}]}}`, now, now, now, now-900)
+				return
+			}
+			fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"__name__":"job:requests:rate5m","location":"@a"},"value":[%v,"100"]},{"metric":{"__name__":"job:requests:rate5m","location":"@b"},"value":[%v,"200"]}]}}`, now, now)
+		}))
+		defer server.Close()
+
+		source := &PrometheusMetricSource{Defaults: PrometheusEndpoint{URL: server.URL}}
+
+		// act
+		requestRateA, errA := source.GetRequestRate(context.Background(), MIGConfiguration{RequestRateQuery: "job:requests:rate5m", SeriesSelector: map[string]string{"location": "@a"}, MaxMetricAgeSeconds: 600})
+		_, errB := source.GetRequestRate(context.Background(), MIGConfiguration{RequestRateQuery: "job:requests:rate5m", SeriesSelector: map[string]string{"location": "@b"}, MaxMetricAgeSeconds: 600})
+
+		assert.Nil(t, errA)
+		assert.Equal(t, 100.0, requestRateA)
+		assert.NotNil(t, errB)
+		assert.True(t, IsMissingData(errB))
+	})
+
+	t.Run("ReturnsErrorIfLastSampleOfRangeQueryIsOlderThanMaxMetricAge", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			stoppedAt := time.Now().Add(-15 * time.Minute).Unix()
+			fmt.Fprintf(w, `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{},"values":[[%v,"100"],[%v,"150"]]}]}}`, stoppedAt-60, stoppedAt)
+		}))
+		defer server.Close()
+
+		source := &PrometheusMetricSource{Defaults: PrometheusEndpoint{URL: server.URL}}
+
+		// act
+		_, err := source.GetRequestRate(context.Background(), MIGConfiguration{RequestRateQuery: "sum(up)", QueryType: "range", RangeWindowSeconds: 1800, MaxMetricAgeSeconds: 600})
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("ReturnsValueOfOldSampleWithoutTimestampQueryIfMaxMetricAgeIsNotSet", func(t *testing.T) {
+
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			writePrometheusVector(w, r, time.Now().Add(-15*time.Minute).Unix(), "100")
+		}))
+		defer server.Close()
+
+		source := &PrometheusMetricSource{Defaults: PrometheusEndpoint{URL: server.URL}}
+
+		// act
+		requestRate, err := source.GetRequestRate(context.Background(), MIGConfiguration{RequestRateQuery: "sum(up)"})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 100.0, requestRate)
+		assert.Equal(t, 1, requests)
+	})
+}
+
+func TestPrometheusMetricSourceSeriesSelector(t *testing.T) {
+
+	t.Run("ExecutesSharedQueryOncePerIterationAndSelectsSeriesPerMIG", func(t *testing.T) {
+
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			now := time.Now().Unix()
+			valueA, valueB := "100", "200"
+			if strings.Contains(r.FormValue("query"), "timestamp(") {
+				valueA, valueB = fmt.Sprint(now), fmt.Sprint(now)
+			}
+			fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"location":"@a"},"value":[%v,"%v"]},{"metric":{"location":"@b"},"value":[%v,"%v"]}]}}`, now, valueA, now, valueB)
+		}))
+		defer server.Close()
+
+		source := &PrometheusMetricSource{Defaults: PrometheusEndpoint{URL: server.URL}}
+		ctx := WithQueryCache(context.Background())
+		query := "sum by (location) (rate(nginx_http_requests_total[10m]))"
+
+		// act
+		requestRateA, errA := source.GetRequestRate(ctx, MIGConfiguration{RequestRateQuery: query, SeriesSelector: map[string]string{"location": "@a"}, MaxMetricAgeSeconds: 600})
+		requestRateB, errB := source.GetRequestRate(ctx, MIGConfiguration{RequestRateQuery: query, SeriesSelector: map[string]string{"location": "@b"}, MaxMetricAgeSeconds: 600})
+		_, errC := source.GetRequestRate(ctx, MIGConfiguration{RequestRateQuery: query, SeriesSelector: map[string]string{"location": "@c"}, MaxMetricAgeSeconds: 600})
+
+		assert.Nil(t, errA)
+		assert.Nil(t, errB)
+		assert.NotNil(t, errC)
+		assert.Equal(t, 100.0, requestRateA)
+		assert.Equal(t, 200.0, requestRateB)
+		assert.Equal(t, 2, requests)
+	})
+}
+
+func TestPrometheusMetricSourceReplicas(t *testing.T) {
+
+	newReplica := func(value string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if value == "" {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			writePrometheusVector(w, r, time.Now().Unix(), value)
+		}))
+	}
+
+	t.Run("ReturnsHighestValueOfReplicas", func(t *testing.T) {
+
+		replicaA := newReplica("180")
+		defer replicaA.Close()
+		replicaB := newReplica("225.4")
+		defer replicaB.Close()
+
+		source := &PrometheusMetricSource{Defaults: PrometheusEndpoint{URL: replicaA.URL + "," + replicaB.URL}}
+
+		// act
+		requestRate, err := source.GetRequestRate(context.Background(), MIGConfiguration{RequestRateQuery: "sum(up)"})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 225.4, requestRate)
+	})
+
+	t.Run("ReturnsErrorIfNoQuorumOfReplicasResponded", func(t *testing.T) {
+
+		replicaA := newReplica("180")
+		defer replicaA.Close()
+		replicaB := newReplica("")
+		defer replicaB.Close()
+
+		source := &PrometheusMetricSource{Defaults: PrometheusEndpoint{URL: replicaA.URL + "," + replicaB.URL}}
+
+		// act
+		_, err := source.GetRequestRate(context.Background(), MIGConfiguration{RequestRateQuery: "sum(up)", PrometheusReplicaAggregation: "quorum"})
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestCheckPromQLBrackets(t *testing.T) {
+
+	t.Run("ReturnsNilForBalancedQuery", func(t *testing.T) {
+
+		// act
+		err := CheckPromQLBrackets("sum(rate(nginx_http_requests_total{host!~\"^(?:[0-9.]+)$\",location=\"@searchfareapi_gcloud\"}[10m])) by (location)")
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("ReturnsErrorForUnclosedBracket", func(t *testing.T) {
+
+		// act
+		err := CheckPromQLBrackets("sum(rate(nginx_http_requests_total[10m])")
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("ReturnsErrorForMismatchedBracket", func(t *testing.T) {
+
+		// act
+		err := CheckPromQLBrackets("sum(rate(nginx_http_requests_total[10m)))")
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("ReturnsErrorForUnterminatedString", func(t *testing.T) {
+
+		// act
+		err := CheckPromQLBrackets("sum(rate(nginx_http_requests_total{location=\"@searchfareapi_gcloud}[10m]))")
+
+		assert.NotNil(t, err)
+	})
 }
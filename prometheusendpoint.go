@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sethgrid/pester"
+)
+
+// PrometheusEndpoint holds the settings for connecting to a prometheus server, or a server with a compatible query api
+type PrometheusEndpoint struct {
+	// URL is a comma separated list for highly available prometheus servers, to query all replicas and combine their values with ReplicaAggregation
+	URL                string
+	ReplicaAggregation string
+	Username           string
+	Password           string
+	BearerTokenFile    string
+	TLS                PrometheusTLSConfig
+
+	// IAPAudience is the oauth client id of the identity-aware proxy in front of prometheus, if any
+	IAPAudience string
+
+	// Headers are sent with every query, like X-Scope-OrgID to select the tenant of cortex, mimir or thanos
+	Headers map[string]string
+
+	Retry PrometheusRetryConfig
+
+	// UsePOST sends queries as form body instead of in the url, for queries longer than urls can practically be; get requests answered with 414 uri too long are resent as post as well
+	UsePOST bool
+
+	// MaxResponseBytes limits the size of a response, so a query returning millions of series can't run the scaler out of memory; 0 means no limit
+	MaxResponseBytes int64
+
+	// ProxyURL is the http(s) proxy to reach prometheus through; without it the HTTP_PROXY, HTTPS_PROXY and NO_PROXY envvars apply
+	ProxyURL string
+}
+
+// PrometheusRetryConfig holds the retry settings for prometheus requests; fields that aren't set keep the pester defaults of 3 attempts, 1 second apart
+type PrometheusRetryConfig struct {
+	MaxRetries     int
+	Backoff        string
+	Concurrency    int
+	AttemptTimeout time.Duration
+}
+
+// prometheusBackoffStrategies are the supported values for the backoff between retries of prometheus requests
+var prometheusBackoffStrategies = map[string]pester.BackoffStrategy{
+	"constant":           pester.DefaultBackoff,
+	"linear":             pester.LinearBackoff,
+	"linear-jitter":      pester.LinearJitterBackoff,
+	"exponential":        pester.ExponentialBackoff,
+	"exponential-jitter": pester.ExponentialJitterBackoff,
+}
+
+// prometheusClientConfig identifies the settings an http client for prometheus is created with
+type prometheusClientConfig struct {
+	TLS      PrometheusTLSConfig
+	Retry    PrometheusRetryConfig
+	ProxyURL string
+}
+
+// PrometheusTLSConfig holds the tls settings for https prometheus endpoints
+type PrometheusTLSConfig struct {
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	InsecureSkipVerify bool
+}
+
+// prometheusClients caches an http client per tls and retry configuration, so connections are reused across queries
+var prometheusClients = struct {
+	sync.Mutex
+	clients map[prometheusClientConfig]*pester.Client
+}{clients: map[prometheusClientConfig]*pester.Client{}}
+
+// endpoint returns the prometheus endpoint for a managed instance group, where each of its prometheus settings overrides the corresponding default
+func (s *PrometheusMetricSource) endpoint(configItem MIGConfiguration) PrometheusEndpoint {
+
+	endpoint := s.Defaults
+	if configItem.PrometheusURL != "" {
+		endpoint.URL = configItem.PrometheusURL
+	}
+	if configItem.PrometheusReplicaAggregation != "" {
+		endpoint.ReplicaAggregation = configItem.PrometheusReplicaAggregation
+	}
+	if configItem.PrometheusUsername != "" {
+		endpoint.Username = configItem.PrometheusUsername
+		endpoint.Password = configItem.PrometheusPassword
+	}
+	if configItem.PrometheusBearerTokenFile != "" {
+		endpoint.BearerTokenFile = configItem.PrometheusBearerTokenFile
+	}
+	if configItem.PrometheusCAFile != "" {
+		endpoint.TLS.CAFile = configItem.PrometheusCAFile
+	}
+	if configItem.PrometheusCertFile != "" {
+		endpoint.TLS.CertFile = configItem.PrometheusCertFile
+		endpoint.TLS.KeyFile = configItem.PrometheusKeyFile
+	}
+	if configItem.PrometheusInsecureSkipVerify {
+		endpoint.TLS.InsecureSkipVerify = true
+	}
+	if configItem.PrometheusIAPAudience != "" {
+		endpoint.IAPAudience = configItem.PrometheusIAPAudience
+	}
+	if configItem.PrometheusProxyURL != "" {
+		endpoint.ProxyURL = configItem.PrometheusProxyURL
+	}
+	if configItem.PrometheusUsePOST {
+		endpoint.UsePOST = true
+	}
+	if len(configItem.PrometheusHeaders) > 0 {
+		headers := map[string]string{}
+		for name, value := range s.Defaults.Headers {
+			headers[name] = value
+		}
+		for name, value := range configItem.PrometheusHeaders {
+			headers[name] = value
+		}
+		endpoint.Headers = headers
+	}
+
+	return endpoint
+}
+
+// replicaURLs returns the urls of all replicas of the endpoint
+func (e *PrometheusEndpoint) replicaURLs() (urls []string) {
+	for _, u := range strings.Split(e.URL, ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			urls = append(urls, u)
+		}
+	}
+	return
+}
+
+// newRequest creates a request to the endpoint with its credentials attached; the bearer token file is read for every request so rotated tokens are picked up
+func (e *PrometheusEndpoint) newRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
+
+	request, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	request = request.WithContext(ctx)
+
+	for name, value := range e.Headers {
+		request.Header.Set(name, value)
+	}
+
+	if e.Username != "" {
+		request.SetBasicAuth(e.Username, e.Password)
+	}
+
+	if e.BearerTokenFile != "" {
+		token, err := ioutil.ReadFile(e.BearerTokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("Reading prometheus bearer token file %v failed: %v", e.BearerTokenFile, err)
+		}
+		request.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	if e.IAPAudience != "" {
+		token, err := GetIDToken(ctx, e.IAPAudience)
+		if err != nil {
+			return nil, err
+		}
+		// identity-aware proxy also accepts its token in Proxy-Authorization, leaving Authorization to prometheus itself
+		if request.Header.Get("Authorization") != "" {
+			request.Header.Set("Proxy-Authorization", "Bearer "+token)
+		} else {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+
+	return request, nil
+}
+
+// query sends a request to an endpoint of the prometheus query api, with the parameters in queryURL moved into a form body if the endpoint uses post or the url turns out too long
+func (e *PrometheusEndpoint) query(ctx context.Context, queryURL string) (*http.Response, error) {
+
+	if !e.UsePOST {
+		request, err := e.newRequest(ctx, http.MethodGet, queryURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := e.do(request)
+		if err != nil || resp.StatusCode != http.StatusRequestURITooLong {
+			return resp, err
+		}
+		resp.Body.Close()
+		log.Debug().Msg("Prometheus query url is too long, resending it as post")
+	}
+
+	parsedURL, err := url.Parse(queryURL)
+	if err != nil {
+		return nil, err
+	}
+	form := parsedURL.RawQuery
+	parsedURL.RawQuery = ""
+
+	request, err := e.newRequest(ctx, http.MethodPost, parsedURL.String(), strings.NewReader(form))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return e.do(request)
+}
+
+// do sends the request with an http client configured with the tls and retry settings of the endpoint
+func (e *PrometheusEndpoint) do(request *http.Request) (*http.Response, error) {
+
+	clientConfig := prometheusClientConfig{TLS: e.TLS, Retry: e.Retry, ProxyURL: e.ProxyURL}
+
+	prometheusClients.Lock()
+	client, ok := prometheusClients.clients[clientConfig]
+	if !ok {
+		httpClient := &http.Client{
+			Timeout: e.Retry.AttemptTimeout,
+		}
+		if e.TLS != (PrometheusTLSConfig{}) || e.ProxyURL != "" {
+			transport, err := e.transport()
+			if err != nil {
+				prometheusClients.Unlock()
+				return nil, err
+			}
+			httpClient.Transport = transport
+		}
+
+		client = pester.NewExtendedClient(httpClient)
+		if e.Retry.MaxRetries > 0 {
+			client.MaxRetries = e.Retry.MaxRetries
+		}
+		if backoff, ok := prometheusBackoffStrategies[e.Retry.Backoff]; ok {
+			client.Backoff = backoff
+		}
+		if e.Retry.Concurrency > 0 {
+			client.Concurrency = e.Retry.Concurrency
+		}
+		maxAttempts := client.MaxRetries
+		client.LogHook = func(entry pester.ErrEntry) {
+			if entry.Attempt < maxAttempts {
+				prometheusRetriesCounter.Inc()
+			}
+		}
+
+		prometheusClients.clients[clientConfig] = client
+	}
+	prometheusClients.Unlock()
+
+	return client.Do(request)
+}
+
+// readResponseBody reads the response body, aborting if it's larger than the endpoint's maximum response size
+func (e *PrometheusEndpoint) readResponseBody(resp *http.Response) ([]byte, error) {
+
+	if e.MaxResponseBytes <= 0 {
+		return ioutil.ReadAll(resp.Body)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, e.MaxResponseBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > e.MaxResponseBytes {
+		prometheusResponsesTooLargeCounter.Inc()
+		return nil, fmt.Errorf("Response is larger than the maximum of %v bytes, make the query return fewer series", e.MaxResponseBytes)
+	}
+
+	return body, nil
+}
+
+// transport returns an http transport with the tls settings and proxy of the endpoint
+func (e *PrometheusEndpoint) transport() (*http.Transport, error) {
+
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+	}
+
+	if e.ProxyURL != "" {
+		proxyURL, err := url.Parse(e.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("Parsing prometheus proxy url %v failed: %v", e.ProxyURL, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if e.TLS != (PrometheusTLSConfig{}) {
+		tlsConfig, err := e.TLS.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	return transport, nil
+}
+
+// tlsConfig loads the ca bundle and client certificate
+func (c *PrometheusTLSConfig) tlsConfig() (*tls.Config, error) {
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+
+	if c.CAFile != "" {
+		ca, err := ioutil.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("Reading prometheus ca file %v failed: %v", c.CAFile, err)
+		}
+		certPool := x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("Prometheus ca file %v contains no pem encoded certificates", c.CAFile)
+		}
+		tlsConfig.RootCAs = certPool
+	}
+
+	if c.CertFile != "" {
+		certificate, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("Loading prometheus client certificate %v failed: %v", c.CertFile, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
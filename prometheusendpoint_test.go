@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrometheusMetricSourceAuthentication(t *testing.T) {
+
+	t.Run("AttachesBasicAuthCredentials", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			username, password, ok := r.BasicAuth()
+			assert.True(t, ok)
+			assert.Equal(t, "scaler", username)
+			assert.Equal(t, "secret", password)
+			writePrometheusVector(w, r, time.Now().Unix(), "225.4")
+		}))
+		defer server.Close()
+
+		source := &PrometheusMetricSource{Defaults: PrometheusEndpoint{URL: server.URL, Username: "scaler", Password: "secret"}}
+
+		// act
+		requestRate, err := source.GetRequestRate(context.Background(), MIGConfiguration{RequestRateQuery: "sum(up)"})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 225.4, requestRate)
+	})
+
+	t.Run("AttachesBearerTokenFromPerMIGTokenFile", func(t *testing.T) {
+
+		dir, _ := ioutil.TempDir("", "prometheus-token")
+		defer os.RemoveAll(dir)
+		tokenFile := filepath.Join(dir, "token")
+		ioutil.WriteFile(tokenFile, []byte("abc123\n"), 0600)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "Bearer abc123", r.Header.Get("Authorization"))
+			writePrometheusVector(w, r, time.Now().Unix(), "225.4")
+		}))
+		defer server.Close()
+
+		source := &PrometheusMetricSource{Defaults: PrometheusEndpoint{URL: server.URL}}
+
+		// act
+		_, err := source.GetRequestRate(context.Background(), MIGConfiguration{RequestRateQuery: "sum(up)", PrometheusBearerTokenFile: tokenFile})
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("MergesPerMIGHeadersWithDefaultHeaders", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "tenant-b", r.Header.Get("X-Scope-OrgID"))
+			assert.Equal(t, "mig-scaler", r.Header.Get("X-Client"))
+			writePrometheusVector(w, r, time.Now().Unix(), "225.4")
+		}))
+		defer server.Close()
+
+		source := &PrometheusMetricSource{Defaults: PrometheusEndpoint{URL: server.URL, Headers: map[string]string{"X-Scope-OrgID": "tenant-a", "X-Client": "mig-scaler"}}}
+
+		// act
+		_, err := source.GetRequestRate(context.Background(), MIGConfiguration{RequestRateQuery: "sum(up)", PrometheusHeaders: map[string]string{"X-Scope-OrgID": "tenant-b"}})
+
+		assert.Nil(t, err)
+		assert.Equal(t, "tenant-a", source.Defaults.Headers["X-Scope-OrgID"])
+	})
+
+	t.Run("SendsRequestsThroughProxy", func(t *testing.T) {
+
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "prometheus.monitoring.svc", r.URL.Host)
+			writePrometheusVector(w, r, time.Now().Unix(), "225.4")
+		}))
+		defer proxy.Close()
+
+		source := &PrometheusMetricSource{Defaults: PrometheusEndpoint{URL: "http://prometheus.monitoring.svc"}}
+
+		// act
+		requestRate, err := source.GetRequestRate(context.Background(), MIGConfiguration{RequestRateQuery: "sum(up)", PrometheusProxyURL: proxy.URL})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 225.4, requestRate)
+	})
+
+	t.Run("ResendsQueryAsPostIfURLIsTooLong", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet {
+				w.WriteHeader(http.StatusRequestURITooLong)
+				return
+			}
+			assert.Equal(t, "/api/v1/query", r.URL.Path)
+			assert.Contains(t, []string{"sum(up)", "timestamp(sum(up))"}, r.PostFormValue("query"))
+			writePrometheusVector(w, r, time.Now().Unix(), "225.4")
+		}))
+		defer server.Close()
+
+		source := &PrometheusMetricSource{Defaults: PrometheusEndpoint{URL: server.URL}}
+
+		// act
+		requestRate, err := source.GetRequestRate(context.Background(), MIGConfiguration{RequestRateQuery: "sum(up)"})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 225.4, requestRate)
+	})
+
+	t.Run("ReturnsErrorForResponseLargerThanMaxResponseBytes", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			writePrometheusVector(w, r, time.Now().Unix(), "225.4")
+		}))
+		defer server.Close()
+
+		source := &PrometheusMetricSource{Defaults: PrometheusEndpoint{URL: server.URL, MaxResponseBytes: 64}}
+		tooLarge := testutil.ToFloat64(prometheusResponsesTooLargeCounter)
+
+		// act
+		_, err := source.GetRequestRate(context.Background(), MIGConfiguration{RequestRateQuery: "sum(up)"})
+
+		assert.NotNil(t, err)
+		assert.Equal(t, tooLarge+1, testutil.ToFloat64(prometheusResponsesTooLargeCounter))
+	})
+
+	t.Run("RetriesFailedRequestsAndCountsRetries", func(t *testing.T) {
+
+		attempts := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			if attempts == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			writePrometheusVector(w, r, time.Now().Unix(), "225.4")
+		}))
+		defer server.Close()
+
+		source := &PrometheusMetricSource{Defaults: PrometheusEndpoint{URL: server.URL, Retry: PrometheusRetryConfig{MaxRetries: 2, Backoff: "constant"}}}
+		retries := testutil.ToFloat64(prometheusRetriesCounter)
+
+		// act
+		_, err := source.GetRequestRate(context.Background(), MIGConfiguration{RequestRateQuery: "sum(up)"})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 2, attempts)
+		assert.Equal(t, retries+1, testutil.ToFloat64(prometheusRetriesCounter))
+	})
+
+	t.Run("VerifiesServerCertificateWithCAFile", func(t *testing.T) {
+
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			writePrometheusVector(w, r, time.Now().Unix(), "225.4")
+		}))
+		defer server.Close()
+
+		dir, _ := ioutil.TempDir("", "prometheus-ca")
+		defer os.RemoveAll(dir)
+		caFile := filepath.Join(dir, "ca.pem")
+		ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0644)
+
+		source := &PrometheusMetricSource{Defaults: PrometheusEndpoint{URL: server.URL}}
+
+		// act
+		requestRate, err := source.GetRequestRate(context.Background(), MIGConfiguration{RequestRateQuery: "sum(up)", PrometheusCAFile: caFile})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 225.4, requestRate)
+	})
+}
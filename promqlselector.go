@@ -0,0 +1,123 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// promqlLabelListKeywords are followed by a list of label names instead of an expression
+var promqlLabelListKeywords = map[string]bool{"by": true, "without": true, "on": true, "ignoring": true, "group_left": true, "group_right": true}
+
+// promqlKeywords are identifiers in a query that aren't metric names, including the aggregations since those can be followed by a by or without clause instead of a parenthesis
+var promqlKeywords = map[string]bool{
+	"and": true, "or": true, "unless": true, "bool": true, "offset": true, "atan2": true, "inf": true, "nan": true,
+	"sum": true, "min": true, "max": true, "avg": true, "group": true, "stddev": true, "stdvar": true, "count": true, "count_values": true, "bottomk": true, "topk": true, "quantile": true,
+}
+
+// PromQLSeriesSelectors returns the distinct series selectors of a PromQL query, like nginx_http_requests_total{location="@a"} in sum(rate(nginx_http_requests_total{location="@a"}[10m])), without their range or offset; it only scans the query, so for a query that isn't valid PromQL the result is undefined
+func PromQLSeriesSelectors(query string) (selectors []string) {
+
+	runes := []rune(query)
+	seen := map[string]bool{}
+	add := func(selector string) {
+		if !seen[selector] {
+			seen[selector] = true
+			selectors = append(selectors, selector)
+		}
+	}
+
+	nextNonSpace := func(i int) int {
+		for i < len(runes) && unicode.IsSpace(runes[i]) {
+			i++
+		}
+		return i
+	}
+
+	for i := 0; i < len(runes); {
+		c := runes[i]
+		switch {
+		case c == '"' || c == '\'' || c == '`':
+			i = skipPromQLString(runes, i)
+
+		case c == '[':
+			// ranges and subquery resolutions
+			i = skipPromQLGroup(runes, i, '[', ']')
+
+		case c == '{':
+			end := skipPromQLGroup(runes, i, '{', '}')
+			add(string(runes[i:end]))
+			i = end
+
+		case unicode.IsDigit(c) || c == '.':
+			// numbers and durations
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+
+		case unicode.IsLetter(c) || c == '_' || c == ':':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == ':') {
+				i++
+			}
+			identifier := string(runes[start:i])
+			next := nextNonSpace(i)
+
+			switch {
+			case promqlLabelListKeywords[strings.ToLower(identifier)]:
+				if next < len(runes) && runes[next] == '(' {
+					i = skipPromQLGroup(runes, next, '(', ')')
+				}
+			case promqlKeywords[strings.ToLower(identifier)]:
+			case next < len(runes) && runes[next] == '(':
+				// functions and aggregations
+			case next < len(runes) && runes[next] == '{':
+				end := skipPromQLGroup(runes, next, '{', '}')
+				add(identifier + string(runes[next:end]))
+				i = end
+			default:
+				add(identifier)
+			}
+
+		default:
+			i++
+		}
+	}
+
+	return selectors
+}
+
+// skipPromQLString returns the position after the string starting at i
+func skipPromQLString(runes []rune, i int) int {
+	quote := runes[i]
+	for i++; i < len(runes); i++ {
+		if runes[i] == '\\' && quote != '`' {
+			i++
+			continue
+		}
+		if runes[i] == quote {
+			return i + 1
+		}
+	}
+	return i
+}
+
+// skipPromQLGroup returns the position after the closing bracket of the group opened at i, skipping strings in it
+func skipPromQLGroup(runes []rune, i int, open, close rune) int {
+	depth := 0
+	for i < len(runes) {
+		switch runes[i] {
+		case '"', '\'', '`':
+			i = skipPromQLString(runes, i)
+			continue
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+		i++
+	}
+	return i
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPromQLSeriesSelectors(t *testing.T) {
+
+	t.Run("ReturnsSelectorOfAggregatedRate", func(t *testing.T) {
+
+		// act
+		selectors := PromQLSeriesSelectors(`sum(rate(nginx_http_requests_total{host!~"^(?:[0-9.]+)$",location="@searchfareapi_gcloud"}[10m] offset 5m)) by (location)`)
+
+		assert.Equal(t, []string{`nginx_http_requests_total{host!~"^(?:[0-9.]+)$",location="@searchfareapi_gcloud"}`}, selectors)
+	})
+
+	t.Run("ReturnsDistinctSelectorsOfBinaryExpression", func(t *testing.T) {
+
+		// act
+		selectors := PromQLSeriesSelectors(`sum by (location) (rate(requests_total[5m])) / on (location) group_left sum(rate(requests_total[5m])) * 2 + job:errors:rate5m and {__name__="up"}`)
+
+		assert.Equal(t, []string{"requests_total", "job:errors:rate5m", `{__name__="up"}`}, selectors)
+	})
+
+	t.Run("ReturnsQueryItselfForSelector", func(t *testing.T) {
+
+		// act
+		selectors := PromQLSeriesSelectors("job:requests:rate5m")
+
+		assert.Equal(t, []string{"job:requests:rate5m"}, selectors)
+	})
+
+	t.Run("ReturnsNothingForQueryWithoutSeries", func(t *testing.T) {
+
+		// act
+		selectors := PromQLSeriesSelectors(`vector(1) + scalar(label_replace(vector(2), "a", "b", "c", "d"))`)
+
+		assert.Equal(t, 0, len(selectors))
+	})
+}
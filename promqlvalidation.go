@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+
+	"github.com/rs/zerolog/log"
+)
+
+// UnvalidatedQuery is a query ValidateQueries couldn't check, since its prometheus server couldn't be reached or doesn't have the format_query endpoint yet (before prometheus 2.38)
+type UnvalidatedQuery struct {
+	InstanceGroupName string
+	Field             string
+	PrometheusURL     string
+	Err               error
+}
+
+// ValidateQueries checks the request rate queries of all managed instance groups using prometheus, including those in queries and fallbacks and the trend query, with the format_query endpoint of their prometheus server, so they're parsed exactly as they would be executed; it returns the queries it couldn't check, for servers that can't be reached or don't have that endpoint, next to the validation errors of the queries prometheus rejects
+func (s *PrometheusMetricSource) ValidateQueries(ctx context.Context, migConfigs []MIGConfiguration) (unvalidated []UnvalidatedQuery, err error) {
+
+	errs := ValidationErrors{}
+
+	// a server that can't be reached is only tried once, so validation doesn't wait for its timeout for every query
+	unreachable := map[string]error{}
+
+	for i, c := range migConfigs {
+		configs, err := c.requestRateConfigs()
+		if err != nil {
+			// reported by the configuration validation
+			continue
+		}
+
+		fields := []string{}
+		for field := range configs {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+
+		for _, field := range fields {
+			config := configs[field]
+			if config.MetricSourceName() != prometheusMetricSource || config.RequestRateQuery == "" {
+				continue
+			}
+
+			endpoint := s.endpoint(config)
+			replicaURLs := endpoint.replicaURLs()
+			if len(replicaURLs) == 0 {
+				unvalidated = append(unvalidated, UnvalidatedQuery{InstanceGroupName: c.InstanceGroupName, Field: field, Err: errors.New("No prometheus url is configured")})
+				continue
+			}
+
+			prometheusURL := replicaURLs[0]
+			serverErr, ok := unreachable[prometheusURL]
+			if !ok {
+				var syntaxErr string
+				syntaxErr, serverErr = formatPrometheusQuery(ctx, endpoint, prometheusURL, config.RequestRateQuery)
+				if serverErr != nil {
+					unreachable[prometheusURL] = serverErr
+				} else if syntaxErr != "" {
+					errs = append(errs, ValidationError{Index: i, InstanceGroupName: c.InstanceGroupName, Field: field, Message: syntaxErr})
+				}
+			}
+			if serverErr != nil {
+				unvalidated = append(unvalidated, UnvalidatedQuery{InstanceGroupName: c.InstanceGroupName, Field: field, PrometheusURL: prometheusURL, Err: serverErr})
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return unvalidated, errs
+	}
+
+	return unvalidated, nil
+}
+
+// logUnvalidatedQueries warns about the queries ValidateQueries couldn't check, once for every prometheus server
+func logUnvalidatedQueries(unvalidated []UnvalidatedQuery) {
+
+	queriesByURL := map[string][]string{}
+	errsByURL := map[string]error{}
+	urls := []string{}
+	for _, query := range unvalidated {
+		if _, ok := queriesByURL[query.PrometheusURL]; !ok {
+			urls = append(urls, query.PrometheusURL)
+		}
+		queriesByURL[query.PrometheusURL] = append(queriesByURL[query.PrometheusURL], fmt.Sprintf("%v %v", query.InstanceGroupName, query.Field))
+		errsByURL[query.PrometheusURL] = query.Err
+	}
+
+	for _, prometheusURL := range urls {
+		log.Warn().Err(errsByURL[prometheusURL]).Strs("queries", queriesByURL[prometheusURL]).Msgf("Validating %v request rate queries with prometheus %v failed, they're not checked", len(queriesByURL[prometheusURL]), prometheusURL)
+	}
+}
+
+// formatPrometheusQuery sends the query to the format_query endpoint and returns the syntax error prometheus reports for it, if any
+func formatPrometheusQuery(ctx context.Context, endpoint PrometheusEndpoint, prometheusURL, query string) (syntaxErr string, err error) {
+
+	resp, err := endpoint.query(ctx, fmt.Sprintf("%v/api/v1/format_query?query=%v", prometheusURL, url.QueryEscape(query)))
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return "", nil
+	case http.StatusBadRequest:
+		queryResponse, err := UnmarshalPrometheusQueryResponse(body)
+		if err != nil {
+			return "", err
+		}
+		return queryResponse.Error, nil
+	}
+
+	return "", fmt.Errorf("Prometheus format_query endpoint returned status code %v", resp.StatusCode)
+}
+
+// requestRateConfigs returns the managed instance group's own configuration and those of its queries, fallbacks and trend query, by the field of their query to report validation errors with
+func (c *MIGConfiguration) requestRateConfigs() (map[string]MIGConfiguration, error) {
+
+	configs := map[string]MIGConfiguration{}
+
+	addWithFallbacks := func(prefix string, config MIGConfiguration) error {
+		configs[prefix+"requestRateQuery"] = config
+		fallbackConfigs, err := config.FallbackConfigs()
+		if err != nil {
+			return err
+		}
+		for i, fallbackConfig := range fallbackConfigs {
+			configs[fmt.Sprintf("%vfallbacks[%v].requestRateQuery", prefix, i)] = fallbackConfig
+		}
+		return nil
+	}
+
+	if c.TrendQuery != "" {
+		configs["trendQuery"] = c.TrendConfig()
+	}
+
+	if len(c.Queries) == 0 {
+		return configs, addWithFallbacks("", *c)
+	}
+
+	queryConfigs, err := c.QueryConfigs()
+	if err != nil {
+		return configs, err
+	}
+	for i, queryConfig := range queryConfigs {
+		if err := addWithFallbacks(fmt.Sprintf("queries[%v].", i), queryConfig); err != nil {
+			return configs, err
+		}
+	}
+
+	return configs, nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrometheusMetricSourceValidateQueries(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/format_query", r.URL.Path)
+		if r.URL.Query().Get("query") == "sum(rate(nginx_http_requests_total[10m])) by" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","errorType":"bad_data","error":"1:43: parse error: unexpected end of input"}`))
+			return
+		}
+		w.Write([]byte(`{"status":"success","data":"sum(rate(nginx_http_requests_total[10m]))"}`))
+	}))
+	defer server.Close()
+
+	source := &PrometheusMetricSource{Defaults: PrometheusEndpoint{URL: server.URL}}
+
+	t.Run("ReturnsNilForValidQueries", func(t *testing.T) {
+
+		// act
+		unvalidated, err := source.ValidateQueries(context.Background(), []MIGConfiguration{
+			MIGConfiguration{InstanceGroupName: "instance-group-name", RequestRateQuery: "sum(rate(nginx_http_requests_total[10m]))"},
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(unvalidated))
+	})
+
+	t.Run("ReturnsValidationErrorForQueryRejectedByPrometheus", func(t *testing.T) {
+
+		// act
+		_, err := source.ValidateQueries(context.Background(), []MIGConfiguration{
+			MIGConfiguration{InstanceGroupName: "instance-group-a", RequestRateQuery: "sum(rate(nginx_http_requests_total[10m]))"},
+			MIGConfiguration{InstanceGroupName: "instance-group-b", Queries: []map[string]interface{}{
+				map[string]interface{}{"requestRateQuery": "sum(rate(nginx_http_requests_total[10m])) by"},
+			}},
+		})
+
+		if assert.IsType(t, ValidationErrors{}, err) {
+			validationErrors := err.(ValidationErrors)
+			assert.Equal(t, 1, len(validationErrors))
+			assert.Equal(t, 1, validationErrors[0].Index)
+			assert.Equal(t, "queries[0].requestRateQuery", validationErrors[0].Field)
+			assert.Equal(t, "1:43: parse error: unexpected end of input", validationErrors[0].Message)
+		}
+	})
+
+	t.Run("ReturnsValidationErrorForTrendQueryRejectedByPrometheus", func(t *testing.T) {
+
+		// act
+		_, err := source.ValidateQueries(context.Background(), []MIGConfiguration{
+			MIGConfiguration{InstanceGroupName: "instance-group-name", RequestRateQuery: "sum(rate(nginx_http_requests_total[10m]))", TrendQuery: "sum(rate(nginx_http_requests_total[10m])) by"},
+		})
+
+		if assert.IsType(t, ValidationErrors{}, err) {
+			validationErrors := err.(ValidationErrors)
+			assert.Equal(t, 1, len(validationErrors))
+			assert.Equal(t, "trendQuery", validationErrors[0].Field)
+		}
+	})
+
+	t.Run("ReturnsQueriesOfServerWithoutFormatQueryEndpointOnlyTryingItOnce", func(t *testing.T) {
+
+		requests := 0
+		oldServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer oldServer.Close()
+
+		oldSource := &PrometheusMetricSource{Defaults: PrometheusEndpoint{URL: oldServer.URL}}
+
+		// act
+		unvalidated, err := oldSource.ValidateQueries(context.Background(), []MIGConfiguration{
+			MIGConfiguration{InstanceGroupName: "instance-group-a", RequestRateQuery: "sum(rate(nginx_http_requests_total[10m]))"},
+			MIGConfiguration{InstanceGroupName: "instance-group-b", RequestRateQuery: "sum(rate(nginx_http_requests_total[10m]))"},
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, requests)
+		if assert.Equal(t, 2, len(unvalidated)) {
+			assert.Equal(t, "instance-group-b", unvalidated[1].InstanceGroupName)
+			assert.Equal(t, "requestRateQuery", unvalidated[1].Field)
+			assert.Equal(t, oldServer.URL, unvalidated[1].PrometheusURL)
+			assert.NotNil(t, unvalidated[1].Err)
+		}
+	})
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// PubSubMetricSource uses the number of undelivered messages of a pub/sub subscription, retrieved from cloud monitoring, as scaling signal for pull workers
+type PubSubMetricSource struct {
+	CloudMonitoring *CloudMonitoringMetricSource
+}
+
+// GetRequestRate returns the backlog of subscription pubsubSubscription in pubsubProject, which defaults to the project of the managed instance group
+func (s *PubSubMetricSource) GetRequestRate(ctx context.Context, configItem MIGConfiguration) (float64, error) {
+
+	project := configItem.PubSubProject
+	if project == "" {
+		project = configItem.GCloudProject
+	}
+
+	return s.CloudMonitoring.getRequestRateWithFilter(ctx, project, PubSubBacklogFilter(configItem.PubSubSubscription), "ALIGN_MEAN")
+}
+
+// PubSubBacklogFilter returns the cloud monitoring filter for the number of undelivered messages of a subscription
+func PubSubBacklogFilter(subscription string) string {
+	return fmt.Sprintf("metric.type=\"pubsub.googleapis.com/subscription/num_undelivered_messages\" AND resource.type=\"pubsub_subscription\" AND resource.labels.subscription_id=\"%v\"", subscription)
+}
+
+// validatePubSubConfig checks the fields required for the pubsub metric source, which reads a subscription's backlog instead of executing requestRateQuery
+func validatePubSubConfig(c *MIGConfiguration, addError func(field, message string)) {
+	if c.PubSubSubscription == "" {
+		addError("pubsubSubscription", "is required for metricSource pubsub")
+	}
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPubSubBacklogFilter(t *testing.T) {
+
+	t.Run("ReturnsFilterForUndeliveredMessagesOfSubscription", func(t *testing.T) {
+
+		// act
+		filter := PubSubBacklogFilter("orders-worker")
+
+		assert.Equal(t, "metric.type=\"pubsub.googleapis.com/subscription/num_undelivered_messages\" AND resource.type=\"pubsub_subscription\" AND resource.labels.subscription_id=\"orders-worker\"", filter)
+	})
+}
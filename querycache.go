@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+type queryCacheContextKey struct{}
+
+// queryCache holds the responses of queries executed during a single scaling iteration, so managed instance groups sharing a query only execute it once
+type queryCache struct {
+	sync.Mutex
+	entries map[string]*queryCacheEntry
+}
+
+type queryCacheEntry struct {
+	once     sync.Once
+	response PrometheusQueryResponse
+	err      error
+}
+
+// WithQueryCache returns a context that caches query responses until it's discarded; use a new one for every scaling iteration
+func WithQueryCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, queryCacheContextKey{}, &queryCache{entries: map[string]*queryCacheEntry{}})
+}
+
+// cachedPrometheusQuery returns the cached response for the key if the context has a query cache, and executes the query otherwise; concurrent callers for the same key wait for a single execution
+func cachedPrometheusQuery(ctx context.Context, key string, query func() (PrometheusQueryResponse, error)) (PrometheusQueryResponse, error) {
+
+	cache, ok := ctx.Value(queryCacheContextKey{}).(*queryCache)
+	if !ok {
+		return query()
+	}
+
+	cache.Lock()
+	entry, ok := cache.entries[key]
+	if ok {
+		queryCacheHitsCounter.Inc()
+	} else {
+		entry = &queryCacheEntry{}
+		cache.entries[key] = entry
+	}
+	cache.Unlock()
+
+	entry.once.Do(func() {
+		entry.response, entry.err = query()
+	})
+
+	return entry.response, entry.err
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachedPrometheusQuery(t *testing.T) {
+
+	t.Run("ExecutesIdenticalQueryOnceWithQueryCache", func(t *testing.T) {
+
+		executions := 0
+		query := func() (PrometheusQueryResponse, error) {
+			executions++
+			return PrometheusQueryResponse{Status: "success"}, nil
+		}
+		ctx := WithQueryCache(context.Background())
+
+		// act
+		cachedPrometheusQuery(ctx, "sum(up)", query)
+		response, err := cachedPrometheusQuery(ctx, "sum(up)", query)
+		cachedPrometheusQuery(ctx, "sum(down)", query)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "success", response.Status)
+		assert.Equal(t, 2, executions)
+	})
+
+	t.Run("ExecutesQueryEveryTimeWithoutQueryCache", func(t *testing.T) {
+
+		executions := 0
+		query := func() (PrometheusQueryResponse, error) {
+			executions++
+			return PrometheusQueryResponse{}, nil
+		}
+
+		// act
+		cachedPrometheusQuery(context.Background(), "sum(up)", query)
+		cachedPrometheusQuery(context.Background(), "sum(up)", query)
+
+		assert.Equal(t, 2, executions)
+	})
+}
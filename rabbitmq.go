@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/sethgrid/pester"
+)
+
+const (
+	rabbitMQQueueDepthMetric  = "messages"
+	rabbitMQPublishRateMetric = "publishRate"
+)
+
+// RabbitMQMetricSource uses the depth or publish rate of a rabbitmq queue, retrieved from the management api, as scaling signal for consumer instance groups
+type RabbitMQMetricSource struct {
+	// ManagementURL is the default management api, used for managed instance groups that don't set rabbitmqUrl
+	ManagementURL string
+	Username      string
+	Password      string
+}
+
+// RabbitMQQueueResponse is used to unmarshal the response of the management api for a single queue
+type RabbitMQQueueResponse struct {
+	Messages     *float64 `json:"messages"`
+	MessageStats struct {
+		PublishDetails struct {
+			Rate *float64 `json:"rate"`
+		} `json:"publish_details"`
+	} `json:"message_stats"`
+}
+
+// GetRequestRate returns the number of messages in the queue, or its publish rate per second if rabbitmqMetric is publishRate
+func (s *RabbitMQMetricSource) GetRequestRate(ctx context.Context, configItem MIGConfiguration) (float64, error) {
+
+	managementURL := s.ManagementURL
+	if configItem.RabbitMQURL != "" {
+		managementURL = configItem.RabbitMQURL
+	}
+	vhost := configItem.RabbitMQVhost
+	if vhost == "" {
+		vhost = "/"
+	}
+	queueURL := fmt.Sprintf("%v/api/queues/%v/%v", strings.TrimSuffix(managementURL, "/"), url.PathEscape(vhost), url.PathEscape(configItem.RabbitMQQueue))
+
+	request, err := http.NewRequest(http.MethodGet, queueURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	if s.Username != "" {
+		request.SetBasicAuth(s.Username, s.Password)
+	}
+
+	resp, err := pester.Do(request.WithContext(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("Retrieving rabbitmq queue %v failed: %v", configItem.RabbitMQQueue, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("Reading rabbitmq queue response body failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("Rabbitmq management api returned status code %v: %v", resp.StatusCode, string(body))
+	}
+
+	var queueResponse RabbitMQQueueResponse
+	if err = json.Unmarshal(body, &queueResponse); err != nil {
+		return 0, fmt.Errorf("Unmarshalling rabbitmq queue response body failed: %v", err)
+	}
+
+	if configItem.RabbitMQMetric == rabbitMQPublishRateMetric {
+		// publish details are missing until the first message is published
+		if queueResponse.MessageStats.PublishDetails.Rate == nil {
+			return 0, nil
+		}
+		return *queueResponse.MessageStats.PublishDetails.Rate, nil
+	}
+
+	if queueResponse.Messages == nil {
+		return 0, errors.New("Rabbitmq queue response has no message count")
+	}
+
+	return *queueResponse.Messages, nil
+}
+
+// validateRabbitMQConfig checks the fields required for the rabbitmq metric source, which reads a queue instead of executing requestRateQuery
+func validateRabbitMQConfig(c *MIGConfiguration, addError func(field, message string)) {
+	if c.RabbitMQQueue == "" {
+		addError("rabbitmqQueue", "is required for metricSource rabbitmq")
+	}
+	if c.RabbitMQMetric != "" && c.RabbitMQMetric != rabbitMQQueueDepthMetric && c.RabbitMQMetric != rabbitMQPublishRateMetric {
+		addError("rabbitmqMetric", fmt.Sprintf("should be either %v or %v", rabbitMQQueueDepthMetric, rabbitMQPublishRateMetric))
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRabbitMQMetricSourceGetRequestRate(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, _ := r.BasicAuth()
+		assert.Equal(t, "scaler", username)
+		assert.Equal(t, "secret", password)
+		assert.Equal(t, "/api/queues/%2F/orders", r.URL.EscapedPath())
+		w.Write([]byte(`{"name":"orders","messages":1200,"message_stats":{"publish_details":{"rate":35.5}}}`))
+	}))
+	defer server.Close()
+
+	source := &RabbitMQMetricSource{ManagementURL: server.URL, Username: "scaler", Password: "secret"}
+
+	t.Run("ReturnsQueueDepthByDefault", func(t *testing.T) {
+
+		// act
+		requestRate, err := source.GetRequestRate(context.Background(), MIGConfiguration{RabbitMQQueue: "orders"})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1200.0, requestRate)
+	})
+
+	t.Run("ReturnsPublishRateIfConfigured", func(t *testing.T) {
+
+		// act
+		requestRate, err := source.GetRequestRate(context.Background(), MIGConfiguration{RabbitMQQueue: "orders", RabbitMQMetric: "publishRate"})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 35.5, requestRate)
+	})
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+const (
+	instantQueryType = "instant"
+	rangeQueryType   = "range"
+
+	defaultRangeWindowSeconds = 600
+	defaultRangeStepSeconds   = 60
+
+	maxRangeAggregation = "max"
+	avgRangeAggregation = "avg"
+)
+
+// percentileRangeAggregation matches range aggregations like p95 or p99.9
+var percentileRangeAggregation = regexp.MustCompile(`^p(\d+(\.\d+)?)$`)
+
+// RangeWindow returns the number of seconds a range query looks back; it defaults to 10 minutes
+func (c *MIGConfiguration) RangeWindow() int {
+	if c.RangeWindowSeconds > 0 {
+		return c.RangeWindowSeconds
+	}
+	return defaultRangeWindowSeconds
+}
+
+// RangeStep returns the number of seconds between the samples of a range query; it defaults to 1 minute
+func (c *MIGConfiguration) RangeStep() int {
+	if c.RangeStepSeconds > 0 {
+		return c.RangeStepSeconds
+	}
+	return defaultRangeStepSeconds
+}
+
+// AggregateRangeValues reduces the samples of a range query to a single request rate with max (default), avg or a percentile like p95
+func AggregateRangeValues(values []float64, aggregation string) (float64, error) {
+
+	if len(values) == 0 {
+		return 0, errors.New("No samples to aggregate")
+	}
+
+	switch aggregation {
+	case "", maxRangeAggregation:
+		return percentile(values, 100), nil
+
+	case avgRangeAggregation:
+		sum := 0.0
+		for _, value := range values {
+			sum += value
+		}
+		return sum / float64(len(values)), nil
+	}
+
+	p, err := parsePercentile(aggregation)
+	if err != nil {
+		return 0, err
+	}
+
+	return percentile(values, p), nil
+}
+
+// percentile returns the nearest-rank percentile p of the values
+func percentile(values []float64, p float64) float64 {
+
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+
+	return sorted[rank-1]
+}
+
+// parsePercentile returns the percentile of a range aggregation like p95
+func parsePercentile(aggregation string) (float64, error) {
+
+	matches := percentileRangeAggregation.FindStringSubmatch(aggregation)
+	if matches == nil {
+		return 0, fmt.Errorf("Range aggregation %v is not supported", aggregation)
+	}
+
+	p, err := strconv.ParseFloat(matches[1], 64)
+	if err != nil || p <= 0 || p > 100 {
+		return 0, fmt.Errorf("Range aggregation %v should be a percentile between 0 and 100", aggregation)
+	}
+
+	return p, nil
+}
+
+// validateRangeQuery checks the query type and range query settings
+func (c *MIGConfiguration) validateRangeQuery(addError func(field, message string)) {
+
+	// rangeAggregation also applies to instant queries returning a range vector
+	switch c.RangeAggregation {
+	case "", maxRangeAggregation, avgRangeAggregation:
+	default:
+		if _, err := parsePercentile(c.RangeAggregation); err != nil {
+			addError("rangeAggregation", "should be max, avg or a percentile like p95")
+		}
+	}
+
+	switch c.QueryType {
+	case "", instantQueryType:
+		return
+	case rangeQueryType:
+	default:
+		addError("queryType", fmt.Sprintf("should be one of %v", []string{instantQueryType, rangeQueryType}))
+		return
+	}
+
+	if c.RangeWindowSeconds < 0 {
+		addError("rangeWindowSeconds", "should be 0 or larger")
+	}
+	if c.RangeStepSeconds < 0 {
+		addError("rangeStepSeconds", "should be 0 or larger")
+	}
+	if c.RangeStep() > c.RangeWindow() {
+		addError("rangeStepSeconds", "should not be larger than rangeWindowSeconds")
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregateRangeValues(t *testing.T) {
+
+	values := []float64{10, 50, 20, 40, 30, 60, 70, 80, 90, 100}
+
+	t.Run("ReturnsMaxIfAggregationIsNotSet", func(t *testing.T) {
+
+		// act
+		requestRate, err := AggregateRangeValues(values, "")
+
+		assert.Nil(t, err)
+		assert.Equal(t, 100.0, requestRate)
+	})
+
+	t.Run("ReturnsAverage", func(t *testing.T) {
+
+		// act
+		requestRate, err := AggregateRangeValues(values, "avg")
+
+		assert.Nil(t, err)
+		assert.Equal(t, 55.0, requestRate)
+	})
+
+	t.Run("ReturnsNearestRankPercentile", func(t *testing.T) {
+
+		// act
+		requestRate, err := AggregateRangeValues(values, "p75")
+
+		assert.Nil(t, err)
+		assert.Equal(t, 80.0, requestRate)
+	})
+
+	t.Run("ReturnsErrorForUnsupportedAggregation", func(t *testing.T) {
+
+		// act
+		_, err := AggregateRangeValues(values, "p101")
+
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRecurrences is the maximum number of occurrences of a recurring calendar event that are looked at, so a rule without end starting long ago can't keep a refresh busy
+const maxRecurrences = 100000
+
+// recurrenceWeekdays are the weekdays of BYDAY in an RRULE
+var recurrenceWeekdays = map[string]time.Weekday{"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday}
+
+// RecurrenceRule is a parsed iCalendar RRULE; only the DAILY, WEEKLY and MONTHLY frequencies with INTERVAL, COUNT, UNTIL and, for WEEKLY, BYDAY with plain weekdays are supported, since those cover the recurring events people schedule capacity with
+type RecurrenceRule struct {
+	frequency string
+	interval  int
+	count     int
+	until     time.Time
+	weekdays  []time.Weekday
+}
+
+// ParseRecurrenceRule parses an RRULE value like FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;UNTIL=20201231T235959Z, returning an error for the parts it doesn't support
+func ParseRecurrenceRule(value string) (rule RecurrenceRule, err error) {
+
+	rule.interval = 1
+	for _, part := range strings.Split(value, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return rule, fmt.Errorf("Recurrence rule part %v is invalid", part)
+		}
+
+		switch key, val := strings.ToUpper(kv[0]), kv[1]; key {
+		case "FREQ":
+			rule.frequency = strings.ToUpper(val)
+		case "INTERVAL":
+			if rule.interval, err = strconv.Atoi(val); err != nil || rule.interval < 1 {
+				return rule, fmt.Errorf("Recurrence rule interval %v is invalid", val)
+			}
+		case "COUNT":
+			if rule.count, err = strconv.Atoi(val); err != nil || rule.count < 1 {
+				return rule, fmt.Errorf("Recurrence rule count %v is invalid", val)
+			}
+		case "UNTIL":
+			params := map[string]string{}
+			if len(val) == len("20060102") {
+				params["VALUE"] = "DATE"
+			}
+			if rule.until, err = parseICalendarTime(params, val); err != nil {
+				return rule, fmt.Errorf("Recurrence rule until %v is invalid: %v", val, err)
+			}
+		case "BYDAY":
+			for _, day := range strings.Split(val, ",") {
+				weekday, ok := recurrenceWeekdays[strings.ToUpper(day)]
+				if !ok {
+					return rule, fmt.Errorf("Recurrence rule weekday %v is not supported", day)
+				}
+				rule.weekdays = append(rule.weekdays, weekday)
+			}
+		case "WKST":
+			// weeks start on monday, which only makes a difference for weekly rules with an interval and multiple weekdays
+		default:
+			return rule, fmt.Errorf("Recurrence rule part %v is not supported", key)
+		}
+	}
+
+	switch rule.frequency {
+	case "DAILY", "MONTHLY":
+		if len(rule.weekdays) > 0 {
+			return rule, fmt.Errorf("Recurrence rule BYDAY is only supported with FREQ=WEEKLY")
+		}
+	case "WEEKLY":
+	default:
+		return rule, fmt.Errorf("Recurrence rule frequency %v is not supported", rule.frequency)
+	}
+
+	return rule, nil
+}
+
+// Occurrences returns the starts of the occurrences of the rule for an event starting at start that begin before until, according to the wall clock of the location of start so they follow daylight saving time; the first occurrence is start itself
+func (r RecurrenceRule) Occurrences(start, until time.Time) (starts []time.Time) {
+
+	number := 0
+	add := func(occurrence time.Time) bool {
+		if !r.until.IsZero() && occurrence.After(r.until) || !occurrence.Before(until) || (r.count > 0 && number >= r.count) {
+			return false
+		}
+		starts = append(starts, occurrence)
+		number++
+		return true
+	}
+
+	for i := 0; i < maxRecurrences; i++ {
+		switch r.frequency {
+		case "DAILY":
+			if !add(start.AddDate(0, 0, i*r.interval)) {
+				return
+			}
+
+		case "MONTHLY":
+			// months without the day of month of the start are skipped, rather than rolled over into the next month
+			occurrence := start.AddDate(0, i*r.interval, 0)
+			if occurrence.Day() == start.Day() && !add(occurrence) {
+				return
+			}
+
+		case "WEEKLY":
+			if len(r.weekdays) == 0 {
+				if !add(start.AddDate(0, 0, 7*i*r.interval)) {
+					return
+				}
+				continue
+			}
+
+			monday := start.AddDate(0, 0, -((int(start.Weekday())+6)%7)+7*i*r.interval)
+			days := []int{}
+			for _, weekday := range r.weekdays {
+				days = append(days, (int(weekday)+6)%7)
+			}
+			sort.Ints(days)
+			for _, day := range days {
+				occurrence := monday.AddDate(0, 0, day)
+				if occurrence.Before(start) {
+					continue
+				}
+				if !add(occurrence) {
+					return
+				}
+			}
+		}
+	}
+
+	return
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRecurrenceRule(t *testing.T) {
+
+	t.Run("ParsesSupportedParts", func(t *testing.T) {
+
+		// act
+		rule, err := ParseRecurrenceRule("FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=MO,FR;WKST=MO")
+
+		assert.Nil(t, err)
+		assert.Equal(t, "WEEKLY", rule.frequency)
+		assert.Equal(t, 2, rule.interval)
+		assert.Equal(t, 4, rule.count)
+		assert.Equal(t, []time.Weekday{time.Monday, time.Friday}, rule.weekdays)
+	})
+
+	t.Run("ReturnsErrorForUnsupportedParts", func(t *testing.T) {
+
+		for _, value := range []string{"FREQ=YEARLY", "FREQ=MONTHLY;BYDAY=1MO", "FREQ=WEEKLY;BYDAY=1MO", "FREQ=DAILY;BYHOUR=9", "FREQ=DAILY;INTERVAL=0", "INTERVAL=2"} {
+
+			// act
+			_, err := ParseRecurrenceRule(value)
+
+			assert.NotNil(t, err, value)
+		}
+	})
+}
+
+func TestOccurrences(t *testing.T) {
+
+	t.Run("FollowsWallClockAcrossDaylightSavingTime", func(t *testing.T) {
+
+		location, _ := time.LoadLocation("Europe/Amsterdam")
+		rule, _ := ParseRecurrenceRule("FREQ=DAILY;COUNT=3")
+
+		// act
+		starts := rule.Occurrences(time.Date(2020, 3, 28, 9, 0, 0, 0, location), time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+
+		if assert.Equal(t, 3, len(starts)) {
+			assert.Equal(t, time.Date(2020, 3, 28, 8, 0, 0, 0, time.UTC), starts[0].UTC())
+			assert.Equal(t, time.Date(2020, 3, 29, 7, 0, 0, 0, time.UTC), starts[1].UTC())
+			assert.Equal(t, time.Date(2020, 3, 30, 7, 0, 0, 0, time.UTC), starts[2].UTC())
+		}
+	})
+
+	t.Run("ReturnsWeekdaysOfEveryOtherWeekUntilEndOfRule", func(t *testing.T) {
+
+		rule, _ := ParseRecurrenceRule("FREQ=WEEKLY;INTERVAL=2;BYDAY=FR,MO;UNTIL=20200320")
+
+		// act
+		starts := rule.Occurrences(time.Date(2020, 3, 4, 9, 0, 0, 0, time.UTC), time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+
+		assert.Equal(t, []time.Time{
+			time.Date(2020, 3, 6, 9, 0, 0, 0, time.UTC),
+			time.Date(2020, 3, 16, 9, 0, 0, 0, time.UTC),
+		}, starts)
+	})
+
+	t.Run("SkipsMonthsWithoutDayOfMonthOfStart", func(t *testing.T) {
+
+		rule, _ := ParseRecurrenceRule("FREQ=MONTHLY")
+
+		// act
+		starts := rule.Occurrences(time.Date(2020, 1, 31, 9, 0, 0, 0, time.UTC), time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC))
+
+		assert.Equal(t, []time.Time{
+			time.Date(2020, 1, 31, 9, 0, 0, 0, time.UTC),
+			time.Date(2020, 3, 31, 9, 0, 0, 0, time.UTC),
+		}, starts)
+	})
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/rs/zerolog/log"
+)
+
+// remoteWriteTimeout is the maximum time for pushing a scaling decision, independent of the compute api calls of the iteration
+const remoteWriteTimeout = 10 * time.Second
+
+// outcomes of the autoscaler update for a scaling decision
+const (
+	updatedDecisionOutcome   = "updated"
+	unchangedDecisionOutcome = "unchanged"
+	skippedDecisionOutcome   = "skipped"
+	failedDecisionOutcome    = "failed"
+)
+
+// RemoteWriter pushes samples to a prometheus remote write endpoint, like prometheus itself, cortex, mimir or thanos receive
+type RemoteWriter struct {
+	URL string
+
+	// Headers are sent with every write, like X-Scope-OrgID for the tenant of cortex or mimir
+	Headers map[string]string
+
+	// Username and Password are used for basic auth, BearerTokenFile is read for every write
+	Username        string
+	Password        string
+	BearerTokenFile string
+
+	client    *http.Client
+	waitGroup sync.WaitGroup
+}
+
+// RemoteWriteSample is a single sample of a series to push
+type RemoteWriteSample struct {
+	Labels    map[string]string
+	Value     float64
+	Timestamp time.Time
+}
+
+// NewRemoteWriter returns a remote writer for the remote write endpoint url
+func NewRemoteWriter(url string) *RemoteWriter {
+	return &RemoteWriter{
+		URL:    url,
+		client: &http.Client{Timeout: remoteWriteTimeout},
+	}
+}
+
+// WriteDecision pushes the request rate, the target and the decided minimum number of instances of a scaling decision, with the outcome of the autoscaler update, in the background; failures are logged, since they shouldn't stop or slow down scaling
+func (w *RemoteWriter) WriteDecision(instanceGroupName string, requestRate float64, targetMinimumNumberOfInstances, minimumNumberOfInstances int, outcome string) {
+
+	now := time.Now()
+	samples := []RemoteWriteSample{
+		RemoteWriteSample{Labels: map[string]string{"__name__": "estafette_gcloud_mig_scaler_decision_request_rate", "mig": instanceGroupName}, Value: requestRate, Timestamp: now},
+		RemoteWriteSample{Labels: map[string]string{"__name__": "estafette_gcloud_mig_scaler_decision_target_min_instances", "mig": instanceGroupName}, Value: float64(targetMinimumNumberOfInstances), Timestamp: now},
+		RemoteWriteSample{Labels: map[string]string{"__name__": "estafette_gcloud_mig_scaler_decision_min_instances", "mig": instanceGroupName, "outcome": outcome}, Value: float64(minimumNumberOfInstances), Timestamp: now},
+	}
+
+	w.waitGroup.Add(1)
+	go func() {
+		defer w.waitGroup.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), remoteWriteTimeout)
+		defer cancel()
+
+		if err := w.Write(ctx, samples); err != nil {
+			log.Warn().Err(err).Msgf("Remote writing scaling decision for mig %v failed", instanceGroupName)
+		}
+	}()
+}
+
+// Wait waits for the scaling decisions that are still being pushed
+func (w *RemoteWriter) Wait() {
+	w.waitGroup.Wait()
+}
+
+// Write pushes the samples as a snappy compressed protobuf write request
+func (w *RemoteWriter) Write(ctx context.Context, samples []RemoteWriteSample) error {
+
+	body := snappy.Encode(nil, EncodeRemoteWriteRequest(samples))
+
+	request, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Encoding", "snappy")
+	request.Header.Set("Content-Type", "application/x-protobuf")
+	request.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	for name, value := range w.Headers {
+		request.Header.Set(name, value)
+	}
+	if w.Username != "" {
+		request.SetBasicAuth(w.Username, w.Password)
+	}
+	if w.BearerTokenFile != "" {
+		token, err := ioutil.ReadFile(w.BearerTokenFile)
+		if err != nil {
+			return fmt.Errorf("Reading remote write bearer token file %v failed: %v", w.BearerTokenFile, err)
+		}
+		request.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := w.client.Do(request.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		message, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("Remote write endpoint returned status code %v: %v", resp.StatusCode, string(message))
+	}
+
+	return nil
+}
+
+// EncodeRemoteWriteRequest encodes the samples as prometheus.WriteRequest protobuf message, with a time series per sample
+func EncodeRemoteWriteRequest(samples []RemoteWriteSample) []byte {
+
+	// field numbers and wire types of the remote write protobuf messages
+	const (
+		writeRequestTimeseries = 1<<3 | 2
+		timeSeriesLabels       = 1<<3 | 2
+		timeSeriesSamples      = 2<<3 | 2
+		labelName              = 1<<3 | 2
+		labelValue             = 2<<3 | 2
+		sampleValue            = 1<<3 | 1
+		sampleTimestamp        = 2<<3 | 0
+	)
+
+	writeRequest := proto.NewBuffer(nil)
+	for _, sample := range samples {
+
+		// labels have to be sorted by name
+		names := []string{}
+		for name := range sample.Labels {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		timeSeries := proto.NewBuffer(nil)
+		for _, name := range names {
+			label := proto.NewBuffer(nil)
+			label.EncodeVarint(labelName)
+			label.EncodeStringBytes(name)
+			label.EncodeVarint(labelValue)
+			label.EncodeStringBytes(sample.Labels[name])
+
+			timeSeries.EncodeVarint(timeSeriesLabels)
+			timeSeries.EncodeRawBytes(label.Bytes())
+		}
+
+		s := proto.NewBuffer(nil)
+		s.EncodeVarint(sampleValue)
+		s.EncodeFixed64(math.Float64bits(sample.Value))
+		s.EncodeVarint(sampleTimestamp)
+		s.EncodeVarint(uint64(sample.Timestamp.UnixNano() / int64(time.Millisecond)))
+
+		timeSeries.EncodeVarint(timeSeriesSamples)
+		timeSeries.EncodeRawBytes(s.Bytes())
+
+		writeRequest.EncodeVarint(writeRequestTimeseries)
+		writeRequest.EncodeRawBytes(timeSeries.Bytes())
+	}
+
+	return writeRequest.Bytes()
+}
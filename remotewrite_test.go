@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemoteWriter(t *testing.T) {
+
+	t.Run("PushesSnappyCompressedWriteRequest", func(t *testing.T) {
+
+		samples := []RemoteWriteSample{
+			RemoteWriteSample{Labels: map[string]string{"mig": "web", "__name__": "estafette_gcloud_mig_scaler_decision_min_instances"}, Value: 12, Timestamp: time.Unix(1500000000, 0)},
+		}
+
+		var body []byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "snappy", r.Header.Get("Content-Encoding"))
+			assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+			assert.Equal(t, "0.1.0", r.Header.Get("X-Prometheus-Remote-Write-Version"))
+			compressed, _ := ioutil.ReadAll(r.Body)
+			body, _ = snappy.Decode(nil, compressed)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		// act
+		err := NewRemoteWriter(server.URL).Write(context.Background(), samples)
+
+		assert.Nil(t, err)
+		assert.Equal(t, EncodeRemoteWriteRequest(samples), body)
+	})
+
+	t.Run("ReturnsErrorForNon2xxResponse", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("out of order sample"))
+		}))
+		defer server.Close()
+
+		// act
+		err := NewRemoteWriter(server.URL).Write(context.Background(), []RemoteWriteSample{})
+
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "out of order sample")
+		}
+	})
+
+	t.Run("SendsHeadersAndBasicAuth", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			username, password, ok := r.BasicAuth()
+			assert.True(t, ok)
+			assert.Equal(t, "scaler", username)
+			assert.Equal(t, "secret", password)
+			assert.Equal(t, "tenant-a", r.Header.Get("X-Scope-OrgID"))
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		remoteWriter := NewRemoteWriter(server.URL)
+		remoteWriter.Headers = map[string]string{"X-Scope-OrgID": "tenant-a"}
+		remoteWriter.Username = "scaler"
+		remoteWriter.Password = "secret"
+
+		// act
+		err := remoteWriter.Write(context.Background(), []RemoteWriteSample{})
+
+		assert.Nil(t, err)
+	})
+}
+
+func TestWriteDecision(t *testing.T) {
+
+	t.Run("PushesDecisionWithOutcomeInBackground", func(t *testing.T) {
+
+		var body []byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			compressed, _ := ioutil.ReadAll(r.Body)
+			body, _ = snappy.Decode(nil, compressed)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		remoteWriter := NewRemoteWriter(server.URL)
+
+		// act
+		remoteWriter.WriteDecision("web", 225.4, 12, 10, skippedDecisionOutcome)
+		remoteWriter.Wait()
+
+		assert.Contains(t, string(body), "outcome")
+		assert.Contains(t, string(body), skippedDecisionOutcome)
+	})
+}
+
+func TestEncodeRemoteWriteRequest(t *testing.T) {
+
+	t.Run("EncodesSortedLabelsAndSample", func(t *testing.T) {
+
+		samples := []RemoteWriteSample{
+			RemoteWriteSample{Labels: map[string]string{"mig": "a", "__name__": "m"}, Value: 1, Timestamp: time.Unix(0, 2*int64(time.Millisecond))},
+		}
+
+		// act
+		encoded := EncodeRemoteWriteRequest(samples)
+
+		expected := []byte{
+			0x0a, 0x26, // timeseries
+			0x0a, 0x0d, 0x0a, 0x08, '_', '_', 'n', 'a', 'm', 'e', '_', '_', 0x12, 0x01, 'm', // label __name__=m
+			0x0a, 0x08, 0x0a, 0x03, 'm', 'i', 'g', 0x12, 0x01, 'a', // label mig=a
+			0x12, 0x0b, 0x09, 0, 0, 0, 0, 0, 0, 0xf0, 0x3f, 0x10, 0x02, // sample 1 at 2ms
+		}
+		assert.Equal(t, expected, encoded)
+	})
+}
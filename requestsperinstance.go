@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+)
+
+// RequestsPerInstanceConfig returns the configuration to execute the number of requests per instance query with; it inherits all fields of the managed instance group except its other queries
+func (c *MIGConfiguration) RequestsPerInstanceConfig() MIGConfiguration {
+	requestsPerInstanceConfig := c.TrendConfig()
+	requestsPerInstanceConfig.RequestRateQuery = c.RequestsPerInstanceQuery
+	requestsPerInstanceConfig.RequestsPerInstanceQuery = ""
+	requestsPerInstanceConfig.SpotPreemptionRateQuery = ""
+	requestsPerInstanceConfig.SLOQuery = ""
+	return requestsPerInstanceConfig
+}
+
+// applyRequestsPerInstanceQuery replaces numberOfRequestsPerInstance with the result of numberOfRequestsPerInstanceQuery, like the observed request rate per healthy instance at target cpu, kept between minNumberOfRequestsPerInstance and maxNumberOfRequestsPerInstance so it can't run away in a feedback loop with the minimum it drives; if the query fails the static numberOfRequestsPerInstance is used, if set
+func (s *MIGScaler) applyRequestsPerInstanceQuery(ctx context.Context, configItem MIGConfiguration) (MIGConfiguration, error) {
+
+	if configItem.RequestsPerInstanceQuery == "" {
+		return configItem, nil
+	}
+
+	queryCtx, cancelQuery := withTimeout(ctx, configItem.QueryTimeout(s.options.QueryTimeout))
+	defer cancelQuery()
+
+	numberOfRequestsPerInstance, err := getSingleRequestRate(queryCtx, s.metricSources, configItem.RequestsPerInstanceConfig())
+	if err != nil {
+		if configItem.NumberOfRequestsPerInstance <= 0 {
+			return configItem, fmt.Errorf("Retrieving number of requests per instance failed and numberOfRequestsPerInstance isn't set: %w", err)
+		}
+		log.Warn().Err(err).Msgf("Retrieving number of requests per instance for mig %v failed, using numberOfRequestsPerInstance %v", configItem.InstanceGroupName, configItem.NumberOfRequestsPerInstance)
+		return configItem, nil
+	}
+
+	if numberOfRequestsPerInstance < configItem.MinRequestsPerInstance {
+		log.Warn().Msgf("Number of requests per instance %v for mig %v is below minNumberOfRequestsPerInstance, raising it to %v", numberOfRequestsPerInstance, configItem.InstanceGroupName, configItem.MinRequestsPerInstance)
+		numberOfRequestsPerInstance = configItem.MinRequestsPerInstance
+	}
+	if numberOfRequestsPerInstance > configItem.MaxRequestsPerInstance {
+		log.Warn().Msgf("Number of requests per instance %v for mig %v is above maxNumberOfRequestsPerInstance, lowering it to %v", numberOfRequestsPerInstance, configItem.InstanceGroupName, configItem.MaxRequestsPerInstance)
+		numberOfRequestsPerInstance = configItem.MaxRequestsPerInstance
+	}
+
+	requestsPerInstanceVector.WithLabelValues(configItem.InstanceGroupName).Set(numberOfRequestsPerInstance)
+	configItem.NumberOfRequestsPerInstance = numberOfRequestsPerInstance
+
+	return configItem, nil
+}
+
+// validateRequestsPerInstanceQuery checks whether numberOfRequestsPerInstanceQuery has bounds that are larger than 0
+func (c *MIGConfiguration) validateRequestsPerInstanceQuery(addError func(field, message string)) {
+
+	if c.RequestsPerInstanceQuery == "" {
+		if c.MinRequestsPerInstance != 0 || c.MaxRequestsPerInstance != 0 {
+			addError("numberOfRequestsPerInstanceQuery", "is required for minNumberOfRequestsPerInstance and maxNumberOfRequestsPerInstance")
+		}
+		return
+	}
+
+	if c.MinRequestsPerInstance <= 0 {
+		addError("minNumberOfRequestsPerInstance", "should be larger than 0")
+	}
+	if c.MaxRequestsPerInstance < c.MinRequestsPerInstance {
+		addError("maxNumberOfRequestsPerInstance", "should be larger than or equal to minNumberOfRequestsPerInstance")
+	}
+}
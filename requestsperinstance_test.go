@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyRequestsPerInstanceQuery(t *testing.T) {
+
+	metricSources := map[string]MetricSource{"prometheus": &fakeMetricSource{requestRates: map[string]float64{
+		"rps_per_instance:at_target_cpu":      7.5,
+		"rps_per_instance:at_target_cpu:low":  0.2,
+		"rps_per_instance:at_target_cpu:high": 80,
+	}}}
+
+	t.Run("ReplacesNumberOfRequestsPerInstanceWithQueryResult", func(t *testing.T) {
+
+		configItem := MIGConfiguration{InstanceGroupName: "instance-group-name", NumberOfRequestsPerInstance: 5, RequestsPerInstanceQuery: "rps_per_instance:at_target_cpu", MinRequestsPerInstance: 2, MaxRequestsPerInstance: 20}
+		scaler := NewMIGScaler(nil, metricSources, MIGScalerOptions{})
+
+		// act
+		configItem, err := scaler.applyRequestsPerInstanceQuery(context.Background(), configItem)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 7.5, configItem.NumberOfRequestsPerInstance)
+	})
+
+	t.Run("KeepsQueryResultWithinBounds", func(t *testing.T) {
+
+		configItem := MIGConfiguration{InstanceGroupName: "instance-group-name", MinRequestsPerInstance: 2, MaxRequestsPerInstance: 20}
+		scaler := NewMIGScaler(nil, metricSources, MIGScalerOptions{})
+
+		// act
+		configItem.RequestsPerInstanceQuery = "rps_per_instance:at_target_cpu:low"
+		lowConfig, _ := scaler.applyRequestsPerInstanceQuery(context.Background(), configItem)
+		configItem.RequestsPerInstanceQuery = "rps_per_instance:at_target_cpu:high"
+		highConfig, _ := scaler.applyRequestsPerInstanceQuery(context.Background(), configItem)
+
+		assert.Equal(t, 2.0, lowConfig.NumberOfRequestsPerInstance)
+		assert.Equal(t, 20.0, highConfig.NumberOfRequestsPerInstance)
+	})
+
+	t.Run("UsesNumberOfRequestsPerInstanceIfQueryFails", func(t *testing.T) {
+
+		configItem := MIGConfiguration{InstanceGroupName: "instance-group-name", NumberOfRequestsPerInstance: 5, RequestsPerInstanceQuery: "rps_per_instance:at_target_cpu", MinRequestsPerInstance: 2, MaxRequestsPerInstance: 20}
+		scaler := NewMIGScaler(nil, map[string]MetricSource{"prometheus": &failingMetricSource{}}, MIGScalerOptions{})
+
+		// act
+		configItem, err := scaler.applyRequestsPerInstanceQuery(context.Background(), configItem)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 5.0, configItem.NumberOfRequestsPerInstance)
+	})
+
+	t.Run("ReturnsErrorIfQueryFailsWithoutNumberOfRequestsPerInstance", func(t *testing.T) {
+
+		configItem := MIGConfiguration{InstanceGroupName: "instance-group-name", RequestsPerInstanceQuery: "rps_per_instance:at_target_cpu", MinRequestsPerInstance: 2, MaxRequestsPerInstance: 20}
+		scaler := NewMIGScaler(nil, map[string]MetricSource{"prometheus": &failingMetricSource{}}, MIGScalerOptions{})
+
+		// act
+		_, err := scaler.applyRequestsPerInstanceQuery(context.Background(), configItem)
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestValidateRequestsPerInstanceQuery(t *testing.T) {
+
+	t.Run("ReturnsErrorForQueryWithoutBounds", func(t *testing.T) {
+
+		configItem := MIGConfiguration{RequestsPerInstanceQuery: "rps_per_instance:at_target_cpu"}
+		fields := []string{}
+
+		// act
+		configItem.validateRequestsPerInstanceQuery(func(field, message string) {
+			fields = append(fields, field)
+		})
+
+		assert.Equal(t, []string{"minNumberOfRequestsPerInstance"}, fields)
+	})
+
+	t.Run("ReturnsErrorForMaxBelowMin", func(t *testing.T) {
+
+		configItem := MIGConfiguration{RequestsPerInstanceQuery: "rps_per_instance:at_target_cpu", MinRequestsPerInstance: 10, MaxRequestsPerInstance: 5}
+		fields := []string{}
+
+		// act
+		configItem.validateRequestsPerInstanceQuery(func(field, message string) {
+			fields = append(fields, field)
+		})
+
+		assert.Equal(t, []string{"maxNumberOfRequestsPerInstance"}, fields)
+	})
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// confirmScaleDown returns the minimum number of instances to use; a minimum lower than the previous one is only used once it's been calculated for scaleDownConfirmations consecutive iterations, and then the highest of those lower minimums is used
+func (s *MIGScaler) confirmScaleDown(configItem MIGConfiguration, minimumNumberOfInstances int) (confirmed int) {
+
+	s.withState(configItem, func(state *migState) {
+
+		if !state.hasMinimumNumberOfInstances || minimumNumberOfInstances >= state.minimumNumberOfInstances || configItem.ScaleDownConfirmations <= 1 {
+			state.minimumNumberOfInstances, state.hasMinimumNumberOfInstances = minimumNumberOfInstances, true
+			state.lowerMinimumNumberOfInstances = nil
+			confirmed = minimumNumberOfInstances
+			return
+		}
+
+		state.lowerMinimumNumberOfInstances = append(state.lowerMinimumNumberOfInstances, minimumNumberOfInstances)
+		if len(state.lowerMinimumNumberOfInstances) < configItem.ScaleDownConfirmations {
+			log.Info().Msgf("Holding min instances for mig %v at %v, lower value %v observed %v of %v times", configItem.InstanceGroupName, state.minimumNumberOfInstances, minimumNumberOfInstances, len(state.lowerMinimumNumberOfInstances), configItem.ScaleDownConfirmations)
+			confirmed = state.minimumNumberOfInstances
+			return
+		}
+
+		confirmed = state.lowerMinimumNumberOfInstances[0]
+		for _, lower := range state.lowerMinimumNumberOfInstances {
+			if lower > confirmed {
+				confirmed = lower
+			}
+		}
+		state.minimumNumberOfInstances = confirmed
+		state.lowerMinimumNumberOfInstances = nil
+	})
+
+	return
+}
+
+// ScaleDownCooldown returns how long after an increase of the minimum number of instances decreases are held back
+func (c *MIGConfiguration) ScaleDownCooldown() time.Duration {
+	return time.Duration(c.ScaleDownCooldownSeconds) * time.Second
+}
+
+// holdScaleDownDuringCooldown returns the previously applied minimum number of instances instead of a lower one while within scaleDownCooldownSeconds of the last increase
+func (s *MIGScaler) holdScaleDownDuringCooldown(configItem MIGConfiguration, minimumNumberOfInstances int, now time.Time) int {
+
+	if configItem.ScaleDownCooldownSeconds <= 0 {
+		return minimumNumberOfInstances
+	}
+
+	applied, lastIncrease, ok := s.appliedMinimumNumberOfInstances(configItem)
+	if !ok || minimumNumberOfInstances >= applied || now.Sub(lastIncrease) >= configItem.ScaleDownCooldown() {
+		return minimumNumberOfInstances
+	}
+
+	log.Info().Msgf("Holding min instances for mig %v at %v instead of %v, it was raised %v ago which is within the scale down cooldown of %v", configItem.InstanceGroupName, applied, minimumNumberOfInstances, now.Sub(lastIncrease).Round(time.Second), configItem.ScaleDownCooldown())
+
+	return applied
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfirmScaleDown(t *testing.T) {
+
+	configItem := MIGConfiguration{InstanceGroupName: "instance-group-name", ScaleDownConfirmations: 3}
+
+	t.Run("LowersMinimumAfterConsecutiveConfirmations", func(t *testing.T) {
+
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+
+		// act
+		minimums := []int{}
+		for _, calculated := range []int{10, 6, 7, 5, 4} {
+			minimums = append(minimums, scaler.confirmScaleDown(configItem, calculated))
+		}
+
+		assert.Equal(t, []int{10, 10, 10, 7, 7}, minimums)
+	})
+
+	t.Run("ResetsConfirmationsWhenMinimumIsNotLower", func(t *testing.T) {
+
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+
+		// act
+		minimums := []int{}
+		for _, calculated := range []int{10, 6, 6, 12, 6, 6} {
+			minimums = append(minimums, scaler.confirmScaleDown(configItem, calculated))
+		}
+
+		assert.Equal(t, []int{10, 10, 10, 12, 12, 12}, minimums)
+	})
+
+	t.Run("LowersMinimumImmediatelyWithoutScaleDownConfirmations", func(t *testing.T) {
+
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+		scaler.confirmScaleDown(MIGConfiguration{InstanceGroupName: "instance-group-name"}, 10)
+
+		// act
+		minimum := scaler.confirmScaleDown(MIGConfiguration{InstanceGroupName: "instance-group-name"}, 6)
+
+		assert.Equal(t, 6, minimum)
+	})
+}
+
+func TestHoldScaleDownDuringCooldown(t *testing.T) {
+
+	configItem := MIGConfiguration{InstanceGroupName: "instance-group-name", ScaleDownCooldownSeconds: 300}
+	start := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("HoldsMinimumWithinCooldownAfterIncrease", func(t *testing.T) {
+
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+		scaler.setAppliedMinimumNumberOfInstances(configItem, 5, start)
+		scaler.setAppliedMinimumNumberOfInstances(configItem, 10, start.Add(time.Minute))
+
+		// act
+		minimumNumberOfInstances := scaler.holdScaleDownDuringCooldown(configItem, 6, start.Add(3*time.Minute))
+
+		assert.Equal(t, 10, minimumNumberOfInstances)
+	})
+
+	t.Run("LowersMinimumAfterCooldown", func(t *testing.T) {
+
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+		scaler.setAppliedMinimumNumberOfInstances(configItem, 5, start)
+		scaler.setAppliedMinimumNumberOfInstances(configItem, 10, start.Add(time.Minute))
+
+		// act
+		minimumNumberOfInstances := scaler.holdScaleDownDuringCooldown(configItem, 6, start.Add(6*time.Minute))
+
+		assert.Equal(t, 6, minimumNumberOfInstances)
+	})
+
+	t.Run("LowersMinimumIfItWasNeverIncreased", func(t *testing.T) {
+
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+		scaler.setAppliedMinimumNumberOfInstances(configItem, 10, start)
+
+		// act
+		minimumNumberOfInstances := scaler.holdScaleDownDuringCooldown(configItem, 6, start.Add(time.Minute))
+
+		assert.Equal(t, 6, minimumNumberOfInstances)
+	})
+}
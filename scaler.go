@@ -0,0 +1,485 @@
+package main
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	compute "google.golang.org/api/compute/v1"
+)
+
+// MIGScaler sets the minimum number of instances of managed instance groups based on their request rate
+type MIGScaler struct {
+	computeClient ComputeClient
+	metricSources map[string]MetricSource
+	options       MIGScalerOptions
+
+	// states holds what the scaler remembers about each managed instance group between iterations
+	states   map[migStateKey]*migState
+	statesMu sync.Mutex
+
+	// cache holds which autoscaler targets each mig for up to ComputeCacheTTL
+	cache *computeCache
+
+	// clients holds the clients of migs with credentialsFile or credentialsSecret by their service account key
+	clients   map[string]*migClient
+	clientsMu sync.Mutex
+
+	// expressions holds the parsed target expressions of the configuration revision being scaled
+	expressions *expressionCache
+}
+
+const (
+	ceilRounding  = "ceil"
+	roundRounding = "round"
+	floorRounding = "floor"
+)
+
+// roundings are the supported values for rounding
+var roundings = []string{ceilRounding, roundRounding, floorRounding}
+
+// MIGScalerOptions holds the settings that apply to scaling all managed instance groups
+type MIGScalerOptions struct {
+	// DisableAllUpdates keeps collecting and exporting metrics, but never updates any autoscaler
+	DisableAllUpdates bool
+
+	// QueryTimeout and ComputeTimeout apply to retrieving the request rate and to compute api calls respectively; 0 means no timeout
+	QueryTimeout   time.Duration
+	ComputeTimeout time.Duration
+
+	// OperationPoll is the interval at which autoscaler update operations are checked for completion
+	OperationPoll time.Duration
+
+	// ComputeScope is the oauth scope the clients of migs with their own service account key request; empty means cloud-platform
+	ComputeScope string
+
+	// ComputeConcurrency is the number of locations whose instance group managers are listed in parallel at the start of an iteration; 0 means every mig retrieves its own
+	ComputeConcurrency int
+
+	// ComputeCacheTTL is how long the autoscaler found for a mig is retrieved by name before it's searched for again; 0 means it's searched for every iteration
+	ComputeCacheTTL time.Duration
+
+	// RemoteWriter pushes every scaling decision to prometheus, if set
+	RemoteWriter *RemoteWriter
+
+	// EventRecorder creates kubernetes events for zone outage compensation, if set
+	EventRecorder *KubernetesEventRecorder
+
+	// Calendar raises the minimum number of instances during the events it has for a managed instance group, if set
+	Calendar *Calendar
+
+	// BillingCatalog has the prices for instanceCostSkus, if set, and MaxHourlyCost caps the estimated hourly cost of the minimums of all migs together; 0 means no cap
+	BillingCatalog *BillingCatalog
+	MaxHourlyCost  float64
+
+	// ScalingSchedules manages the scaling schedules of autoscalers for migs with useScalingSchedules
+	ScalingSchedules *ScalingSchedulesClient
+}
+
+// NewMIGScaler returns a scaler using the compute service for autoscaler updates and the metric sources by name for request rates
+func NewMIGScaler(computeClient ComputeClient, metricSources map[string]MetricSource, options MIGScalerOptions) *MIGScaler {
+	return &MIGScaler{
+		computeClient: computeClient,
+		metricSources: metricSources,
+		options:       options,
+		states:        map[migStateKey]*migState{},
+		cache:         newComputeCache(options.ComputeCacheTTL),
+		clients:       map[string]*migClient{},
+		expressions:   newExpressionCache(),
+	}
+}
+
+// Scale retrieves the request rate for a managed instance group, exports the calculated minimum number of instances and updates its autoscaler if enabled
+func (s *MIGScaler) Scale(ctx context.Context, configItem MIGConfiguration, configRevision string) {
+
+	if !configItem.IsEnabled() {
+		log.Info().Msgf("Skipping managed instance group %v, it's disabled", configItem.InstanceGroupName)
+		return
+	}
+
+	s.expressions.useRevision(configRevision)
+
+	now := time.Now()
+	profileConfig, err := configItem.ProfileConfig(now)
+	if err != nil {
+		log.Error().Err(err).Msgf("Applying profile for mig %v failed", configItem.InstanceGroupName)
+		return
+	}
+	configItem = profileConfig
+
+	log.Info().Msgf("Retrieving data for managed instance group %v scaling...", configItem.InstanceGroupName)
+
+	// a mig following another mig doesn't have a request rate of its own, and one chained to an upstream mig uses the upstream mig's target size instead
+	var requestRate float64
+	switch {
+	case configItem.FollowMIG != "":
+	case configItem.UpstreamMIG != "":
+		requestRate, err = s.getUpstreamTargetSize(ctx, configItem)
+		if err != nil {
+			log.Error().Err(err).Msgf("Retrieving target size of upstream mig %v for mig %v failed", configItem.UpstreamMIG, configItem.InstanceGroupName)
+			return
+		}
+	default:
+		configItem, err = s.applyRequestsPerInstanceQuery(ctx, configItem)
+		if err != nil {
+			log.Error().Err(err).Msgf("Retrieving number of requests per instance for mig %v failed", configItem.InstanceGroupName)
+			return
+		}
+
+		queryCtx, cancelQuery := withTimeout(ctx, configItem.QueryTimeout(s.options.QueryTimeout))
+		requestRate, err = s.getRequestRate(queryCtx, configItem)
+		cancelQuery()
+		if err == nil {
+			s.setLastRequestRate(configItem, requestRate)
+			requestRate = s.applyBootTimeLead(configItem, requestRate, now)
+		} else if IsMissingData(err) && configItem.MissingDataPolicy != "" {
+			log.Warn().Err(err).Msgf("Request rate for mig %v is missing, applying missing data policy %v", configItem.InstanceGroupName, configItem.MissingDataPolicy)
+			requestRate, err = s.getRequestRateForMissingData(configItem, err)
+		}
+		if err != nil {
+			log.Error().Err(err).Msgf("Retrieving request rate for mig %v failed", configItem.InstanceGroupName)
+			return
+		}
+	}
+
+	spotOverprovisionPercent := s.getSpotOverprovisionPercent(ctx, configItem)
+	sloScaleUpPercent := s.getSLOScaleUpPercent(ctx, configItem)
+
+	// compute api calls share a timeout, so a slow api can't stall the loop
+	ctx, cancelCompute := withTimeout(ctx, s.options.ComputeTimeout)
+	defer cancelCompute()
+
+	// get actual number of instances
+	instanceGroupManager, err := s.getInstanceGroupManager(ctx, configItem)
+	if err != nil {
+		log.Error().Err(err).Msgf("Retrieving instance group manager %v failed", configItem.InstanceGroupName)
+		return
+	}
+	migTargetSize := instanceGroupManager.TargetSize
+
+	// the autoscaler is retrieved before deciding whether to update it, so its mode and status are exported while updates are skipped as well
+	autoScaler, err := s.findAutoscaler(ctx, configItem, instanceGroupManager)
+	if err != nil {
+		log.Error().Err(err).Msgf("Retrieving autoscaler %v failed", configItem.InstanceGroupName)
+	} else {
+		reportAutoscalerMode(configItem, autoScaler, configRevision)
+		s.reportAutoscalerStatus(configItem, autoScaler)
+	}
+
+	targetMinimumNumberOfInstances, err := s.calculateTargetMinimumNumberOfInstances(configItem, requestRate, migTargetSize, now)
+	if err != nil {
+		log.Error().Err(err).Msgf("Calculating minimum number of instances for mig %v failed", configItem.InstanceGroupName)
+		return
+	}
+
+	// spot vms, a breached slo and zone outages inflate the target itself, so the scaling policies work towards the inflated target
+	unavailableZones, numberOfZones := s.getUnavailableZones(ctx, configItem, migTargetSize)
+	s.reportZoneOutage(configItem, unavailableZones)
+	inflate := func(minimumNumberOfInstances int) int {
+		minimumNumberOfInstances = overprovisionForSpot(minimumNumberOfInstances, spotOverprovisionPercent)
+		minimumNumberOfInstances = scaleUpForSLO(minimumNumberOfInstances, sloScaleUpPercent)
+		return compensateForZoneOutage(minimumNumberOfInstances, len(unavailableZones), numberOfZones)
+	}
+
+	targetMinimumNumberOfInstances = inflate(targetMinimumNumberOfInstances)
+	s.setTargetMinimumNumberOfInstances(configItem, targetMinimumNumberOfInstances)
+	calculate := func(requestRate float64) int {
+		minimumNumberOfInstances, err := s.calculateTargetMinimumNumberOfInstances(configItem, requestRate, migTargetSize, now)
+		if err != nil {
+			return targetMinimumNumberOfInstances
+		}
+		return inflate(minimumNumberOfInstances)
+	}
+
+	// the scaling policies advance the state of the mig, which is undone if the minimum they decide on doesn't get applied, so skipped or failed updates don't count as changes
+	snapshot := s.snapshotState(configItem)
+
+	minimumNumberOfInstances := s.applyHysteresis(configItem, requestRate, targetMinimumNumberOfInstances, calculate)
+	minimumNumberOfInstances = s.confirmScaleDown(configItem, minimumNumberOfInstances)
+	minimumNumberOfInstances = s.holdScaleDownDuringCooldown(configItem, minimumNumberOfInstances, now)
+	minimumNumberOfInstances = s.limitScaleStep(configItem, minimumNumberOfInstances)
+	minimumNumberOfInstances = s.limitRateOfChange(configItem, minimumNumberOfInstances, now)
+	minimumNumberOfInstances = applySchedules(configItem, minimumNumberOfInstances, now)
+	minimumNumberOfInstances = s.applyCalendar(configItem, minimumNumberOfInstances, now)
+	minimumNumberOfInstances = clampToMaximumNumberOfInstances(configItem, minimumNumberOfInstances)
+	minimumNumberOfInstances = s.applyCostCeilings(configItem, minimumNumberOfInstances)
+	if autoScaler != nil {
+		minimumNumberOfInstances = clampToAutoscalerMax(configItem, autoScaler.Autoscaler, minimumNumberOfInstances)
+	}
+
+	log.Info().Str("configRevision", configRevision).Msgf("Setting data for managed instance group %v in prometheus (min: %v, actual: %v, source request rate:%v)...", configItem.InstanceGroupName, minimumNumberOfInstances, migTargetSize, requestRate)
+
+	// set prometheus gauge values
+	minInstancesVector.WithLabelValues(configItem.InstanceGroupName).Set(float64(minimumNumberOfInstances))
+	actualInstancesVector.WithLabelValues(configItem.InstanceGroupName).Set(float64(migTargetSize))
+	requestRateVector.WithLabelValues(configItem.InstanceGroupName).Set(requestRate)
+
+	// set min and max instances on managed instance group; without settings to update the minimum is only exported, and counts as applied
+	outcome := skippedDecisionOutcome
+	exportOnly := false
+	switch {
+	case !configItem.EnableSettingMinInstances && !configItem.EnableSettingMaxInstances && !configItem.UseScalingSchedules && (s.options.ScalingSchedules == nil || s.scalingSchedulesRemoved(configItem)):
+		exportOnly = true
+	case s.options.DisableAllUpdates:
+		log.Info().Str("configRevision", configRevision).Msgf("Skipped updating autoscaler for mig %v to min instances %v, all updates are disabled", configItem.InstanceGroupName, minimumNumberOfInstances)
+	case configItem.InMaintenanceWindow(now):
+		log.Info().Str("configRevision", configRevision).Msgf("Skipped updating autoscaler for mig %v to min instances %v, it's in a maintenance window", configItem.InstanceGroupName, minimumNumberOfInstances)
+	case autoScaler == nil:
+		outcome = failedDecisionOutcome
+	default:
+		minimumNumberOfInstances, outcome = s.updateAutoscaler(ctx, configItem, autoScaler, minimumNumberOfInstances, configRevision)
+		if outcome != failedDecisionOutcome {
+			minInstancesVector.WithLabelValues(configItem.InstanceGroupName).Set(float64(minimumNumberOfInstances))
+		}
+	}
+
+	if exportOnly || outcome == updatedDecisionOutcome || outcome == unchangedDecisionOutcome {
+		s.setAppliedMinimumNumberOfInstances(configItem, minimumNumberOfInstances, now)
+	} else {
+		s.restoreState(configItem, snapshot)
+	}
+
+	// the decision is pushed with the outcome of the update, in the background, so a slow endpoint doesn't use up the compute timeout
+	if s.options.RemoteWriter != nil {
+		s.options.RemoteWriter.WriteDecision(configItem.InstanceGroupName, requestRate, targetMinimumNumberOfInstances, minimumNumberOfInstances, outcome)
+	}
+}
+
+// getRequestRate retrieves the current request rate for a managed instance group, filters it for anomalies, and raises it to the predicted request rate of the trend query or the historical request rate if those are higher; a predicted ramp-up is never filtered as anomaly
+func (s *MIGScaler) getRequestRate(ctx context.Context, configItem MIGConfiguration) (float64, error) {
+
+	requestRate, err := s.getCurrentRequestRate(ctx, configItem)
+	if err != nil {
+		return 0, err
+	}
+
+	requestRate = s.filterAnomaly(configItem, requestRate)
+
+	requestRate = applyTrend(ctx, s.metricSources, configItem, requestRate)
+
+	return s.applyHistoricalRequestRate(ctx, configItem, requestRate), nil
+}
+
+// getCurrentRequestRate executes the request rate query for a managed instance group against its metric source, or all its queries if it has multiple, or the query for each of its evaluation windows
+func (s *MIGScaler) getCurrentRequestRate(ctx context.Context, configItem MIGConfiguration) (float64, error) {
+
+	if len(configItem.EvaluationWindows) > 0 {
+		return getMultiWindowRequestRate(ctx, s.metricSources, configItem)
+	}
+
+	if len(configItem.Queries) > 0 {
+		return getCompositeRequestRate(ctx, s.metricSources, configItem)
+	}
+
+	return getRequestRateWithFallbacks(ctx, s.metricSources, configItem)
+}
+
+// calculateTargetMinimumNumberOfInstances calculates the minimum number of instances for the request rate, or derives it from the followed mig's target if followMig is set or the upstream mig's target size, passed as request rate, if upstreamMig is set
+func (s *MIGScaler) calculateTargetMinimumNumberOfInstances(configItem MIGConfiguration, requestRate float64, currentSize int64, now time.Time) (int, error) {
+	if configItem.FollowMIG != "" {
+		return s.followMIGTarget(configItem)
+	}
+	if configItem.UpstreamMIG != "" {
+		return configItem.ratioTarget(requestRate), nil
+	}
+	return s.calculateMinimumNumberOfInstances(configItem, requestRate, currentSize, now)
+}
+
+// IsSignificantChange returns whether changing the minimum number of instances of the autoscaler from current to minimumNumberOfInstances is at least minChangeInstances
+func (c *MIGConfiguration) IsSignificantChange(current int64, minimumNumberOfInstances int) bool {
+	change := int64(minimumNumberOfInstances) - current
+	if change < 0 {
+		change = -change
+	}
+	return change >= int64(c.MinChangeInstances)
+}
+
+// clampToMaximumNumberOfInstances caps the minimum number of instances at maximumNumberOfInstances if set, so a bad query can't run up the bill
+func clampToMaximumNumberOfInstances(configItem MIGConfiguration, minimumNumberOfInstances int) int {
+
+	if configItem.MaximumNumberOfInstances <= 0 || minimumNumberOfInstances <= configItem.MaximumNumberOfInstances {
+		return minimumNumberOfInstances
+	}
+
+	log.Warn().Msgf("Minimum number of instances %v for mig %v exceeds maximum number of instances %v, capping it", minimumNumberOfInstances, configItem.InstanceGroupName, configItem.MaximumNumberOfInstances)
+	maxInstancesClampedVector.WithLabelValues(configItem.InstanceGroupName).Inc()
+
+	return configItem.MaximumNumberOfInstances
+}
+
+// clampToAutoscalerMax caps the minimum number of instances at the max instances the autoscaler has or gets with maximumNumberOfInstancesToSet, since a higher minimum is rejected or meaningless
+func clampToAutoscalerMax(configItem MIGConfiguration, autoScaler *compute.Autoscaler, minimumNumberOfInstances int) int {
+
+	maxNumReplicas := autoScaler.AutoscalingPolicy.MaxNumReplicas
+	if configItem.EnableSettingMaxInstances {
+		maxNumReplicas = int64(configItem.MaxInstancesToSet)
+	}
+	if maxNumReplicas <= 0 || int64(minimumNumberOfInstances) <= maxNumReplicas {
+		return minimumNumberOfInstances
+	}
+
+	log.Warn().Msgf("Minimum number of instances %v for mig %v exceeds the autoscaler's max instances %v, capping it", minimumNumberOfInstances, configItem.InstanceGroupName, maxNumReplicas)
+	autoscalerMaxClampedVector.WithLabelValues(configItem.InstanceGroupName).Inc()
+
+	return int(maxNumReplicas)
+}
+
+// withTimeout returns a context that's cancelled after the timeout, or one without deadline if the timeout is 0
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// QueryTimeout returns queryTimeoutSeconds, or the default timeout for retrieving the request rate if it isn't set
+func (c *MIGConfiguration) QueryTimeout(defaultTimeout time.Duration) time.Duration {
+	if c.QueryTimeoutSeconds > 0 {
+		return time.Duration(c.QueryTimeoutSeconds) * time.Second
+	}
+	return defaultTimeout
+}
+
+// RequestsPerInstance returns the number of requests a single instance handles: numberOfRequestsPerInstance multiplied by capacityWeight, so managed instance groups with larger machine types serving the same traffic can share numberOfRequestsPerInstance; capacityWeight defaults to 1
+func (c *MIGConfiguration) RequestsPerInstance() float64 {
+	if c.CapacityWeight <= 0 {
+		return c.NumberOfRequestsPerInstance
+	}
+	return c.NumberOfRequestsPerInstance * c.CapacityWeight
+}
+
+// CalculateMinimumNumberOfInstances returns the minimum number of instances to set for the request rate of a managed instance group
+func CalculateMinimumNumberOfInstances(configItem MIGConfiguration, requestRate float64) int {
+
+	// calculate target # of instances
+	targetNumberOfInstances := configItem.RoundTarget(requestRate / configItem.RequestsPerInstance())
+
+	return configItem.minimumForTarget(targetNumberOfInstances, requestRate)
+}
+
+// RoundTarget rounds the number of instances needed to a whole number of instances with rounding; it defaults to ceil, so there's always enough capacity
+func (c *MIGConfiguration) RoundTarget(numberOfInstances float64) int {
+	switch c.Rounding {
+	case roundRounding:
+		return int(math.Round(numberOfInstances))
+	case floorRounding:
+		return int(math.Floor(numberOfInstances))
+	}
+	return int(math.Ceil(numberOfInstances))
+}
+
+// minimumForTarget returns the minimum number of instances for the number of instances needed for the request rate
+func (c *MIGConfiguration) minimumForTarget(targetNumberOfInstances int, requestRate float64) int {
+
+	// substract number of instances below target, either as percentage of the target or absolute
+	minimumNumberOfInstances := targetNumberOfInstances - c.NumberOfInstancesBelowTarget
+	if c.TargetHeadroomPercent > 0 {
+		minimumNumberOfInstances = int(math.Floor(float64(targetNumberOfInstances) * (100 - c.TargetHeadroomPercent) / 100))
+	}
+
+	// ensure minimumNumberOfInstances is larger than MinimumNumberOfInstances from the config
+	if minimumNumberOfInstances < c.MinimumNumberOfInstances {
+		minimumNumberOfInstances = c.MinimumNumberOfInstances
+	}
+
+	// a managed instance group allowed to scale to zero only drains once it's idle, so any remaining work still gets an instance
+	if minimumNumberOfInstances < 1 && requestRate > 0 {
+		minimumNumberOfInstances = 1
+	}
+
+	return minimumNumberOfInstances
+}
+
+// getInstanceGroupManager retrieves the regional or zonal instance group manager for a managed instance group
+func (s *MIGScaler) getInstanceGroupManager(ctx context.Context, configItem MIGConfiguration) (instanceGroupManager *compute.InstanceGroupManager, err error) {
+
+	if instanceGroupManager, ok := prefetchedInstanceGroupManager(ctx, configItem); ok {
+		return instanceGroupManager, nil
+	}
+
+	computeClient, err := s.computeClientFor(ctx, configItem)
+	if err != nil {
+		return nil, err
+	}
+
+	return computeClient.GetInstanceGroupManager(ctx, configItem)
+}
+
+// updateAutoscaler sets the minimum number of instances, and maximumNumberOfInstancesToSet as maximum, on the autoscaler targeting the instance group manager, for those that are enabled and differ from the current value; it returns the min instances of the autoscaler afterwards, which is what it already was if the min wasn't patched, like for a change smaller than minChangeInstances, and whether it was updated, unchanged or failed; on failure it returns the minimum it decided on
+func (s *MIGScaler) updateAutoscaler(ctx context.Context, configItem MIGConfiguration, autoScaler *Autoscaler, minimumNumberOfInstances int, configRevision string) (int, string) {
+
+	s.enforceAutoscalerMode(ctx, configItem, autoScaler, configRevision)
+
+	// scaling schedules are patched separately, since the compute client in use doesn't know about them
+	defer s.syncScalingSchedules(ctx, configItem, autoScaler.Name, configRevision)
+
+	// patch autoscaler, rereading it and deciding again when it was modified concurrently; the minimum is already capped at the max instances of the autoscaler as it was read, but a concurrent modification can lower those
+	requestedMinimumNumberOfInstances := minimumNumberOfInstances
+	for attempt := 1; ; attempt++ {
+		minimumNumberOfInstances = clampToAutoscalerMax(configItem, autoScaler.Autoscaler, requestedMinimumNumberOfInstances)
+		minimumNumberOfInstances = holdScaleDownOnAutoscalerErrors(configItem, autoScaler, minimumNumberOfInstances)
+		updateMin := configItem.EnableSettingMinInstances && autoScaler.AutoscalingPolicy.MinNumReplicas != int64(minimumNumberOfInstances)
+		if configItem.EnableSettingMinInstances && !updateMin {
+			log.Info().Str("configRevision", configRevision).Msgf("Skipped updating autoscaler for mig %v, min instances is already at %v", configItem.InstanceGroupName, minimumNumberOfInstances)
+		}
+		if updateMin && !configItem.IsSignificantChange(autoScaler.AutoscalingPolicy.MinNumReplicas, minimumNumberOfInstances) {
+			log.Info().Str("configRevision", configRevision).Msgf("Skipped updating autoscaler for mig %v from min instances %v to %v, the change is smaller than %v instances", configItem.InstanceGroupName, autoScaler.AutoscalingPolicy.MinNumReplicas, minimumNumberOfInstances, configItem.MinChangeInstances)
+			updateMin = false
+		}
+
+		updateMax := configItem.EnableSettingMaxInstances && autoScaler.AutoscalingPolicy.MaxNumReplicas != int64(configItem.MaxInstancesToSet)
+		if configItem.EnableSettingMaxInstances && !updateMax {
+			log.Info().Str("configRevision", configRevision).Msgf("Skipped updating autoscaler for mig %v, max instances is already at %v", configItem.InstanceGroupName, configItem.MaxInstancesToSet)
+		}
+
+		if !updateMin && !updateMax {
+			return int(autoScaler.AutoscalingPolicy.MinNumReplicas), unchangedDecisionOutcome
+		}
+
+		// autoscalers have no fingerprint to make the patch conditional on, so it's read again right before patching, and the decision is made again if its min or max instances changed since it was based on them
+		current, err := s.getAutoscaler(ctx, configItem, autoScaler.Name)
+		if err != nil {
+			log.Error().Err(err).Msgf("Retrieving autoscaler %v failed", configItem.InstanceGroupName)
+			s.cache.invalidateOnNotFound(configItem, err)
+			return minimumNumberOfInstances, failedDecisionOutcome
+		}
+		if current.AutoscalingPolicy.MinNumReplicas != autoScaler.AutoscalingPolicy.MinNumReplicas || current.AutoscalingPolicy.MaxNumReplicas != autoScaler.AutoscalingPolicy.MaxNumReplicas {
+			if attempt >= maxAutoscalerPatchAttempts {
+				log.Error().Msgf("Autoscaler for mig %v keeps being modified concurrently, giving up after %v attempts", configItem.InstanceGroupName, attempt)
+				return minimumNumberOfInstances, failedDecisionOutcome
+			}
+			log.Warn().Msgf("Autoscaler for mig %v was modified concurrently from min instances %v and max instances %v to %v and %v, deciding again for attempt %v of %v", configItem.InstanceGroupName, autoScaler.AutoscalingPolicy.MinNumReplicas, autoScaler.AutoscalingPolicy.MaxNumReplicas, current.AutoscalingPolicy.MinNumReplicas, current.AutoscalingPolicy.MaxNumReplicas, attempt+1, maxAutoscalerPatchAttempts)
+			autoScaler = current
+			continue
+		}
+
+		patch := AutoscalerPatch(autoScaler.Name, updateMin, int64(minimumNumberOfInstances), updateMax, int64(configItem.MaxInstancesToSet))
+		operation, err := s.patchAutoscaler(ctx, configItem, patch)
+		if err != nil && IsConcurrentAutoscalerModification(err) && attempt < maxAutoscalerPatchAttempts {
+			log.Warn().Err(err).Msgf("Autoscaler for mig %v was modified concurrently, rereading it for attempt %v of %v", configItem.InstanceGroupName, attempt+1, maxAutoscalerPatchAttempts)
+			autoScaler = current
+			continue
+		}
+		if err != nil {
+			log.Error().Err(err).Msgf("Updating autoscaler %v failed", configItem.InstanceGroupName)
+			autoscalerUpdatesVector.WithLabelValues(configItem.InstanceGroupName, failedOperationResult).Inc()
+			s.cache.invalidateOnNotFound(configItem, err)
+			return minimumNumberOfInstances, failedDecisionOutcome
+		}
+		if !s.awaitAutoscalerUpdate(ctx, configItem, operation) {
+			return minimumNumberOfInstances, failedDecisionOutcome
+		}
+
+		if updateMin {
+			autoScaler.AutoscalingPolicy.MinNumReplicas = patch.AutoscalingPolicy.MinNumReplicas
+		}
+		if updateMax {
+			autoScaler.AutoscalingPolicy.MaxNumReplicas = patch.AutoscalingPolicy.MaxNumReplicas
+		}
+
+		log.Info().Str("configRevision", configRevision).Interface("operation", *operation).Msgf("Updated autoscaler for mig %v to min instances %v and max instances %v", configItem.InstanceGroupName, autoScaler.AutoscalingPolicy.MinNumReplicas, autoScaler.AutoscalingPolicy.MaxNumReplicas)
+		return int(autoScaler.AutoscalingPolicy.MinNumReplicas), updatedDecisionOutcome
+	}
+}
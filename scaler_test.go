@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	compute "google.golang.org/api/compute/v1"
+)
+
+func TestQueryTimeout(t *testing.T) {
+
+	t.Run("ReturnsDefaultTimeoutIfQueryTimeoutSecondsIsNotSet", func(t *testing.T) {
+
+		configItem := MIGConfiguration{}
+
+		// act
+		timeout := configItem.QueryTimeout(30 * time.Second)
+
+		assert.Equal(t, 30*time.Second, timeout)
+	})
+
+	t.Run("ReturnsQueryTimeoutSeconds", func(t *testing.T) {
+
+		configItem := MIGConfiguration{QueryTimeoutSeconds: 5}
+
+		// act
+		timeout := configItem.QueryTimeout(30 * time.Second)
+
+		assert.Equal(t, 5*time.Second, timeout)
+	})
+}
+
+func TestCalculateMinimumNumberOfInstances(t *testing.T) {
+
+	t.Run("ReturnsRequestRateDividedByRequestsPerInstanceRoundedUpMinusInstancesBelowTarget", func(t *testing.T) {
+
+		configItem := MIGConfiguration{
+			NumberOfRequestsPerInstance:  2.0,
+			NumberOfInstancesBelowTarget: 1,
+		}
+
+		// act
+		minimumNumberOfInstances := CalculateMinimumNumberOfInstances(configItem, 9.0)
+
+		assert.Equal(t, 4, minimumNumberOfInstances)
+	})
+
+	t.Run("ReturnsTargetMinusHeadroomPercentage", func(t *testing.T) {
+
+		configItem := MIGConfiguration{NumberOfRequestsPerInstance: 10, TargetHeadroomPercent: 10, MinimumNumberOfInstances: 3}
+
+		// act
+		minimumNumberOfInstances := CalculateMinimumNumberOfInstances(configItem, 995)
+
+		assert.Equal(t, 90, minimumNumberOfInstances)
+	})
+
+	t.Run("DividesRequestRateByWeightedCapacity", func(t *testing.T) {
+
+		configItem := MIGConfiguration{NumberOfRequestsPerInstance: 10, CapacityWeight: 4}
+
+		// act
+		minimumNumberOfInstances := CalculateMinimumNumberOfInstances(configItem, 95)
+
+		assert.Equal(t, 3, minimumNumberOfInstances)
+	})
+
+	t.Run("RoundsTargetWithRounding", func(t *testing.T) {
+
+		results := []int{}
+		for _, rounding := range []string{"", "ceil", "round", "floor"} {
+			configItem := MIGConfiguration{NumberOfRequestsPerInstance: 10, Rounding: rounding}
+
+			// act
+			results = append(results, CalculateMinimumNumberOfInstances(configItem, 92))
+		}
+
+		assert.Equal(t, []int{10, 10, 9, 9}, results)
+	})
+
+	t.Run("ReturnsZeroForIdleMIGWithMinimumOfZero", func(t *testing.T) {
+
+		configItem := MIGConfiguration{NumberOfRequestsPerInstance: 10, NumberOfInstancesBelowTarget: 2}
+
+		// act
+		minimumNumberOfInstances := CalculateMinimumNumberOfInstances(configItem, 0)
+
+		assert.Equal(t, 0, minimumNumberOfInstances)
+	})
+
+	t.Run("ReturnsOneForMIGWithMinimumOfZeroWithRemainingWork", func(t *testing.T) {
+
+		configItem := MIGConfiguration{NumberOfRequestsPerInstance: 10, NumberOfInstancesBelowTarget: 2}
+
+		// act
+		minimumNumberOfInstances := CalculateMinimumNumberOfInstances(configItem, 0.5)
+
+		assert.Equal(t, 1, minimumNumberOfInstances)
+	})
+
+	t.Run("ReturnsConfiguredMinimumIfCalculatedMinimumIsLower", func(t *testing.T) {
+
+		configItem := MIGConfiguration{
+			MinimumNumberOfInstances:     3,
+			NumberOfRequestsPerInstance:  2.0,
+			NumberOfInstancesBelowTarget: 1,
+		}
+
+		// act
+		minimumNumberOfInstances := CalculateMinimumNumberOfInstances(configItem, 2.0)
+
+		assert.Equal(t, 3, minimumNumberOfInstances)
+	})
+}
+
+func TestClampToMaximumNumberOfInstances(t *testing.T) {
+
+	t.Run("ReturnsMinimumIfMaximumIsNotSet", func(t *testing.T) {
+
+		configItem := MIGConfiguration{InstanceGroupName: "unbounded"}
+
+		// act
+		minimumNumberOfInstances := clampToMaximumNumberOfInstances(configItem, 500)
+
+		assert.Equal(t, 500, minimumNumberOfInstances)
+	})
+
+	t.Run("ReturnsMaximumAndCountsClampIfMinimumIsHigher", func(t *testing.T) {
+
+		configItem := MIGConfiguration{InstanceGroupName: "capped", MaximumNumberOfInstances: 20}
+
+		// act
+		minimumNumberOfInstances := clampToMaximumNumberOfInstances(configItem, 500)
+
+		assert.Equal(t, 20, minimumNumberOfInstances)
+		assert.Equal(t, float64(1), testutil.ToFloat64(maxInstancesClampedVector.WithLabelValues("capped")))
+	})
+}
+
+func TestClampToAutoscalerMax(t *testing.T) {
+
+	t.Run("ReturnsMinimumIfWithinAutoscalerMax", func(t *testing.T) {
+
+		configItem := MIGConfiguration{InstanceGroupName: "within-autoscaler-max"}
+		autoScaler := &compute.Autoscaler{AutoscalingPolicy: &compute.AutoscalingPolicy{MaxNumReplicas: 50}}
+
+		// act
+		minimumNumberOfInstances := clampToAutoscalerMax(configItem, autoScaler, 50)
+
+		assert.Equal(t, 50, minimumNumberOfInstances)
+	})
+
+	t.Run("ReturnsAutoscalerMaxAndCountsClampIfMinimumIsHigher", func(t *testing.T) {
+
+		configItem := MIGConfiguration{InstanceGroupName: "autoscaler-max-capped"}
+		autoScaler := &compute.Autoscaler{AutoscalingPolicy: &compute.AutoscalingPolicy{MaxNumReplicas: 50}}
+
+		// act
+		minimumNumberOfInstances := clampToAutoscalerMax(configItem, autoScaler, 80)
+
+		assert.Equal(t, 50, minimumNumberOfInstances)
+		assert.Equal(t, float64(1), testutil.ToFloat64(autoscalerMaxClampedVector.WithLabelValues("autoscaler-max-capped")))
+	})
+
+	t.Run("ClampsToMaximumNumberOfInstancesToSetIfMaxIsManaged", func(t *testing.T) {
+
+		configItem := MIGConfiguration{InstanceGroupName: "managed-autoscaler-max", MaxInstancesToSet: 100, EnableSettingMaxInstances: true}
+		autoScaler := &compute.Autoscaler{AutoscalingPolicy: &compute.AutoscalingPolicy{MaxNumReplicas: 50}}
+
+		// act
+		minimumNumberOfInstances := clampToAutoscalerMax(configItem, autoScaler, 80)
+
+		assert.Equal(t, 80, minimumNumberOfInstances)
+	})
+}
+
+func TestIsSignificantChange(t *testing.T) {
+
+	t.Run("ReturnsTrueForAnyChangeWithoutMinChangeInstances", func(t *testing.T) {
+
+		configItem := MIGConfiguration{}
+
+		// act
+		significant := configItem.IsSignificantChange(40, 41)
+
+		assert.True(t, significant)
+	})
+
+	t.Run("ReturnsWhetherChangeInEitherDirectionIsAtLeastMinChangeInstances", func(t *testing.T) {
+
+		configItem := MIGConfiguration{MinChangeInstances: 3}
+
+		// act
+		up := configItem.IsSignificantChange(40, 42)
+		down := configItem.IsSignificantChange(40, 37)
+
+		assert.False(t, up)
+		assert.True(t, down)
+	})
+}
+
+func TestUpdateAutoscaler(t *testing.T) {
+
+	instanceGroupManager := &compute.InstanceGroupManager{SelfLink: "https://www.googleapis.com/compute/v1/projects/project-id/zones/europe-west1-b/instanceGroupManagers/web"}
+	configItem := MIGConfiguration{GCloudProject: "project-id", GCloudZone: "europe-west1-b", InstanceGroupName: "web", AutoscalerName: "web-autoscaler", EnableSettingMinInstances: true, MaximumNumberOfInstances: 20}
+
+	newScaler := func(patchStatusCode int) (*MIGScaler, func()) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPatch {
+				w.WriteHeader(patchStatusCode)
+				w.Write([]byte(`{"error":{"code":500,"message":"backend error"}}`))
+				return
+			}
+			w.Write([]byte(`{"name":"web-autoscaler","target":"https://www.googleapis.com/compute/v1/projects/project-id/zones/europe-west1-b/instanceGroupManagers/web","autoscalingPolicy":{"minNumReplicas":5,"maxNumReplicas":20}}`))
+		}))
+		computeClient, _ := NewGAComputeClient(server.Client(), ComputeClientOptions{BasePath: server.URL + "/"})
+		return NewMIGScaler(computeClient, nil, MIGScalerOptions{}), server.Close
+	}
+
+	t.Run("ReturnsUnchangedIfMinInstancesIsAlreadySet", func(t *testing.T) {
+
+		scaler, closeServer := newScaler(http.StatusInternalServerError)
+		defer closeServer()
+		autoScaler, err := scaler.findAutoscaler(context.Background(), configItem, instanceGroupManager)
+		assert.Nil(t, err)
+
+		// act
+		minimumNumberOfInstances, outcome := scaler.updateAutoscaler(context.Background(), configItem, autoScaler, 5, "")
+
+		assert.Equal(t, 5, minimumNumberOfInstances)
+		assert.Equal(t, unchangedDecisionOutcome, outcome)
+	})
+
+	t.Run("DecidesAgainIfAutoscalerWasModifiedSinceItWasRead", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPatch {
+				t.Errorf("Unexpected request %v %v", r.Method, r.URL.Path)
+				return
+			}
+			w.Write([]byte(`{"name":"web-autoscaler","target":"https://www.googleapis.com/compute/v1/projects/project-id/zones/europe-west1-b/instanceGroupManagers/web","autoscalingPolicy":{"minNumReplicas":8,"maxNumReplicas":20}}`))
+		}))
+		defer server.Close()
+		computeClient, _ := NewGAComputeClient(server.Client(), ComputeClientOptions{BasePath: server.URL + "/"})
+		scaler := NewMIGScaler(computeClient, nil, MIGScalerOptions{})
+		autoScaler := &Autoscaler{Autoscaler: &compute.Autoscaler{Name: "web-autoscaler", AutoscalingPolicy: &compute.AutoscalingPolicy{MinNumReplicas: 5, MaxNumReplicas: 20}}}
+
+		// act
+		minimumNumberOfInstances, outcome := scaler.updateAutoscaler(context.Background(), configItem, autoScaler, 8, "")
+
+		assert.Equal(t, 8, minimumNumberOfInstances)
+		assert.Equal(t, unchangedDecisionOutcome, outcome)
+	})
+
+	t.Run("ReturnsFailedIfPatchFails", func(t *testing.T) {
+
+		scaler, closeServer := newScaler(http.StatusInternalServerError)
+		defer closeServer()
+		autoScaler, err := scaler.findAutoscaler(context.Background(), configItem, instanceGroupManager)
+		assert.Nil(t, err)
+
+		// act
+		_, outcome := scaler.updateAutoscaler(context.Background(), configItem, autoScaler, 8, "")
+
+		assert.Equal(t, failedDecisionOutcome, outcome)
+	})
+}
+
+func TestScale(t *testing.T) {
+
+	t.Run("ExportsMinimumCappedAtAutoscalerMaxAndModeWhileUpdatesAreDisabled", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/project-id/zones/europe-west1-b/instanceGroupManagers/scale-capped":
+				w.Write([]byte(`{"name":"scale-capped","selfLink":"https://www.googleapis.com/compute/v1/projects/project-id/zones/europe-west1-b/instanceGroupManagers/scale-capped","targetSize":4}`))
+			case "/project-id/zones/europe-west1-b/autoscalers/scale-capped-autoscaler":
+				w.Write([]byte(`{"name":"scale-capped-autoscaler","target":"https://www.googleapis.com/compute/v1/projects/project-id/zones/europe-west1-b/instanceGroupManagers/scale-capped","autoscalingPolicy":{"minNumReplicas":4,"maxNumReplicas":10,"mode":"OFF"}}`))
+			default:
+				t.Errorf("Unexpected request %v %v", r.Method, r.URL.Path)
+			}
+		}))
+		defer server.Close()
+
+		computeClient, _ := NewGAComputeClient(server.Client(), ComputeClientOptions{BasePath: server.URL + "/"})
+		metricSources := map[string]MetricSource{
+			"prometheus": &fakeMetricSource{requestRates: map[string]float64{"requests": 300}},
+		}
+		scaler := NewMIGScaler(computeClient, metricSources, MIGScalerOptions{DisableAllUpdates: true})
+		configItem := MIGConfiguration{GCloudProject: "project-id", GCloudZone: "europe-west1-b", InstanceGroupName: "scale-capped", AutoscalerName: "scale-capped-autoscaler", RequestRateQuery: "requests", NumberOfRequestsPerInstance: 10, EnableSettingMinInstances: true}
+
+		// act
+		scaler.Scale(context.Background(), configItem, "")
+
+		assert.Equal(t, 10.0, testutil.ToFloat64(minInstancesVector.WithLabelValues("scale-capped")))
+		assert.Equal(t, 1.0, testutil.ToFloat64(autoscalerModeVector.WithLabelValues("scale-capped", "OFF")))
+		_, _, ok := scaler.appliedMinimumNumberOfInstances(configItem)
+		assert.False(t, ok)
+	})
+
+	t.Run("KeepsAppliedMinimumOfAutoscalerForChangeSmallerThanMinChangeInstances", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == "GET" && r.URL.Path == "/project-id/zones/europe-west1-b/instanceGroupManagers/scale-insignificant":
+				w.Write([]byte(`{"name":"scale-insignificant","selfLink":"https://www.googleapis.com/compute/v1/projects/project-id/zones/europe-west1-b/instanceGroupManagers/scale-insignificant","targetSize":4}`))
+			case r.Method == "GET" && r.URL.Path == "/project-id/zones/europe-west1-b/autoscalers/scale-insignificant-autoscaler":
+				w.Write([]byte(`{"name":"scale-insignificant-autoscaler","target":"https://www.googleapis.com/compute/v1/projects/project-id/zones/europe-west1-b/instanceGroupManagers/scale-insignificant","autoscalingPolicy":{"minNumReplicas":4,"maxNumReplicas":10,"mode":"ON"}}`))
+			default:
+				t.Errorf("Unexpected request %v %v", r.Method, r.URL.Path)
+			}
+		}))
+		defer server.Close()
+
+		computeClient, _ := NewGAComputeClient(server.Client(), ComputeClientOptions{BasePath: server.URL + "/"})
+		metricSources := map[string]MetricSource{
+			"prometheus": &fakeMetricSource{requestRates: map[string]float64{"requests": 50}},
+		}
+		scaler := NewMIGScaler(computeClient, metricSources, MIGScalerOptions{})
+		configItem := MIGConfiguration{GCloudProject: "project-id", GCloudZone: "europe-west1-b", InstanceGroupName: "scale-insignificant", AutoscalerName: "scale-insignificant-autoscaler", RequestRateQuery: "requests", NumberOfRequestsPerInstance: 10, EnableSettingMinInstances: true, MinChangeInstances: 3}
+		scaler.setAppliedMinimumNumberOfInstances(configItem, 4, time.Now().Add(-time.Hour))
+
+		// act
+		scaler.Scale(context.Background(), configItem, "")
+
+		applied, _, ok := scaler.appliedMinimumNumberOfInstances(configItem)
+		assert.True(t, ok)
+		assert.Equal(t, 4, applied)
+		assert.Equal(t, 4.0, testutil.ToFloat64(minInstancesVector.WithLabelValues("scale-insignificant")))
+	})
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// migState holds what the scaler remembers about a managed instance group between iterations
+type migState struct {
+	// lastRequestRate is the last request rate retrieved, for the holdLastValue missing data policy
+	lastRequestRate    float64
+	hasLastRequestRate bool
+
+	// minimumNumberOfInstances is the last minimum number of instances decided on
+	minimumNumberOfInstances    int
+	hasMinimumNumberOfInstances bool
+
+	// lowerMinimumNumberOfInstances are the consecutive lower minimums calculated since, waiting for scaleDownConfirmations
+	lowerMinimumNumberOfInstances []int
+
+	// targetMinimumNumberOfInstances is the minimum number of instances the last iteration calculated, before any scaling policies, for migs following this one
+	targetMinimumNumberOfInstances    int
+	hasTargetMinimumNumberOfInstances bool
+
+	// appliedMinimumNumberOfInstances is the minimum number of instances the last iteration ended up with, after all scaling policies
+	appliedMinimumNumberOfInstances    int
+	hasAppliedMinimumNumberOfInstances bool
+
+	// instanceHourlyCost is the estimated hourly cost of an instance, to add up the cost of the applied minimums of all migs for --max-hourly-cost
+	instanceHourlyCost float64
+
+	// lastIncrease is when the applied minimum number of instances was last raised, for scaleDownCooldownSeconds
+	lastIncrease time.Time
+
+	// lastChange is when the applied minimum number of instances was last changed or first applied, for maxInstancesChangePerMinute
+	lastChange time.Time
+
+	// rateOfChangeRemainder is the fraction of an instance maxInstancesChangePerMinute allowed but didn't use at the change at rateOfChangeRemainderAt, carried over so a rate that isn't a whole number of instances per iteration isn't rounded down at every change
+	rateOfChangeRemainder   float64
+	rateOfChangeRemainderAt time.Time
+
+	// observedRequestRate is the request rate retrieved at observedAt, to calculate its rate of change for bootTimeLeadSeconds
+	observedRequestRate float64
+	observedAt          time.Time
+
+	// candidateUnavailableZones are the zones that were unavailable in the last iteration and unavailableZones the ones compensated for, after being unavailable for two iterations, for zoneOutageCompensation
+	candidateUnavailableZones []string
+	unavailableZones          []string
+
+	// autoscalerStatusDetails are the status detail messages by type the autoscaler reported in the last iteration, to log them only when they change
+	autoscalerStatusDetails map[string]string
+
+	// scalingSchedulesRemoved is whether the scaling schedules managed by the scaler were removed from the autoscaler after useScalingSchedules was turned off, so that's only checked once
+	scalingSchedulesRemoved bool
+
+	// recentRequestRates are the last request rates retrieved, for anomalyFilterDeviations
+	recentRequestRates []float64
+}
+
+// migStateKey identifies the state of a managed instance group by its project, zone or region and name, so migs with the same name elsewhere don't share state
+type migStateKey struct {
+	project           string
+	location          string
+	instanceGroupName string
+}
+
+func newMIGStateKey(configItem MIGConfiguration) migStateKey {
+	return migStateKey{
+		project:           configItem.GCloudProject,
+		location:          configItem.GCloudZone + configItem.GCloudRegion,
+		instanceGroupName: configItem.InstanceGroupName,
+	}
+}
+
+// String returns the key as project/location/name, to report managed instance groups unambiguously
+func (k migStateKey) String() string {
+	return fmt.Sprintf("%v/%v/%v", k.project, k.location, k.instanceGroupName)
+}
+
+// withState calls update with the state of the managed instance group, while holding the lock on all states
+func (s *MIGScaler) withState(configItem MIGConfiguration, update func(state *migState)) {
+	s.statesMu.Lock()
+	defer s.statesMu.Unlock()
+
+	update(s.stateLocked(newMIGStateKey(configItem)))
+}
+
+// stateLocked returns the state for the key, creating it if it doesn't exist yet; the lock on all states has to be held
+func (s *MIGScaler) stateLocked(key migStateKey) *migState {
+	state, ok := s.states[key]
+	if !ok {
+		state = &migState{}
+		s.states[key] = state
+	}
+	return state
+}
+
+// snapshotState returns a copy of the state of the managed instance group, for restoreState
+func (s *MIGScaler) snapshotState(configItem MIGConfiguration) (snapshot migState) {
+	s.withState(configItem, func(state *migState) {
+		snapshot = *state
+		snapshot.lowerMinimumNumberOfInstances = append([]int(nil), state.lowerMinimumNumberOfInstances...)
+	})
+	return
+}
+
+// restoreState undoes the changes the scaling policies made to the state of the managed instance group since the snapshot was taken
+func (s *MIGScaler) restoreState(configItem MIGConfiguration, snapshot migState) {
+	s.withState(configItem, func(state *migState) {
+		state.minimumNumberOfInstances, state.hasMinimumNumberOfInstances = snapshot.minimumNumberOfInstances, snapshot.hasMinimumNumberOfInstances
+		state.lowerMinimumNumberOfInstances = snapshot.lowerMinimumNumberOfInstances
+		state.rateOfChangeRemainder, state.rateOfChangeRemainderAt = snapshot.rateOfChangeRemainder, snapshot.rateOfChangeRemainderAt
+	})
+}
+
+// RetainStates removes the state of managed instance groups that are no longer in the configuration or are disabled, so they don't count towards --max-hourly-cost or can be followed anymore
+func (s *MIGScaler) RetainStates(configItems []MIGConfiguration) {
+
+	retained := map[migStateKey]bool{}
+	for _, configItem := range configItems {
+		if configItem.IsEnabled() {
+			retained[newMIGStateKey(configItem)] = true
+		}
+	}
+
+	s.statesMu.Lock()
+	defer s.statesMu.Unlock()
+
+	for key := range s.states {
+		if !retained[key] {
+			delete(s.states, key)
+		}
+	}
+}
+
+func (s *MIGScaler) lastRequestRate(configItem MIGConfiguration) (requestRate float64, ok bool) {
+	s.withState(configItem, func(state *migState) {
+		requestRate, ok = state.lastRequestRate, state.hasLastRequestRate
+	})
+	return
+}
+
+func (s *MIGScaler) setLastRequestRate(configItem MIGConfiguration, requestRate float64) {
+	s.withState(configItem, func(state *migState) {
+		state.lastRequestRate, state.hasLastRequestRate = requestRate, true
+	})
+}
+
+// followedTargetMinimumNumberOfInstances returns the target of the mig that the mig follows with followMig: the one with that name in the same project and zone or region, or else the only one with that name
+func (s *MIGScaler) followedTargetMinimumNumberOfInstances(configItem MIGConfiguration) (minimumNumberOfInstances int, err error) {
+	s.statesMu.Lock()
+	defer s.statesMu.Unlock()
+
+	key := newMIGStateKey(configItem)
+	key.instanceGroupName = configItem.FollowMIG
+	if state, ok := s.states[key]; ok && state.hasTargetMinimumNumberOfInstances {
+		return state.targetMinimumNumberOfInstances, nil
+	}
+
+	found := 0
+	for key, state := range s.states {
+		if key.instanceGroupName == configItem.FollowMIG && state.hasTargetMinimumNumberOfInstances {
+			minimumNumberOfInstances = state.targetMinimumNumberOfInstances
+			found++
+		}
+	}
+	switch found {
+	case 0:
+		return 0, fmt.Errorf("Followed mig %v has no calculated target yet", configItem.FollowMIG)
+	case 1:
+		return minimumNumberOfInstances, nil
+	}
+	return 0, fmt.Errorf("Followed mig %v is ambiguous, there are %v migs with that name in other projects, zones or regions", configItem.FollowMIG, found)
+}
+
+func (s *MIGScaler) setTargetMinimumNumberOfInstances(configItem MIGConfiguration, minimumNumberOfInstances int) {
+	s.withState(configItem, func(state *migState) {
+		state.targetMinimumNumberOfInstances, state.hasTargetMinimumNumberOfInstances = minimumNumberOfInstances, true
+	})
+}
+
+func (s *MIGScaler) appliedMinimumNumberOfInstances(configItem MIGConfiguration) (minimumNumberOfInstances int, lastIncrease time.Time, ok bool) {
+	s.withState(configItem, func(state *migState) {
+		minimumNumberOfInstances, lastIncrease, ok = state.appliedMinimumNumberOfInstances, state.lastIncrease, state.hasAppliedMinimumNumberOfInstances
+	})
+	return
+}
+
+func (s *MIGScaler) setAppliedMinimumNumberOfInstances(configItem MIGConfiguration, minimumNumberOfInstances int, now time.Time) {
+	s.withState(configItem, func(state *migState) {
+		if state.hasAppliedMinimumNumberOfInstances && minimumNumberOfInstances > state.appliedMinimumNumberOfInstances {
+			state.lastIncrease = now
+		}
+		if !state.hasAppliedMinimumNumberOfInstances || minimumNumberOfInstances != state.appliedMinimumNumberOfInstances {
+			state.lastChange = now
+		}
+		state.appliedMinimumNumberOfInstances, state.hasAppliedMinimumNumberOfInstances = minimumNumberOfInstances, true
+	})
+}
+
+// observeRequestRate stores the request rate and returns how fast it changed per second since the previous observation
+func (s *MIGScaler) observeRequestRate(configItem MIGConfiguration, requestRate float64, now time.Time) (changePerSecond float64, ok bool) {
+	s.withState(configItem, func(state *migState) {
+		if !state.observedAt.IsZero() && now.After(state.observedAt) {
+			changePerSecond, ok = (requestRate-state.observedRequestRate)/now.Sub(state.observedAt).Seconds(), true
+		}
+		state.observedRequestRate, state.observedAt = requestRate, now
+	})
+	return
+}
+
+func (s *MIGScaler) lastChange(configItem MIGConfiguration) (lastChange time.Time) {
+	s.withState(configItem, func(state *migState) {
+		lastChange = state.lastChange
+	})
+	return
+}
+
+// rateOfChangeRemainder returns the fraction of an instance carried over from the last change, or 0 if that change wasn't applied after all
+func (s *MIGScaler) rateOfChangeRemainder(configItem MIGConfiguration) (remainder float64) {
+	s.withState(configItem, func(state *migState) {
+		if state.rateOfChangeRemainderAt.Equal(state.lastChange) {
+			remainder = state.rateOfChangeRemainder
+		}
+	})
+	return
+}
+
+func (s *MIGScaler) setRateOfChangeRemainder(configItem MIGConfiguration, remainder float64, now time.Time) {
+	s.withState(configItem, func(state *migState) {
+		state.rateOfChangeRemainder, state.rateOfChangeRemainderAt = remainder, now
+	})
+}
+
+func (s *MIGScaler) scalingSchedulesRemoved(configItem MIGConfiguration) (removed bool) {
+	s.withState(configItem, func(state *migState) {
+		removed = state.scalingSchedulesRemoved
+	})
+	return
+}
+
+func (s *MIGScaler) setScalingSchedulesRemoved(configItem MIGConfiguration, removed bool) {
+	s.withState(configItem, func(state *migState) {
+		state.scalingSchedulesRemoved = removed
+	})
+}
+
+func (s *MIGScaler) setInstanceHourlyCost(configItem MIGConfiguration, hourlyCost float64) {
+	s.withState(configItem, func(state *migState) {
+		state.instanceHourlyCost = hourlyCost
+	})
+}
+
+// hourlyCostOfOtherMIGs returns the estimated hourly cost of the applied minimum number of instances of all migs but the given one
+func (s *MIGScaler) hourlyCostOfOtherMIGs(configItem MIGConfiguration) (hourlyCost float64) {
+	s.statesMu.Lock()
+	defer s.statesMu.Unlock()
+
+	for key, state := range s.states {
+		if key != newMIGStateKey(configItem) && state.hasAppliedMinimumNumberOfInstances {
+			hourlyCost += float64(state.appliedMinimumNumberOfInstances) * state.instanceHourlyCost
+		}
+	}
+	return
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithState(t *testing.T) {
+
+	t.Run("KeepsSeparateStateForMIGsWithSameNameInOtherProjectsOrLocations", func(t *testing.T) {
+
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+		configItem := MIGConfiguration{GCloudProject: "project-a", GCloudRegion: "europe-west1", InstanceGroupName: "web"}
+		otherProjectConfigItem := MIGConfiguration{GCloudProject: "project-b", GCloudRegion: "europe-west1", InstanceGroupName: "web"}
+		otherRegionConfigItem := MIGConfiguration{GCloudProject: "project-a", GCloudRegion: "europe-west4", InstanceGroupName: "web"}
+		scaler.setAppliedMinimumNumberOfInstances(configItem, 10, time.Now())
+
+		// act
+		_, _, otherProjectOk := scaler.appliedMinimumNumberOfInstances(otherProjectConfigItem)
+		_, _, otherRegionOk := scaler.appliedMinimumNumberOfInstances(otherRegionConfigItem)
+		applied, _, ok := scaler.appliedMinimumNumberOfInstances(configItem)
+
+		assert.False(t, otherProjectOk)
+		assert.False(t, otherRegionOk)
+		assert.True(t, ok)
+		assert.Equal(t, 10, applied)
+	})
+}
+
+func TestRestoreState(t *testing.T) {
+
+	t.Run("UndoesScaleDownConfirmationsOfDecisionThatWasNotApplied", func(t *testing.T) {
+
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+		configItem := MIGConfiguration{InstanceGroupName: "web", ScaleDownConfirmations: 2}
+		scaler.confirmScaleDown(configItem, 10)
+		snapshot := scaler.snapshotState(configItem)
+		scaler.confirmScaleDown(configItem, 5)
+
+		// act
+		scaler.restoreState(configItem, snapshot)
+
+		assert.Equal(t, 10, scaler.confirmScaleDown(configItem, 5))
+		assert.Equal(t, 5, scaler.confirmScaleDown(configItem, 5))
+	})
+}
+
+func TestRetainStates(t *testing.T) {
+
+	t.Run("RemovesStateOfMIGsThatAreRemovedOrDisabled", func(t *testing.T) {
+
+		disabled := false
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+		webConfigItem := MIGConfiguration{GCloudProject: "project-a", GCloudZone: "europe-west1-b", InstanceGroupName: "web"}
+		apiConfigItem := MIGConfiguration{GCloudProject: "project-a", GCloudZone: "europe-west1-b", InstanceGroupName: "api"}
+		batchConfigItem := MIGConfiguration{GCloudProject: "project-a", GCloudZone: "europe-west1-b", InstanceGroupName: "batch"}
+		for _, configItem := range []MIGConfiguration{webConfigItem, apiConfigItem, batchConfigItem} {
+			scaler.setInstanceHourlyCost(configItem, 1)
+			scaler.setAppliedMinimumNumberOfInstances(configItem, 10, time.Now())
+		}
+		batchConfigItem.Enabled = &disabled
+
+		// act
+		scaler.RetainStates([]MIGConfiguration{webConfigItem, batchConfigItem})
+
+		assert.Equal(t, 0.0, scaler.hourlyCostOfOtherMIGs(webConfigItem))
+		_, _, ok := scaler.appliedMinimumNumberOfInstances(webConfigItem)
+		assert.True(t, ok)
+	})
+}
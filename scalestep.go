@@ -0,0 +1,66 @@
+package main
+
+import (
+	"math"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// limitScaleStep limits how far the minimum number of instances moves away from the previously applied minimum in a single iteration; with maxScaleDownStep a sudden drop in request rate, for example because of a monitoring outage, turns into a gradual ramp down, and with maxScaleUpStep downstream dependencies are protected from a thundering herd of new instances
+func (s *MIGScaler) limitScaleStep(configItem MIGConfiguration, minimumNumberOfInstances int) int {
+
+	applied, _, ok := s.appliedMinimumNumberOfInstances(configItem)
+	if !ok {
+		return minimumNumberOfInstances
+	}
+
+	if configItem.MaxScaleDownStep > 0 && applied-minimumNumberOfInstances > configItem.MaxScaleDownStep {
+		log.Info().Msgf("Lowering min instances for mig %v to %v instead of %v, limited by max scale down step %v", configItem.InstanceGroupName, applied-configItem.MaxScaleDownStep, minimumNumberOfInstances, configItem.MaxScaleDownStep)
+		return applied - configItem.MaxScaleDownStep
+	}
+
+	if configItem.MaxScaleUpStep > 0 && minimumNumberOfInstances-applied > configItem.MaxScaleUpStep {
+		log.Info().Msgf("Raising min instances for mig %v to %v instead of %v, limited by max scale up step %v", configItem.InstanceGroupName, applied+configItem.MaxScaleUpStep, minimumNumberOfInstances, configItem.MaxScaleUpStep)
+		return applied + configItem.MaxScaleUpStep
+	}
+
+	return minimumNumberOfInstances
+}
+
+// limitRateOfChange limits how many instances per minute the minimum number of instances moves in either direction with maxInstancesChangePerMinute, counting from its last change, so the scaling pace doesn't depend on how often the scaler runs; the fraction of an instance left over at a limited change counts towards the next one
+func (s *MIGScaler) limitRateOfChange(configItem MIGConfiguration, minimumNumberOfInstances int, now time.Time) int {
+
+	if configItem.MaxInstancesChangePerMinute <= 0 {
+		return minimumNumberOfInstances
+	}
+
+	applied, _, ok := s.appliedMinimumNumberOfInstances(configItem)
+	if !ok || applied == minimumNumberOfInstances {
+		return minimumNumberOfInstances
+	}
+
+	budget := s.rateOfChangeRemainder(configItem) + configItem.MaxInstancesChangePerMinute*now.Sub(s.lastChange(configItem)).Minutes()
+	// the epsilon keeps carried over fractions that add up to a whole instance from falling just short of it
+	allowed := int(math.Floor(budget + 1e-9))
+	limited := minimumNumberOfInstances
+	if limited > applied+allowed {
+		limited = applied + allowed
+	}
+	if limited < applied-allowed {
+		limited = applied - allowed
+	}
+
+	if limited == minimumNumberOfInstances {
+		return limited
+	}
+
+	log.Info().Msgf("Changing min instances for mig %v to %v instead of %v, limited to %v instances per minute", configItem.InstanceGroupName, limited, minimumNumberOfInstances, configItem.MaxInstancesChangePerMinute)
+
+	// the remainder only carries over if the limited minimum gets applied, which makes it the last change
+	if limited != applied {
+		s.setRateOfChangeRemainder(configItem, math.Max(budget-float64(allowed), 0), now)
+	}
+
+	return limited
+}
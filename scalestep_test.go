@@ -0,0 +1,120 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimitScaleStep(t *testing.T) {
+
+	t.Run("LowersMinimumByAtMostMaxScaleDownStep", func(t *testing.T) {
+
+		configItem := MIGConfiguration{InstanceGroupName: "instance-group-name", MaxScaleDownStep: 3}
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+
+		// act
+		minimums := []int{}
+		for _, calculated := range []int{20, 2, 2, 15, 14} {
+			minimumNumberOfInstances := scaler.limitScaleStep(configItem, calculated)
+			scaler.setAppliedMinimumNumberOfInstances(configItem, minimumNumberOfInstances, time.Now())
+			minimums = append(minimums, minimumNumberOfInstances)
+		}
+
+		assert.Equal(t, []int{20, 17, 14, 15, 14}, minimums)
+	})
+
+	t.Run("RaisesMinimumByAtMostMaxScaleUpStep", func(t *testing.T) {
+
+		configItem := MIGConfiguration{InstanceGroupName: "instance-group-name", MaxScaleUpStep: 5}
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+
+		// act
+		minimums := []int{}
+		for _, calculated := range []int{4, 40, 40, 12, 2} {
+			minimumNumberOfInstances := scaler.limitScaleStep(configItem, calculated)
+			scaler.setAppliedMinimumNumberOfInstances(configItem, minimumNumberOfInstances, time.Now())
+			minimums = append(minimums, minimumNumberOfInstances)
+		}
+
+		assert.Equal(t, []int{4, 9, 14, 12, 2}, minimums)
+	})
+
+	t.Run("ReturnsCalculatedMinimumWithoutMaxScaleDownStep", func(t *testing.T) {
+
+		configItem := MIGConfiguration{InstanceGroupName: "instance-group-name"}
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+		scaler.setAppliedMinimumNumberOfInstances(configItem, 20, time.Now())
+
+		// act
+		minimumNumberOfInstances := scaler.limitScaleStep(configItem, 2)
+
+		assert.Equal(t, 2, minimumNumberOfInstances)
+	})
+}
+
+func TestLimitRateOfChange(t *testing.T) {
+
+	configItem := MIGConfiguration{InstanceGroupName: "instance-group-name", MaxInstancesChangePerMinute: 0.5}
+	start := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("ChangesMinimumAtConfiguredPaceRegardlessOfInterval", func(t *testing.T) {
+
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+		scaler.setAppliedMinimumNumberOfInstances(configItem, 10, start)
+
+		// act
+		minimums := []int{}
+		for i := 1; i <= 8; i++ {
+			now := start.Add(time.Duration(i) * 30 * time.Second)
+			minimumNumberOfInstances := scaler.limitRateOfChange(configItem, 20, now)
+			scaler.setAppliedMinimumNumberOfInstances(configItem, minimumNumberOfInstances, now)
+			minimums = append(minimums, minimumNumberOfInstances)
+		}
+
+		assert.Equal(t, []int{10, 10, 10, 11, 11, 11, 11, 12}, minimums)
+	})
+
+	t.Run("CarriesFractionOfAnInstanceOverToNextChange", func(t *testing.T) {
+
+		configItem := MIGConfiguration{InstanceGroupName: "instance-group-name", MaxInstancesChangePerMinute: 0.7}
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+		scaler.setAppliedMinimumNumberOfInstances(configItem, 10, start)
+
+		// act
+		minimums := []int{}
+		for i := 1; i <= 10; i++ {
+			now := start.Add(time.Duration(i) * time.Minute)
+			minimumNumberOfInstances := scaler.limitRateOfChange(configItem, 20, now)
+			scaler.setAppliedMinimumNumberOfInstances(configItem, minimumNumberOfInstances, now)
+			minimums = append(minimums, minimumNumberOfInstances)
+		}
+
+		assert.Equal(t, []int{10, 11, 12, 12, 13, 14, 14, 15, 16, 17}, minimums)
+	})
+
+	t.Run("DropsRemainderOfChangeThatWasNotApplied", func(t *testing.T) {
+
+		configItem := MIGConfiguration{InstanceGroupName: "instance-group-name", MaxInstancesChangePerMinute: 0.7}
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+		scaler.setAppliedMinimumNumberOfInstances(configItem, 10, start)
+		scaler.limitRateOfChange(configItem, 20, start.Add(2*time.Minute))
+
+		// act
+		minimumNumberOfInstances := scaler.limitRateOfChange(configItem, 20, start.Add(3*time.Minute))
+
+		assert.Equal(t, 12, minimumNumberOfInstances)
+	})
+
+	t.Run("LimitsDecreaseAsWell", func(t *testing.T) {
+
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+		scaler.setAppliedMinimumNumberOfInstances(configItem, 10, start)
+
+		// act
+		minimumNumberOfInstances := scaler.limitRateOfChange(configItem, 2, start.Add(10*time.Minute))
+
+		assert.Equal(t, 5, minimumNumberOfInstances)
+	})
+}
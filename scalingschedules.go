@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	compute "google.golang.org/api/compute/v1"
+)
+
+// scalingSchedulePrefix is the prefix of the names of the autoscaler scaling schedules the scaler manages; schedules with other names are left alone
+const scalingSchedulePrefix = "mig-scaler-"
+
+// scalingScheduleNameRegexp matches the names of schedule entries, which become part of the name of their scaling schedule
+var scalingScheduleNameRegexp = regexp.MustCompile(`^[a-z0-9-]{1,50}$`)
+
+// minScalingScheduleDurationSeconds is the shortest duration the autoscaler accepts for a scaling schedule
+const minScalingScheduleDurationSeconds = 300
+
+// ScalingSchedule is a scaling schedule of an autoscaler, which keeps at least minRequiredReplicas instances for durationSec from every start matching its cron schedule
+type ScalingSchedule struct {
+	MinRequiredReplicas int64  `json:"minRequiredReplicas"`
+	Schedule            string `json:"schedule"`
+	TimeZone            string `json:"timeZone,omitempty"`
+	DurationSec         int64  `json:"durationSec"`
+	Description         string `json:"description,omitempty"`
+}
+
+// ScalingSchedulesClient reads and patches the scaling schedules of autoscalers with the compute api directly, since the compute client in use predates them
+type ScalingSchedulesClient struct {
+	BasePath string
+	client   *http.Client
+}
+
+// NewScalingSchedulesClient returns a client for the scaling schedules of autoscalers, using the authenticated google client
+func NewScalingSchedulesClient(client *http.Client) *ScalingSchedulesClient {
+	return &ScalingSchedulesClient{
+		BasePath: "https://www.googleapis.com/compute/beta/projects/",
+		client:   client,
+	}
+}
+
+// autoscalersURL returns the url of the zonal or regional autoscalers of the managed instance group
+func (c *ScalingSchedulesClient) autoscalersURL(configItem MIGConfiguration) string {
+	if configItem.GCloudRegion != "" {
+		return fmt.Sprintf("%v%v/regions/%v/autoscalers", c.BasePath, configItem.GCloudProject, configItem.GCloudRegion)
+	}
+	return fmt.Sprintf("%v%v/zones/%v/autoscalers", c.BasePath, configItem.GCloudProject, configItem.GCloudZone)
+}
+
+// Get retrieves the scaling schedules of the autoscaler by name
+func (c *ScalingSchedulesClient) Get(ctx context.Context, configItem MIGConfiguration, autoscalerName string) (map[string]*ScalingSchedule, error) {
+
+	var autoscaler struct {
+		AutoscalingPolicy struct {
+			ScalingSchedules map[string]*ScalingSchedule `json:"scalingSchedules"`
+		} `json:"autoscalingPolicy"`
+	}
+
+	body, err := c.do(ctx, http.MethodGet, fmt.Sprintf("%v/%v", c.autoscalersURL(configItem), url.PathEscape(autoscalerName)), nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(body, &autoscaler); err != nil {
+		return nil, err
+	}
+
+	return autoscaler.AutoscalingPolicy.ScalingSchedules, nil
+}
+
+// Patch creates or updates the scaling schedules of the autoscaler by name, and deletes those set to nil; all other fields of the autoscaler are left as they are, and the returned operation completes once the schedules are updated
+func (c *ScalingSchedulesClient) Patch(ctx context.Context, configItem MIGConfiguration, autoscalerName string, scalingSchedules map[string]*ScalingSchedule) (*compute.Operation, error) {
+
+	patch := map[string]interface{}{
+		"autoscalingPolicy": map[string]interface{}{
+			"scalingSchedules": scalingSchedules,
+		},
+	}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := c.do(ctx, http.MethodPatch, fmt.Sprintf("%v?autoscaler=%v", c.autoscalersURL(configItem), url.QueryEscape(autoscalerName)), data)
+	if err != nil {
+		return nil, err
+	}
+
+	var operation compute.Operation
+	if err := json.Unmarshal(body, &operation); err != nil {
+		return nil, err
+	}
+
+	return &operation, nil
+}
+
+func (c *ScalingSchedulesClient) do(ctx context.Context, method, url string, data []byte) ([]byte, error) {
+
+	request, err := http.NewRequest(method, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(request.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Compute api returned status code %v: %v", resp.StatusCode, string(body))
+	}
+
+	return body, nil
+}
+
+// ScalingSchedules returns a scaling schedule for every entry in schedules, for migs with useScalingSchedules
+func (c *MIGConfiguration) ScalingSchedules() map[string]*ScalingSchedule {
+
+	scalingSchedules := map[string]*ScalingSchedule{}
+	for _, entry := range c.Schedules {
+		scalingSchedules[entry.scalingScheduleName(c)] = &ScalingSchedule{
+			MinRequiredReplicas: int64(entry.MinimumNumberOfInstances),
+			Schedule:            entry.Cron,
+			TimeZone:            entry.scalingScheduleTimezone(c),
+			DurationSec:         int64(entry.DurationSeconds),
+			Description:         "Managed by estafette-gcloud-mig-scaler",
+		}
+	}
+
+	return scalingSchedules
+}
+
+// scalingScheduleName returns the name of the scaling schedule for the entry: its name, or else a hash of its cron expression and timezone, so adding, removing or reordering other entries doesn't rename it
+func (e ScheduleEntry) scalingScheduleName(c *MIGConfiguration) string {
+	if e.Name != "" {
+		return scalingSchedulePrefix + e.Name
+	}
+	hash := sha256.Sum256([]byte(e.Cron + "|" + e.scalingScheduleTimezone(c)))
+	return scalingSchedulePrefix + hex.EncodeToString(hash[:])[:12]
+}
+
+// scalingScheduleTimezone returns the timezone of the entry, or else of the managed instance group, defaulting to UTC
+func (e ScheduleEntry) scalingScheduleTimezone(c *MIGConfiguration) string {
+	if e.Timezone != "" {
+		return e.Timezone
+	}
+	if c.Timezone != "" {
+		return c.Timezone
+	}
+	return "UTC"
+}
+
+// ScalingScheduleChanges returns the desired scaling schedules that are missing or different in the existing ones, and nil for existing scaling schedules managed by the scaler that are no longer desired
+func ScalingScheduleChanges(existing, desired map[string]*ScalingSchedule) map[string]*ScalingSchedule {
+
+	changes := map[string]*ScalingSchedule{}
+	for name, scalingSchedule := range desired {
+		if !reflect.DeepEqual(existing[name], scalingSchedule) {
+			changes[name] = scalingSchedule
+		}
+	}
+	for name := range existing {
+		if _, ok := desired[name]; !ok && strings.HasPrefix(name, scalingSchedulePrefix) {
+			changes[name] = nil
+		}
+	}
+
+	return changes
+}
+
+// syncScalingSchedules makes the scaling schedules of the autoscaler match the schedules of the managed instance group, so they keep applying while the scaler is down; without useScalingSchedules the scaling schedules managed by the scaler are removed, once
+func (s *MIGScaler) syncScalingSchedules(ctx context.Context, configItem MIGConfiguration, autoscalerName, configRevision string) {
+
+	if s.options.ScalingSchedules == nil {
+		if configItem.UseScalingSchedules {
+			log.Warn().Msgf("Mig %v has useScalingSchedules, but no scaling schedules client is configured", configItem.InstanceGroupName)
+		}
+		return
+	}
+	if !configItem.UseScalingSchedules && s.scalingSchedulesRemoved(configItem) {
+		return
+	}
+
+	scalingSchedules, err := s.scalingSchedulesFor(ctx, configItem)
+	if err != nil {
+		log.Error().Err(err).Msgf("Creating client for mig %v failed", configItem.InstanceGroupName)
+		return
+	}
+
+	existing, err := scalingSchedules.Get(ctx, configItem, autoscalerName)
+	if err != nil {
+		log.Error().Err(err).Msgf("Retrieving scaling schedules of autoscaler %v failed", autoscalerName)
+		return
+	}
+
+	desired := map[string]*ScalingSchedule{}
+	if configItem.UseScalingSchedules {
+		desired = configItem.ScalingSchedules()
+	}
+
+	changes := ScalingScheduleChanges(existing, desired)
+	if len(changes) > 0 {
+		operation, err := scalingSchedules.Patch(ctx, configItem, autoscalerName, changes)
+		if err != nil {
+			log.Error().Err(err).Msgf("Updating scaling schedules of autoscaler %v failed", autoscalerName)
+			autoscalerUpdatesVector.WithLabelValues(configItem.InstanceGroupName, failedOperationResult).Inc()
+			return
+		}
+		if !s.awaitAutoscalerUpdate(ctx, configItem, operation) {
+			return
+		}
+
+		log.Info().Str("configRevision", configRevision).Msgf("Updated %v scaling schedules of autoscaler for mig %v", len(changes), configItem.InstanceGroupName)
+	}
+
+	s.setScalingSchedulesRemoved(configItem, !configItem.UseScalingSchedules)
+}
+
+// validateScalingSchedules checks whether every schedule entry has a start time, a duration the autoscaler accepts and a unique name, if the schedules become scaling schedules
+func (c *MIGConfiguration) validateScalingSchedules(addError func(field, message string)) {
+
+	if !c.UseScalingSchedules {
+		return
+	}
+
+	names := map[string]int{}
+	for i, entry := range c.Schedules {
+		// the cron expression of a scaling schedule is when it starts, so it can't start every minute of an hour
+		if cron, err := ParseCronExpression(entry.Cron); err == nil && len(cron.minutes) != 1 {
+			addError(fmt.Sprintf("schedules[%v].cron", i), "should start at a single minute of the hour with useScalingSchedules, like 0 9 * * *")
+		}
+		if entry.DurationSeconds < minScalingScheduleDurationSeconds {
+			addError(fmt.Sprintf("schedules[%v].durationSeconds", i), fmt.Sprintf("should be %v or larger with useScalingSchedules", minScalingScheduleDurationSeconds))
+		}
+		if entry.Name != "" && !scalingScheduleNameRegexp.MatchString(entry.Name) {
+			addError(fmt.Sprintf("schedules[%v].name", i), "should be at most 50 lowercase letters, digits and dashes")
+		}
+		name := entry.scalingScheduleName(c)
+		if j, ok := names[name]; ok {
+			addError(fmt.Sprintf("schedules[%v]", i), fmt.Sprintf("has the same name, or cron and timezone, as schedules[%v]; set a unique name", j))
+			continue
+		}
+		names[name] = i
+	}
+}
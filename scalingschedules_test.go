@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScalingSchedules(t *testing.T) {
+
+	t.Run("ReturnsScalingSchedulePerScheduleEntry", func(t *testing.T) {
+
+		configItem := MIGConfiguration{
+			Timezone: "Europe/Amsterdam",
+			Schedules: []ScheduleEntry{
+				{Name: "weekday-mornings", Cron: "0 8 * * 1-5", MinimumNumberOfInstances: 10, DurationSeconds: 36000},
+				{Name: "evenings", Cron: "30 19 * * *", Timezone: "America/New_York", MinimumNumberOfInstances: 40, DurationSeconds: 7200},
+			},
+		}
+
+		// act
+		scalingSchedules := configItem.ScalingSchedules()
+
+		assert.Equal(t, map[string]*ScalingSchedule{
+			"mig-scaler-weekday-mornings": {MinRequiredReplicas: 10, Schedule: "0 8 * * 1-5", TimeZone: "Europe/Amsterdam", DurationSec: 36000, Description: "Managed by estafette-gcloud-mig-scaler"},
+			"mig-scaler-evenings":         {MinRequiredReplicas: 40, Schedule: "30 19 * * *", TimeZone: "America/New_York", DurationSec: 7200, Description: "Managed by estafette-gcloud-mig-scaler"},
+		}, scalingSchedules)
+	})
+
+	t.Run("KeepsNamesOfUnnamedEntriesWhenOtherEntriesAreRemoved", func(t *testing.T) {
+
+		configItem := MIGConfiguration{
+			Schedules: []ScheduleEntry{
+				{Cron: "0 8 * * 1-5", MinimumNumberOfInstances: 10, DurationSeconds: 36000},
+				{Cron: "30 19 * * *", MinimumNumberOfInstances: 40, DurationSeconds: 7200},
+			},
+		}
+		before := configItem.ScalingSchedules()
+		configItem.Schedules = configItem.Schedules[1:]
+
+		// act
+		after := configItem.ScalingSchedules()
+
+		if assert.Equal(t, 1, len(after)) {
+			for name, scalingSchedule := range after {
+				assert.Equal(t, before[name], scalingSchedule)
+			}
+		}
+	})
+}
+
+func TestValidateScalingSchedules(t *testing.T) {
+
+	validate := func(configItem MIGConfiguration) (fields []string) {
+		configItem.validateScalingSchedules(func(field, message string) {
+			fields = append(fields, field)
+		})
+		return
+	}
+
+	t.Run("ReturnsErrorIfCronStartsEveryMinute", func(t *testing.T) {
+
+		// act
+		fields := validate(MIGConfiguration{UseScalingSchedules: true, Schedules: []ScheduleEntry{{Cron: "* 9 * * *", MinimumNumberOfInstances: 10, DurationSeconds: 3600}}})
+
+		assert.Equal(t, []string{"schedules[0].cron"}, fields)
+	})
+
+	t.Run("ReturnsErrorForEntriesWithSameCronAndTimezone", func(t *testing.T) {
+
+		// act
+		fields := validate(MIGConfiguration{UseScalingSchedules: true, Schedules: []ScheduleEntry{
+			{Cron: "0 9 * * *", MinimumNumberOfInstances: 10, DurationSeconds: 3600},
+			{Cron: "0 9 * * *", MinimumNumberOfInstances: 20, DurationSeconds: 7200},
+		}})
+
+		assert.Equal(t, []string{"schedules[1]"}, fields)
+	})
+
+	t.Run("ReturnsNoErrorsForSchedulesStartingAtSingleMinute", func(t *testing.T) {
+
+		// act
+		fields := validate(MIGConfiguration{UseScalingSchedules: true, Schedules: []ScheduleEntry{
+			{Cron: "0 9 * * 1-5", MinimumNumberOfInstances: 10, DurationSeconds: 3600},
+			{Name: "evenings", Cron: "30 18,20 * * *", MinimumNumberOfInstances: 20, DurationSeconds: 3600},
+		}})
+
+		assert.Nil(t, fields)
+	})
+}
+
+func TestScalingScheduleChanges(t *testing.T) {
+
+	t.Run("ReturnsChangedAndMissingSchedulesAndDeletesManagedSchedulesThatAreNoLongerDesired", func(t *testing.T) {
+
+		existing := map[string]*ScalingSchedule{
+			"mig-scaler-0": {MinRequiredReplicas: 10, Schedule: "0 8 * * 1-5", TimeZone: "UTC", DurationSec: 36000},
+			"mig-scaler-1": {MinRequiredReplicas: 20, Schedule: "0 20 * * *", TimeZone: "UTC", DurationSec: 3600},
+			"mig-scaler-2": {MinRequiredReplicas: 5, Schedule: "0 0 * * *", TimeZone: "UTC", DurationSec: 3600},
+			"black-friday": {MinRequiredReplicas: 100, Schedule: "0 0 27 11 *", TimeZone: "UTC", DurationSec: 86400},
+		}
+		desired := map[string]*ScalingSchedule{
+			"mig-scaler-0": {MinRequiredReplicas: 10, Schedule: "0 8 * * 1-5", TimeZone: "UTC", DurationSec: 36000},
+			"mig-scaler-1": {MinRequiredReplicas: 30, Schedule: "0 20 * * *", TimeZone: "UTC", DurationSec: 3600},
+		}
+
+		// act
+		changes := ScalingScheduleChanges(existing, desired)
+
+		assert.Equal(t, map[string]*ScalingSchedule{
+			"mig-scaler-1": desired["mig-scaler-1"],
+			"mig-scaler-2": nil,
+		}, changes)
+	})
+}
+
+func TestScalingSchedulesClient(t *testing.T) {
+
+	t.Run("PatchesOnlyScalingSchedulesAndDeletesNilSchedules", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			assert.Equal(t, http.MethodPatch, r.Method)
+			assert.Equal(t, "/project-id/regions/europe-west1/autoscalers", r.URL.Path)
+			assert.Equal(t, "web-autoscaler", r.URL.Query().Get("autoscaler"))
+			assert.JSONEq(t, `{"autoscalingPolicy":{"scalingSchedules":{"mig-scaler-0":{"minRequiredReplicas":10,"schedule":"0 8 * * 1-5","timeZone":"UTC","durationSec":36000},"mig-scaler-1":null}}}`, string(body))
+			w.Write([]byte(`{"kind":"compute#operation","status":"RUNNING"}`))
+		}))
+		defer server.Close()
+
+		client := NewScalingSchedulesClient(server.Client())
+		client.BasePath = server.URL + "/"
+		configItem := MIGConfiguration{GCloudProject: "project-id", GCloudRegion: "europe-west1"}
+
+		// act
+		operation, err := client.Patch(context.Background(), configItem, "web-autoscaler", map[string]*ScalingSchedule{
+			"mig-scaler-0": {MinRequiredReplicas: 10, Schedule: "0 8 * * 1-5", TimeZone: "UTC", DurationSec: 36000},
+			"mig-scaler-1": nil,
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, "RUNNING", operation.Status)
+	})
+
+	t.Run("GetsScalingSchedulesOfAutoscaler", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/project-id/zones/europe-west1-b/autoscalers/web-autoscaler", r.URL.Path)
+			w.Write([]byte(`{"name":"web-autoscaler","autoscalingPolicy":{"minNumReplicas":3,"scalingSchedules":{"mig-scaler-0":{"minRequiredReplicas":10,"schedule":"0 8 * * 1-5","timeZone":"UTC","durationSec":36000}}}}`))
+		}))
+		defer server.Close()
+
+		client := NewScalingSchedulesClient(server.Client())
+		client.BasePath = server.URL + "/"
+		configItem := MIGConfiguration{GCloudProject: "project-id", GCloudZone: "europe-west1-b"}
+
+		// act
+		scalingSchedules, err := client.Get(context.Background(), configItem, "web-autoscaler")
+
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]*ScalingSchedule{"mig-scaler-0": {MinRequiredReplicas: 10, Schedule: "0 8 * * 1-5", TimeZone: "UTC", DurationSec: 36000}}, scalingSchedules)
+	})
+}
+
+func TestSyncScalingSchedules(t *testing.T) {
+
+	newScaler := func(t *testing.T, patches *[]string) (*MIGScaler, func()) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == http.MethodPatch:
+				body, _ := ioutil.ReadAll(r.Body)
+				*patches = append(*patches, string(body))
+				w.Write([]byte(`{"name":"operation-1","status":"RUNNING"}`))
+			case strings.HasSuffix(r.URL.Path, "/operations/operation-1"):
+				w.Write([]byte(`{"name":"operation-1","status":"DONE"}`))
+			default:
+				w.Write([]byte(`{"name":"web-autoscaler","autoscalingPolicy":{"scalingSchedules":{"mig-scaler-0":{"minRequiredReplicas":10,"schedule":"0 8 * * 1-5","timeZone":"UTC","durationSec":36000},"black-friday":{"minRequiredReplicas":100,"schedule":"0 0 27 11 *","timeZone":"UTC","durationSec":86400}}}}`))
+			}
+		}))
+		computeClient, _ := NewGAComputeClient(server.Client(), ComputeClientOptions{BasePath: server.URL + "/"})
+		scalingSchedules := NewScalingSchedulesClient(server.Client())
+		scalingSchedules.BasePath = server.URL + "/"
+		return NewMIGScaler(computeClient, nil, MIGScalerOptions{ScalingSchedules: scalingSchedules, OperationPoll: time.Millisecond}), server.Close
+	}
+
+	t.Run("RemovesManagedScalingSchedulesOnceWithoutUseScalingSchedules", func(t *testing.T) {
+
+		patches := []string{}
+		scaler, closeServer := newScaler(t, &patches)
+		defer closeServer()
+		configItem := MIGConfiguration{GCloudProject: "project-id", GCloudZone: "europe-west1-b", InstanceGroupName: "web"}
+		updates := testutil.ToFloat64(autoscalerUpdatesVector.WithLabelValues("web", succeededOperationResult))
+
+		// act
+		scaler.syncScalingSchedules(context.Background(), configItem, "web-autoscaler", "")
+		scaler.syncScalingSchedules(context.Background(), configItem, "web-autoscaler", "")
+
+		if assert.Equal(t, 1, len(patches)) {
+			assert.JSONEq(t, `{"autoscalingPolicy":{"scalingSchedules":{"mig-scaler-0":null}}}`, patches[0])
+		}
+		assert.True(t, scaler.scalingSchedulesRemoved(configItem))
+		assert.Equal(t, updates+1, testutil.ToFloat64(autoscalerUpdatesVector.WithLabelValues("web", succeededOperationResult)))
+	})
+}
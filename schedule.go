@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ScheduleEntry raises the minimum number of instances of a managed instance group during every minute matching its cron expression, in the timezone of the entry or else of the managed instance group; with useScalingSchedules the cron expression is the start of a scaling schedule lasting durationSeconds instead
+type ScheduleEntry struct {
+	Name                     string `json:"name,omitempty"`
+	Cron                     string `json:"cron,omitempty"`
+	Timezone                 string `json:"timezone,omitempty"`
+	MinimumNumberOfInstances int    `json:"minimumNumberOfInstances,omitempty"`
+	DurationSeconds          int    `json:"durationSeconds,omitempty"`
+}
+
+// Location returns the timezone to evaluate schedules for the managed instance group in; it defaults to UTC when timezone isn't set
+func (c *MIGConfiguration) Location() (*time.Location, error) {
+	return loadTimezone(c.Timezone)
+}
+
+// loadTimezone returns the location for an IANA timezone name like Europe/Amsterdam, so cron expressions follow local time across daylight saving time transitions
+func loadTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(name)
+}
+
+// location returns the timezone of the schedule entry, falling back to the timezone of the managed instance group
+func (e ScheduleEntry) location(c *MIGConfiguration) (*time.Location, error) {
+	if e.Timezone != "" {
+		return loadTimezone(e.Timezone)
+	}
+	return c.Location()
+}
+
+// ScheduledMinimumNumberOfInstances returns the highest minimum number of instances of the schedule entries matching the time, and 0 if none match
+func (c *MIGConfiguration) ScheduledMinimumNumberOfInstances(now time.Time) (minimumNumberOfInstances int) {
+
+	for _, entry := range c.Schedules {
+		cron, err := ParseCronExpression(entry.Cron)
+		if err != nil {
+			// reported by the configuration validation
+			continue
+		}
+		location, err := entry.location(c)
+		if err != nil {
+			continue
+		}
+		if cron.Matches(now.In(location)) && entry.MinimumNumberOfInstances > minimumNumberOfInstances {
+			minimumNumberOfInstances = entry.MinimumNumberOfInstances
+		}
+	}
+
+	return
+}
+
+// applySchedules raises the minimum number of instances to the scheduled minimum, so capacity is guaranteed ahead of known events regardless of the request rate; with useScalingSchedules the autoscaler applies the schedules itself
+func applySchedules(configItem MIGConfiguration, minimumNumberOfInstances int, now time.Time) int {
+
+	if configItem.UseScalingSchedules {
+		return minimumNumberOfInstances
+	}
+
+	scheduled := configItem.ScheduledMinimumNumberOfInstances(now)
+	if scheduled <= minimumNumberOfInstances {
+		return minimumNumberOfInstances
+	}
+
+	log.Info().Msgf("Raising min instances for mig %v from %v to scheduled minimum %v", configItem.InstanceGroupName, minimumNumberOfInstances, scheduled)
+
+	return scheduled
+}
+
+// validateSchedules checks the timezone, and the cron expression, timezone and minimum number of instances of every schedule entry
+func (c *MIGConfiguration) validateSchedules(addError func(field, message string)) {
+	if _, err := c.Location(); err != nil {
+		addError("timezone", err.Error())
+	}
+	for i, entry := range c.Schedules {
+		if _, err := ParseCronExpression(entry.Cron); err != nil {
+			addError(fmt.Sprintf("schedules[%v].cron", i), err.Error())
+		}
+		if _, err := loadTimezone(entry.Timezone); err != nil {
+			addError(fmt.Sprintf("schedules[%v].timezone", i), err.Error())
+		}
+		if entry.MinimumNumberOfInstances <= 0 {
+			addError(fmt.Sprintf("schedules[%v].minimumNumberOfInstances", i), "should be larger than 0")
+		}
+	}
+}
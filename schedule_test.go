@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplySchedules(t *testing.T) {
+
+	configItem := MIGConfiguration{
+		InstanceGroupName: "instance-group-name",
+		Schedules: []ScheduleEntry{
+			ScheduleEntry{Cron: "* 18-21 * * 5", MinimumNumberOfInstances: 40},
+			ScheduleEntry{Cron: "* 20 * * 5", MinimumNumberOfInstances: 60},
+		},
+	}
+
+	t.Run("RaisesMinimumToHighestMatchingSchedule", func(t *testing.T) {
+
+		// act
+		minimumNumberOfInstances := applySchedules(configItem, 12, time.Date(2020, 6, 5, 20, 15, 0, 0, time.UTC))
+
+		assert.Equal(t, 60, minimumNumberOfInstances)
+	})
+
+	t.Run("KeepsHigherCalculatedMinimum", func(t *testing.T) {
+
+		// act
+		minimumNumberOfInstances := applySchedules(configItem, 50, time.Date(2020, 6, 5, 18, 15, 0, 0, time.UTC))
+
+		assert.Equal(t, 50, minimumNumberOfInstances)
+	})
+
+	t.Run("KeepsCalculatedMinimumOutsideSchedules", func(t *testing.T) {
+
+		// act
+		minimumNumberOfInstances := applySchedules(configItem, 12, time.Date(2020, 6, 4, 20, 15, 0, 0, time.UTC))
+
+		assert.Equal(t, 12, minimumNumberOfInstances)
+	})
+}
+
+func TestScheduledMinimumNumberOfInstances(t *testing.T) {
+
+	t.Run("EvaluatesCronInTimezoneAcrossDaylightSavingTime", func(t *testing.T) {
+
+		configItem := MIGConfiguration{
+			Timezone:  "Europe/Amsterdam",
+			Schedules: []ScheduleEntry{ScheduleEntry{Cron: "* 9 * * *", MinimumNumberOfInstances: 20}},
+		}
+
+		// act
+		winter := configItem.ScheduledMinimumNumberOfInstances(time.Date(2020, 1, 15, 8, 30, 0, 0, time.UTC))
+		summer := configItem.ScheduledMinimumNumberOfInstances(time.Date(2020, 7, 15, 7, 30, 0, 0, time.UTC))
+		summerOneHourLater := configItem.ScheduledMinimumNumberOfInstances(time.Date(2020, 7, 15, 8, 30, 0, 0, time.UTC))
+
+		assert.Equal(t, 20, winter)
+		assert.Equal(t, 20, summer)
+		assert.Equal(t, 0, summerOneHourLater)
+	})
+
+	t.Run("PrefersTimezoneOfScheduleEntry", func(t *testing.T) {
+
+		configItem := MIGConfiguration{
+			Timezone:  "Europe/Amsterdam",
+			Schedules: []ScheduleEntry{ScheduleEntry{Cron: "* 9 * * *", Timezone: "America/New_York", MinimumNumberOfInstances: 20}},
+		}
+
+		// act
+		minimumNumberOfInstances := configItem.ScheduledMinimumNumberOfInstances(time.Date(2020, 7, 15, 13, 30, 0, 0, time.UTC))
+
+		assert.Equal(t, 20, minimumNumberOfInstances)
+	})
+}
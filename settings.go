@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/ghodss/yaml"
+	"github.com/rs/zerolog/log"
+)
+
+// ApplySettingsFile sets flags from a yaml file mapping flag names to values, for all flags that weren't passed on the command line or via their envvar; precedence is command line flag, envvar, settings file, default
+func ApplySettingsFile(app *kingpin.Application, args []string, settingsFile string) (err error) {
+
+	data, err := ioutil.ReadFile(settingsFile)
+	if err != nil {
+		return
+	}
+
+	var settings map[string]interface{}
+	if err = yaml.Unmarshal(data, &settings); err != nil {
+		return
+	}
+
+	parseContext, err := app.ParseContext(args)
+	if err != nil {
+		return
+	}
+
+	setOnCommandLine := map[string]bool{}
+	for _, element := range parseContext.Elements {
+		if flag, ok := element.Clause.(*kingpin.FlagClause); ok {
+			setOnCommandLine[flag.Model().Name] = true
+		}
+	}
+
+	flagsByName := map[string]*kingpin.FlagModel{}
+	for _, flag := range app.Model().Flags {
+		flagsByName[flag.Name] = flag
+	}
+
+	for name, value := range settings {
+		flag, ok := flagsByName[name]
+		if !ok {
+			return fmt.Errorf("Settings file %v contains unknown flag %v", settingsFile, name)
+		}
+
+		if setOnCommandLine[name] {
+			continue
+		}
+		if _, ok := os.LookupEnv(flag.Envar); ok && flag.Envar != "" {
+			continue
+		}
+
+		if err = flag.Value.Set(fmt.Sprintf("%v", value)); err != nil {
+			return fmt.Errorf("Setting flag %v from settings file %v failed: %v", name, settingsFile, err)
+		}
+	}
+
+	return nil
+}
+
+// LogEffectiveSettings logs the value of every flag after all configuration layers have been applied, masking values of flags that hold credentials
+func LogEffectiveSettings(app *kingpin.Application) {
+
+	settings := map[string]string{}
+	for _, flag := range app.Model().Flags {
+		if flag.Hidden || flag.Name == "help" {
+			continue
+		}
+
+		value := flag.Value.String()
+		if isSensitiveFlag(flag.Name) && value != "" {
+			value = "***"
+		}
+		settings[flag.Name] = value
+	}
+
+	log.Info().Interface("settings", settings).Msg("Effective settings")
+}
+
+func isSensitiveFlag(name string) bool {
+	for _, sensitive := range []string{"password", "token", "api-key", "app-key"} {
+		if strings.Contains(name, sensitive) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplySettingsFile(t *testing.T) {
+
+	t.Run("AppliesSettingsWithLowerPrecedenceThanFlagsAndEnvvars", func(t *testing.T) {
+
+		app := kingpin.New("test", "")
+		fromFlag := app.Flag("from-flag", "").Envar("MIG_SCALER_TEST_FROM_FLAG").Default("default").String()
+		fromEnvar := app.Flag("from-envar", "").Envar("MIG_SCALER_TEST_FROM_ENVAR").Default("default").String()
+		fromFile := app.Flag("from-file", "").Envar("MIG_SCALER_TEST_FROM_FILE").Default("1m").Duration()
+		fromDefault := app.Flag("from-default", "").Default("default").String()
+
+		os.Setenv("MIG_SCALER_TEST_FROM_ENVAR", "envar")
+		defer os.Unsetenv("MIG_SCALER_TEST_FROM_ENVAR")
+
+		settingsFile, _ := ioutil.TempFile("", "settings")
+		defer os.Remove(settingsFile.Name())
+		settingsFile.Write([]byte("from-flag: file\nfrom-envar: file\nfrom-file: 2m\n"))
+		settingsFile.Close()
+
+		args := []string{"--from-flag", "flag"}
+		app.Parse(args)
+
+		// act
+		err := ApplySettingsFile(app, args, settingsFile.Name())
+
+		assert.Nil(t, err)
+		assert.Equal(t, "flag", *fromFlag)
+		assert.Equal(t, "envar", *fromEnvar)
+		assert.Equal(t, "2m0s", fromFile.String())
+		assert.Equal(t, "default", *fromDefault)
+	})
+
+	t.Run("ReturnsErrorForUnknownFlag", func(t *testing.T) {
+
+		app := kingpin.New("test", "")
+		app.Flag("known", "").String()
+
+		settingsFile, _ := ioutil.TempFile("", "settings")
+		defer os.Remove(settingsFile.Name())
+		settingsFile.Write([]byte("unknown: value\n"))
+		settingsFile.Close()
+
+		app.Parse([]string{})
+
+		// act
+		err := ApplySettingsFile(app, []string{}, settingsFile.Name())
+
+		assert.NotNil(t, err)
+	})
+}
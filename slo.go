@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"math"
+
+	"github.com/rs/zerolog/log"
+)
+
+// SLOConfig returns the configuration to execute the slo query with; it inherits all fields of the managed instance group except its other queries
+func (c *MIGConfiguration) SLOConfig() MIGConfiguration {
+	sloConfig := c.TrendConfig()
+	sloConfig.RequestRateQuery = c.SLOQuery
+	sloConfig.SpotPreemptionRateQuery = ""
+	sloConfig.SLOQuery = ""
+	return sloConfig
+}
+
+// getSLOScaleUpPercent executes the slo query, like the p99 latency or 5xx rate, and returns sloScaleUpPercent if its result exceeds sloThreshold, and 0 otherwise; a failing query is ignored, so the slo can only ever add capacity
+func (s *MIGScaler) getSLOScaleUpPercent(ctx context.Context, configItem MIGConfiguration) float64 {
+
+	if configItem.SLOQuery == "" {
+		return 0
+	}
+
+	queryCtx, cancelQuery := withTimeout(ctx, configItem.QueryTimeout(s.options.QueryTimeout))
+	defer cancelQuery()
+
+	value, err := getSingleRequestRate(queryCtx, s.metricSources, configItem.SLOConfig())
+	if err != nil {
+		log.Warn().Err(err).Msgf("Retrieving slo for mig %v failed, ignoring it", configItem.InstanceGroupName)
+		return 0
+	}
+
+	if value <= configItem.SLOThreshold {
+		sloBreachedVector.WithLabelValues(configItem.InstanceGroupName).Set(0)
+		return 0
+	}
+
+	log.Warn().Msgf("Slo query for mig %v returned %v, which exceeds threshold %v; raising min instances by %v%%", configItem.InstanceGroupName, value, configItem.SLOThreshold, configItem.SLOScaleUpPercent)
+	sloBreachedVector.WithLabelValues(configItem.InstanceGroupName).Set(1)
+
+	return configItem.SLOScaleUpPercent
+}
+
+// scaleUpForSLO raises the minimum number of instances by scaleUpPercent, rounded up, to add headroom while the slo is breached
+func scaleUpForSLO(minimumNumberOfInstances int, scaleUpPercent float64) int {
+	if scaleUpPercent <= 0 {
+		return minimumNumberOfInstances
+	}
+	return int(math.Ceil(float64(minimumNumberOfInstances) * (100 + scaleUpPercent) / 100))
+}
+
+// validateSLO checks whether sloQuery has a percentage to raise the minimum by
+func (c *MIGConfiguration) validateSLO(addError func(field, message string)) {
+
+	if c.SLOQuery == "" {
+		if c.SLOScaleUpPercent != 0 {
+			addError("sloScaleUpPercent", "requires sloQuery")
+		}
+		return
+	}
+
+	if c.SLOScaleUpPercent <= 0 {
+		addError("sloScaleUpPercent", "should be larger than 0")
+	}
+}
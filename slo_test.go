@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSLOScaleUpPercent(t *testing.T) {
+
+	metricSources := map[string]MetricSource{"prometheus": &fakeMetricSource{requestRates: map[string]float64{"histogram_quantile(0.99, sum by (le) (rate(http_request_duration_seconds_bucket[5m])))": 0.8}}}
+
+	t.Run("ReturnsSLOScaleUpPercentIfThresholdIsExceeded", func(t *testing.T) {
+
+		configItem := MIGConfiguration{InstanceGroupName: "slo-breached", SLOQuery: "histogram_quantile(0.99, sum by (le) (rate(http_request_duration_seconds_bucket[5m])))", SLOThreshold: 0.5, SLOScaleUpPercent: 20}
+		scaler := NewMIGScaler(nil, metricSources, MIGScalerOptions{})
+
+		// act
+		scaleUpPercent := scaler.getSLOScaleUpPercent(context.Background(), configItem)
+
+		assert.Equal(t, 20.0, scaleUpPercent)
+		assert.Equal(t, 1.0, testutil.ToFloat64(sloBreachedVector.WithLabelValues("slo-breached")))
+	})
+
+	t.Run("ReturnsZeroIfThresholdIsNotExceeded", func(t *testing.T) {
+
+		configItem := MIGConfiguration{InstanceGroupName: "slo-met", SLOQuery: "histogram_quantile(0.99, sum by (le) (rate(http_request_duration_seconds_bucket[5m])))", SLOThreshold: 1, SLOScaleUpPercent: 20}
+		scaler := NewMIGScaler(nil, metricSources, MIGScalerOptions{})
+
+		// act
+		scaleUpPercent := scaler.getSLOScaleUpPercent(context.Background(), configItem)
+
+		assert.Equal(t, 0.0, scaleUpPercent)
+		assert.Equal(t, 0.0, testutil.ToFloat64(sloBreachedVector.WithLabelValues("slo-met")))
+	})
+
+	t.Run("ReturnsZeroIfQueryFails", func(t *testing.T) {
+
+		configItem := MIGConfiguration{InstanceGroupName: "slo-failing", SLOQuery: "sum(rate(http_requests_total{code=~\"5..\"}[5m]))", SLOScaleUpPercent: 20}
+		scaler := NewMIGScaler(nil, map[string]MetricSource{"prometheus": &failingMetricSource{}}, MIGScalerOptions{})
+
+		// act
+		scaleUpPercent := scaler.getSLOScaleUpPercent(context.Background(), configItem)
+
+		assert.Equal(t, 0.0, scaleUpPercent)
+	})
+}
+
+func TestScaleUpForSLO(t *testing.T) {
+
+	t.Run("RaisesMinimumByPercentRoundedUp", func(t *testing.T) {
+
+		// act
+		minimums := []int{scaleUpForSLO(10, 20), scaleUpForSLO(7, 20), scaleUpForSLO(7, 0)}
+
+		assert.Equal(t, []int{12, 9, 7}, minimums)
+	})
+}
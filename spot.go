@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"math"
+
+	"github.com/rs/zerolog/log"
+)
+
+// SpotPreemptionRateConfig returns the configuration to execute the spot preemption rate query with; it inherits all fields of the managed instance group except its other queries
+func (c *MIGConfiguration) SpotPreemptionRateConfig() MIGConfiguration {
+	spotConfig := c.TrendConfig()
+	spotConfig.RequestRateQuery = c.SpotPreemptionRateQuery
+	spotConfig.SpotPreemptionRateQuery = ""
+	spotConfig.SLOQuery = ""
+	return spotConfig
+}
+
+// getSpotOverprovisionPercent returns spotOverprovisionPercent, raised to the result of the spot preemption rate query if that's higher, capped at 100; a failing query is ignored
+func (s *MIGScaler) getSpotOverprovisionPercent(ctx context.Context, configItem MIGConfiguration) float64 {
+
+	overprovisionPercent := configItem.SpotOverprovisionPercent
+	if configItem.SpotPreemptionRateQuery == "" {
+		return overprovisionPercent
+	}
+
+	queryCtx, cancelQuery := withTimeout(ctx, configItem.QueryTimeout(s.options.QueryTimeout))
+	defer cancelQuery()
+
+	preemptionRate, err := getSingleRequestRate(queryCtx, s.metricSources, configItem.SpotPreemptionRateConfig())
+	if err != nil {
+		log.Warn().Err(err).Msgf("Retrieving spot preemption rate for mig %v failed, using spotOverprovisionPercent %v", configItem.InstanceGroupName, overprovisionPercent)
+		return overprovisionPercent
+	}
+
+	if preemptionRate > overprovisionPercent {
+		overprovisionPercent = math.Min(preemptionRate, 100)
+	}
+
+	return overprovisionPercent
+}
+
+// overprovisionForSpot inflates the minimum number of instances by overprovisionPercent, rounded up, to compensate for instances of spot vms being preempted and recreated
+func overprovisionForSpot(minimumNumberOfInstances int, overprovisionPercent float64) int {
+	if overprovisionPercent <= 0 {
+		return minimumNumberOfInstances
+	}
+	return int(math.Ceil(float64(minimumNumberOfInstances) * (100 + overprovisionPercent) / 100))
+}
+
+// validateSpotOverprovision checks whether spotOverprovisionPercent is a percentage
+func (c *MIGConfiguration) validateSpotOverprovision(addError func(field, message string)) {
+	if c.SpotOverprovisionPercent < 0 || c.SpotOverprovisionPercent > 100 {
+		addError("spotOverprovisionPercent", "should be between 0 and 100")
+	}
+}
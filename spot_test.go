@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSpotOverprovisionPercent(t *testing.T) {
+
+	t.Run("ReturnsSpotOverprovisionPercentWithoutQuery", func(t *testing.T) {
+
+		configItem := MIGConfiguration{InstanceGroupName: "instance-group-name", SpotOverprovisionPercent: 15}
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+
+		// act
+		overprovisionPercent := scaler.getSpotOverprovisionPercent(context.Background(), configItem)
+
+		assert.Equal(t, 15.0, overprovisionPercent)
+	})
+
+	t.Run("ReturnsPreemptionRateIfHigherThanSpotOverprovisionPercent", func(t *testing.T) {
+
+		configItem := MIGConfiguration{InstanceGroupName: "instance-group-name", RequestRateQuery: "sum(rate(nginx_http_requests_total[10m]))", SpotOverprovisionPercent: 15, SpotPreemptionRateQuery: "preemptions:rate1h"}
+		scaler := NewMIGScaler(nil, map[string]MetricSource{"prometheus": &fakeMetricSource{requestRates: map[string]float64{"preemptions:rate1h": 22.5}}}, MIGScalerOptions{})
+
+		// act
+		overprovisionPercent := scaler.getSpotOverprovisionPercent(context.Background(), configItem)
+
+		assert.Equal(t, 22.5, overprovisionPercent)
+	})
+
+	t.Run("CapsPreemptionRateAt100Percent", func(t *testing.T) {
+
+		configItem := MIGConfiguration{InstanceGroupName: "instance-group-name", SpotPreemptionRateQuery: "preemptions:rate1h"}
+		scaler := NewMIGScaler(nil, map[string]MetricSource{"prometheus": &fakeMetricSource{requestRates: map[string]float64{"preemptions:rate1h": 250}}}, MIGScalerOptions{})
+
+		// act
+		overprovisionPercent := scaler.getSpotOverprovisionPercent(context.Background(), configItem)
+
+		assert.Equal(t, 100.0, overprovisionPercent)
+	})
+
+	t.Run("ReturnsSpotOverprovisionPercentIfQueryFails", func(t *testing.T) {
+
+		configItem := MIGConfiguration{InstanceGroupName: "instance-group-name", SpotOverprovisionPercent: 15, SpotPreemptionRateQuery: "preemptions:rate1h"}
+		scaler := NewMIGScaler(nil, map[string]MetricSource{"prometheus": &failingMetricSource{}}, MIGScalerOptions{})
+
+		// act
+		overprovisionPercent := scaler.getSpotOverprovisionPercent(context.Background(), configItem)
+
+		assert.Equal(t, 15.0, overprovisionPercent)
+	})
+}
+
+func TestOverprovisionForSpot(t *testing.T) {
+
+	t.Run("InflatesMinimumByPercentRoundedUp", func(t *testing.T) {
+
+		// act
+		minimums := []int{overprovisionForSpot(10, 10), overprovisionForSpot(12, 10), overprovisionForSpot(12, 0)}
+
+		assert.Equal(t, []int{11, 14, 12}, minimums)
+	})
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+
+	// register the database/sql drivers usable with metricSource sql
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+)
+
+// sqlDrivers are the database/sql driver names supported for sqlDriver
+var sqlDrivers = []string{"postgres", "mysql"}
+
+// SQLMetricSource retrieves request rates by running a query returning a single number against a sql database, so counters stored in postgres or mysql can be used without an exporter
+type SQLMetricSource struct {
+	mutex     sync.Mutex
+	databases map[string]*sql.DB
+}
+
+// NewSQLMetricSource returns a sql metric source that keeps a connection pool per driver and dsn
+func NewSQLMetricSource() *SQLMetricSource {
+	return &SQLMetricSource{
+		databases: map[string]*sql.DB{},
+	}
+}
+
+// GetRequestRate runs the request rate query against database sqlDsn with driver sqlDriver and returns the single value it selects
+func (s *SQLMetricSource) GetRequestRate(ctx context.Context, configItem MIGConfiguration) (requestRate float64, err error) {
+
+	db, err := s.getDatabase(configItem.SQLDriver, configItem.SQLDSN)
+	if err != nil {
+		return
+	}
+
+	var value sql.NullFloat64
+	err = db.QueryRowContext(ctx, configItem.RequestRateQuery).Scan(&value)
+	if err == sql.ErrNoRows {
+		return requestRate, MissingDataError{Reason: "Sql query returned no rows"}
+	}
+	if err != nil {
+		return requestRate, fmt.Errorf("Executing sql query failed: %v", err)
+	}
+	if !value.Valid {
+		return requestRate, MissingDataError{Reason: "Sql query returned null"}
+	}
+
+	return value.Float64, nil
+}
+
+func (s *SQLMetricSource) getDatabase(driver, dsn string) (*sql.DB, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := driver + " " + dsn
+	if db, ok := s.databases[key]; ok {
+		return db, nil
+	}
+
+	// sql.Open only validates its arguments, connections are established when querying
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("Opening %v database failed: %v", driver, err)
+	}
+	db.SetMaxOpenConns(2)
+	s.databases[key] = db
+
+	return db, nil
+}
+
+// validateSQLConfig checks the fields required for the sql metric source
+func validateSQLConfig(c *MIGConfiguration, addError func(field, message string)) {
+	requireRequestRateQuery(nil)(c, addError)
+
+	supportedDriver := false
+	for _, driver := range sqlDrivers {
+		if c.SQLDriver == driver {
+			supportedDriver = true
+		}
+	}
+	if !supportedDriver {
+		addError("sqlDriver", fmt.Sprintf("should be one of %v", sqlDrivers))
+	}
+	if c.SQLDSN == "" {
+		addError("sqlDsn", "is required for metricSource sql")
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSQLConfig(t *testing.T) {
+
+	t.Run("ReturnsNoErrorsForPostgresConfig", func(t *testing.T) {
+
+		configItem := MIGConfiguration{RequestRateQuery: "SELECT requests_per_second FROM capacity", SQLDriver: "postgres", SQLDSN: "postgres://scaler@db/capacity"}
+		fields := []string{}
+
+		// act
+		validateSQLConfig(&configItem, func(field, message string) {
+			fields = append(fields, field)
+		})
+
+		assert.Equal(t, 0, len(fields))
+	})
+
+	t.Run("ReturnsErrorsForUnsupportedDriverAndMissingDSN", func(t *testing.T) {
+
+		configItem := MIGConfiguration{RequestRateQuery: "SELECT 1", SQLDriver: "oracle"}
+		fields := []string{}
+
+		// act
+		validateSQLConfig(&configItem, func(field, message string) {
+			fields = append(fields, field)
+		})
+
+		assert.Equal(t, []string{"sqlDriver", "sqlDsn"}, fields)
+	})
+}
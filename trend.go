@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+)
+
+// TrendConfig returns the configuration to execute the trend query with; it inherits all fields of the managed instance group except its other queries
+func (c *MIGConfiguration) TrendConfig() MIGConfiguration {
+	trendConfig := *c
+	trendConfig.RequestRateQuery = c.TrendQuery
+	trendConfig.RequestRateQueryTemplate = ""
+	trendConfig.Queries = nil
+	trendConfig.QueryAggregation = ""
+	trendConfig.EvaluationWindows = nil
+	trendConfig.Fallbacks = nil
+	trendConfig.TrendQuery = ""
+	return trendConfig
+}
+
+// applyTrend executes the trend query, which predicts the request rate in the near future, for example with predict_linear, and returns the predicted request rate if it's higher than the current one, so instances are booted before the request rate actually gets there; the trend never lowers the request rate and failing trend queries are ignored
+func applyTrend(ctx context.Context, metricSources map[string]MetricSource, configItem MIGConfiguration, requestRate float64) float64 {
+
+	if configItem.TrendQuery == "" {
+		return requestRate
+	}
+
+	predictedRequestRate, err := getSingleRequestRate(ctx, metricSources, configItem.TrendConfig())
+	if err != nil {
+		log.Warn().Err(err).Msgf("Retrieving predicted request rate for mig %v failed, using current request rate", configItem.InstanceGroupName)
+		return requestRate
+	}
+
+	if predictedRequestRate <= requestRate {
+		return requestRate
+	}
+
+	log.Info().Msgf("Request rate for mig %v is rising, using predicted request rate %v instead of %v", configItem.InstanceGroupName, predictedRequestRate, requestRate)
+
+	return predictedRequestRate
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyTrend(t *testing.T) {
+
+	metricSources := map[string]MetricSource{
+		"prometheus": &fakeMetricSource{requestRates: map[string]float64{
+			"predict_linear(sum(rate(nginx_http_requests_total[5m]))[15m:1m], 300)": 240,
+		}},
+	}
+
+	t.Run("ReturnsPredictedRequestRateIfHigher", func(t *testing.T) {
+
+		configItem := MIGConfiguration{TrendQuery: "predict_linear(sum(rate(nginx_http_requests_total[5m]))[15m:1m], 300)"}
+
+		// act
+		requestRate := applyTrend(context.Background(), metricSources, configItem, 180)
+
+		assert.Equal(t, float64(240), requestRate)
+	})
+
+	t.Run("ReturnsCurrentRequestRateIfPredictedRequestRateIsLower", func(t *testing.T) {
+
+		configItem := MIGConfiguration{TrendQuery: "predict_linear(sum(rate(nginx_http_requests_total[5m]))[15m:1m], 300)"}
+
+		// act
+		requestRate := applyTrend(context.Background(), metricSources, configItem, 300)
+
+		assert.Equal(t, float64(300), requestRate)
+	})
+
+	t.Run("ReturnsCurrentRequestRateIfTrendQueryFails", func(t *testing.T) {
+
+		configItem := MIGConfiguration{TrendQuery: "predict_linear(sum(rate(nginx_http_requests_total[5m]))[15m:1m], 300)"}
+
+		// act
+		requestRate := applyTrend(context.Background(), map[string]MetricSource{"prometheus": &failingMetricSource{}}, configItem, 180)
+
+		assert.Equal(t, float64(180), requestRate)
+	})
+}
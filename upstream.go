@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+)
+
+// UpstreamConfig returns the configuration to retrieve the instance group manager of upstreamMig with; it's in the same project, and in the zone or region of the managed instance group unless upstreamGcloudZone or upstreamGcloudRegion is set
+func (c *MIGConfiguration) UpstreamConfig() MIGConfiguration {
+
+	upstreamConfig := MIGConfiguration{
+		GCloudProject:     c.GCloudProject,
+		GCloudZone:        c.GCloudZone,
+		GCloudRegion:      c.GCloudRegion,
+		InstanceGroupName: c.UpstreamMIG,
+	}
+	if c.UpstreamGCloudZone != "" || c.UpstreamGCloudRegion != "" {
+		upstreamConfig.GCloudZone, upstreamConfig.GCloudRegion = c.UpstreamGCloudZone, c.UpstreamGCloudRegion
+	}
+
+	return upstreamConfig
+}
+
+// getUpstreamTargetSize retrieves the current target size of upstreamMig from the compute api, so a downstream mig grows in lockstep with it even if its own request rate lags behind
+func (s *MIGScaler) getUpstreamTargetSize(ctx context.Context, configItem MIGConfiguration) (float64, error) {
+
+	ctx, cancel := withTimeout(ctx, s.options.ComputeTimeout)
+	defer cancel()
+
+	instanceGroupManager, err := s.getInstanceGroupManager(ctx, configItem.UpstreamConfig())
+	if err != nil {
+		return 0, err
+	}
+
+	return float64(instanceGroupManager.TargetSize), nil
+}
+
+// validateUpstreamMIG checks whether upstreamMig has a ratio, isn't combined with followMig or a query of its own, and has at most one of upstreamGcloudZone and upstreamGcloudRegion
+func (c *MIGConfiguration) validateUpstreamMIG(addError func(field, message string)) {
+
+	if c.UpstreamMIG == "" {
+		if c.UpstreamGCloudZone != "" || c.UpstreamGCloudRegion != "" {
+			addError("upstreamMig", "is required for upstreamGcloudZone and upstreamGcloudRegion")
+		}
+		return
+	}
+
+	if c.FollowMIG != "" {
+		addError("upstreamMig", "upstreamMig and followMig are mutually exclusive")
+	} else if c.Ratio <= 0 {
+		addError("ratio", "should be larger than 0")
+	}
+	if len(c.Queries) > 0 {
+		addError("upstreamMig", "upstreamMig and queries are mutually exclusive")
+	}
+	if c.UpstreamGCloudZone != "" && c.UpstreamGCloudRegion != "" {
+		addError("upstreamGcloudZone", "upstreamGcloudZone and upstreamGcloudRegion are mutually exclusive")
+	}
+
+	upstreamConfig := c.UpstreamConfig()
+	if upstreamConfig.InstanceGroupName == c.InstanceGroupName && upstreamConfig.GCloudZone == c.GCloudZone && upstreamConfig.GCloudRegion == c.GCloudRegion {
+		addError("upstreamMig", "can't be the mig itself")
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpstreamConfig(t *testing.T) {
+
+	t.Run("ReturnsUpstreamMIGInSameLocation", func(t *testing.T) {
+
+		configItem := MIGConfiguration{GCloudProject: "project-id", GCloudRegion: "europe-west1", InstanceGroupName: "backend", UpstreamMIG: "frontend"}
+
+		// act
+		upstreamConfig := configItem.UpstreamConfig()
+
+		assert.Equal(t, MIGConfiguration{GCloudProject: "project-id", GCloudRegion: "europe-west1", InstanceGroupName: "frontend"}, upstreamConfig)
+	})
+
+	t.Run("ReturnsUpstreamMIGInUpstreamZone", func(t *testing.T) {
+
+		configItem := MIGConfiguration{GCloudProject: "project-id", GCloudRegion: "europe-west1", InstanceGroupName: "backend", UpstreamMIG: "frontend", UpstreamGCloudZone: "europe-west4-a"}
+
+		// act
+		upstreamConfig := configItem.UpstreamConfig()
+
+		assert.Equal(t, MIGConfiguration{GCloudProject: "project-id", GCloudZone: "europe-west4-a", InstanceGroupName: "frontend"}, upstreamConfig)
+	})
+}
+
+func TestCalculateTargetMinimumNumberOfInstancesForUpstreamMIG(t *testing.T) {
+
+	t.Run("ReturnsRatioOfUpstreamTargetSizeRoundedUp", func(t *testing.T) {
+
+		configItem := MIGConfiguration{InstanceGroupName: "backend", UpstreamMIG: "frontend", Ratio: 0.5, MinimumNumberOfInstances: 2}
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+
+		// act
+		minimumNumberOfInstances, err := scaler.calculateTargetMinimumNumberOfInstances(configItem, 15, 4, time.Now())
+
+		assert.Nil(t, err)
+		assert.Equal(t, 8, minimumNumberOfInstances)
+	})
+}
+
+func TestValidateUpstreamMIG(t *testing.T) {
+
+	validConfig := MIGConfiguration{
+		GCloudProject:     "project-id",
+		GCloudRegion:      "europe-west1",
+		InstanceGroupName: "backend",
+		UpstreamMIG:       "frontend",
+		Ratio:             0.5,
+	}
+
+	t.Run("ReturnsNilForUpstreamMIGWithoutQuery", func(t *testing.T) {
+
+		// act
+		err := ValidateMIGConfigs([]MIGConfiguration{validConfig})
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("ReturnsErrorForUpstreamMIGWithoutRatio", func(t *testing.T) {
+
+		invalidConfig := validConfig
+		invalidConfig.Ratio = 0
+
+		// act
+		err := ValidateMIGConfigs([]MIGConfiguration{invalidConfig})
+
+		if assert.IsType(t, ValidationErrors{}, err) {
+			assert.Equal(t, "ratio", err.(ValidationErrors)[0].Field)
+		}
+	})
+
+	t.Run("ReturnsErrorForUpstreamMIGThatIsTheMIGItself", func(t *testing.T) {
+
+		invalidConfig := validConfig
+		invalidConfig.UpstreamMIG = "backend"
+
+		// act
+		err := ValidateMIGConfigs([]MIGConfiguration{invalidConfig})
+
+		if assert.IsType(t, ValidationErrors{}, err) {
+			assert.Equal(t, "upstreamMig", err.(ValidationErrors)[0].Field)
+			assert.Equal(t, "can't be the mig itself", err.(ValidationErrors)[0].Message)
+		}
+	})
+
+	t.Run("ReturnsErrorForUpstreamMIGWithFollowMIG", func(t *testing.T) {
+
+		invalidConfig := validConfig
+		invalidConfig.FollowMIG = "frontend"
+
+		// act
+		err := ValidateMIGConfigs([]MIGConfiguration{{InstanceGroupName: "frontend", GCloudProject: "project-id", GCloudRegion: "europe-west1", RequestRateQuery: "sum(up)", NumberOfRequestsPerInstance: 5}, invalidConfig})
+
+		if assert.IsType(t, ValidationErrors{}, err) {
+			assert.Equal(t, "upstreamMig and followMig are mutually exclusive", err.(ValidationErrors)[0].Message)
+		}
+	})
+}
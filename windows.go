@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+)
+
+// WindowConfigs returns the configuration for each of the evaluation windows, with the request rate query template rendered for that window
+func (c *MIGConfiguration) WindowConfigs() (windowConfigs []MIGConfiguration, err error) {
+	for _, window := range c.EvaluationWindows {
+		windowConfig := *c
+		windowConfig.EvaluationWindows = nil
+		windowConfig.RequestRateQuery = ""
+		if err = windowConfig.renderRequestRateQuery(window); err != nil {
+			return
+		}
+		windowConfigs = append(windowConfigs, windowConfig)
+	}
+	return
+}
+
+// getMultiWindowRequestRate executes the request rate query for every evaluation window and returns the highest value, so a short window reacts quickly to spikes while a long window holds on to capacity after them
+func getMultiWindowRequestRate(ctx context.Context, metricSources map[string]MetricSource, configItem MIGConfiguration) (float64, error) {
+
+	windowConfigs, err := configItem.WindowConfigs()
+	if err != nil {
+		return 0, err
+	}
+
+	requestRates := []float64{}
+	for i, windowConfig := range windowConfigs {
+		requestRate, err := getRequestRateWithFallbacks(ctx, metricSources, windowConfig)
+		if err != nil {
+			return 0, fmt.Errorf("Window %v failed: %w", configItem.EvaluationWindows[i], err)
+		}
+		log.Debug().Msgf("Request rate for mig %v over window %v is %v", configItem.InstanceGroupName, configItem.EvaluationWindows[i], requestRate)
+		requestRates = append(requestRates, requestRate)
+	}
+
+	return AggregateRequestRates(requestRates, maxQueryAggregation)
+}
+
+// validateEvaluationWindows checks that evaluation windows have a query template to render them into
+func (c *MIGConfiguration) validateEvaluationWindows(addError func(field, message string)) {
+
+	if len(c.EvaluationWindows) == 0 {
+		return
+	}
+	if c.RequestRateQueryTemplate == "" {
+		addError("evaluationWindows", "requires requestRateQueryTemplate")
+	}
+	if len(c.Queries) > 0 {
+		addError("evaluationWindows", "can't be combined with queries")
+	}
+	for i, window := range c.EvaluationWindows {
+		if window == "" {
+			addError(fmt.Sprintf("evaluationWindows[%v]", i), "should not be empty")
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetMultiWindowRequestRate(t *testing.T) {
+
+	metricSources := map[string]MetricSource{
+		"prometheus": &fakeMetricSource{requestRates: map[string]float64{
+			"sum(rate(nginx_http_requests_total[2m]))":  180,
+			"sum(rate(nginx_http_requests_total[30m]))": 120,
+		}},
+	}
+
+	t.Run("ReturnsHighestRequestRateOfAllWindows", func(t *testing.T) {
+
+		configItem := MIGConfiguration{
+			RequestRateQueryTemplate: "sum(rate(nginx_http_requests_total[{{ .Window }}]))",
+			EvaluationWindows:        []string{"2m", "30m"},
+		}
+
+		// act
+		requestRate, err := getMultiWindowRequestRate(context.Background(), metricSources, configItem)
+
+		assert.Nil(t, err)
+		assert.Equal(t, float64(180), requestRate)
+	})
+
+	t.Run("ReturnsErrorIfAnyWindowFails", func(t *testing.T) {
+
+		configItem := MIGConfiguration{
+			RequestRateQueryTemplate: "sum(rate(nginx_http_requests_total[{{ .Window }}]))",
+			EvaluationWindows:        []string{"2m", "30m"},
+		}
+
+		// act
+		_, err := getMultiWindowRequestRate(context.Background(), map[string]MetricSource{"prometheus": &failingMetricSource{}}, configItem)
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestRenderRequestRateQueryWithEvaluationWindows(t *testing.T) {
+
+	t.Run("RendersTemplateForFirstWindow", func(t *testing.T) {
+
+		configItem := MIGConfiguration{
+			RequestRateQueryTemplate: "sum(rate(nginx_http_requests_total[{{ .Window }}]))",
+			EvaluationWindows:        []string{"2m", "30m"},
+		}
+
+		// act
+		err := configItem.RenderRequestRateQuery()
+
+		assert.Nil(t, err)
+		assert.Equal(t, "sum(rate(nginx_http_requests_total[2m]))", configItem.RequestRateQuery)
+	})
+}
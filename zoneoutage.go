@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	compute "google.golang.org/api/compute/v1"
+)
+
+// getUnavailableZones returns the zones of a regional mig with zoneOutageCompensation that have been unavailable in this and the previous iteration, and the number of zones the mig distributes its instances over
+func (s *MIGScaler) getUnavailableZones(ctx context.Context, configItem MIGConfiguration, targetSize int64) (unavailableZones []string, numberOfZones int) {
+
+	if !configItem.ZoneOutageCompensation || configItem.GCloudRegion == "" {
+		return nil, 0
+	}
+
+	computeClient, err := s.computeClientFor(ctx, configItem)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Creating client for mig %v failed, skipping zone outage detection", configItem.InstanceGroupName)
+		return nil, 0
+	}
+
+	zones, err := computeClient.GetDistributionZones(ctx, configItem)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Retrieving distribution zones of mig %v failed, skipping zone outage detection", configItem.InstanceGroupName)
+		s.cache.invalidateOnNotFound(configItem, err)
+		return nil, 0
+	}
+
+	instances, err := computeClient.ListManagedInstances(ctx, configItem)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Retrieving instances of mig %v failed, skipping zone outage detection", configItem.InstanceGroupName)
+		s.cache.invalidateOnNotFound(configItem, err)
+		return nil, 0
+	}
+
+	instancesPerZone := InstancesPerZone(zones, instances)
+	candidateZones := UnavailableZones(instancesPerZone, targetSize)
+
+	// instances that are still being created after a scale up aren't running yet either, so only zones that stay unavailable for two iterations count
+	s.withState(configItem, func(state *migState) {
+		unavailableZones = ConfirmedUnavailableZones(state.candidateUnavailableZones, candidateZones)
+		state.candidateUnavailableZones = candidateZones
+	})
+
+	return unavailableZones, len(instancesPerZone)
+}
+
+// ZoneInstances are the number of running and not running instances of a mig in a zone
+type ZoneInstances struct {
+	Running    int
+	NotRunning int
+}
+
+// InstancesPerZone counts the running and not running instances of a regional mig in each zone of its distribution policy
+func InstancesPerZone(zones []string, instances []*compute.ManagedInstance) map[string]ZoneInstances {
+
+	instancesPerZone := map[string]ZoneInstances{}
+	for _, zone := range zones {
+		instancesPerZone[zone] = ZoneInstances{}
+	}
+
+	for _, instance := range instances {
+		zone := zoneOfInstance(instance.Instance)
+		if zone == "" {
+			continue
+		}
+		zoneInstances := instancesPerZone[zone]
+		if instance.InstanceStatus == "RUNNING" {
+			zoneInstances.Running++
+		} else {
+			zoneInstances.NotRunning++
+		}
+		instancesPerZone[zone] = zoneInstances
+	}
+
+	return instancesPerZone
+}
+
+// UnavailableZones returns the zones without running instances, sorted by name, that either have instances that aren't running or should have instances because the target size is at least the number of zones; a mig smaller than its number of zones always leaves a zone empty, and if no zone has running instances the mig is just empty, so then none of them are unavailable
+func UnavailableZones(instancesPerZone map[string]ZoneInstances, targetSize int64) (unavailableZones []string) {
+
+	anyRunning := false
+	for zone, zoneInstances := range instancesPerZone {
+		if zoneInstances.Running > 0 {
+			anyRunning = true
+			continue
+		}
+		if zoneInstances.NotRunning > 0 || targetSize >= int64(len(instancesPerZone)) {
+			unavailableZones = append(unavailableZones, zone)
+		}
+	}
+	if !anyRunning {
+		return nil
+	}
+
+	sort.Strings(unavailableZones)
+
+	return
+}
+
+// ConfirmedUnavailableZones returns the zones that are unavailable in both the previous and the current iteration
+func ConfirmedUnavailableZones(previous, current []string) (confirmedZones []string) {
+	for _, zone := range current {
+		for _, previousZone := range previous {
+			if zone == previousZone {
+				confirmedZones = append(confirmedZones, zone)
+				break
+			}
+		}
+	}
+	return
+}
+
+// compensateForZoneOutage raises the minimum number of instances so the available zones together carry the capacity of all zones
+func compensateForZoneOutage(minimumNumberOfInstances int, numberOfUnavailableZones, numberOfZones int) int {
+	if numberOfUnavailableZones == 0 || numberOfUnavailableZones >= numberOfZones {
+		return minimumNumberOfInstances
+	}
+	return int(math.Ceil(float64(minimumNumberOfInstances) * float64(numberOfZones) / float64(numberOfZones-numberOfUnavailableZones)))
+}
+
+// reportZoneOutage exports whether zone outage compensation is active for the mig, and logs and records a kubernetes event when it starts, changes or ends
+func (s *MIGScaler) reportZoneOutage(configItem MIGConfiguration, unavailableZones []string) {
+
+	if !configItem.ZoneOutageCompensation {
+		return
+	}
+
+	var previous []string
+	s.withState(configItem, func(state *migState) {
+		previous, state.unavailableZones = state.unavailableZones, unavailableZones
+	})
+
+	active := 0.0
+	if len(unavailableZones) > 0 {
+		active = 1.0
+	}
+	zoneOutageCompensationVector.WithLabelValues(configItem.InstanceGroupName).Set(active)
+
+	if reflect.DeepEqual(previous, unavailableZones) {
+		return
+	}
+	if len(unavailableZones) > 0 {
+		message := fmt.Sprintf("Zones %v of mig %v have no running instances, compensating the minimum number of instances in the other zones", strings.Join(unavailableZones, ", "), configItem.InstanceGroupName)
+		log.Warn().Strs("unavailableZones", unavailableZones).Msg(message)
+		if s.options.EventRecorder != nil {
+			s.options.EventRecorder.Record(warningKubernetesEventType, "ZoneOutageCompensation", message)
+		}
+	} else {
+		message := fmt.Sprintf("All zones of mig %v have running instances again, stopped compensating for zone outage", configItem.InstanceGroupName)
+		log.Info().Msg(message)
+		if s.options.EventRecorder != nil {
+			s.options.EventRecorder.Record(normalKubernetesEventType, "ZoneOutageCompensationEnded", message)
+		}
+	}
+}
+
+// zoneOfInstance returns the zone from the url of an instance, like https://www.googleapis.com/compute/beta/projects/p/zones/europe-west1-b/instances/i
+func zoneOfInstance(instanceURL string) string {
+	parts := strings.Split(instanceURL, "/")
+	for i := 0; i < len(parts)-1; i++ {
+		if parts[i] == "zones" {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
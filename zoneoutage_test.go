@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	compute "google.golang.org/api/compute/v1"
+)
+
+func TestInstancesPerZone(t *testing.T) {
+
+	t.Run("CountsRunningAndNotRunningInstancesInEveryZoneOfDistributionPolicy", func(t *testing.T) {
+
+		zones := []string{"europe-west1-b", "europe-west1-c", "europe-west1-d"}
+		instances := []*compute.ManagedInstance{
+			{Instance: "https://www.googleapis.com/compute/v1/projects/project-id/zones/europe-west1-b/instances/web-1", InstanceStatus: "RUNNING"},
+			{Instance: "https://www.googleapis.com/compute/v1/projects/project-id/zones/europe-west1-b/instances/web-2", InstanceStatus: "RUNNING"},
+			{Instance: "https://www.googleapis.com/compute/v1/projects/project-id/zones/europe-west1-c/instances/web-3", InstanceStatus: "RUNNING"},
+			{Instance: "https://www.googleapis.com/compute/v1/projects/project-id/zones/europe-west1-d/instances/web-4", InstanceStatus: "PROVISIONING"},
+		}
+
+		// act
+		instancesPerZone := InstancesPerZone(zones, instances)
+
+		assert.Equal(t, map[string]ZoneInstances{"europe-west1-b": {Running: 2}, "europe-west1-c": {Running: 1}, "europe-west1-d": {NotRunning: 1}}, instancesPerZone)
+	})
+}
+
+func TestUnavailableZones(t *testing.T) {
+
+	t.Run("ReturnsZonesWithOnlyNotRunningInstances", func(t *testing.T) {
+
+		// act
+		unavailableZones := UnavailableZones(map[string]ZoneInstances{"europe-west1-b": {Running: 2}, "europe-west1-c": {NotRunning: 1}, "europe-west1-d": {NotRunning: 2}}, 5)
+
+		assert.Equal(t, []string{"europe-west1-c", "europe-west1-d"}, unavailableZones)
+	})
+
+	t.Run("ReturnsEmptyZonesIfTargetSizeIsAtLeastNumberOfZones", func(t *testing.T) {
+
+		// act
+		unavailableZones := UnavailableZones(map[string]ZoneInstances{"europe-west1-b": {Running: 2}, "europe-west1-c": {Running: 1}, "europe-west1-d": {}}, 3)
+
+		assert.Equal(t, []string{"europe-west1-d"}, unavailableZones)
+	})
+
+	t.Run("ReturnsNoEmptyZonesIfTargetSizeIsBelowNumberOfZones", func(t *testing.T) {
+
+		// act
+		unavailableZones := UnavailableZones(map[string]ZoneInstances{"europe-west1-b": {Running: 1}, "europe-west1-c": {Running: 1}, "europe-west1-d": {}}, 2)
+
+		assert.Nil(t, unavailableZones)
+	})
+
+	t.Run("ReturnsNoZonesIfNoZoneHasRunningInstances", func(t *testing.T) {
+
+		// act
+		unavailableZones := UnavailableZones(map[string]ZoneInstances{"europe-west1-b": {NotRunning: 1}, "europe-west1-c": {NotRunning: 1}, "europe-west1-d": {}}, 3)
+
+		assert.Nil(t, unavailableZones)
+	})
+}
+
+func TestConfirmedUnavailableZones(t *testing.T) {
+
+	t.Run("ReturnsZonesUnavailableInPreviousAndCurrentIteration", func(t *testing.T) {
+
+		// act
+		confirmedZones := ConfirmedUnavailableZones([]string{"europe-west1-c"}, []string{"europe-west1-c", "europe-west1-d"})
+
+		assert.Equal(t, []string{"europe-west1-c"}, confirmedZones)
+	})
+
+	t.Run("ReturnsNoZonesInFirstIterationTheyAreUnavailable", func(t *testing.T) {
+
+		// act
+		confirmedZones := ConfirmedUnavailableZones(nil, []string{"europe-west1-d"})
+
+		assert.Nil(t, confirmedZones)
+	})
+}
+
+func TestCompensateForZoneOutage(t *testing.T) {
+
+	t.Run("RaisesMinimumSoAvailableZonesCarryFullCapacity", func(t *testing.T) {
+
+		// act
+		minimums := []int{compensateForZoneOutage(10, 1, 3), compensateForZoneOutage(10, 2, 3), compensateForZoneOutage(10, 0, 3), compensateForZoneOutage(10, 3, 3)}
+
+		assert.Equal(t, []int{15, 30, 10, 10}, minimums)
+	})
+}
+
+func TestReportZoneOutage(t *testing.T) {
+
+	t.Run("SetsGaugeWhileCompensationIsActive", func(t *testing.T) {
+
+		configItem := MIGConfiguration{InstanceGroupName: "zone-outage", GCloudRegion: "europe-west1", ZoneOutageCompensation: true}
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{})
+
+		// act
+		scaler.reportZoneOutage(configItem, []string{"europe-west1-c"})
+
+		assert.Equal(t, 1.0, testutil.ToFloat64(zoneOutageCompensationVector.WithLabelValues("zone-outage")))
+		scaler.reportZoneOutage(configItem, nil)
+		assert.Equal(t, 0.0, testutil.ToFloat64(zoneOutageCompensationVector.WithLabelValues("zone-outage")))
+	})
+
+	t.Run("RecordsKubernetesEventsWhenCompensationStartsAndEnds", func(t *testing.T) {
+
+		var reasons []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var event kubernetesEvent
+			json.NewDecoder(r.Body).Decode(&event)
+			reasons = append(reasons, event.Reason)
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer server.Close()
+
+		configItem := MIGConfiguration{InstanceGroupName: "zone-outage-events", GCloudRegion: "europe-west1", ZoneOutageCompensation: true}
+		scaler := NewMIGScaler(nil, nil, MIGScalerOptions{
+			EventRecorder: &KubernetesEventRecorder{client: server.Client(), apiURL: server.URL, namespace: "estafette", podName: "estafette-gcloud-mig-scaler-0"},
+		})
+
+		// act
+		scaler.reportZoneOutage(configItem, []string{"europe-west1-c"})
+		scaler.reportZoneOutage(configItem, []string{"europe-west1-c"})
+		scaler.reportZoneOutage(configItem, nil)
+
+		assert.Equal(t, []string{"ZoneOutageCompensation", "ZoneOutageCompensationEnded"}, reasons)
+	})
+}